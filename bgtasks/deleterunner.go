@@ -35,6 +35,19 @@ func (jobContext *Context) DeleteRunnerTask(job *work.Job) error {
 
 	// stop all workspaces and set runner as null
 	for _, w := range workspaces {
+		// the stop is attributed to the admin who deleted the runner
+		if w.Status != models.WorkspaceStatusStopped {
+			previousStatus := w.Status
+			w.Status = models.WorkspaceStatusStopping
+			models.CreateWorkspaceEvent(
+				w,
+				previousStatus,
+				models.WorkspaceEventSourceAdmin,
+				nil,
+				"Workspace stopped because its runner is being deleted",
+			)
+		}
+
 		err := StopWorkspace(&w, true)
 		if err != nil {
 			runner.DeletionInProgress = false
@@ -1,17 +1,22 @@
 package bgtasks
 
 import (
+	"fmt"
+
 	"github.com/gocraft/work"
 	"gitlab.com/codebox4073715/codebox/db/models"
 )
 
 /*
 Background task that deletes a user,
-this task deletes the user and all his workspaces
+this task deletes the user and all his workspaces.
+If transfer_to is set the workspaces are transferred
+to that user instead of being deleted
 */
 func (jobContext *Context) DeleteUserTask(job *work.Job) error {
 	// TODO: send emails to admin for errors
 	userEmail := job.ArgString("user_email")
+	transferTo, _ := job.Args["transfer_to"].(string)
 
 	user, err := models.RetrieveUserByEmail(userEmail)
 	if err != nil {
@@ -28,12 +33,37 @@ func (jobContext *Context) DeleteUserTask(job *work.Job) error {
 		return nil
 	}
 
-	for _, w := range workspaces {
-		err := RemoveWorkspace(w, true)
-		if err != nil {
+	if transferTo != "" {
+		owner, err := models.RetrieveUserByEmail(transferTo)
+		if err != nil || owner == nil {
 			user.DeletionInProgress = false
 			models.UpdateUser(user)
 			// TODO: log error
+			return nil
+		}
+
+		for _, w := range workspaces {
+			warnings, err := TransferWorkspace(&w, *owner, nil)
+			if err != nil {
+				// the workspace would be deleted with the user
+				user.DeletionInProgress = false
+				models.UpdateUser(user)
+				w.AppendLogs(fmt.Sprintf("failed to transfer the workspace, %s", err.Error()))
+				return nil
+			}
+
+			for _, warning := range warnings {
+				w.AppendLogs(warning)
+			}
+		}
+	} else {
+		for _, w := range workspaces {
+			err := RemoveWorkspace(w, true)
+			if err != nil {
+				user.DeletionInProgress = false
+				models.UpdateUser(user)
+				// TODO: log error
+			}
 		}
 	}
 
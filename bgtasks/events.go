@@ -0,0 +1,29 @@
+package bgtasks
+
+import (
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+/*
+Record the completion of a transition performed by a background task,
+source and actor are inherited from the event that started the transition
+*/
+func recordWorkspaceTransition(workspace *models.Workspace, fromStatus string) {
+	message := ""
+	switch workspace.Status {
+	case models.WorkspaceStatusRunning:
+		message = "Workspace is running"
+	case models.WorkspaceStatusStopped:
+		message = "Workspace has been stopped"
+	case models.WorkspaceStatusStarting:
+		message = "Workspace is starting"
+	case models.WorkspaceStatusStopping:
+		message = "Workspace is stopping"
+	case models.WorkspaceStatusDeleting:
+		message = "Workspace is being deleted"
+	case models.WorkspaceStatusError:
+		message = "Workspace moved to error"
+	}
+
+	models.CreateWorkspaceTransitionEvent(*workspace, fromStatus, message)
+}
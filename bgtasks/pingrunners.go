@@ -11,6 +11,7 @@ import (
 
 /*
 Ping all runners to check if they are online and get their version
+and the capabilities they advertise
 */
 func (jobContext *Context) PingRunnersTask(job *work.Job) error {
 	runners, err := models.ListRunners(-1, 0)
@@ -22,10 +23,11 @@ func (jobContext *Context) PingRunnersTask(job *work.Job) error {
 	for _, runner := range runners {
 		ri := runnerinterface.RunnerInterface{Runner: &runner}
 
-		version, err := ri.GetRunnerVersion()
+		info, err := ri.GetRunnerInfo()
 		if err == nil {
 			now := time.Now()
-			runner.Version = version
+			runner.Version = info.Version
+			runner.Capabilities = info.Capabilities
 			runner.LastContact = &now
 			dbconn.DB.Save(&runner)
 		}
@@ -0,0 +1,245 @@
+package bgtasks
+
+import (
+	"fmt"
+
+	"github.com/gocraft/work"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/runnerinterface"
+)
+
+/*
+Reconcile the state of the workspaces stored in the db with
+the state reported by the runners, for all online runners
+*/
+func (jobContext *Context) ReconcileRunnersTask(job *work.Job) error {
+	runners, err := models.ListRunners(-1, 0)
+	if err != nil {
+		// TODO: log error
+		return nil
+	}
+
+	for _, runner := range runners {
+		if !runner.IsOnline() || runner.DeletionInProgress {
+			continue
+		}
+		ReconcileRunner(runner)
+	}
+
+	return nil
+}
+
+/*
+Reconcile the state of the workspaces of a single runner,
+this task is enqueued on demand by administrators
+*/
+func (jobContext *Context) ReconcileRunnerTask(job *work.Job) error {
+	runnerId := job.ArgInt64("runner_id")
+
+	runner, err := models.RetrieveRunnerByID(uint(runnerId))
+	if err != nil {
+		// TODO: log error
+		return nil
+	}
+
+	if runner == nil || runner.DeletionInProgress {
+		return nil
+	}
+
+	ReconcileRunner(*runner)
+	return nil
+}
+
+/*
+ReconcileRunner fetches the details of every workspace on the runner and
+fixes status, containers and ports stored in the db.
+Workspaces in a transitional status (starting, stopping, deleting) are skipped,
+they are managed by the tasks that are performing the transition.
+Every correction is written to the logs of the workspace
+*/
+func ReconcileRunner(runner models.Runner) {
+	workspaces, err := models.ListWorkspacesByRunner(runner)
+	if err != nil {
+		// TODO: log error
+		return
+	}
+
+	ri := runnerinterface.RunnerInterface{
+		Runner: &runner,
+	}
+
+	for _, workspace := range workspaces {
+		if workspace.Status != models.WorkspaceStatusRunning &&
+			workspace.Status != models.WorkspaceStatusStopped &&
+			workspace.Status != models.WorkspaceStatusError {
+			continue
+		}
+
+		details, err := ri.GetWorkspaceDetails(&workspace)
+		if err != nil {
+			continue
+		}
+
+		reconcileWorkspace(&workspace, &ri, details)
+	}
+}
+
+func reconcileWorkspace(
+	workspace *models.Workspace,
+	ri *runnerinterface.RunnerInterface,
+	details runnerinterface.RunnerWorkspaceStatusResponse,
+) {
+	// the runner is performing a transition, nothing to fix for now
+	if details.Status != models.WorkspaceStatusRunning &&
+		details.Status != models.WorkspaceStatusStopped &&
+		details.Status != models.WorkspaceStatusError {
+		return
+	}
+
+	// the status may have been changed while waiting for the runner
+	status, err := models.RetrieveWorkspaceStatus(workspace.ID)
+	if err != nil || status != workspace.Status {
+		return
+	}
+
+	if details.Status != workspace.Status {
+		workspace.AppendLogs(
+			fmt.Sprintf(
+				"[reconciliation] status changed from '%s' to '%s'",
+				workspace.Status,
+				details.Status,
+			),
+		)
+
+		if err := dbconn.DB.
+			Model(&models.Workspace{}).
+			Where("id = ?", workspace.ID).
+			UpdateColumn("status", details.Status).Error; err != nil {
+			return
+		}
+		previousStatus := workspace.Status
+		workspace.Status = details.Status
+		models.CreateWorkspaceEvent(
+			*workspace,
+			previousStatus,
+			models.WorkspaceEventSourceReconciler,
+			nil,
+			"Status changed on the runner",
+		)
+	}
+
+	containers, err := models.ListWorkspaceContainersByWorkspace(*workspace)
+	if err != nil {
+		return
+	}
+
+	// a stopped workspace has no containers
+	runnerContainers := details.Containers
+	if details.Status == models.WorkspaceStatusStopped {
+		runnerContainers = []runnerinterface.RunnerContainer{}
+	}
+
+	runnerContainersByName := map[string]runnerinterface.RunnerContainer{}
+	for _, c := range runnerContainers {
+		runnerContainersByName[c.Name] = c
+	}
+
+	// remove containers that no longer exist on the runner
+	existingContainers := map[string]models.WorkspaceContainer{}
+	for _, container := range containers {
+		if _, ok := runnerContainersByName[container.ContainerName]; !ok {
+			workspace.AppendLogs(
+				fmt.Sprintf("[reconciliation] removed container '%s'", container.ContainerName),
+			)
+			models.DeleteWorkspaceContainer(container)
+			continue
+		}
+		existingContainers[container.ContainerName] = container
+	}
+
+	for _, c := range runnerContainers {
+		container, ok := existingContainers[c.Name]
+		if !ok {
+			workspace.AppendLogs(
+				fmt.Sprintf("[reconciliation] added container '%s'", c.Name),
+			)
+			createWorkspaceContainer(workspace, ri, c)
+			continue
+		}
+
+		// the container has been recreated on the runner
+		if container.ContainerID != c.ID || container.ContainerImage != c.Image {
+			workspace.AppendLogs(
+				fmt.Sprintf("[reconciliation] updated container '%s'", c.Name),
+			)
+			container.ContainerID = c.ID
+			container.ContainerImage = c.Image
+			container.ContainerUserName = c.ContainerUserName
+			container.WorkspacePath = c.WorkspacePath
+			container.ResourceLimits = c.GetResourceLimits()
+			dbconn.DB.Save(&container)
+		}
+
+		reconcileContainerPorts(workspace, container, c)
+	}
+}
+
+/*
+Add the ports exposed by the runner that are missing in the db and update the
+service name of the existing ones. Ports that are not reported by the runner are
+kept since they may have been added by the user, and the public flag is never
+changed since the user may have toggled it
+*/
+func reconcileContainerPorts(
+	workspace *models.Workspace,
+	container models.WorkspaceContainer,
+	c runnerinterface.RunnerContainer,
+) {
+	ports, err := models.ListContainerPortsByWorkspaceContainer(container)
+	if err != nil {
+		return
+	}
+
+	existingPorts := map[uint]models.WorkspaceContainerPort{}
+	for _, port := range ports {
+		existingPorts[port.PortNumber] = port
+	}
+
+	for _, p := range c.ExposedPorts {
+		if port, ok := existingPorts[uint(p.PortNumber)]; ok {
+			if p.ServiceName == "" || port.ServiceName == p.ServiceName {
+				continue
+			}
+
+			workspace.AppendLogs(
+				fmt.Sprintf(
+					"[reconciliation] updated port %d of container '%s'",
+					p.PortNumber,
+					container.ContainerName,
+				),
+			)
+			dbconn.DB.
+				Model(&models.WorkspaceContainerPort{}).
+				Where("id = ?", port.ID).
+				UpdateColumn("service_name", p.ServiceName)
+			continue
+		}
+
+		workspace.AppendLogs(
+			fmt.Sprintf(
+				"[reconciliation] added port %d to container '%s'",
+				p.PortNumber,
+				container.ContainerName,
+			),
+		)
+
+		port := models.WorkspaceContainerPort{
+			ContainerID: container.ID,
+			ServiceName: p.ServiceName,
+			PortNumber:  uint(p.PortNumber),
+			Public:      p.Public,
+		}
+		dbconn.DB.Create(&port)
+	}
+}
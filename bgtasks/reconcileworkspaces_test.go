@@ -0,0 +1,133 @@
+package bgtasks_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/bgtasks"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/runnerinterface"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+// creates an online runner reachable at the url of the mock server
+func createTestRunner(t *testing.T, name string, url string) *models.Runner {
+	runner, err := models.CreateRunner(name, "docker", true, url)
+	if err != nil {
+		t.Fatalf("Failed to create runner: '%s'", err)
+	}
+
+	now := time.Now()
+	runner.Port = 1
+	runner.LastContact = &now
+	if err := models.UpdateRunner(*runner); err != nil {
+		t.Fatalf("Failed to update runner: '%s'", err)
+	}
+	return runner
+}
+
+// creates a workspace of user1 on the runner with the given status
+func createTestWorkspace(t *testing.T, runner *models.Runner, status string) *models.Workspace {
+	user, err := models.RetrieveUserByEmail("user1@user.com")
+	if err != nil || user == nil {
+		t.Fatalf("Failed to retrieve user: '%s'", err)
+	}
+
+	gitSource, err := models.CreateGitWorkspaceSource("https://example.com/repo.git", "main", "docker-compose.yml")
+	if err != nil {
+		t.Fatalf("Failed to create git source: '%s'", err)
+	}
+
+	workspace, err := models.CreateWorkspace(
+		"test-workspace",
+		user,
+		"docker_compose",
+		runner,
+		models.WorkspaceConfigSourceGit,
+		nil,
+		gitSource,
+		[]string{},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create workspace: '%s'", err)
+	}
+
+	workspace.Status = status
+	if err := dbconn.DB.Save(workspace).Error; err != nil {
+		t.Fatalf("Failed to update workspace: '%s'", err)
+	}
+	return workspace
+}
+
+/*
+The reconciliation adds the ports exposed by the runner and updates
+their service name, the ports added by the user and the public flag are kept
+*/
+func TestReconcileContainerPorts(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		details := runnerinterface.RunnerWorkspaceStatusResponse{
+			Status: models.WorkspaceStatusRunning,
+			Containers: []runnerinterface.RunnerContainer{
+				{
+					ID:    "container-id",
+					Name:  "app",
+					Image: "node:22",
+					ExposedPorts: []runnerinterface.RunnerExposedPort{
+						{PortNumber: 8080, ServiceName: "web", Public: false},
+						{PortNumber: 9000, ServiceName: "api", Public: false},
+					},
+				},
+			},
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(details)
+		}))
+		defer server.Close()
+
+		runner := createTestRunner(t, "reconcile-runner", server.URL)
+		workspace := createTestWorkspace(t, runner, models.WorkspaceStatusRunning)
+
+		container := models.WorkspaceContainer{
+			WorkspaceID:    workspace.ID,
+			ContainerID:    "container-id",
+			ContainerName:  "app",
+			ContainerImage: "node:22",
+		}
+		if err := dbconn.DB.Create(&container).Error; err != nil {
+			t.Fatalf("Failed to create container: '%s'", err)
+		}
+
+		// the user made the port public, then added a port not exposed by the runner
+		if _, err := models.CreateContainerPort(container, "old-name", 8080, true); err != nil {
+			t.Fatalf("Failed to create port: '%s'", err)
+		}
+		if _, err := models.CreateContainerPort(container, "debugger", 3000, false); err != nil {
+			t.Fatalf("Failed to create port: '%s'", err)
+		}
+
+		bgtasks.ReconcileRunner(*runner)
+
+		ports, err := models.ListContainerPortsByWorkspaceContainer(container)
+		if err != nil {
+			t.Fatalf("Failed to list ports: '%s'", err)
+		}
+		assert.Len(t, ports, 3)
+
+		portsByNumber := map[uint]models.WorkspaceContainerPort{}
+		for _, port := range ports {
+			portsByNumber[port.PortNumber] = port
+		}
+
+		assert.Equal(t, "web", portsByNumber[8080].ServiceName)
+		assert.True(t, portsByNumber[8080].Public)
+		assert.Equal(t, "debugger", portsByNumber[3000].ServiceName)
+		assert.Equal(t, "api", portsByNumber[9000].ServiceName)
+	})
+}
@@ -42,6 +42,7 @@ Function that removes a workspace and all its associated data
 It's a separate function so it can be called from multiple places
 */
 func RemoveWorkspace(workspace models.Workspace, skipErrors bool) error {
+	previousStatus := workspace.Status
 	if workspace.Runner != nil {
 		ri := runnerinterface.RunnerInterface{
 			Runner: workspace.Runner,
@@ -52,6 +53,7 @@ func RemoveWorkspace(workspace models.Workspace, skipErrors bool) error {
 			workspace.AppendLogs(fmt.Sprintf("failed to remove workspace, %s", err.Error()))
 			workspace.Status = models.WorkspaceStatusError
 			dbconn.DB.Save(&workspace)
+			recordWorkspaceTransition(&workspace, previousStatus)
 			return errors.New("failed to remove workspace")
 		}
 
@@ -68,6 +70,7 @@ func RemoveWorkspace(workspace models.Workspace, skipErrors bool) error {
 					} else {
 						workspace.Status = models.WorkspaceStatusError
 						dbconn.DB.Save(&workspace)
+						recordWorkspaceTransition(&workspace, previousStatus)
 						return fmt.Errorf("failed to fetch workspace details, %s", err.Error())
 					}
 				}
@@ -96,6 +99,7 @@ func RemoveWorkspace(workspace models.Workspace, skipErrors bool) error {
 			if !skipErrors {
 				workspace.Status = models.WorkspaceStatusError
 				dbconn.DB.Save(&workspace)
+				recordWorkspaceTransition(&workspace, previousStatus)
 				return fmt.Errorf("failed to fetch workspace details, %s", err.Error())
 			}
 			workspace.Status = models.WorkspaceStatusDeleting
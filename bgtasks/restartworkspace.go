@@ -24,7 +24,6 @@ func (jobContext *Context) RestartWorkspaceTask(job *work.Job) error {
 	if workspace == nil {
 		return nil
 	}
-	defer dbconn.DB.Save(&workspace)
 
 	notifications.SendWorkspaceRestartNotification(*workspace)
 
@@ -34,8 +33,10 @@ func (jobContext *Context) RestartWorkspaceTask(job *work.Job) error {
 		return nil
 	}
 
+	previousStatus := workspace.Status
 	workspace.Status = models.WorkspaceStatusStarting
 	dbconn.DB.Save(&workspace)
+	recordWorkspaceTransition(workspace, previousStatus)
 
 	// Give a moment for the workspace to fully stop before restarting
 	time.Sleep(1 * time.Second)
@@ -44,9 +45,10 @@ func (jobContext *Context) RestartWorkspaceTask(job *work.Job) error {
 	workspace.Status = models.WorkspaceStatusStarting
 	workspace.AppendLogs("Starting workspace...")
 
-	if err := StartWorkspace(workspace); err != nil {
-		return nil
-	}
+	err = StartWorkspace(workspace)
+	dbconn.DB.Save(&workspace)
+	recordWorkspaceTransition(workspace, models.WorkspaceStatusStarting)
+	stopCancelledWorkspaceStart(workspace, err)
 
 	return nil
 }
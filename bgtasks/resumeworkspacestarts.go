@@ -0,0 +1,38 @@
+package bgtasks
+
+import (
+	"time"
+
+	"github.com/gocraft/work"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// a start job is considered lost if it has not been seen alive for this period
+const stalledStartThreshold = 2 * time.Minute
+
+/*
+Resume the start of workspaces whose start job has been interrupted,
+e.g. because the server has been restarted while the workspace was starting.
+The new job continues polling the runner from where the previous one stopped
+*/
+func (jobContext *Context) ResumeWorkspaceStartsTask(job *work.Job) error {
+	workspaces, err := models.ListStalledWorkspaceStarts(time.Now().Add(-stalledStartThreshold))
+	if err != nil {
+		// TODO: log error
+		return nil
+	}
+
+	now := time.Now()
+	for _, workspace := range workspaces {
+		// refresh the heartbeat so that the workspace is not enqueued
+		// again while the new job is waiting in the queue
+		workspace.StartHeartbeatAt = &now
+		if err := models.UpdateWorkspaceStartProgress(&workspace); err != nil {
+			continue
+		}
+
+		BgTasksEnqueuer.Enqueue("start_workspace", work.Q{"workspace_id": workspace.ID})
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package bgtasks
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gocraft/work"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+/*
+Start and stop workspaces according to their schedules.
+Schedules are evaluated in the time zone of the owner of the workspace,
+if the runner is offline or the quota of the owner is exhausted when
+a scheduled action is due, the run is skipped and recorded.
+*/
+func (jobContext *Context) RunWorkspaceSchedulesTask(job *work.Job) error {
+	workspaces, err := models.ListScheduledWorkspaces()
+	if err != nil {
+		// TODO: log error
+		return nil
+	}
+
+	now := time.Now()
+	for _, workspace := range workspaces {
+		lastCheck := now.Add(-time.Minute)
+		if workspace.ScheduleLastCheck != nil {
+			lastCheck = *workspace.ScheduleLastCheck
+		}
+
+		if err := dbconn.DB.
+			Model(&models.Workspace{}).
+			Where("id = ?", workspace.ID).
+			UpdateColumn("schedule_last_check", now).Error; err != nil {
+			continue
+		}
+
+		location := time.UTC
+		if workspace.User != nil {
+			location = workspace.User.GetLocation()
+		}
+
+		startAt := models.NextScheduledRun(workspace.StartSchedule, lastCheck, location)
+		stopAt := models.NextScheduledRun(workspace.StopSchedule, lastCheck, location)
+
+		startDue := startAt != nil && !startAt.After(now)
+		stopDue := stopAt != nil && !stopAt.After(now)
+
+		// if both actions are due, only the most recent one is performed
+		if startDue && stopDue {
+			if startAt.After(*stopAt) {
+				stopDue = false
+			} else {
+				startDue = false
+			}
+		}
+
+		if startDue {
+			runScheduledWorkspaceAction(workspace, models.WorkspaceScheduleActionStart, *startAt)
+		} else if stopDue {
+			runScheduledWorkspaceAction(workspace, models.WorkspaceScheduleActionStop, *stopAt)
+		}
+	}
+
+	return nil
+}
+
+func runScheduledWorkspaceAction(workspace models.Workspace, action string, scheduledAt time.Time) {
+	if workspace.Status == models.WorkspaceStatusDeleting {
+		return
+	}
+
+	// nothing to do if the workspace is already in the requested state
+	if action == models.WorkspaceScheduleActionStart && workspace.Status != models.WorkspaceStatusStopped {
+		return
+	}
+
+	if action == models.WorkspaceScheduleActionStop && workspace.Status != models.WorkspaceStatusRunning {
+		return
+	}
+
+	if workspace.Runner == nil {
+		models.CreateWorkspaceScheduleSkippedRun(workspace, action, scheduledAt, "no runner selected")
+		return
+	}
+
+	if !workspace.Runner.IsOnline() {
+		models.CreateWorkspaceScheduleSkippedRun(workspace, action, scheduledAt, "runner is offline")
+		return
+	}
+
+	if action == models.WorkspaceScheduleActionStart && workspace.RunnerAccessRevoked {
+		models.CreateWorkspaceScheduleSkippedRun(workspace, action, scheduledAt, "the owner is no longer allowed to use the runner")
+		return
+	}
+
+	// scheduled starts are subject to the same quotas of the manual ones
+	if action == models.WorkspaceScheduleActionStart && workspace.User != nil {
+		if err := models.CheckStartWorkspaceQuota(*workspace.User, workspace); err != nil {
+			reason := "failed to check the quota of the owner"
+			var quotaErr *models.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				reason = quotaErr.Error()
+			}
+			models.CreateWorkspaceScheduleSkippedRun(workspace, action, scheduledAt, reason)
+			return
+		}
+	}
+
+	previousStatus := workspace.Status
+	switch action {
+	case models.WorkspaceScheduleActionStart:
+		if !updateWorkspaceColumnsIfStatus(workspace.ID, previousStatus, map[string]interface{}{
+			"status": models.WorkspaceStatusStarting,
+		}) {
+			return
+		}
+		workspace.Status = models.WorkspaceStatusStarting
+		models.CreateWorkspaceEvent(
+			workspace,
+			previousStatus,
+			models.WorkspaceEventSourceScheduler,
+			nil,
+			"Scheduled start",
+		)
+
+		workspace.ClearLogs()
+		workspace.AppendLogs("Starting workspace (scheduled)...")
+		BgTasksEnqueuer.Enqueue("start_workspace", work.Q{"workspace_id": workspace.ID})
+	case models.WorkspaceScheduleActionStop:
+		if !updateWorkspaceColumnsIfStatus(workspace.ID, previousStatus, map[string]interface{}{
+			"status": models.WorkspaceStatusStopping,
+		}) {
+			return
+		}
+		workspace.Status = models.WorkspaceStatusStopping
+		models.CreateWorkspaceEvent(
+			workspace,
+			previousStatus,
+			models.WorkspaceEventSourceScheduler,
+			nil,
+			"Scheduled stop",
+		)
+
+		workspace.ClearLogs()
+		workspace.AppendLogs("Stopping workspace (scheduled)...")
+		BgTasksEnqueuer.Enqueue("stop_workspace", work.Q{"workspace_id": workspace.ID})
+	}
+}
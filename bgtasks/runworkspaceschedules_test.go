@@ -0,0 +1,154 @@
+package bgtasks_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/bgtasks"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+// returns a daily schedule that fired five minutes ago in the given location
+func scheduleFiredFiveMinutesAgo(location *time.Location) string {
+	firedAt := time.Now().Add(-5 * time.Minute).In(location)
+	return fmt.Sprintf("%d %d * * *", firedAt.Minute(), firedAt.Hour())
+}
+
+// sets the start schedule of a stopped workspace, the last check
+// is moved back so that the schedule is due at the next run of the task
+func setTestStartSchedule(t *testing.T, workspace *models.Workspace, schedule string) {
+	lastCheck := time.Now().Add(-10 * time.Minute)
+	workspace.StartSchedule = schedule
+	workspace.ScheduleLastCheck = &lastCheck
+	if err := dbconn.DB.Save(workspace).Error; err != nil {
+		t.Fatalf("Failed to update workspace: '%s'", err)
+	}
+}
+
+func setTestUserTimeZone(t *testing.T, email string, timeZone string) *models.User {
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil || user == nil {
+		t.Fatalf("Failed to retrieve user: '%s'", err)
+	}
+
+	if err := dbconn.DB.
+		Model(&models.User{}).
+		Where("id = ?", user.ID).
+		UpdateColumn("time_zone", timeZone).Error; err != nil {
+		t.Fatalf("Failed to update user: '%s'", err)
+	}
+	return user
+}
+
+/*
+Schedules are evaluated in the time zone of the owner of the workspace
+*/
+func TestRunWorkspaceSchedulesTaskTimeZone(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		tokyo, err := time.LoadLocation("Asia/Tokyo")
+		if err != nil {
+			t.Skipf("Time zone database not available: '%s'", err)
+		}
+
+		runner := createTestRunner(t, "schedule-runner", "http://127.0.0.1:1")
+		schedule := scheduleFiredFiveMinutesAgo(tokyo)
+
+		// same schedule, the owner of the second workspace is in UTC
+		tokyoWorkspace := createTestWorkspace(t, runner, models.WorkspaceStatusStopped)
+		setTestStartSchedule(t, tokyoWorkspace, schedule)
+
+		utcWorkspace := createTestWorkspace(t, runner, models.WorkspaceStatusStopped)
+		setTestStartSchedule(t, utcWorkspace, schedule)
+
+		setTestUserTimeZone(t, "user1@user.com", "Asia/Tokyo")
+		user2 := setTestUserTimeZone(t, "user2@user.com", "UTC")
+		if err := dbconn.DB.
+			Model(&models.Workspace{}).
+			Where("id = ?", utcWorkspace.ID).
+			UpdateColumn("user_id", user2.ID).Error; err != nil {
+			t.Fatalf("Failed to update workspace: '%s'", err)
+		}
+
+		jobContext := &bgtasks.Context{}
+		assert.Nil(t, jobContext.RunWorkspaceSchedulesTask(&work.Job{}))
+
+		status, err := models.RetrieveWorkspaceStatus(tokyoWorkspace.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, models.WorkspaceStatusStarting, status)
+
+		status, err = models.RetrieveWorkspaceStatus(utcWorkspace.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, models.WorkspaceStatusStopped, status)
+
+		assert.Equal(
+			t,
+			[]string{"start_workspace"},
+			bgtasks.BgTasksEnqueuer.(*testutils.MockEnqueuer).CalledJobs,
+		)
+	})
+}
+
+/*
+The scheduled start is skipped and recorded if the runner is offline
+*/
+func TestRunWorkspaceSchedulesTaskRunnerOffline(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		runner := createTestRunner(t, "schedule-runner", "http://127.0.0.1:1")
+		lastContact := time.Now().Add(-time.Hour)
+		runner.LastContact = &lastContact
+		if err := models.UpdateRunner(*runner); err != nil {
+			t.Fatalf("Failed to update runner: '%s'", err)
+		}
+
+		workspace := createTestWorkspace(t, runner, models.WorkspaceStatusStopped)
+		setTestStartSchedule(t, workspace, scheduleFiredFiveMinutesAgo(time.UTC))
+
+		jobContext := &bgtasks.Context{}
+		assert.Nil(t, jobContext.RunWorkspaceSchedulesTask(&work.Job{}))
+
+		status, err := models.RetrieveWorkspaceStatus(workspace.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, models.WorkspaceStatusStopped, status)
+		assert.Empty(t, bgtasks.BgTasksEnqueuer.(*testutils.MockEnqueuer).CalledJobs)
+
+		runs, err := models.ListWorkspaceScheduleSkippedRuns(*workspace, 10)
+		assert.Nil(t, err)
+		if assert.Len(t, runs, 1) {
+			assert.Equal(t, models.WorkspaceScheduleActionStart, runs[0].Action)
+			assert.Equal(t, "runner is offline", runs[0].Reason)
+		}
+	})
+}
+
+/*
+The scheduled start is skipped and recorded if the owner
+is no longer allowed to use the runner
+*/
+func TestRunWorkspaceSchedulesTaskRunnerAccessRevoked(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		runner := createTestRunner(t, "schedule-runner", "http://127.0.0.1:1")
+		workspace := createTestWorkspace(t, runner, models.WorkspaceStatusStopped)
+		workspace.RunnerAccessRevoked = true
+		setTestStartSchedule(t, workspace, scheduleFiredFiveMinutesAgo(time.UTC))
+
+		jobContext := &bgtasks.Context{}
+		assert.Nil(t, jobContext.RunWorkspaceSchedulesTask(&work.Job{}))
+
+		status, err := models.RetrieveWorkspaceStatus(workspace.ID)
+		assert.Nil(t, err)
+		assert.Equal(t, models.WorkspaceStatusStopped, status)
+		assert.Empty(t, bgtasks.BgTasksEnqueuer.(*testutils.MockEnqueuer).CalledJobs)
+
+		runs, err := models.ListWorkspaceScheduleSkippedRuns(*workspace, 10)
+		assert.Nil(t, err)
+		if assert.Len(t, runs, 1) {
+			assert.Equal(t, models.WorkspaceScheduleActionStart, runs[0].Action)
+			assert.Equal(t, "the owner is no longer allowed to use the runner", runs[0].Reason)
+		}
+	})
+}
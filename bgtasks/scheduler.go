@@ -48,14 +48,25 @@ func InitBgTasks(
 	pool.Job("update_workspace_config", (*Context).UpdateWorkspaceConfigFilesTask)
 	pool.Job("ping_agents", (*Context).PingAgentsTask)
 	pool.PeriodicallyEnqueue("0 */2 * * * *", "ping_agents") // every 2 minutes (0 */2 * * * *)
+	pool.Job("stop_idle_workspaces", (*Context).StopIdleWorkspacesTask)
+	pool.PeriodicallyEnqueue("0 * * * * *", "stop_idle_workspaces") // every minute
+	pool.Job("run_workspace_schedules", (*Context).RunWorkspaceSchedulesTask)
+	pool.PeriodicallyEnqueue("30 * * * * *", "run_workspace_schedules") // every minute
+	pool.Job("resume_workspace_starts", (*Context).ResumeWorkspaceStartsTask)
+	pool.PeriodicallyEnqueue("15 * * * * *", "resume_workspace_starts") // every minute
 
 	// runners jobs
 	pool.Job("ping_runners", (*Context).PingRunnersTask)
 	pool.PeriodicallyEnqueue("0 */2 * * * *", "ping_runners") // every 2 minutes (0 */2 * * * *)
 	pool.Job("delete_runner", (*Context).DeleteRunnerTask)
+	pool.Job("reconcile_runners", (*Context).ReconcileRunnersTask)
+	pool.PeriodicallyEnqueue("0 */5 * * * *", "reconcile_runners") // every 5 minutes
+	pool.Job("reconcile_runner", (*Context).ReconcileRunnerTask)
 
 	// user jobs
 	pool.Job("delete_user", (*Context).DeleteUserTask)
+	pool.Job("sync_ldap_users", (*Context).SyncLdapUsersTask)
+	pool.PeriodicallyEnqueue("0 0 * * * *", "sync_ldap_users") // every hour
 
 	// email jobs
 	pool.Job("send_email", (*Context).SendEmailTask)
@@ -1,6 +1,7 @@
 package bgtasks
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/gocraft/work"
 	"github.com/google/uuid"
+	"gitlab.com/codebox4073715/codebox/config"
 	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
 	"gitlab.com/codebox4073715/codebox/db/models"
 	"gitlab.com/codebox4073715/codebox/git"
@@ -17,6 +19,12 @@ import (
 	"gitlab.com/codebox4073715/codebox/utils/targz"
 )
 
+// returned by StartWorkspace when the start is cancelled by the user
+var ErrWorkspaceStartCancelled = errors.New("workspace start cancelled")
+
+// interval between two updates of the start job heartbeat
+const startHeartbeatInterval = 15 * time.Second
+
 func (jobContext *Context) StartWorkspaceTask(job *work.Job) error {
 	workspaceId := job.ArgInt64("workspace_id")
 
@@ -28,14 +36,138 @@ func (jobContext *Context) StartWorkspaceTask(job *work.Job) error {
 	if workspace == nil {
 		return nil
 	}
-	defer dbconn.DB.Save(&workspace)
 
-	notifications.SendWorkspaceStartNotification(*workspace)
-	StartWorkspace(workspace)
+	// the start has already been completed, e.g. a resumed job
+	// for a workspace whose start has been completed in the meantime
+	if workspace.Status != models.WorkspaceStatusStarting && workspace.Status != models.WorkspaceStatusStopping {
+		return nil
+	}
+
+	// a stopping workspace is a cancelled start only if a start is in progress,
+	// otherwise it is a regular stop that must not be touched by the start job
+	if workspace.Status == models.WorkspaceStatusStopping && workspace.StartDeadline == nil {
+		return nil
+	}
+
+	if workspace.StartDeadline == nil {
+		notifications.SendWorkspaceStartNotification(*workspace)
+	}
+
+	previousStatus := workspace.Status
+	err = StartWorkspace(workspace)
+	dbconn.DB.Save(&workspace)
+	recordWorkspaceTransition(workspace, previousStatus)
+	stopCancelledWorkspaceStart(workspace, err)
 	return nil
 }
 
+/*
+Stop a workspace whose start has been cancelled, the workspace is stopped
+only after the start job has saved its state, to avoid that the start job
+overrides the status set by the stop job
+*/
+func stopCancelledWorkspaceStart(workspace *models.Workspace, err error) {
+	if errors.Is(err, ErrWorkspaceStartCancelled) && workspace.Status == models.WorkspaceStatusStopping {
+		BgTasksEnqueuer.Enqueue("stop_workspace", work.Q{"workspace_id": workspace.ID})
+	}
+}
+
+/*
+Refresh the heartbeat of the start job while a long step is running,
+e.g. the clone of the repository or the upload of the sources to the runner,
+so that the start is not considered stalled and resumed by another job.
+The returned function stops the refresh
+*/
+func keepStartHeartbeatAlive(workspace *models.Workspace) func() {
+	done := make(chan struct{})
+	workspaceID := workspace.ID
+	go func() {
+		ticker := time.NewTicker(startHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				models.UpdateWorkspaceStartHeartbeat(workspaceID, now)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+/*
+Move a workspace to error, the reason is appended to the
+logs and is stored with the workspace
+*/
+func setWorkspaceStartError(workspace *models.Workspace, reason string) error {
+	workspace.AppendLogs(reason)
+	workspace.Status = models.WorkspaceStatusError
+	workspace.ErrorReason = reason
+	return errors.New(reason)
+}
+
+/*
+Check if the start of a workspace has been cancelled by the user,
+a start is cancelled when the status has been changed to stopping.
+If the runner has not been contacted yet there is nothing to stop, so
+the workspace is marked as stopped
+*/
+func isWorkspaceStartCancelled(workspace *models.Workspace) bool {
+	status, err := models.RetrieveWorkspaceStatus(workspace.ID)
+	if err != nil || status != models.WorkspaceStatusStopping {
+		return false
+	}
+
+	workspace.AppendLogs("Workspace start has been cancelled")
+	if workspace.StartSubmitted {
+		workspace.Status = models.WorkspaceStatusStopping
+	} else {
+		workspace.Status = models.WorkspaceStatusStopped
+		notifications.SendWorkspaceStoppedNotification(*workspace)
+	}
+	return true
+}
+
+/*
+Start a workspace, this is a separate function so it can be called from multiple places.
+The start must complete within the deadline set in the configuration, the progress
+is stored on the workspace so that an interrupted start can be resumed by another job.
+The workspace is not saved by this function, the caller is responsible for saving it
+*/
 func StartWorkspace(workspace *models.Workspace) error {
+	now := time.Now()
+	if workspace.StartDeadline == nil {
+		deadline := now.Add(time.Duration(config.Environment.WorkspaceStartTimeout) * time.Minute)
+		workspace.StartDeadline = &deadline
+		workspace.StartSubmitted = false
+		workspace.ErrorReason = ""
+	} else {
+		workspace.AppendLogs("Resuming workspace start...")
+	}
+	workspace.StartHeartbeatAt = &now
+	models.UpdateWorkspaceStartProgress(workspace)
+
+	// the start is completed, regardless of the result
+	defer func() {
+		workspace.StartDeadline = nil
+		workspace.StartHeartbeatAt = nil
+		workspace.StartSubmitted = false
+	}()
+
+	if isWorkspaceStartCancelled(workspace) {
+		return ErrWorkspaceStartCancelled
+	}
+
+	// the runner has already received the start request,
+	// the previous job has been interrupted while waiting for the runner
+	if workspace.StartSubmitted {
+		ri := runnerinterface.RunnerInterface{
+			Runner: workspace.Runner,
+		}
+		return waitWorkspaceStart(workspace, &ri)
+	}
+
 	// if workspace config source is a git repository retrieve latest version
 	if workspace.ConfigSource == models.WorkspaceConfigSourceGit {
 		if workspace.GitSource != nil {
@@ -48,86 +180,112 @@ func StartWorkspace(workspace *models.Workspace) error {
 				workspace.GitSource.SourcesID = &gitSources.ID
 				workspace.GitSource.Sources = &gitSources
 				dbconn.DB.Save(&workspace.GitSource)
-				dbconn.DB.Save(&workspace)
 			}
 
 			// check if config files exists, clone them if not exist
 			if !workspace.GitSource.Sources.Exists() {
 				tempDirPath, err := os.MkdirTemp("", fmt.Sprintf("codebox-%d", workspace.ID))
 				if err != nil {
-					workspace.AppendLogs(fmt.Sprintf("failed to create tmp folder, %s", err.Error()))
-					workspace.Status = models.WorkspaceStatusError
-					return fmt.Errorf("failed to create tmp folder, %s", err.Error())
+					return setWorkspaceStartError(workspace, fmt.Sprintf("failed to create tmp folder, %s", err.Error()))
 				}
 				defer os.RemoveAll(tempDirPath)
 
-				if err = git.CloneRepo(
+				stopHeartbeat := keepStartHeartbeatAlive(workspace)
+				err = git.CloneRepo(
 					workspace.GitSource.RepositoryURL,
 					workspace.GitSource.RefName,
 					tempDirPath,
 					[]byte(workspace.User.SshPrivateKey),
 					1,
-				); err != nil {
-					workspace.AppendLogs(fmt.Sprintf("failed to clone git repository, %s", err.Error()))
-					workspace.Status = models.WorkspaceStatusError
-					return fmt.Errorf("failed to clone git repository, %s", err.Error())
+				)
+				if err != nil {
+					stopHeartbeat()
+					return setWorkspaceStartError(workspace, fmt.Sprintf("failed to clone git repository, %s", err.Error()))
 				}
 
 				// create targz archive
 				tgm := targz.TarGZManager{Filepath: workspace.GitSource.Sources.GetAbsolutePath()}
-				if err = tgm.CompressFolder(tempDirPath); err != nil {
-					workspace.AppendLogs(fmt.Sprintf("failed to create targz archive, %s", err.Error()))
-					workspace.Status = models.WorkspaceStatusError
-					return fmt.Errorf("failed to create targz archive, %s", err.Error())
+				err = tgm.CompressFolder(tempDirPath)
+				stopHeartbeat()
+				if err != nil {
+					return setWorkspaceStartError(workspace, fmt.Sprintf("failed to create targz archive, %s", err.Error()))
 				}
 
 				workspace.AppendLogs("the git repository has been cloned")
 			}
 		} else {
-			workspace.AppendLogs("git source is nil")
-			workspace.Status = models.WorkspaceStatusError
-			return fmt.Errorf("git source is nil")
+			return setWorkspaceStartError(workspace, "git source is nil")
 		}
 	} else {
 		// check if config files exist
 		if workspace.TemplateVersion.Sources == nil {
-			workspace.AppendLogs("Template version has no sources")
-			workspace.Status = models.WorkspaceStatusError
-			return fmt.Errorf("template version has no sources")
+			return setWorkspaceStartError(workspace, "Template version has no sources")
 		}
 
 		if !workspace.TemplateVersion.Sources.Exists() {
-			workspace.AppendLogs("Template version has no sources")
-			workspace.Status = models.WorkspaceStatusError
-			return fmt.Errorf("template version has no sources")
+			return setWorkspaceStartError(workspace, "Template version has no sources")
 		}
 	}
 
 	if workspace.Runner == nil {
-		workspace.AppendLogs("runner does not exist")
-		workspace.Status = models.WorkspaceStatusError
-		return fmt.Errorf("runner does not exist")
+		return setWorkspaceStartError(workspace, "runner does not exist")
 	}
 
 	ri := runnerinterface.RunnerInterface{
 		Runner: workspace.Runner,
 	}
 
-	if err := ri.StartWorkspace(workspace); err != nil {
-		workspace.AppendLogs(fmt.Sprintf("failed to start workspace, %s", err.Error()))
-		workspace.Status = models.WorkspaceStatusError
-		return fmt.Errorf("failed to start workspace, %s", err.Error())
+	if isWorkspaceStartCancelled(workspace) {
+		return ErrWorkspaceStartCancelled
+	}
+
+	// the sources are uploaded to the runner
+	stopHeartbeat := keepStartHeartbeatAlive(workspace)
+	err := ri.StartWorkspace(workspace)
+	stopHeartbeat()
+	if err != nil {
+		return setWorkspaceStartError(workspace, fmt.Sprintf("failed to start workspace, %s", err.Error()))
 	}
 
+	workspace.StartSubmitted = true
+	models.UpdateWorkspaceStartProgress(workspace)
+
+	return waitWorkspaceStart(workspace, &ri)
+}
+
+/*
+Wait until the runner completes the start of the workspace, then map
+containers and ports. The wait is interrupted if the deadline is exceeded
+or if the user cancels the start
+*/
+func waitWorkspaceStart(workspace *models.Workspace, ri *runnerinterface.RunnerInterface) error {
 	// fetch workspace details and logs
 	starting := true
 	logsIndex := 0
 	for starting {
+		if time.Now().After(*workspace.StartDeadline) {
+			return setWorkspaceStartError(
+				workspace,
+				fmt.Sprintf(
+					"the workspace did not start within %d minutes",
+					config.Environment.WorkspaceStartTimeout,
+				),
+			)
+		}
+
+		if isWorkspaceStartCancelled(workspace) {
+			return ErrWorkspaceStartCancelled
+		}
+
+		if time.Since(*workspace.StartHeartbeatAt) >= startHeartbeatInterval {
+			now := time.Now()
+			workspace.StartHeartbeatAt = &now
+			models.UpdateWorkspaceStartProgress(workspace)
+		}
+
 		details, err := ri.GetWorkspaceDetails(workspace)
 		if err != nil {
-			workspace.AppendLogs(fmt.Sprintf("failed to fetch workspace details, %s", err.Error()))
-			workspace.Status = models.WorkspaceStatusError
-			return fmt.Errorf("failed to fetch workspace details, %s", err.Error())
+			return setWorkspaceStartError(workspace, fmt.Sprintf("failed to fetch workspace details, %s", err.Error()))
 		}
 
 		if details.Status == models.WorkspaceStatusStarting {
@@ -149,48 +307,23 @@ func StartWorkspace(workspace *models.Workspace) error {
 
 	details, err := ri.GetWorkspaceDetails(workspace)
 	if err != nil {
-		workspace.AppendLogs(fmt.Sprintf("failed to fetch workspace details, %s", err.Error()))
-		workspace.Status = models.WorkspaceStatusError
-		return fmt.Errorf("failed to fetch workspace details, %s", err.Error())
+		return setWorkspaceStartError(workspace, fmt.Sprintf("failed to fetch workspace details, %s", err.Error()))
 	}
 
-	// map container
-	for _, c := range details.Containers {
-		containerUserId, err := strconv.Atoi(c.ContainerUserID)
-		if err != nil {
-			containerUserId = 0
-		}
-
-		workspaceContainer := models.WorkspaceContainer{
-			Workspace:         *workspace,
-			ContainerID:       c.ID,
-			ContainerName:     c.Name,
-			ContainerImage:    c.Image,
-			ContainerUserID:   uint(containerUserId),
-			ContainerUserName: c.ContainerUserName,
-			WorkspacePath:     c.WorkspacePath,
-		}
-
-		dbconn.DB.Create(&workspaceContainer)
-
-		// map ports
-		for _, p := range c.ExposedPorts {
-			port := models.WorkspaceContainerPort{
-				Container:   workspaceContainer,
-				ServiceName: p.ServiceName,
-				PortNumber:  uint(p.PortNumber),
-				Public:      p.Public,
+	// the number of containers is known only when the workspace is started
+	if workspace.User != nil {
+		if err := models.CheckContainersQuota(*workspace.User, len(details.Containers)); err != nil {
+			var quotaErr *models.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				ri.StopWorkspace(workspace)
+				return setWorkspaceStartError(workspace, quotaErr.Error())
 			}
-
-			dbconn.DB.Create(&port)
 		}
+	}
 
-		// ping agent
-		if ri.PingAgent(&workspaceContainer) {
-			now := time.Now()
-			workspaceContainer.AgentLastContact = &now
-			dbconn.DB.Save(&workspaceContainer)
-		}
+	// map container
+	for _, c := range details.Containers {
+		createWorkspaceContainer(workspace, ri, c)
 	}
 
 	workspace.Status = details.Status
@@ -201,3 +334,52 @@ func StartWorkspace(workspace *models.Workspace) error {
 
 	return nil
 }
+
+/*
+Create a workspace container and its ports from the
+details returned by the runner, then ping the agent
+*/
+func createWorkspaceContainer(
+	workspace *models.Workspace,
+	ri *runnerinterface.RunnerInterface,
+	c runnerinterface.RunnerContainer,
+) models.WorkspaceContainer {
+	containerUserId, err := strconv.Atoi(c.ContainerUserID)
+	if err != nil {
+		containerUserId = 0
+	}
+
+	workspaceContainer := models.WorkspaceContainer{
+		Workspace:         *workspace,
+		ContainerID:       c.ID,
+		ContainerName:     c.Name,
+		ContainerImage:    c.Image,
+		ContainerUserID:   uint(containerUserId),
+		ContainerUserName: c.ContainerUserName,
+		WorkspacePath:     c.WorkspacePath,
+		ResourceLimits:    c.GetResourceLimits(),
+	}
+
+	dbconn.DB.Create(&workspaceContainer)
+
+	// map ports
+	for _, p := range c.ExposedPorts {
+		port := models.WorkspaceContainerPort{
+			Container:   workspaceContainer,
+			ServiceName: p.ServiceName,
+			PortNumber:  uint(p.PortNumber),
+			Public:      p.Public,
+		}
+
+		dbconn.DB.Create(&port)
+	}
+
+	// ping agent
+	if ri.PingAgent(&workspaceContainer) {
+		now := time.Now()
+		workspaceContainer.AgentLastContact = &now
+		dbconn.DB.Save(&workspaceContainer)
+	}
+
+	return workspaceContainer
+}
@@ -0,0 +1,110 @@
+package bgtasks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gocraft/work"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/notifications"
+)
+
+// how long before the automatic shutdown the owner is warned
+const idleShutdownWarningPeriod = 10 * time.Minute
+
+/*
+Stop running workspaces that have been inactive for longer than the
+inactivity window set on the workspace or on its template.
+The owner of the workspace receives a notification before the workspace
+is stopped, if an activity is detected in the meantime the shutdown is cancelled.
+*/
+func (jobContext *Context) StopIdleWorkspacesTask(job *work.Job) error {
+	workspaces, err := models.ListWorkspacesByStatus(models.WorkspaceStatusRunning)
+	if err != nil {
+		// TODO: log error
+		return nil
+	}
+
+	now := time.Now()
+	for _, workspace := range workspaces {
+		idleTimeout := workspace.GetIdleTimeout()
+		if idleTimeout <= 0 {
+			continue
+		}
+
+		containers, err := models.ListWorkspaceContainersByWorkspace(workspace)
+		if err != nil || len(containers) == 0 {
+			continue
+		}
+
+		lastActivity := time.Time{}
+		for _, container := range containers {
+			if activity := container.GetLastActivity(); activity.After(lastActivity) {
+				lastActivity = activity
+			}
+		}
+
+		idleFor := now.Sub(lastActivity)
+		if idleFor >= idleTimeout {
+			previousStatus := workspace.Status
+			if !updateWorkspaceColumnsIfStatus(workspace.ID, models.WorkspaceStatusRunning, map[string]interface{}{
+				"status":           models.WorkspaceStatusStopping,
+				"idle_shutdown_at": nil,
+			}) {
+				continue
+			}
+			workspace.Status = models.WorkspaceStatusStopping
+			workspace.IdleShutdownAt = nil
+			models.CreateWorkspaceEvent(
+				workspace,
+				previousStatus,
+				models.WorkspaceEventSourceScheduler,
+				nil,
+				fmt.Sprintf("Stopped after %d minutes of inactivity", int(idleTimeout.Minutes())),
+			)
+
+			workspace.ClearLogs()
+			workspace.AppendLogs(
+				fmt.Sprintf(
+					"Stopping workspace after %d minutes of inactivity...",
+					int(idleTimeout.Minutes()),
+				),
+			)
+			BgTasksEnqueuer.Enqueue("stop_workspace", work.Q{"workspace_id": workspace.ID})
+		} else if idleFor >= idleTimeout-idleShutdownWarningPeriod {
+			if workspace.IdleShutdownAt == nil {
+				shutdownAt := lastActivity.Add(idleTimeout)
+				if !updateWorkspaceColumnsIfStatus(workspace.ID, models.WorkspaceStatusRunning, map[string]interface{}{
+					"idle_shutdown_at": &shutdownAt,
+				}) {
+					continue
+				}
+				workspace.IdleShutdownAt = &shutdownAt
+				notifications.SendWorkspaceIdleWarningNotification(workspace)
+			}
+		} else if workspace.IdleShutdownAt != nil {
+			// an activity has been detected, cancel the shutdown
+			updateWorkspaceColumnsIfStatus(workspace.ID, models.WorkspaceStatusRunning, map[string]interface{}{
+				"idle_shutdown_at": nil,
+			})
+		}
+	}
+
+	return nil
+}
+
+/*
+updateWorkspaceColumnsIfStatus updates only the given columns of the workspace
+if it's still in the expected status, so that the changes made in the meantime
+(e.g. a stop requested by the user) are not overwritten.
+It returns false if the workspace has not been updated
+*/
+func updateWorkspaceColumnsIfStatus(workspaceID uint, status string, columns map[string]interface{}) bool {
+	result := dbconn.DB.
+		Model(&models.Workspace{}).
+		Where("id = ?", workspaceID).
+		Where("status = ?", status).
+		UpdateColumns(columns)
+	return result.Error == nil && result.RowsAffected > 0
+}
@@ -0,0 +1,117 @@
+package bgtasks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/bgtasks"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+// creates a running workspace with a 30 minutes inactivity window,
+// whose only container has been idle for the given duration
+func createIdleWorkspace(t *testing.T, idleFor time.Duration) *models.Workspace {
+	runner := createTestRunner(t, "idle-runner", "http://127.0.0.1:1")
+	workspace := createTestWorkspace(t, runner, models.WorkspaceStatusRunning)
+
+	idleTimeout := uint(30)
+	workspace.IdleTimeoutMinutes = &idleTimeout
+	if err := dbconn.DB.Save(workspace).Error; err != nil {
+		t.Fatalf("Failed to update workspace: '%s'", err)
+	}
+
+	lastActivity := time.Now().Add(-idleFor)
+	container := models.WorkspaceContainer{
+		WorkspaceID:     workspace.ID,
+		ContainerName:   "development",
+		LastSshActivity: &lastActivity,
+		CreatedAt:       lastActivity,
+	}
+	if err := dbconn.DB.Create(&container).Error; err != nil {
+		t.Fatalf("Failed to create container: '%s'", err)
+	}
+	return workspace
+}
+
+func retrieveTestWorkspace(t *testing.T, id uint) *models.Workspace {
+	workspace, err := models.RetrieveWorkspaceById(id)
+	if err != nil || workspace == nil {
+		t.Fatalf("Failed to retrieve workspace: '%s'", err)
+	}
+	return workspace
+}
+
+/*
+The owner is warned when the workspace is about to be stopped
+*/
+func TestStopIdleWorkspacesTaskWarning(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		workspace := createIdleWorkspace(t, 25*time.Minute)
+
+		jobContext := &bgtasks.Context{}
+		assert.Nil(t, jobContext.StopIdleWorkspacesTask(&work.Job{}))
+
+		workspace = retrieveTestWorkspace(t, workspace.ID)
+		assert.Equal(t, models.WorkspaceStatusRunning, workspace.Status)
+		assert.NotNil(t, workspace.IdleShutdownAt)
+		assert.Empty(t, bgtasks.BgTasksEnqueuer.(*testutils.MockEnqueuer).CalledJobs)
+	})
+}
+
+/*
+The workspace is stopped once the inactivity window has elapsed
+*/
+func TestStopIdleWorkspacesTaskStop(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		workspace := createIdleWorkspace(t, 40*time.Minute)
+		shutdownAt := time.Now().Add(-10 * time.Minute)
+		workspace.IdleShutdownAt = &shutdownAt
+		if err := dbconn.DB.Save(workspace).Error; err != nil {
+			t.Fatalf("Failed to update workspace: '%s'", err)
+		}
+
+		jobContext := &bgtasks.Context{}
+		assert.Nil(t, jobContext.StopIdleWorkspacesTask(&work.Job{}))
+
+		workspace = retrieveTestWorkspace(t, workspace.ID)
+		assert.Equal(t, models.WorkspaceStatusStopping, workspace.Status)
+		assert.Nil(t, workspace.IdleShutdownAt)
+		assert.Equal(
+			t,
+			[]string{"stop_workspace"},
+			bgtasks.BgTasksEnqueuer.(*testutils.MockEnqueuer).CalledJobs,
+		)
+
+		event, err := models.RetrieveLatestWorkspaceEvent(*workspace)
+		assert.Nil(t, err)
+		if assert.NotNil(t, event) {
+			assert.Equal(t, models.WorkspaceEventSourceScheduler, event.Source)
+		}
+	})
+}
+
+/*
+The shutdown is cancelled if an activity is detected after the warning
+*/
+func TestStopIdleWorkspacesTaskCancelledShutdown(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		workspace := createIdleWorkspace(t, time.Minute)
+		shutdownAt := time.Now().Add(5 * time.Minute)
+		workspace.IdleShutdownAt = &shutdownAt
+		if err := dbconn.DB.Save(workspace).Error; err != nil {
+			t.Fatalf("Failed to update workspace: '%s'", err)
+		}
+
+		jobContext := &bgtasks.Context{}
+		assert.Nil(t, jobContext.StopIdleWorkspacesTask(&work.Job{}))
+
+		workspace = retrieveTestWorkspace(t, workspace.ID)
+		assert.Equal(t, models.WorkspaceStatusRunning, workspace.Status)
+		assert.Nil(t, workspace.IdleShutdownAt)
+		assert.Empty(t, bgtasks.BgTasksEnqueuer.(*testutils.MockEnqueuer).CalledJobs)
+	})
+}
@@ -38,6 +38,9 @@ Stop a running workspace,
 this is a separate function so it can be called from multiple places
 */
 func StopWorkspace(workspace *models.Workspace, skipErrors bool) error {
+	// the event is recorded once the workspace has been saved
+	previousStatus := workspace.Status
+	defer recordWorkspaceTransition(workspace, previousStatus)
 	defer dbconn.DB.Save(&workspace)
 
 	ri := runnerinterface.RunnerInterface{
@@ -95,6 +98,8 @@ func StopWorkspace(workspace *models.Workspace, skipErrors bool) error {
 	}
 
 	workspace.Status = details.Status
+	workspace.ErrorReason = ""
+	workspace.IdleShutdownAt = nil
 
 	var containers []models.WorkspaceContainer
 	dbconn.DB.Find(&containers, map[string]interface{}{
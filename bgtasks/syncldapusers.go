@@ -0,0 +1,62 @@
+package bgtasks
+
+import (
+	"github.com/gocraft/work"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/logging"
+)
+
+/*
+Deactivate the users whose entry has been removed from the LDAP directory,
+does not match the user filter anymore or has been disabled,
+their tokens are revoked so they are logged out immediately.
+If the directory cannot be queried no user is deactivated
+*/
+func (jobContext *Context) SyncLdapUsersTask(job *work.Job) error {
+	s, err := models.GetSingletonModelInstance[models.LDAPSettings]()
+	if err != nil || !s.Enabled {
+		return nil
+	}
+
+	users, err := models.ListLdapUsers()
+	if err != nil || len(users) == 0 {
+		return nil
+	}
+
+	dns := make([]string, len(users))
+	for i, user := range users {
+		dns[i] = user.LdapDN
+	}
+
+	missing, err := s.GetLDAPConfig().FindMissingUsers(dns)
+	if err != nil {
+		logging.Error("cannot sync the users with the ldap directory: %s", err)
+		return nil
+	}
+
+	// a wrong base DN or missing permissions of the service account
+	// look like an empty directory, all the users would be locked out
+	if len(users) > 1 && len(missing) == len(users) {
+		logging.Warn("no user has been found in the ldap directory, check the ldap settings, users have not been deactivated")
+		return nil
+	}
+
+	missingDNs := map[string]bool{}
+	for _, dn := range missing {
+		missingDNs[dn] = true
+	}
+
+	for _, user := range users {
+		if !missingDNs[user.LdapDN] {
+			continue
+		}
+
+		if err := models.DeactivateUser(&user); err != nil {
+			logging.Error("cannot deactivate the user %s: %s", user.Email, err)
+			continue
+		}
+		logging.Info("user %s has been deactivated, it has been removed or disabled in the ldap directory", user.Email)
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package bgtasks
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/work"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// returned by TransferWorkspace when the workspace is changing status
+var ErrWorkspaceTransferNotAllowed = errors.New("cannot transfer a workspace while it is starting, stopping or being deleted")
+
+/*
+CheckWorkspaceTransferAllowed checks that the workspace is not changing status,
+ErrWorkspaceTransferNotAllowed is returned otherwise
+*/
+func CheckWorkspaceTransferAllowed(workspace models.Workspace) error {
+	if workspace.Status != models.WorkspaceStatusRunning &&
+		workspace.Status != models.WorkspaceStatusStopped &&
+		workspace.Status != models.WorkspaceStatusError {
+		return ErrWorkspaceTransferNotAllowed
+	}
+	return nil
+}
+
+/*
+TransferWorkspace assigns a workspace to another user on behalf of an administrator.
+A running workspace is restarted, so the environment variables derived from the owner
+and the secrets of the previous owner and of its groups are replaced. If the new owner
+cannot use the runner the workspace is stopped instead.
+It returns the warnings the administrator should be aware of
+*/
+func TransferWorkspace(workspace *models.Workspace, owner models.User, admin *models.User) ([]string, error) {
+	if err := CheckWorkspaceTransferAllowed(*workspace); err != nil {
+		return nil, err
+	}
+
+	previousOwner := ""
+	if workspace.User != nil {
+		previousOwner = workspace.User.Email
+	}
+
+	workspace, err := models.TransferWorkspaceOwnership(workspace, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Ownership transferred from '%s' to '%s'", previousOwner, owner.Email)
+	workspace.AppendLogs(message)
+
+	// git operations are signed by the runner with the ssh key of the owner
+	warnings := []string{
+		fmt.Sprintf(
+			"git operations of the workspace are now authenticated with the SSH key of '%s'",
+			owner.Email,
+		),
+	}
+
+	if workspace.ConfigSource == models.WorkspaceConfigSourceGit && workspace.GitSource != nil {
+		warnings = append(warnings, fmt.Sprintf(
+			"the SSH key of '%s' must be allowed to access '%s' to update the configuration",
+			owner.Email,
+			workspace.GitSource.RepositoryURL,
+		))
+	}
+
+	if workspace.RunnerAccessRevoked {
+		warnings = append(warnings, fmt.Sprintf(
+			"'%s' is not allowed to use the runner '%s', the workspace cannot be started",
+			owner.Email,
+			workspace.Runner.Name,
+		))
+	}
+
+	if workspace.Status == models.WorkspaceStatusRunning {
+		// the environment of the running workspace contains the secrets of the
+		// previous owner, so it is restarted or, if it cannot be started, stopped
+		task := "restart_workspace"
+		details := "restart requested"
+		if workspace.RunnerAccessRevoked {
+			task = "stop_workspace"
+			details = "stop requested"
+		}
+
+		previousStatus := workspace.Status
+		workspace, err = models.UpdateWorkspace(
+			workspace,
+			workspace.Name,
+			models.WorkspaceStatusStopping,
+			workspace.Runner,
+			workspace.ConfigSource,
+			workspace.TemplateVersion,
+			workspace.GitSource,
+			workspace.EnvironmentVariables,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if workspace.RunnerAccessRevoked {
+			workspace.AppendLogs("Stopping workspace...")
+		} else {
+			workspace.AppendLogs("Restarting workspace...")
+		}
+		models.CreateWorkspaceEvent(
+			*workspace,
+			previousStatus,
+			models.WorkspaceEventSourceAdmin,
+			admin,
+			fmt.Sprintf("%s, %s", message, details),
+		)
+		BgTasksEnqueuer.Enqueue(task, work.Q{"workspace_id": workspace.ID})
+	}
+
+	return warnings, nil
+}
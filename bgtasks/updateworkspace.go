@@ -31,6 +31,7 @@ func (jobContext *Context) UpdateWorkspaceConfigFilesTask(job *work.Job) error {
 		return nil
 	}
 
+	previousStatus := workspace.Status
 	if workspace.ConfigSource == models.WorkspaceConfigSourceGit {
 		if workspace.GitSource.Sources == nil {
 			gitSources := models.File{
@@ -51,6 +52,7 @@ func (jobContext *Context) UpdateWorkspaceConfigFilesTask(job *work.Job) error {
 			workspace.AppendLogs(fmt.Sprintf("failed to create tmp folder, %s", err.Error()))
 			workspace.Status = models.WorkspaceStatusError
 			dbconn.DB.Save(&workspace)
+			recordWorkspaceTransition(workspace, previousStatus)
 			return nil
 		}
 		defer os.RemoveAll(tempDirPath)
@@ -65,6 +67,7 @@ func (jobContext *Context) UpdateWorkspaceConfigFilesTask(job *work.Job) error {
 			workspace.AppendLogs(fmt.Sprintf("failed to clone git repository, %s", err.Error()))
 			workspace.Status = models.WorkspaceStatusError
 			dbconn.DB.Save(&workspace)
+			recordWorkspaceTransition(workspace, previousStatus)
 			return nil
 		}
 
@@ -74,6 +77,7 @@ func (jobContext *Context) UpdateWorkspaceConfigFilesTask(job *work.Job) error {
 			workspace.AppendLogs(fmt.Sprintf("failed to create targz archive, %s", err.Error()))
 			workspace.Status = models.WorkspaceStatusError
 			dbconn.DB.Save(&workspace)
+			recordWorkspaceTransition(workspace, previousStatus)
 			return nil
 		}
 	} else {
@@ -82,6 +86,7 @@ func (jobContext *Context) UpdateWorkspaceConfigFilesTask(job *work.Job) error {
 			workspace.AppendLogs(fmt.Sprintf("failed to create targz archive, %s", err.Error()))
 			workspace.Status = models.WorkspaceStatusError
 			dbconn.DB.Save(&workspace)
+			recordWorkspaceTransition(workspace, previousStatus)
 			return nil
 		}
 
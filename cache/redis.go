@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -67,6 +68,26 @@ func SetKeyToCache(key string, value []byte, ttlSeconds int) error {
 	return err
 }
 
+/*
+Retrieve the value of a key from redis cache,
+nil is returned if the key does not exist
+*/
+func GetKeyFromCache(key string) ([]byte, error) {
+	pool := GetRedisCachePool()
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do("GET", key))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error retrieving key %s: %v", key, err)
+	}
+	return value, nil
+}
+
 /*
 Retrieve keys matching pattern from redis cache
 */
@@ -108,3 +129,26 @@ func DeleteKeyFromCache(key string) error {
 	_, err := conn.Do("DEL", key)
 	return err
 }
+
+/*
+Atomically increment the integer value of a key and return the new value,
+a key that does not exist is created with the given expiration
+*/
+func IncrementKeyInCache(key string, ttlSeconds int) (int64, error) {
+	pool := GetRedisCachePool()
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Int64(conn.Do("INCR", key))
+	if err != nil {
+		return 0, fmt.Errorf("error incrementing key %s: %v", key, err)
+	}
+
+	if value == 1 && ttlSeconds > 0 {
+		if _, err := conn.Do("EXPIRE", key, ttlSeconds); err != nil {
+			return 0, fmt.Errorf("error setting expiration of key %s: %v", key, err)
+		}
+	}
+	return value, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/runnerscheduler"
 )
 
 /*
@@ -15,6 +16,11 @@ func HandleCheckEnv() uint {
 		return 1
 	}
 
+	if err := runnerscheduler.ValidateStrategy(config.Environment.RunnerSchedulingStrategy); err != nil {
+		fmt.Printf("Failed to load server configuration from environment: '%s'\n", err)
+		return 1
+	}
+
 	fmt.Println("Config is valid")
 	return 0
 }
@@ -9,6 +9,7 @@ import (
 	"gitlab.com/codebox4073715/codebox/config"
 	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
 	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/runnerscheduler"
 )
 
 /*
@@ -23,6 +24,11 @@ func HandleRunServer() uint {
 		return 1
 	}
 
+	if err := runnerscheduler.ValidateStrategy(config.Environment.RunnerSchedulingStrategy); err != nil {
+		log.Fatalf("Failed to load server configuration from environment: '%s'\n", err)
+		return 1
+	}
+
 	// init db connection
 	if err = dbconn.ConnectDB(); err != nil {
 		log.Fatalf("Cannot init connection with DB: '%s'\n", err)
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/url"
@@ -30,8 +31,10 @@ type EnvVars struct {
 	CliBinariesPath string `env:"CODEBOX_CLI_BINARIES_PATH" envDefault:"./cli"`
 	TemplatesFolder string `env:"CODEBOX_TEMPLATES_FOLDER" envDefault:"./templates"`
 	// runner
-	RunnerTokenHeader     string `env:"CODEBOX_RUNNER_TOKEN_HEADER" envDefault:"X-Codebox-Runner-Token"`
-	RunnerTokenQueryParam string `env:"CODEBOX_RUNNER_TOKEN_QUERY_PARAM" envDefault:"runner_token"`
+	RunnerTokenHeader        string `env:"CODEBOX_RUNNER_TOKEN_HEADER" envDefault:"X-Codebox-Runner-Token"`
+	RunnerTokenQueryParam    string `env:"CODEBOX_RUNNER_TOKEN_QUERY_PARAM" envDefault:"runner_token"`
+	RunnerSchedulingStrategy string `env:"CODEBOX_RUNNER_SCHEDULING_STRATEGY" envDefault:"least_workspaces"`
+	RunnerMaxWorkspaces      int    `env:"CODEBOX_RUNNER_MAX_WORKSPACES" envDefault:"0"` // 0 means unlimited
 	// database
 	DBDriver   string `env:"CODEBOX_DB_DRIVER" envDefault:"mysql"`
 	DBHost     string `env:"CODEBOX_DB_HOST" envDefault:"db"`
@@ -41,7 +44,8 @@ type EnvVars struct {
 	DBUser     string `env:"CODEBOX_DB_USER" envDefault:"codebox"`
 	DBPassword string `env:"CODEBOX_DB_PASSWORD" envDefault:"password"`
 	// bg tasks
-	TasksConcurrency int `env:"CODEBOX_BG_TASKS_CONCURRENCY" envDefault:"5"`
+	TasksConcurrency      int `env:"CODEBOX_BG_TASKS_CONCURRENCY" envDefault:"5"`
+	WorkspaceStartTimeout int `env:"CODEBOX_WORKSPACE_START_TIMEOUT" envDefault:"30"` // minutes
 	// redis
 	RedisHost string `env:"CODEBOX_REDIS_HOST" envDefault:"redis"`
 	RedisPort int    `env:"CODEBOX_REDIS_PORT" envDefault:"6379"`
@@ -50,6 +54,8 @@ type EnvVars struct {
 	EmailSMTPPort     int    `env:"CODEBOX_EMAIL_SMTP_PORT" envDefault:"0"`
 	EmailSMTPUser     string `env:"CODEBOX_EMAIL_SMTP_USER"`
 	EmailSMTPPassword string `env:"CODEBOX_EMAIL_SMTP_PASSWORD"`
+	// secrets
+	SecretsMasterKey string `env:"CODEBOX_SECRETS_MASTER_KEY"` // base64 encoded 32 bytes key
 }
 
 var Environment *EnvVars
@@ -67,6 +73,15 @@ func (e *EnvVars) ValidateExternalUrl() error {
 	return nil
 }
 
+/*
+UsesHttps checks if the server is served over https,
+secure cookies are used only in that case
+*/
+func (e *EnvVars) UsesHttps() bool {
+	parsedURL, err := url.Parse(e.ExternalUrl)
+	return err == nil && parsedURL.Scheme == "https"
+}
+
 func (e *EnvVars) ValidateWildcardDomain() error {
 	if e.UseSubDomains {
 		if e.WildcardDomain == "" {
@@ -158,6 +173,13 @@ func (e *EnvVars) ValidateRunnerTokenQueryParam() error {
 	return nil
 }
 
+func (e *EnvVars) ValidateRunnerMaxWorkspaces() error {
+	if e.RunnerMaxWorkspaces < 0 {
+		return errors.New("CODEBOX_RUNNER_MAX_WORKSPACES cannot be less than 0")
+	}
+	return nil
+}
+
 func (e *EnvVars) ValidateDBDriver() error {
 	if e.DBDriver == "" {
 		return errors.New("CODEBOX_DB_DRIVER cannot be empty")
@@ -219,6 +241,30 @@ func (e *EnvVars) ValidateTasksConcurrency() error {
 	return nil
 }
 
+func (e *EnvVars) ValidateWorkspaceStartTimeout() error {
+	if e.WorkspaceStartTimeout < 1 {
+		return errors.New("CODEBOX_WORKSPACE_START_TIMEOUT cannot be less than 1")
+	}
+	return nil
+}
+
+func (e *EnvVars) ValidateSecretsMasterKey() error {
+	// secrets are disabled if the key is not set
+	if e.SecretsMasterKey == "" {
+		return nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(e.SecretsMasterKey)
+	if err != nil {
+		return errors.New("CODEBOX_SECRETS_MASTER_KEY must be base64 encoded")
+	}
+
+	if len(key) != 32 {
+		return errors.New("CODEBOX_SECRETS_MASTER_KEY must be 32 bytes long")
+	}
+	return nil
+}
+
 func (e *EnvVars) ValidateRedisHost() error {
 	if e.RedisHost == "" {
 		return errors.New("CODEBOX_REDIS_HOST cannot be empty")
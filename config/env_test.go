@@ -422,6 +422,42 @@ func TestValidateRunnerTokenQueryParam(t *testing.T) {
 	}
 }
 
+func TestValidateRunnerMaxWorkspaces(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxWorkspaces int
+		expectError   bool
+	}{
+		{
+			name:          "unlimited",
+			maxWorkspaces: 0,
+			expectError:   false,
+		},
+		{
+			name:          "valid limit",
+			maxWorkspaces: 10,
+			expectError:   false,
+		},
+		{
+			name:          "negative limit",
+			maxWorkspaces: -1,
+			expectError:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EnvVars{
+				RunnerMaxWorkspaces: tt.maxWorkspaces,
+			}
+			err := e.ValidateRunnerMaxWorkspaces()
+			if (err != nil) != tt.expectError {
+				t.Errorf("ValidateRunnerMaxWorkspaces() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
 func TestValidateDBDriver(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -710,6 +746,88 @@ func TestValidateTasksConcurrency(t *testing.T) {
 	}
 }
 
+func TestValidateWorkspaceStartTimeout(t *testing.T) {
+	tests := []struct {
+		name                  string
+		workspaceStartTimeout int
+		expectError           bool
+	}{
+		{
+			name:                  "valid timeout 30",
+			workspaceStartTimeout: 30,
+			expectError:           false,
+		},
+		{
+			name:                  "valid timeout 1",
+			workspaceStartTimeout: 1,
+			expectError:           false,
+		},
+		{
+			name:                  "invalid timeout 0",
+			workspaceStartTimeout: 0,
+			expectError:           true,
+		},
+		{
+			name:                  "invalid timeout negative",
+			workspaceStartTimeout: -1,
+			expectError:           true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EnvVars{
+				WorkspaceStartTimeout: tt.workspaceStartTimeout,
+			}
+			err := e.ValidateWorkspaceStartTimeout()
+			if (err != nil) != tt.expectError {
+				t.Errorf("ValidateWorkspaceStartTimeout() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestValidateSecretsMasterKey(t *testing.T) {
+	tests := []struct {
+		name             string
+		secretsMasterKey string
+		expectError      bool
+	}{
+		{
+			name:             "empty key",
+			secretsMasterKey: "",
+			expectError:      false,
+		},
+		{
+			name:             "valid key",
+			secretsMasterKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+			expectError:      false,
+		},
+		{
+			name:             "invalid base64",
+			secretsMasterKey: "not a base64 string!",
+			expectError:      true,
+		},
+		{
+			name:             "invalid key length",
+			secretsMasterKey: "c2hvcnQta2V5",
+			expectError:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EnvVars{
+				SecretsMasterKey: tt.secretsMasterKey,
+			}
+			err := e.ValidateSecretsMasterKey()
+			if (err != nil) != tt.expectError {
+				t.Errorf("ValidateSecretsMasterKey() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
 func TestValidateRedisHost(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -902,85 +1020,91 @@ func TestIsValid(t *testing.T) {
 		{
 			name: "all valid environment variables",
 			envVars: &EnvVars{
-				ExternalUrl:             "https://example.com",
-				UseSubDomains:           true,
-				WildcardDomain:          "example.com",
-				ServerPort:              8080,
-				DebugEnabled:            true,
-				AuthCookieName:          "codebox_auth_token",
-				SubdomainAuthCookieName: "subdomain_codebox_auth_token",
-				UploadsPath:             "./data",
-				CliBinariesPath:         tempDir,
-				TemplatesFolder:         tempDir,
-				RunnerTokenHeader:       "X-Codebox-Runner-Token",
-				RunnerTokenQueryParam:   "runner_token",
-				DBDriver:                "mysql",
-				DBHost:                  "localhost",
-				DBPort:                  3306,
-				DBName:                  "codebox",
-				DBTestName:              "codebox-test",
-				DBUser:                  "codebox",
-				DBPassword:              "password",
-				TasksConcurrency:        5,
-				RedisHost:               "redis",
-				RedisPort:               6379,
-				EmailSMTPPort:           587,
+				ExternalUrl:              "https://example.com",
+				UseSubDomains:            true,
+				WildcardDomain:           "example.com",
+				ServerPort:               8080,
+				DebugEnabled:             true,
+				AuthCookieName:           "codebox_auth_token",
+				SubdomainAuthCookieName:  "subdomain_codebox_auth_token",
+				UploadsPath:              "./data",
+				CliBinariesPath:          tempDir,
+				TemplatesFolder:          tempDir,
+				RunnerTokenHeader:        "X-Codebox-Runner-Token",
+				RunnerTokenQueryParam:    "runner_token",
+				RunnerSchedulingStrategy: "least_workspaces",
+				DBDriver:                 "mysql",
+				DBHost:                   "localhost",
+				DBPort:                   3306,
+				DBName:                   "codebox",
+				DBTestName:               "codebox-test",
+				DBUser:                   "codebox",
+				DBPassword:               "password",
+				TasksConcurrency:         5,
+				WorkspaceStartTimeout:    30,
+				RedisHost:                "redis",
+				RedisPort:                6379,
+				EmailSMTPPort:            587,
 			},
 			expectError: false,
 		},
 		{
 			name: "invalid server port",
 			envVars: &EnvVars{
-				ExternalUrl:             "https://example.com",
-				UseSubDomains:           false,
-				WildcardDomain:          "",
-				ServerPort:              70000,
-				DebugEnabled:            true,
-				AuthCookieName:          "codebox_auth_token",
-				SubdomainAuthCookieName: "subdomain_codebox_auth_token",
-				UploadsPath:             "./data",
-				CliBinariesPath:         tempDir,
-				TemplatesFolder:         tempDir,
-				RunnerTokenHeader:       "X-Codebox-Runner-Token",
-				RunnerTokenQueryParam:   "runner_token",
-				DBDriver:                "mysql",
-				DBHost:                  "localhost",
-				DBPort:                  3306,
-				DBName:                  "codebox",
-				DBTestName:              "codebox-test",
-				DBUser:                  "codebox",
-				DBPassword:              "password",
-				TasksConcurrency:        5,
-				RedisHost:               "redis",
-				RedisPort:               6379,
+				ExternalUrl:              "https://example.com",
+				UseSubDomains:            false,
+				WildcardDomain:           "",
+				ServerPort:               70000,
+				DebugEnabled:             true,
+				AuthCookieName:           "codebox_auth_token",
+				SubdomainAuthCookieName:  "subdomain_codebox_auth_token",
+				UploadsPath:              "./data",
+				CliBinariesPath:          tempDir,
+				TemplatesFolder:          tempDir,
+				RunnerTokenHeader:        "X-Codebox-Runner-Token",
+				RunnerTokenQueryParam:    "runner_token",
+				RunnerSchedulingStrategy: "least_workspaces",
+				DBDriver:                 "mysql",
+				DBHost:                   "localhost",
+				DBPort:                   3306,
+				DBName:                   "codebox",
+				DBTestName:               "codebox-test",
+				DBUser:                   "codebox",
+				DBPassword:               "password",
+				TasksConcurrency:         5,
+				WorkspaceStartTimeout:    30,
+				RedisHost:                "redis",
+				RedisPort:                6379,
 			},
 			expectError: true,
 		},
 		{
 			name: "missing required cookie name",
 			envVars: &EnvVars{
-				ExternalUrl:             "https://example.com",
-				UseSubDomains:           false,
-				WildcardDomain:          "",
-				ServerPort:              8080,
-				DebugEnabled:            true,
-				AuthCookieName:          "",
-				SubdomainAuthCookieName: "subdomain_codebox_auth_token",
-				UploadsPath:             "./data",
-				CliBinariesPath:         tempDir,
-				TemplatesFolder:         tempDir,
-				RunnerTokenHeader:       "X-Codebox-Runner-Token",
-				RunnerTokenQueryParam:   "runner_token",
-				DBDriver:                "mysql",
-				DBHost:                  "localhost",
-				DBPort:                  3306,
-				DBName:                  "codebox",
-				DBTestName:              "codebox-test",
-				DBUser:                  "codebox",
-				DBPassword:              "password",
-				TasksConcurrency:        5,
-				RedisHost:               "redis",
-				RedisPort:               6379,
+				ExternalUrl:              "https://example.com",
+				UseSubDomains:            false,
+				WildcardDomain:           "",
+				ServerPort:               8080,
+				DebugEnabled:             true,
+				AuthCookieName:           "",
+				SubdomainAuthCookieName:  "subdomain_codebox_auth_token",
+				UploadsPath:              "./data",
+				CliBinariesPath:          tempDir,
+				TemplatesFolder:          tempDir,
+				RunnerTokenHeader:        "X-Codebox-Runner-Token",
+				RunnerTokenQueryParam:    "runner_token",
+				RunnerSchedulingStrategy: "least_workspaces",
+				DBDriver:                 "mysql",
+				DBHost:                   "localhost",
+				DBPort:                   3306,
+				DBName:                   "codebox",
+				DBTestName:               "codebox-test",
+				DBUser:                   "codebox",
+				DBPassword:               "password",
+				TasksConcurrency:         5,
+				WorkspaceStartTimeout:    30,
+				RedisHost:                "redis",
+				RedisPort:                6379,
 			},
 			expectError: true,
 		},
@@ -1071,3 +1195,36 @@ func TestIsEmailConfigured(t *testing.T) {
 		})
 	}
 }
+
+func TestUsesHttps(t *testing.T) {
+	tests := []struct {
+		name        string
+		externalUrl string
+		expected    bool
+	}{
+		{
+			name:        "https url",
+			externalUrl: "https://example.com",
+			expected:    true,
+		},
+		{
+			name:        "http url",
+			externalUrl: "http://localhost:8080",
+			expected:    false,
+		},
+		{
+			name:        "empty url",
+			externalUrl: "",
+			expected:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EnvVars{ExternalUrl: tt.externalUrl}
+			if got := e.UsesHttps(); got != tt.expected {
+				t.Errorf("UsesHttps() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
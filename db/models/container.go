@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
@@ -8,19 +9,74 @@ import (
 )
 
 type WorkspaceContainer struct {
-	ID                uint           `gorm:"primarykey" json:"-"`
-	WorkspaceID       uint           `gorm:"column:workspace_id;" json:"-"`
-	Workspace         Workspace      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
-	ContainerID       string         `gorm:"column:container_id; size:255" json:"container_id"`
-	ContainerName     string         `gorm:"column:container_name; size:255" json:"container_name"`
-	ContainerImage    string         `gorm:"column:container_image; size:255" json:"container_image"`
-	ContainerUserID   uint           `gorm:"column:container_user_id;" json:"container_user_id"`
-	ContainerUserName string         `gorm:"size:255" json:"container_user_name"`
-	AgentLastContact  *time.Time     `gorm:"column:agent_last_contact;" json:"agent_last_contact"`
-	WorkspacePath     string         `gorm:"column:workspace_path; size:255" json:"workspace_path"`
-	CreatedAt         time.Time      `gorm:"column:created_at;" json:"created_at"`
-	UpdatedAt         time.Time      `gorm:"column:updated_at;" json:"updated_at"`
-	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                      uint           `gorm:"primarykey" json:"-"`
+	WorkspaceID             uint           `gorm:"column:workspace_id;" json:"-"`
+	Workspace               Workspace      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	ContainerID             string         `gorm:"column:container_id; size:255" json:"container_id"`
+	ContainerName           string         `gorm:"column:container_name; size:255" json:"container_name"`
+	ContainerImage          string         `gorm:"column:container_image; size:255" json:"container_image"`
+	ContainerUserID         uint           `gorm:"column:container_user_id;" json:"container_user_id"`
+	ContainerUserName       string         `gorm:"size:255" json:"container_user_name"`
+	AgentLastContact        *time.Time     `gorm:"column:agent_last_contact;" json:"agent_last_contact"`
+	WorkspacePath           string         `gorm:"column:workspace_path; size:255" json:"workspace_path"`
+	LastSshActivity         *time.Time     `gorm:"column:last_ssh_activity;" json:"last_ssh_activity"`
+	LastTerminalActivity    *time.Time     `gorm:"column:last_terminal_activity;" json:"last_terminal_activity"`
+	LastPortForwardActivity *time.Time     `gorm:"column:last_port_forward_activity;" json:"last_port_forward_activity"`
+	ResourceLimits                         // limits reported by the runner
+	CreatedAt               time.Time      `gorm:"column:created_at;" json:"created_at"`
+	UpdatedAt               time.Time      `gorm:"column:updated_at;" json:"updated_at"`
+	DeletedAt               gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// container activity types
+const (
+	ContainerActivitySsh         = "ssh"
+	ContainerActivityTerminal    = "terminal"
+	ContainerActivityPortForward = "port_forward"
+)
+
+/*
+GetLastActivity returns the time of the most recent activity
+(ssh, terminal or port forwarding) on the container.
+If there is no recorded activity, the creation time of the
+container is returned.
+*/
+func (c *WorkspaceContainer) GetLastActivity() time.Time {
+	lastActivity := c.CreatedAt
+	for _, activity := range []*time.Time{
+		c.LastSshActivity,
+		c.LastTerminalActivity,
+		c.LastPortForwardActivity,
+	} {
+		if activity != nil && activity.After(lastActivity) {
+			lastActivity = *activity
+		}
+	}
+	return lastActivity
+}
+
+/*
+RegisterWorkspaceContainerActivity records that an activity of the given
+type happened on the container now.
+*/
+func RegisterWorkspaceContainerActivity(container WorkspaceContainer, activityType string) error {
+	column := ""
+	switch activityType {
+	case ContainerActivitySsh:
+		column = "last_ssh_activity"
+	case ContainerActivityTerminal:
+		column = "last_terminal_activity"
+	case ContainerActivityPortForward:
+		column = "last_port_forward_activity"
+	default:
+		return fmt.Errorf("unknown activity type '%s'", activityType)
+	}
+
+	return dbconn.DB.
+		Model(&WorkspaceContainer{}).
+		Where("id = ?", container.ID).
+		UpdateColumn(column, time.Now()).
+		Error
 }
 
 /*
@@ -74,3 +130,15 @@ func RetrieveWorkspaceContainerByName(workspace Workspace, containerName string)
 	}
 	return &container, nil
 }
+
+/*
+DeleteWorkspaceContainer permanently deletes a workspace container and its ports.
+*/
+func DeleteWorkspaceContainer(container WorkspaceContainer) error {
+	if err := dbconn.DB.Unscoped().Delete(&[]WorkspaceContainerPort{}, map[string]interface{}{
+		"container_id": container.ID,
+	}).Error; err != nil {
+		return err
+	}
+	return dbconn.DB.Unscoped().Delete(&container).Error
+}
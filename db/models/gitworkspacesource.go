@@ -8,15 +8,17 @@ import (
 )
 
 type GitWorkspaceSource struct {
-	ID             uint           `gorm:"primarykey" json:"id"`
-	RepositoryURL  string         `gorm:"column:repository_url; type:text;not null;" json:"repository_url"`
-	RefName        string         `gorm:"column:ref_name; size:255; not null;" json:"ref_name"`
-	ConfigFilePath string         `gorm:"column:config_file_path; type:text; not null;" json:"config_file_relative_path"` // path of the configuration files relative to the root of the repo
-	SourcesID      *uint          `gorm:"column:sources_id; default: null;" json:"-"`
-	Sources        *File          `json:"-"`
-	CreatedAt      time.Time      `gorm:"column:created_at;" json:"-"`
-	UpdatedAt      time.Time      `gorm:"column:updated_at;" json:"-"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                    uint           `gorm:"primarykey" json:"id"`
+	RepositoryURL         string         `gorm:"column:repository_url; type:text;not null;" json:"repository_url"`
+	RefName               string         `gorm:"column:ref_name; size:255; not null;" json:"ref_name"`
+	ConfigFilePath        string         `gorm:"column:config_file_path; type:text; not null;" json:"config_file_relative_path"` // path of the configuration files relative to the root of the repo
+	SourcesID             *uint          `gorm:"column:sources_id; default: null;" json:"-"`
+	Sources               *File          `json:"-"`
+	RequiredRunnerLabels  RunnerLabels   `gorm:"column:required_runner_labels; type:text; serializer:json;" json:"required_runner_labels"`
+	PreferredRunnerLabels RunnerLabels   `gorm:"column:preferred_runner_labels; type:text; serializer:json;" json:"preferred_runner_labels"`
+	CreatedAt             time.Time      `gorm:"column:created_at;" json:"-"`
+	UpdatedAt             time.Time      `gorm:"column:updated_at;" json:"-"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func CreateGitWorkspaceSource(gitRepoUrl, gitRefName, configSourceFilePath string) (*GitWorkspaceSource, error) {
@@ -52,3 +54,17 @@ func UpdateGitWorkspaceSource(
 	}
 	return gitSource, nil
 }
+
+/*
+SetGitWorkspaceSourceRunnerLabels sets the runner labels
+required and preferred by the git source
+*/
+func SetGitWorkspaceSourceRunnerLabels(
+	gitSource *GitWorkspaceSource,
+	required RunnerLabels,
+	preferred RunnerLabels,
+) error {
+	gitSource.RequiredRunnerLabels = required
+	gitSource.PreferredRunnerLabels = preferred
+	return dbconn.DB.Save(gitSource).Error
+}
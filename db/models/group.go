@@ -1,15 +1,318 @@
 package models
 
 import (
+	"errors"
+	"slices"
 	"time"
 
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
 	"gorm.io/gorm"
 )
 
 type Group struct {
-	ID        uint           `gorm:"primarykey"`
-	Name      string         `gorm:"column:name; size:255;unique"`
-	CreatedAt time.Time      `gorm:"column:created_at;"`
-	UpdatedAt time.Time      `gorm:"column:updated_at;"`
+	ID        uint           `gorm:"primarykey" json:"id"`
+	Name      string         `gorm:"column:name; size:255;unique" json:"name"`
+	CreatedAt time.Time      `gorm:"column:created_at;" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"column:updated_at;" json:"-"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+/*
+ListGroups retrieves all the groups ordered by name
+*/
+func ListGroups() ([]Group, error) {
+	groups := []Group{}
+	if err := dbconn.DB.Order("name ASC").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+/*
+ListGroupsByIDs retrieves the groups with the given ids,
+groups that do not exist are ignored
+*/
+func ListGroupsByIDs(ids []uint) ([]Group, error) {
+	groups := []Group{}
+	if len(ids) == 0 {
+		return groups, nil
+	}
+
+	if err := dbconn.DB.Where("id IN ?", ids).Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+/*
+RetrieveGroupByID retrieves a group by its id,
+nil is returned if the group does not exist
+*/
+func RetrieveGroupByID(id uint) (*Group, error) {
+	var group Group
+	if err := dbconn.DB.First(&group, map[string]interface{}{
+		"id": id,
+	}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+/*
+RetrieveGroupByName retrieves a group by its name,
+nil is returned if the group does not exist
+*/
+func RetrieveGroupByName(name string) (*Group, error) {
+	var group Group
+	if err := dbconn.DB.First(&group, map[string]interface{}{
+		"name": name,
+	}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+/*
+CreateGroup creates a new group
+*/
+func CreateGroup(name string) (*Group, error) {
+	group := Group{
+		Name: name,
+	}
+
+	if err := dbconn.DB.Create(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+/*
+UpdateGroup updates a group
+*/
+func UpdateGroup(group Group) error {
+	return dbconn.DB.Save(&group).Error
+}
+
+/*
+DeleteGroup deletes a group, members, runners and templates
+are removed from the group before deleting it
+*/
+func DeleteGroup(group Group) error {
+	return dbconn.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_groups WHERE group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM runner_allowed_groups WHERE group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM workspace_template_allowed_groups WHERE group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&group).Error
+	})
+}
+
+/*
+ListGroupMembers retrieves the users that are members of the group
+*/
+func ListGroupMembers(group Group) ([]User, error) {
+	users := []User{}
+	if err := dbconn.DB.
+		Joins("JOIN user_groups ON user_groups.user_id = users.id").
+		Where("user_groups.group_id = ?", group.ID).
+		Order("users.email ASC").
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+/*
+CountGroupMembers counts the users that are members of the group
+*/
+func CountGroupMembers(group Group) (int64, error) {
+	var count int64
+	if err := dbconn.DB.
+		Table("user_groups").
+		Joins("JOIN users ON users.id = user_groups.user_id AND users.deleted_at IS NULL").
+		Where("user_groups.group_id = ?", group.ID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+/*
+CountMembersByGroup counts the members of the groups with a single query,
+groups without members are not in the map
+*/
+func CountMembersByGroup(groups []Group) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	if len(groups) == 0 {
+		return counts, nil
+	}
+
+	groupIDs := make([]uint, len(groups))
+	for i, group := range groups {
+		groupIDs[i] = group.ID
+	}
+
+	rows := []struct {
+		GroupID uint
+		Count   int64
+	}{}
+	if err := dbconn.DB.
+		Table("user_groups").
+		Select("user_groups.group_id AS group_id, COUNT(*) AS count").
+		Joins("JOIN users ON users.id = user_groups.user_id AND users.deleted_at IS NULL").
+		Where("user_groups.group_id IN ?", groupIDs).
+		Group("user_groups.group_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.GroupID] = row.Count
+	}
+	return counts, nil
+}
+
+/*
+AddUsersToGroup adds the users to the group,
+users that are already members are ignored
+*/
+func AddUsersToGroup(group Group, users []User) error {
+	for _, user := range users {
+		if err := dbconn.DB.Model(&user).Association("Groups").Append(&group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+RemoveUserFromGroup removes the user from the group
+*/
+func RemoveUserFromGroup(group Group, user User) error {
+	return dbconn.DB.Model(&user).Association("Groups").Delete(&group)
+}
+
+/*
+SetGroupMembers replaces the members of the group with the given users
+*/
+func SetGroupMembers(group Group, users []User) error {
+	return dbconn.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_groups WHERE group_id = ?", group.ID).Error; err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := tx.Model(&user).Association("Groups").Append(&group); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+/*
+SetUserGroupsByName replaces the groups of the user with the existing
+groups whose name is in the list, names that do not match a group are ignored
+*/
+func SetUserGroupsByName(user User, names []string) error {
+	flagged := map[uint][]Workspace{}
+	runners := []Runner{}
+
+	err := dbconn.DB.Transaction(func(tx *gorm.DB) error {
+		groups := []Group{}
+		if len(names) > 0 {
+			if err := tx.Where("name IN ?", names).Find(&groups).Error; err != nil {
+				return err
+			}
+		}
+
+		previousGroups := []Group{}
+		if err := tx.Model(&user).Association("Groups").Find(&previousGroups); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user).Association("Groups").Replace(groups); err != nil {
+			return err
+		}
+
+		// the access to the runners restricted to the added or removed groups may have changed
+		changedGroupIDs := []uint{}
+		for _, group := range append(groups, previousGroups...) {
+			if !slices.Contains(changedGroupIDs, group.ID) {
+				changedGroupIDs = append(changedGroupIDs, group.ID)
+			}
+		}
+
+		if len(changedGroupIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.
+			Distinct("runners.*").
+			Joins("JOIN runner_allowed_groups ON runner_allowed_groups.runner_id = runners.id").
+			Where("runner_allowed_groups.group_id IN ?", changedGroupIDs).
+			Find(&runners).Error; err != nil {
+			return err
+		}
+
+		for _, runner := range runners {
+			workspaces, err := refreshRunnerAccessFlags(tx, runner)
+			if err != nil {
+				return err
+			}
+			flagged[runner.ID] = workspaces
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, runner := range runners {
+		logRunnerAccessRevoked(runner, flagged[runner.ID])
+	}
+	return nil
+}
+
+/*
+ListRunnersByAllowedGroup retrieves the runners that the group is allowed to use
+*/
+func ListRunnersByAllowedGroup(group Group) ([]Runner, error) {
+	runners := []Runner{}
+	if err := dbconn.DB.
+		Joins("JOIN runner_allowed_groups ON runner_allowed_groups.runner_id = runners.id").
+		Where("runner_allowed_groups.group_id = ?", group.ID).
+		Find(&runners).Error; err != nil {
+		return nil, err
+	}
+	return runners, nil
+}
+
+/*
+GetGroups retrieves the groups the user is member of
+*/
+func (u *User) GetGroups() ([]Group, error) {
+	groups := []Group{}
+	if u.ID == 0 {
+		return groups, nil
+	}
+
+	if err := dbconn.DB.Model(u).Association("Groups").Find(&groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
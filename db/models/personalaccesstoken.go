@@ -0,0 +1,243 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gorm.io/gorm"
+)
+
+// prefix of the personal access tokens, used to tell them apart from the session tokens
+const PersonalAccessTokenPrefix = "cbx_"
+
+// personal access tokens cannot last more than one year
+const PersonalAccessTokenMaxDurationDays = 365
+
+// scopes that can be granted to a personal access token
+const (
+	PersonalAccessTokenScopeWorkspacesRead  = "workspaces:read"
+	PersonalAccessTokenScopeWorkspacesWrite = "workspaces:write"
+	PersonalAccessTokenScopeTemplatesWrite  = "templates:write"
+	PersonalAccessTokenScopeSsh             = "ssh"
+	PersonalAccessTokenScopeAdmin           = "admin"
+)
+
+var PersonalAccessTokenScopes = []string{
+	PersonalAccessTokenScopeWorkspacesRead,
+	PersonalAccessTokenScopeWorkspacesWrite,
+	PersonalAccessTokenScopeTemplatesWrite,
+	PersonalAccessTokenScopeSsh,
+	PersonalAccessTokenScopeAdmin,
+}
+
+/*
+PersonalAccessToken authenticates api requests on behalf of the user,
+it can be used only for the apis allowed by its scopes.
+Only the hash of the token is stored, the prefix is kept to recognize it
+*/
+type PersonalAccessToken struct {
+	ID          uint       `gorm:"primarykey"`
+	UserID      uint       `gorm:"column:user_id; not null;"`
+	User        User       `gorm:"constraint:OnDelete:CASCADE;"`
+	Name        string     `gorm:"column:name; size:255; not null;"`
+	TokenHash   string     `gorm:"column:token_hash; size:64; unique; not null;"`
+	TokenPrefix string     `gorm:"column:token_prefix; size:16; not null;"`
+	Scopes      string     `gorm:"column:scopes; size:255; not null;"` // space separated
+	ExpiresAt   time.Time  `gorm:"column:expires_at; not null;"`
+	LastUsedAt  *time.Time `gorm:"column:last_used_at;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func hashPersonalAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func generatePersonalAccessToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return PersonalAccessTokenPrefix + base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(b)
+}
+
+/*
+IsPersonalAccessToken checks if the value of an authorization token is a personal access token
+*/
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+/*
+ValidatePersonalAccessTokenScopes checks that the scopes are
+known and removes the duplicates, at least one scope is required
+*/
+func ValidatePersonalAccessTokenScopes(scopes []string) ([]string, error) {
+	validScopes := []string{}
+	for _, scope := range scopes {
+		if !slices.Contains(PersonalAccessTokenScopes, scope) {
+			return nil, errors.New("invalid scope '" + scope + "'")
+		}
+		if !slices.Contains(validScopes, scope) {
+			validScopes = append(validScopes, scope)
+		}
+	}
+
+	if len(validScopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return validScopes, nil
+}
+
+/*
+GetScopes returns the scopes granted to the token
+*/
+func (t *PersonalAccessToken) GetScopes() []string {
+	return strings.Fields(t.Scopes)
+}
+
+/*
+HasScope checks if the scope has been granted to the token,
+the workspaces:write scope includes workspaces:read
+*/
+func (t *PersonalAccessToken) HasScope(scope string) bool {
+	scopes := t.GetScopes()
+	if slices.Contains(scopes, scope) {
+		return true
+	}
+	return scope == PersonalAccessTokenScopeWorkspacesRead &&
+		slices.Contains(scopes, PersonalAccessTokenScopeWorkspacesWrite)
+}
+
+/*
+IsExpired checks if the token has expired
+*/
+func (t *PersonalAccessToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
+
+/*
+ListPersonalAccessTokens retrieves the personal access tokens of the user
+*/
+func ListPersonalAccessTokens(user User) ([]PersonalAccessToken, error) {
+	tokens := []PersonalAccessToken{}
+	if err := dbconn.DB.
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+/*
+RetrievePersonalAccessTokenByID retrieves a personal access token
+of the user by id, nil is returned if it does not exist
+*/
+func RetrievePersonalAccessTokenByID(user User, id uint) (*PersonalAccessToken, error) {
+	var token PersonalAccessToken
+	if err := dbconn.DB.
+		First(&token, map[string]interface{}{
+			"id":      id,
+			"user_id": user.ID,
+		}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+/*
+RetrievePersonalAccessTokenByToken retrieves a personal access token
+by its value, nil is returned if it does not exist
+*/
+func RetrievePersonalAccessTokenByToken(token string) (*PersonalAccessToken, error) {
+	var pat PersonalAccessToken
+	if err := dbconn.DB.
+		Preload("User").
+		First(&pat, map[string]interface{}{
+			"token_hash": hashPersonalAccessToken(token),
+		}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &pat, nil
+}
+
+/*
+CreatePersonalAccessToken creates a personal access token for the user,
+the returned value of the token must be shown to the user because it cannot be retrieved later
+*/
+func CreatePersonalAccessToken(
+	user User,
+	name string,
+	scopes []string,
+	expiresAt time.Time,
+) (*PersonalAccessToken, string, error) {
+	value := generatePersonalAccessToken()
+
+	token := PersonalAccessToken{
+		UserID:      user.ID,
+		User:        user,
+		Name:        name,
+		TokenHash:   hashPersonalAccessToken(value),
+		TokenPrefix: value[:len(PersonalAccessTokenPrefix)+8],
+		Scopes:      strings.Join(scopes, " "),
+		ExpiresAt:   expiresAt,
+	}
+
+	if err := dbconn.DB.Create(&token).Error; err != nil {
+		return nil, "", err
+	}
+	return &token, value, nil
+}
+
+/*
+TouchPersonalAccessToken records the use of the token,
+the last use is updated at most once per minute
+*/
+func TouchPersonalAccessToken(token *PersonalAccessToken) error {
+	now := time.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < time.Minute {
+		return nil
+	}
+
+	if err := dbconn.DB.
+		Model(&PersonalAccessToken{}).
+		Where("id = ?", token.ID).
+		UpdateColumn("last_used_at", &now).Error; err != nil {
+		return err
+	}
+	token.LastUsedAt = &now
+	return nil
+}
+
+/*
+RevokeAllPersonalAccessTokensForUser deletes all the personal access tokens of the user,
+including the ones created by the cli login
+*/
+func RevokeAllPersonalAccessTokensForUser(user User) error {
+	return dbconn.DB.Unscoped().
+		Where("user_id = ?", user.ID).
+		Delete(&PersonalAccessToken{}).Error
+}
+
+/*
+DeletePersonalAccessToken revokes a personal access token
+*/
+func DeletePersonalAccessToken(token *PersonalAccessToken) error {
+	return dbconn.DB.Unscoped().Delete(token).Error
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// query parameter used to pass a share token to a forwarded port
+const PortShareTokenQueryParam = "codebox_share_token"
+
+/*
+PortShareToken gives access to a private port to users that are not
+collaborators of the workspace until it expires or it is used max uses times.
+The cookie secret is the value of the cookie set when the token is used,
+it is never exposed so the password cannot be bypassed knowing the token
+*/
+type PortShareToken struct {
+	ID           uint                    `gorm:"primarykey"`
+	PortID       uint                    `gorm:"column:port_id; not null;"`
+	Port         *WorkspaceContainerPort `gorm:"constraint:OnDelete:CASCADE;"`
+	Name         string                  `gorm:"column:name; size:255;"`
+	Token        string                  `gorm:"column:token; size:255; unique; not null;"`
+	CookieSecret string                  `gorm:"column:cookie_secret; size:255; not null;"`
+	PasswordHash string                  `gorm:"column:password_hash; size:255;"`
+	ExpiresAt    time.Time               `gorm:"column:expires_at; not null;"`
+	MaxUses      *uint                   `gorm:"column:max_uses;"` // nil means unlimited
+	Uses         uint                    `gorm:"column:uses; default:0;"`
+	CreatedByID  *uint                   `gorm:"column:created_by_id;"`
+	CreatedBy    *User                   `gorm:"constraint:OnDelete:SET NULL;"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func generatePortShareSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(b)
+}
+
+/*
+HasPassword checks if a password is required to use the token
+*/
+func (t *PortShareToken) HasPassword() bool {
+	return t.PasswordHash != ""
+}
+
+/*
+CheckPassword checks the password of the token
+*/
+func (t *PortShareToken) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(t.PasswordHash), []byte(password)) == nil
+}
+
+/*
+CheckCookieSecret checks the value of the cookie set when the token has been used
+*/
+func (t *PortShareToken) CheckCookieSecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(t.CookieSecret), []byte(secret)) == 1
+}
+
+/*
+IsValid checks that the token has not expired and that it has not
+been used more times than allowed, a token can be used max uses times
+*/
+func (t *PortShareToken) IsValid() bool {
+	if time.Now().After(t.ExpiresAt) {
+		return false
+	}
+	return t.MaxUses == nil || t.Uses < *t.MaxUses
+}
+
+/*
+ListPortShareTokens retrieves the share tokens of a port
+*/
+func ListPortShareTokens(port WorkspaceContainerPort) ([]PortShareToken, error) {
+	tokens := []PortShareToken{}
+	if err := dbconn.DB.
+		Preload("CreatedBy").
+		Where("port_id = ?", port.ID).
+		Order("created_at ASC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+/*
+RetrievePortShareTokenByID retrieves a share token of a port by id,
+nil is returned if it does not exist
+*/
+func RetrievePortShareTokenByID(port WorkspaceContainerPort, id uint) (*PortShareToken, error) {
+	var token PortShareToken
+	if err := dbconn.DB.
+		Preload("CreatedBy").
+		First(&token, map[string]interface{}{
+			"id":      id,
+			"port_id": port.ID,
+		}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+/*
+RetrievePortShareTokenByToken retrieves a share token of a port
+by its value, nil is returned if it does not exist
+*/
+func RetrievePortShareTokenByToken(port WorkspaceContainerPort, token string) (*PortShareToken, error) {
+	var shareToken PortShareToken
+	if err := dbconn.DB.
+		First(&shareToken, map[string]interface{}{
+			"token":   token,
+			"port_id": port.ID,
+		}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &shareToken, nil
+}
+
+/*
+CreatePortShareToken creates a share token for a port,
+an empty password means that no password is required
+*/
+func CreatePortShareToken(
+	port WorkspaceContainerPort,
+	createdBy User,
+	name string,
+	expiresAt time.Time,
+	password string,
+	maxUses *uint,
+) (*PortShareToken, error) {
+	passwordHash := ""
+	if password != "" {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		passwordHash = hash
+	}
+
+	token := PortShareToken{
+		PortID:       port.ID,
+		Name:         name,
+		Token:        generatePortShareSecret(),
+		CookieSecret: generatePortShareSecret(),
+		PasswordHash: passwordHash,
+		ExpiresAt:    expiresAt,
+		MaxUses:      maxUses,
+		CreatedByID:  &createdBy.ID,
+		CreatedBy:    &createdBy,
+	}
+
+	if err := dbconn.DB.Create(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+/*
+UsePortShareToken counts a use of the token, false is returned if the
+token cannot be used anymore. The check is performed by the update itself
+so concurrent requests cannot exceed max uses
+*/
+func UsePortShareToken(token *PortShareToken) (bool, error) {
+	r := dbconn.DB.
+		Model(&PortShareToken{}).
+		Where("id = ? AND expires_at > ? AND (max_uses IS NULL OR uses < max_uses)", token.ID, time.Now()).
+		Update("uses", gorm.Expr("uses + 1"))
+
+	if r.Error != nil {
+		return false, r.Error
+	}
+
+	if r.RowsAffected == 0 {
+		return false, nil
+	}
+
+	token.Uses++
+	return true, nil
+}
+
+/*
+DeletePortShareToken revokes a share token, the
+cookies set using the token stop working as well
+*/
+func DeletePortShareToken(token *PortShareToken) error {
+	return dbconn.DB.Delete(token).Error
+}
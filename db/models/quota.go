@@ -0,0 +1,399 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gorm.io/gorm"
+)
+
+// source of an effective quota limit
+const (
+	QuotaSourceGlobal = "global"
+	QuotaSourceGroup  = "group"
+	QuotaSourceUser   = "user"
+)
+
+/*
+QuotaLimits contains the limits of a quota, a nil limit is not set:
+it is inherited from the group or global quota, or unlimited
+*/
+type QuotaLimits struct {
+	MaxWorkspaces             *uint `gorm:"column:max_workspaces;" json:"max_workspaces"`
+	MaxRunningWorkspaces      *uint `gorm:"column:max_running_workspaces;" json:"max_running_workspaces"`
+	MaxContainersPerWorkspace *uint `gorm:"column:max_containers_per_workspace;" json:"max_containers_per_workspace"`
+}
+
+/*
+WorkspaceQuota overrides the global quota for a group or for a user,
+exactly one of group and user is set
+*/
+type WorkspaceQuota struct {
+	ID      uint   `gorm:"primarykey"`
+	GroupID *uint  `gorm:"column:group_id; uniqueIndex;"`
+	Group   *Group `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID  *uint  `gorm:"column:user_id; uniqueIndex;"`
+	User    *User  `gorm:"constraint:OnDelete:CASCADE;"`
+	QuotaLimits
+	CreatedAt time.Time      `gorm:"column:created_at;"`
+	UpdatedAt time.Time      `gorm:"column:updated_at;"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// the table is created by the migrations as workspace_quotas
+func (WorkspaceQuota) TableName() string {
+	return "workspace_quotas"
+}
+
+/*
+EffectiveQuotaLimit is the limit applied to a user,
+Source describes where the limit has been set
+*/
+type EffectiveQuotaLimit struct {
+	Limit  *uint
+	Source string
+}
+
+/*
+EffectiveQuota contains the limits applied to a user
+*/
+type EffectiveQuota struct {
+	MaxWorkspaces             EffectiveQuotaLimit
+	MaxRunningWorkspaces      EffectiveQuotaLimit
+	MaxContainersPerWorkspace EffectiveQuotaLimit
+}
+
+/*
+QuotaExceededError is returned when an action would exceed a quota,
+the message explains which limit has been hit
+*/
+type QuotaExceededError struct {
+	Message string
+}
+
+func (e *QuotaExceededError) Error() string {
+	return e.Message
+}
+
+/*
+RetrieveGroupQuota retrieves the quota of a group,
+nil is returned if the group has no quota
+*/
+func RetrieveGroupQuota(group Group) (*WorkspaceQuota, error) {
+	return retrieveWorkspaceQuota("group_id", group.ID)
+}
+
+/*
+RetrieveUserQuota retrieves the quota of a user,
+nil is returned if the user has no quota
+*/
+func RetrieveUserQuota(user User) (*WorkspaceQuota, error) {
+	return retrieveWorkspaceQuota("user_id", user.ID)
+}
+
+func retrieveWorkspaceQuota(column string, id uint) (*WorkspaceQuota, error) {
+	var quota WorkspaceQuota
+	if err := dbconn.DB.First(&quota, map[string]interface{}{
+		column: id,
+	}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &quota, nil
+}
+
+/*
+SetGroupQuota creates or updates the quota of a group
+*/
+func SetGroupQuota(group Group, limits QuotaLimits) (*WorkspaceQuota, error) {
+	quota, err := RetrieveGroupQuota(group)
+	if err != nil {
+		return nil, err
+	}
+
+	if quota == nil {
+		quota = &WorkspaceQuota{GroupID: &group.ID}
+	}
+
+	quota.QuotaLimits = limits
+	if err := dbconn.DB.Save(quota).Error; err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
+/*
+SetUserQuota creates or updates the quota of a user
+*/
+func SetUserQuota(user User, limits QuotaLimits) (*WorkspaceQuota, error) {
+	quota, err := RetrieveUserQuota(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if quota == nil {
+		quota = &WorkspaceQuota{UserID: &user.ID}
+	}
+
+	quota.QuotaLimits = limits
+	if err := dbconn.DB.Save(quota).Error; err != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
+/*
+DeleteWorkspaceQuota deletes a group or user quota
+*/
+func DeleteWorkspaceQuota(quota WorkspaceQuota) error {
+	return dbconn.DB.Unscoped().Delete(&quota).Error
+}
+
+/*
+GetEffectiveQuota retrieves the limits applied to a user. For each limit
+the user quota has the precedence, then the highest limit among the groups
+of the user, then the global quota. If none of them is set there is no limit
+*/
+func GetEffectiveQuota(user User) (*EffectiveQuota, error) {
+	settings, err := GetSingletonModelInstance[QuotaSettings]()
+	if err != nil {
+		return nil, err
+	}
+
+	quota := EffectiveQuota{
+		MaxWorkspaces:             EffectiveQuotaLimit{settings.MaxWorkspaces, QuotaSourceGlobal},
+		MaxRunningWorkspaces:      EffectiveQuotaLimit{settings.MaxRunningWorkspaces, QuotaSourceGlobal},
+		MaxContainersPerWorkspace: EffectiveQuotaLimit{settings.MaxContainersPerWorkspace, QuotaSourceGlobal},
+	}
+
+	// group quotas, the highest limit wins
+	var groupQuotas []WorkspaceQuota
+	if err := dbconn.DB.
+		Preload("Group").
+		Joins("JOIN user_groups ON user_groups.group_id = workspace_quotas.group_id").
+		Where("user_groups.user_id = ?", user.ID).
+		Find(&groupQuotas).Error; err != nil {
+		return nil, err
+	}
+
+	groupLimits := EffectiveQuota{}
+	for _, groupQuota := range groupQuotas {
+		source := QuotaSourceGroup
+		if groupQuota.Group != nil {
+			source = fmt.Sprintf("group '%s'", groupQuota.Group.Name)
+		}
+
+		mergeHighestQuotaLimit(&groupLimits.MaxWorkspaces, groupQuota.MaxWorkspaces, source)
+		mergeHighestQuotaLimit(&groupLimits.MaxRunningWorkspaces, groupQuota.MaxRunningWorkspaces, source)
+		mergeHighestQuotaLimit(&groupLimits.MaxContainersPerWorkspace, groupQuota.MaxContainersPerWorkspace, source)
+	}
+
+	overrideQuotaLimit(&quota.MaxWorkspaces, groupLimits.MaxWorkspaces)
+	overrideQuotaLimit(&quota.MaxRunningWorkspaces, groupLimits.MaxRunningWorkspaces)
+	overrideQuotaLimit(&quota.MaxContainersPerWorkspace, groupLimits.MaxContainersPerWorkspace)
+
+	// user quota
+	userQuota, err := RetrieveUserQuota(user)
+	if err != nil {
+		return nil, err
+	}
+
+	if userQuota != nil {
+		overrideQuotaLimit(&quota.MaxWorkspaces, EffectiveQuotaLimit{userQuota.MaxWorkspaces, QuotaSourceUser})
+		overrideQuotaLimit(&quota.MaxRunningWorkspaces, EffectiveQuotaLimit{userQuota.MaxRunningWorkspaces, QuotaSourceUser})
+		overrideQuotaLimit(&quota.MaxContainersPerWorkspace, EffectiveQuotaLimit{userQuota.MaxContainersPerWorkspace, QuotaSourceUser})
+	}
+
+	return &quota, nil
+}
+
+func mergeHighestQuotaLimit(current *EffectiveQuotaLimit, limit *uint, source string) {
+	if limit == nil {
+		return
+	}
+
+	if current.Limit == nil || *limit > *current.Limit {
+		current.Limit = limit
+		current.Source = source
+	}
+}
+
+func overrideQuotaLimit(current *EffectiveQuotaLimit, override EffectiveQuotaLimit) {
+	if override.Limit != nil {
+		*current = override
+	}
+}
+
+/*
+CountUserWorkspaces counts the workspaces owned by the user
+*/
+func CountUserWorkspaces(user User) (int64, error) {
+	var count int64
+	if err := dbconn.DB.
+		Model(&Workspace{}).
+		Where("user_id = ?", user.ID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+/*
+CountUserRunningWorkspaces counts the workspaces owned by the user that
+are starting or running, the workspace with the excluded id is not counted
+*/
+func CountUserRunningWorkspaces(user User, excludedWorkspaceId uint) (int64, error) {
+	var count int64
+	if err := dbconn.DB.
+		Model(&Workspace{}).
+		Where("user_id = ?", user.ID).
+		Where("id <> ?", excludedWorkspaceId).
+		Where("status IN ?", []string{
+			WorkspaceStatusStarting,
+			WorkspaceStatusRunning,
+		}).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+/*
+CheckCreateWorkspaceQuota checks if the user can create another workspace,
+a QuotaExceededError is returned if the limit has been reached
+*/
+func CheckCreateWorkspaceQuota(user User) error {
+	quota, err := GetEffectiveQuota(user)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxWorkspaces.Limit == nil {
+		return nil
+	}
+
+	count, err := CountUserWorkspaces(user)
+	if err != nil {
+		return err
+	}
+
+	if count >= int64(*quota.MaxWorkspaces.Limit) {
+		return &QuotaExceededError{Message: fmt.Sprintf(
+			"quota exceeded: you can have at most %d workspaces (%s limit)",
+			*quota.MaxWorkspaces.Limit,
+			quota.MaxWorkspaces.Source,
+		)}
+	}
+	return nil
+}
+
+/*
+CheckStartWorkspaceQuota checks if the user can start the workspace without
+exceeding the limit of running workspaces, a QuotaExceededError is returned
+if the limit has been reached
+*/
+func CheckStartWorkspaceQuota(user User, workspace Workspace) error {
+	quota, err := GetEffectiveQuota(user)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxRunningWorkspaces.Limit == nil {
+		return nil
+	}
+
+	count, err := CountUserRunningWorkspaces(user, workspace.ID)
+	if err != nil {
+		return err
+	}
+
+	if count >= int64(*quota.MaxRunningWorkspaces.Limit) {
+		return &QuotaExceededError{Message: fmt.Sprintf(
+			"quota exceeded: you can have at most %d running workspaces (%s limit)",
+			*quota.MaxRunningWorkspaces.Limit,
+			quota.MaxRunningWorkspaces.Source,
+		)}
+	}
+	return nil
+}
+
+/*
+CheckTransferWorkspacesQuota checks if the workspaces can be transferred to the
+user without exceeding the limits of workspaces and of running workspaces,
+a QuotaExceededError is returned if a limit would be exceeded
+*/
+func CheckTransferWorkspacesQuota(user User, workspaces []Workspace) error {
+	quota, err := GetEffectiveQuota(user)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxWorkspaces.Limit != nil {
+		count, err := CountUserWorkspaces(user)
+		if err != nil {
+			return err
+		}
+
+		if count+int64(len(workspaces)) > int64(*quota.MaxWorkspaces.Limit) {
+			return &QuotaExceededError{Message: fmt.Sprintf(
+				"quota exceeded: '%s' can have at most %d workspaces (%s limit)",
+				user.Email,
+				*quota.MaxWorkspaces.Limit,
+				quota.MaxWorkspaces.Source,
+			)}
+		}
+	}
+
+	if quota.MaxRunningWorkspaces.Limit != nil {
+		count, err := CountUserRunningWorkspaces(user, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, workspace := range workspaces {
+			if workspace.Status == WorkspaceStatusRunning {
+				count++
+			}
+		}
+
+		if count > int64(*quota.MaxRunningWorkspaces.Limit) {
+			return &QuotaExceededError{Message: fmt.Sprintf(
+				"quota exceeded: '%s' can have at most %d running workspaces (%s limit)",
+				user.Email,
+				*quota.MaxRunningWorkspaces.Limit,
+				quota.MaxRunningWorkspaces.Source,
+			)}
+		}
+	}
+
+	return nil
+}
+
+/*
+CheckContainersQuota checks if a workspace of the user can have the given
+number of containers, a QuotaExceededError is returned if the limit is exceeded
+*/
+func CheckContainersQuota(user User, containersCount int) error {
+	quota, err := GetEffectiveQuota(user)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxContainersPerWorkspace.Limit == nil {
+		return nil
+	}
+
+	if containersCount > int(*quota.MaxContainersPerWorkspace.Limit) {
+		return &QuotaExceededError{Message: fmt.Sprintf(
+			"quota exceeded: a workspace can have at most %d containers (%s limit), the workspace has %d containers",
+			*quota.MaxContainersPerWorkspace.Limit,
+			quota.MaxContainersPerWorkspace.Source,
+			containersCount,
+		)}
+	}
+	return nil
+}
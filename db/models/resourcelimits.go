@@ -0,0 +1,180 @@
+package models
+
+import (
+	"fmt"
+)
+
+// capability advertised by runners that can enforce resource limits
+const RunnerCapabilityResourceLimits = "resource_limits"
+
+/*
+ResourceLimits contains the resources a workspace can use,
+a nil limit is not set
+*/
+type ResourceLimits struct {
+	CPULimit      *float64 `gorm:"column:cpu_limit;" json:"cpu_limit"`             // number of cpus
+	MemoryLimitMB *uint    `gorm:"column:memory_limit_mb;" json:"memory_limit_mb"` // megabytes
+	DiskLimitMB   *uint    `gorm:"column:disk_limit_mb;" json:"disk_limit_mb"`     // megabytes
+}
+
+/*
+InvalidResourceLimitsError is returned when a limit is not valid
+or exceeds the maximum set by the administrators
+*/
+type InvalidResourceLimitsError struct {
+	Message string
+}
+
+func (e *InvalidResourceLimitsError) Error() string {
+	return e.Message
+}
+
+// IsSet checks if at least one limit is set
+func (l ResourceLimits) IsSet() bool {
+	return l.CPULimit != nil || l.MemoryLimitMB != nil || l.DiskLimitMB != nil
+}
+
+/*
+ValidateResourceLimits checks that the limits are positive
+and that they do not exceed the maximum resource limits
+*/
+func ValidateResourceLimits(limits ResourceLimits) error {
+	settings, err := GetSingletonModelInstance[ResourceLimitsSettings]()
+	if err != nil {
+		return err
+	}
+	return validateResourceLimits(limits, settings.MaxResourceLimits)
+}
+
+/*
+ValidateMaxResourceLimits checks that the maximum
+resource limits set by the administrators are positive
+*/
+func ValidateMaxResourceLimits(limits ResourceLimits) error {
+	return validateResourceLimits(limits, ResourceLimits{})
+}
+
+func validateResourceLimits(limits ResourceLimits, maxLimits ResourceLimits) error {
+	if limits.CPULimit != nil {
+		if *limits.CPULimit <= 0 {
+			return &InvalidResourceLimitsError{Message: "cpu_limit must be greater than 0"}
+		}
+
+		if maxLimits.CPULimit != nil && *limits.CPULimit > *maxLimits.CPULimit {
+			return &InvalidResourceLimitsError{
+				Message: fmt.Sprintf("cpu_limit cannot exceed %g cpus", *maxLimits.CPULimit),
+			}
+		}
+	}
+
+	if limits.MemoryLimitMB != nil {
+		if *limits.MemoryLimitMB == 0 {
+			return &InvalidResourceLimitsError{Message: "memory_limit_mb must be greater than 0"}
+		}
+
+		if maxLimits.MemoryLimitMB != nil && *limits.MemoryLimitMB > *maxLimits.MemoryLimitMB {
+			return &InvalidResourceLimitsError{
+				Message: fmt.Sprintf("memory_limit_mb cannot exceed %d MB", *maxLimits.MemoryLimitMB),
+			}
+		}
+	}
+
+	if limits.DiskLimitMB != nil {
+		if *limits.DiskLimitMB == 0 {
+			return &InvalidResourceLimitsError{Message: "disk_limit_mb must be greater than 0"}
+		}
+
+		if maxLimits.DiskLimitMB != nil && *limits.DiskLimitMB > *maxLimits.DiskLimitMB {
+			return &InvalidResourceLimitsError{
+				Message: fmt.Sprintf("disk_limit_mb cannot exceed %d MB", *maxLimits.DiskLimitMB),
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+GetRequestedResourceLimits retrieves the limits requested for the workspace:
+the limits set on the workspace override the ones set on the template.
+The maximum resource limits are not applied
+*/
+func (w *Workspace) GetRequestedResourceLimits() (ResourceLimits, error) {
+	limits := ResourceLimits{}
+	if w.ConfigSource == WorkspaceConfigSourceTemplate && w.TemplateVersion != nil {
+		template := w.TemplateVersion.Template
+		if template == nil {
+			var err error
+			template, err = RetrieveWorkspaceTemplateByID(w.TemplateVersion.TemplateID)
+			if err != nil {
+				return ResourceLimits{}, err
+			}
+		}
+
+		if template != nil {
+			limits = template.ResourceLimits
+		}
+	}
+
+	if w.CPULimit != nil {
+		limits.CPULimit = w.CPULimit
+	}
+
+	if w.MemoryLimitMB != nil {
+		limits.MemoryLimitMB = w.MemoryLimitMB
+	}
+
+	if w.DiskLimitMB != nil {
+		limits.DiskLimitMB = w.DiskLimitMB
+	}
+
+	return limits, nil
+}
+
+/*
+GetResourceLimits retrieves the limits applied to the workspace: the requested
+limits are bounded by the maximum resource limits, so a workspace without
+limits gets the maximum ones
+*/
+func (w *Workspace) GetResourceLimits() (ResourceLimits, error) {
+	limits, err := w.GetRequestedResourceLimits()
+	if err != nil {
+		return ResourceLimits{}, err
+	}
+
+	settings, err := GetSingletonModelInstance[ResourceLimitsSettings]()
+	if err != nil {
+		return ResourceLimits{}, err
+	}
+	maxLimits := settings.MaxResourceLimits
+
+	if maxLimits.CPULimit != nil && (limits.CPULimit == nil || *limits.CPULimit > *maxLimits.CPULimit) {
+		limits.CPULimit = maxLimits.CPULimit
+	}
+
+	if maxLimits.MemoryLimitMB != nil && (limits.MemoryLimitMB == nil || *limits.MemoryLimitMB > *maxLimits.MemoryLimitMB) {
+		limits.MemoryLimitMB = maxLimits.MemoryLimitMB
+	}
+
+	if maxLimits.DiskLimitMB != nil && (limits.DiskLimitMB == nil || *limits.DiskLimitMB > *maxLimits.DiskLimitMB) {
+		limits.DiskLimitMB = maxLimits.DiskLimitMB
+	}
+
+	return limits, nil
+}
+
+/*
+CheckRunnerResourceLimitsSupport checks if the runner can enforce
+the limits, limits can be applied only by runners that advertise it.
+The limits must be the requested ones: the maximum resource limits are
+enforced only by the runners that support them, so that workspaces without
+limits can still run on the other runners
+*/
+func CheckRunnerResourceLimitsSupport(runner Runner, limits ResourceLimits) error {
+	if !limits.IsSet() || runner.HasCapability(RunnerCapabilityResourceLimits) {
+		return nil
+	}
+	return &InvalidResourceLimitsError{
+		Message: fmt.Sprintf("runner '%s' does not support resource limits", runner.Name),
+	}
+}
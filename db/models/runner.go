@@ -10,6 +10,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// capability advertised by runners that can forward tcp streams to the agents
+const RunnerCapabilityTcpForwarding = "tcp_forwarding"
+
 type Runner struct {
 	ID                 uint           `gorm:"primarykey" json:"id"`
 	Name               string         `gorm:"column:name; size:255;unique;not null;" json:"name"`
@@ -22,12 +25,37 @@ type Runner struct {
 	PublicUrl          string         `gorm:"column:public_url; type:text;" json:"public_url"`
 	LastContact        *time.Time     `gorm:"column:last_contact;" json:"last_contact"`
 	Version            string         `gorm:"column:version; default:''; size:255;" json:"version"`
+	Labels             RunnerLabels   `gorm:"column:labels; type:text; serializer:json;" json:"labels"`
+	Capabilities       []string       `gorm:"column:capabilities; type:text; serializer:json;" json:"capabilities"` // advertised by the runner
 	DeletionInProgress bool           `gorm:"column:deletion_in_progress;default:false;not null;"`
 	CreatedAt          time.Time      `gorm:"column:created_at;" json:"-"`
 	UpdatedAt          time.Time      `gorm:"column:updated_at;" json:"-"`
 	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+/*
+GetCapabilities retrieves the capabilities advertised by the
+runner, runners that have not advertised any have none
+*/
+func (r Runner) GetCapabilities() []string {
+	if r.Capabilities == nil {
+		return []string{}
+	}
+	return r.Capabilities
+}
+
+/*
+HasCapability checks if the runner has advertised a capability
+*/
+func (r Runner) HasCapability(capability string) bool {
+	for _, c := range r.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
 /*
 ListRunners retrieves a list of runners with pagination support.
 If limit is -1, it retrieves all runners.
@@ -40,6 +68,22 @@ func ListRunners(limit int, offset int) ([]Runner, error) {
 	return runners, nil
 }
 
+/*
+ListRunnersWithAllowedGroups retrieves a list of runners together with
+the groups allowed to use them. If limit is -1, it retrieves all runners.
+*/
+func ListRunnersWithAllowedGroups(limit int, offset int) ([]Runner, error) {
+	var runners []Runner
+	if err := dbconn.DB.
+		Preload("AllowedGroups").
+		Limit(limit).
+		Offset(offset).
+		Find(&runners).Error; err != nil {
+		return nil, err
+	}
+	return runners, nil
+}
+
 /*
 RetrieveRunnerByID retrieves a runner by its ID
 */
@@ -113,6 +157,170 @@ func CountOnlineRunners() (int64, error) {
 	return count, nil
 }
 
+/*
+IsOnline checks if the runner is online, a runner is
+considered online if its last contact time is within the last 5 minutes.
+*/
+func (r *Runner) IsOnline() bool {
+	if r.LastContact == nil {
+		return false
+	}
+	return r.LastContact.After(time.Now().Add(-5 * time.Minute))
+}
+
+/*
+IsAllowedForUser checks if the user can use the runner,
+restricted runners can be used only by members of the allowed groups
+and by administrators
+*/
+func (r *Runner) IsAllowedForUser(user User) (bool, error) {
+	return r.isAllowedForUser(dbconn.DB, user)
+}
+
+func (r *Runner) isAllowedForUser(tx *gorm.DB, user User) (bool, error) {
+	if !r.Restricted || user.IsSuperuser {
+		return true, nil
+	}
+
+	var count int64
+	if err := tx.
+		Table("user_groups").
+		Joins("JOIN runner_allowed_groups ON runner_allowed_groups.group_id = user_groups.group_id").
+		Where("user_groups.user_id = ? AND runner_allowed_groups.runner_id = ?", user.ID, r.ID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+/*
+ListRunnersAllowedForUser retrieves the runners that the user can use
+*/
+func ListRunnersAllowedForUser(user User) ([]Runner, error) {
+	runners, err := ListRunners(-1, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedRunners := []Runner{}
+	for _, runner := range runners {
+		allowed, err := runner.IsAllowedForUser(user)
+		if err != nil {
+			return nil, err
+		}
+
+		if allowed {
+			allowedRunners = append(allowedRunners, runner)
+		}
+	}
+	return allowedRunners, nil
+}
+
+/*
+SetRunnerAllowedGroups replaces the groups allowed to use a restricted runner
+*/
+func SetRunnerAllowedGroups(r *Runner, groups []Group) error {
+	return dbconn.DB.Model(r).Association("AllowedGroups").Replace(groups)
+}
+
+/*
+RefreshRunnerAccessFlags flags the workspaces on the runner whose owner is
+no longer allowed to use it, the flag is removed if the access is granted again.
+It returns the number of workspaces that have been flagged
+*/
+func RefreshRunnerAccessFlags(r Runner) (int, error) {
+	flagged, err := refreshRunnerAccessFlags(dbconn.DB, r)
+	logRunnerAccessRevoked(r, flagged)
+	return len(flagged), err
+}
+
+// refreshRunnerAccessFlags updates the flags using the given transaction
+// and returns the workspaces that have been flagged
+func refreshRunnerAccessFlags(tx *gorm.DB, r Runner) ([]Workspace, error) {
+	workspaces := []Workspace{}
+	if err := tx.
+		Preload("User").
+		Where("runner_id = ?", r.ID).
+		Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+
+	flagged := []Workspace{}
+	for _, workspace := range workspaces {
+		if workspace.User == nil {
+			continue
+		}
+
+		allowed, err := r.isAllowedForUser(tx, *workspace.User)
+		if err != nil {
+			return flagged, err
+		}
+
+		if workspace.RunnerAccessRevoked == !allowed {
+			continue
+		}
+
+		if err := tx.
+			Model(&Workspace{}).
+			Where("id = ?", workspace.ID).
+			UpdateColumn("runner_access_revoked", !allowed).Error; err != nil {
+			return flagged, err
+		}
+
+		if !allowed {
+			flagged = append(flagged, workspace)
+		}
+	}
+	return flagged, nil
+}
+
+// write in the logs of the flagged workspaces why they cannot be started anymore
+func logRunnerAccessRevoked(r Runner, flagged []Workspace) {
+	for _, workspace := range flagged {
+		workspace.AppendLogs(fmt.Sprintf(
+			"The owner of the workspace is no longer allowed to use the runner '%s'",
+			r.Name,
+		))
+	}
+}
+
+/*
+ListOnlineRunners retrieves the runners that are online and
+not being deleted
+*/
+func ListOnlineRunners() ([]Runner, error) {
+	var runners []Runner
+	fiveMinutesAgo := time.Now().Add(-5 * time.Minute)
+	if err := dbconn.DB.
+		Preload("AllowedGroups").
+		Where("last_contact >= ?", fiveMinutesAgo).
+		Where("deletion_in_progress = ?", false).
+		Find(&runners).Error; err != nil {
+		return nil, err
+	}
+	return runners, nil
+}
+
+/*
+CountActiveWorkspacesByRunner counts the workspaces on the runner
+that are not stopped, these are the workspaces using resources of the runner
+*/
+func CountActiveWorkspacesByRunner(r Runner) (int64, error) {
+	var count int64
+	if err := dbconn.DB.
+		Model(&Workspace{}).
+		Where("runner_id = ?", r.ID).
+		Where("status IN ?", []string{
+			WorkspaceStatusStarting,
+			WorkspaceStatusRunning,
+			WorkspaceStatusStopping,
+		}).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 /*
 Generate a random string long as the input param
 */
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+/*
+RunnerLabels is a set of key/value labels, it is used both for the labels
+assigned to runners and for the selectors declared by templates and git sources.
+In a selector an empty value means that the runner must have the key,
+regardless of its value
+*/
+type RunnerLabels map[string]string
+
+var runnerLabelKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._/-]{0,61}[a-zA-Z0-9])?$`)
+
+/*
+ValidateRunnerLabels checks that keys and values of the labels are valid,
+keys must be at most 63 characters long and can contain letters, digits and . _ / -
+*/
+func ValidateRunnerLabels(labels RunnerLabels) error {
+	for key, value := range labels {
+		if !runnerLabelKeyRegex.MatchString(key) {
+			return fmt.Errorf("invalid label key '%s'", key)
+		}
+
+		if len(value) > 255 {
+			return fmt.Errorf("the value of the label '%s' is too long", key)
+		}
+	}
+	return nil
+}
+
+/*
+Unmet returns the entries of the selector that are not satisfied
+by the labels, formatted as key=value and sorted by key
+*/
+func (selector RunnerLabels) Unmet(labels RunnerLabels) []string {
+	unmet := []string{}
+	for key, value := range selector {
+		runnerValue, found := labels[key]
+		if !found || (value != "" && runnerValue != value) {
+			if value == "" {
+				unmet = append(unmet, key)
+			} else {
+				unmet = append(unmet, fmt.Sprintf("%s=%s", key, value))
+			}
+		}
+	}
+	sort.Strings(unmet)
+	return unmet
+}
+
+/*
+String formats the labels as a comma separated list of key=value
+*/
+func (labels RunnerLabels) String() string {
+	return strings.Join(labels.Unmet(RunnerLabels{}), ", ")
+}
+
+/*
+CountMatches counts the entries of the selector that are satisfied by the labels
+*/
+func (selector RunnerLabels) CountMatches(labels RunnerLabels) int {
+	return len(selector) - len(selector.Unmet(labels))
+}
+
+/*
+CheckRunnerLabels checks if the runner satisfies the required labels,
+the returned error describes the labels that are missing
+*/
+func CheckRunnerLabels(runner Runner, required RunnerLabels) error {
+	unmet := required.Unmet(runner.Labels)
+	if len(unmet) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"runner '%s' does not satisfy the required labels: %s",
+		runner.Name,
+		strings.Join(unmet, ", "),
+	)
+}
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/utils/secrets"
+	"gorm.io/gorm"
+)
+
+// scope of a secret
+const (
+	SecretScopeUser      = "user"
+	SecretScopeGroup     = "group"
+	SecretScopeWorkspace = "workspace"
+)
+
+// the name of a secret is exported as an environment variable
+var secretNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// returned when a secret with the same name already exists in the same scope
+var ErrSecretAlreadyExists = errors.New("a secret with the same name already exists")
+
+/*
+InvalidSecretError is returned when the name or the value
+of a secret cannot be used
+*/
+type InvalidSecretError struct {
+	Message string
+}
+
+func (e *InvalidSecretError) Error() string {
+	return e.Message
+}
+
+/*
+Secret is a value injected in the environment of workspaces,
+the value is encrypted with the master key and it is never returned
+by the api. Exactly one of user, group and workspace is set
+*/
+type Secret struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	Name           string     `gorm:"column:name; size:255; not null; uniqueIndex:idx_secrets_user_name,priority:2; uniqueIndex:idx_secrets_group_name,priority:2; uniqueIndex:idx_secrets_workspace_name,priority:2;" json:"name"`
+	EncryptedValue string     `gorm:"column:encrypted_value; type:text;" json:"-"`
+	Scope          string     `gorm:"column:scope; size:255; not null;" json:"scope"`
+	UserID         *uint      `gorm:"column:user_id; uniqueIndex:idx_secrets_user_name,priority:1;" json:"-"`
+	User           *User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	GroupID        *uint      `gorm:"column:group_id; uniqueIndex:idx_secrets_group_name,priority:1;" json:"-"`
+	Group          *Group     `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	WorkspaceID    *uint      `gorm:"column:workspace_id; uniqueIndex:idx_secrets_workspace_name,priority:1;" json:"-"`
+	Workspace      *Workspace `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt      time.Time  `gorm:"column:created_at;" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;" json:"updated_at"`
+}
+
+/*
+ValidateSecret checks that the name of a secret can be used as the
+name of an environment variable and that the value can be sent to runners
+*/
+func ValidateSecret(name string, value string) error {
+	if !secretNameRegex.MatchString(name) || len(name) > 255 {
+		return &InvalidSecretError{
+			Message: fmt.Sprintf("invalid secret name '%s', only letters, digits and underscores are allowed", name),
+		}
+	}
+
+	if strings.HasPrefix(strings.ToUpper(name), "CODEBOX_") {
+		return &InvalidSecretError{Message: "secret names cannot start with CODEBOX_"}
+	}
+
+	// environment variables are sent to runners separated by ';'
+	if strings.Contains(value, ";") {
+		return &InvalidSecretError{Message: "secret values cannot contain ';'"}
+	}
+	return nil
+}
+
+/*
+SetValue encrypts the value and stores it in the secret,
+the secret is not saved
+*/
+func (s *Secret) SetValue(value string) error {
+	encrypted, err := secrets.Encrypt(value)
+	if err != nil {
+		return err
+	}
+	s.EncryptedValue = encrypted
+	return nil
+}
+
+/*
+GetValue decrypts the value of the secret
+*/
+func (s *Secret) GetValue() (string, error) {
+	return secrets.Decrypt(s.EncryptedValue)
+}
+
+/*
+ListUserSecrets retrieves the secrets of a user
+*/
+func ListUserSecrets(user User) ([]Secret, error) {
+	return listSecrets("user_id", user.ID)
+}
+
+/*
+ListGroupSecrets retrieves the secrets of a group
+*/
+func ListGroupSecrets(group Group) ([]Secret, error) {
+	return listSecrets("group_id", group.ID)
+}
+
+/*
+ListWorkspaceSecrets retrieves the secrets of a workspace
+*/
+func ListWorkspaceSecrets(workspace Workspace) ([]Secret, error) {
+	return listSecrets("workspace_id", workspace.ID)
+}
+
+func listSecrets(column string, id uint) ([]Secret, error) {
+	secrets := []Secret{}
+	if err := dbconn.DB.
+		Where(map[string]interface{}{column: id}).
+		Order("name ASC").
+		Find(&secrets).Error; err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+/*
+RetrieveUserSecretByID retrieves a secret of a user by its id,
+nil is returned if the secret does not exist
+*/
+func RetrieveUserSecretByID(user User, id uint) (*Secret, error) {
+	return retrieveSecret("user_id", user.ID, map[string]interface{}{"id": id})
+}
+
+/*
+RetrieveGroupSecretByID retrieves a secret of a group by its id,
+nil is returned if the secret does not exist
+*/
+func RetrieveGroupSecretByID(group Group, id uint) (*Secret, error) {
+	return retrieveSecret("group_id", group.ID, map[string]interface{}{"id": id})
+}
+
+/*
+RetrieveWorkspaceSecretByID retrieves a secret of a workspace by its id,
+nil is returned if the secret does not exist
+*/
+func RetrieveWorkspaceSecretByID(workspace Workspace, id uint) (*Secret, error) {
+	return retrieveSecret("workspace_id", workspace.ID, map[string]interface{}{"id": id})
+}
+
+func retrieveSecret(column string, ownerId uint, conditions map[string]interface{}) (*Secret, error) {
+	var secret Secret
+	conditions[column] = ownerId
+	if err := dbconn.DB.First(&secret, conditions).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &secret, nil
+}
+
+/*
+CreateUserSecret creates a new secret for a user
+*/
+func CreateUserSecret(user User, name string, value string) (*Secret, error) {
+	return createSecret(Secret{Scope: SecretScopeUser, UserID: &user.ID}, "user_id", user.ID, name, value)
+}
+
+/*
+CreateGroupSecret creates a new secret for a group
+*/
+func CreateGroupSecret(group Group, name string, value string) (*Secret, error) {
+	return createSecret(Secret{Scope: SecretScopeGroup, GroupID: &group.ID}, "group_id", group.ID, name, value)
+}
+
+/*
+CreateWorkspaceSecret creates a new secret for a workspace
+*/
+func CreateWorkspaceSecret(workspace Workspace, name string, value string) (*Secret, error) {
+	return createSecret(
+		Secret{Scope: SecretScopeWorkspace, WorkspaceID: &workspace.ID},
+		"workspace_id",
+		workspace.ID,
+		name,
+		value,
+	)
+}
+
+func createSecret(secret Secret, column string, ownerId uint, name string, value string) (*Secret, error) {
+	if err := ValidateSecret(name, value); err != nil {
+		return nil, err
+	}
+
+	existing, err := retrieveSecret(column, ownerId, map[string]interface{}{"name": name})
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		return nil, ErrSecretAlreadyExists
+	}
+
+	secret.Name = name
+	if err := secret.SetValue(value); err != nil {
+		return nil, err
+	}
+
+	if err := dbconn.DB.Create(&secret).Error; err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+/*
+UpdateSecretValue replaces the value of a secret
+*/
+func UpdateSecretValue(secret *Secret, value string) (*Secret, error) {
+	if err := ValidateSecret(secret.Name, value); err != nil {
+		return nil, err
+	}
+
+	if err := secret.SetValue(value); err != nil {
+		return nil, err
+	}
+
+	if err := dbconn.DB.Save(secret).Error; err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+/*
+DeleteSecret deletes a secret
+*/
+func DeleteSecret(secret Secret) error {
+	return dbconn.DB.Delete(&secret).Error
+}
+
+/*
+ResolveWorkspaceSecrets retrieves the decrypted secrets that must be injected
+in the environment of a workspace as NAME=value. When the same name is defined
+in more scopes, workspace secrets override user secrets, that override the
+secrets of the groups of the owner
+*/
+func ResolveWorkspaceSecrets(workspace Workspace) ([]string, error) {
+	var secretsList []Secret
+
+	if workspace.User != nil {
+		groups, err := workspace.User.GetGroups()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range groups {
+			groupSecrets, err := ListGroupSecrets(group)
+			if err != nil {
+				return nil, err
+			}
+			secretsList = append(secretsList, groupSecrets...)
+		}
+
+		userSecrets, err := ListUserSecrets(*workspace.User)
+		if err != nil {
+			return nil, err
+		}
+		secretsList = append(secretsList, userSecrets...)
+	}
+
+	workspaceSecrets, err := ListWorkspaceSecrets(workspace)
+	if err != nil {
+		return nil, err
+	}
+	secretsList = append(secretsList, workspaceSecrets...)
+
+	if len(secretsList) == 0 {
+		return []string{}, nil
+	}
+
+	// secrets are decrypted only when there is at least one of them,
+	// so workspaces can be started when the master key is not set
+	names := []string{}
+	values := map[string]string{}
+	for _, secret := range secretsList {
+		value, err := secret.GetValue()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt secret '%s', %s", secret.Name, err.Error())
+		}
+
+		if _, ok := values[secret.Name]; !ok {
+			names = append(names, secret.Name)
+		}
+		values[secret.Name] = value
+	}
+
+	env := make([]string, len(names))
+	for i, name := range names {
+		env[i] = fmt.Sprintf("%s=%s", name, values[name])
+	}
+	return env, nil
+}
@@ -1,15 +1,183 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/utils/ldap"
+)
 
 type AuthenticationSettings struct {
 	SingletonModel
-	IsSignUpOpen                bool   `gorm:"column:is_signup_open; default:false"`
-	IsSignUpRestricted          bool   `gorm:"column:is_signup_restricted; default:false"`
-	AllowedEmailRegex           string `gorm:"column:allowed_email_regex; type:text;"`
-	BlockedEmailRegex           string `gorm:"column:blocked_email_regex; type:text;"`
-	UsersMustBeApproved         bool   `gorm:"column:users_must_be_approved; default:false"`
-	ApprovedByDefaultEmailRegex string `gorm:"column:approved_by_default_email_regex; type:text;"`
+	IsSignUpOpen                         bool   `gorm:"column:is_signup_open; default:false"`
+	IsSignUpRestricted                   bool   `gorm:"column:is_signup_restricted; default:false"`
+	AllowedEmailRegex                    string `gorm:"column:allowed_email_regex; type:text;"`
+	BlockedEmailRegex                    string `gorm:"column:blocked_email_regex; type:text;"`
+	UsersMustBeApproved                  bool   `gorm:"column:users_must_be_approved; default:false"`
+	ApprovedByDefaultEmailRegex          string `gorm:"column:approved_by_default_email_regex; type:text;"`
+	TwoFactorRequiredForSuperusers       bool   `gorm:"column:two_factor_required_for_superusers; default:false"`
+	TwoFactorRequiredForTemplateManagers bool   `gorm:"column:two_factor_required_for_template_managers; default:false"`
+}
+
+/*
+IsTwoFactorRequired checks if the user must use two-factor authentication
+*/
+func (s *AuthenticationSettings) IsTwoFactorRequired(user User) bool {
+	return (s.TwoFactorRequiredForSuperusers && user.IsSuperuser) ||
+		(s.TwoFactorRequiredForTemplateManagers && user.IsTemplateManager)
+}
+
+/*
+OIDCSettings contains the configuration of the OpenID Connect provider used for
+single sign-on. Empty claim names fall back to the standard ones, if the groups claim
+is not set the groups of the users are not managed by the provider
+*/
+type OIDCSettings struct {
+	SingletonModel
+	Enabled               bool   `gorm:"column:enabled; default:false"`
+	ProviderName          string `gorm:"column:provider_name; size:255;"`
+	IssuerUrl             string `gorm:"column:issuer_url; size:255;"`
+	ClientID              string `gorm:"column:client_id; size:255;"`
+	ClientSecret          string `gorm:"column:client_secret; size:255;"`
+	Scopes                string `gorm:"column:scopes; size:255;"` // space separated
+	EmailClaim            string `gorm:"column:email_claim; size:255;"`
+	FirstNameClaim        string `gorm:"column:first_name_claim; size:255;"`
+	LastNameClaim         string `gorm:"column:last_name_claim; size:255;"`
+	GroupsClaim           string `gorm:"column:groups_claim; size:255;"`
+	AutoProvisionUsers    bool   `gorm:"column:auto_provision_users; default:false"`
+	PasswordLoginDisabled bool   `gorm:"column:password_login_disabled; default:false"`
+}
+
+// the table is created by the migrations as oidc_settings
+func (OIDCSettings) TableName() string {
+	return "oidc_settings"
+}
+
+/*
+GetScopes returns the scopes requested to the provider, openid is always included
+*/
+func (s *OIDCSettings) GetScopes() []string {
+	scopes := strings.Fields(s.Scopes)
+	if len(scopes) == 0 {
+		return []string{"openid", "profile", "email"}
+	}
+
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return scopes
+		}
+	}
+	return append([]string{"openid"}, scopes...)
+}
+
+/*
+GetRedirectUrl returns the url the provider redirects the users to after
+the authentication, it must be registered in the provider
+*/
+func (s *OIDCSettings) GetRedirectUrl() string {
+	return strings.TrimSuffix(config.Environment.ExternalUrl, "/") + "/api/v1/auth/oidc/callback"
+}
+
+func (s *OIDCSettings) GetEmailClaim() string {
+	if s.EmailClaim == "" {
+		return "email"
+	}
+	return s.EmailClaim
+}
+
+func (s *OIDCSettings) GetFirstNameClaim() string {
+	if s.FirstNameClaim == "" {
+		return "given_name"
+	}
+	return s.FirstNameClaim
+}
+
+func (s *OIDCSettings) GetLastNameClaim() string {
+	if s.LastNameClaim == "" {
+		return "family_name"
+	}
+	return s.LastNameClaim
+}
+
+/*
+IsPasswordLoginAllowed checks if a user can login with the password,
+password login can be disabled only if single sign-on is enabled and
+superusers can always use it, so the instance cannot be locked out
+*/
+func (s *OIDCSettings) IsPasswordLoginAllowed(user User) bool {
+	if !s.Enabled || !s.PasswordLoginDisabled {
+		return true
+	}
+	return user.IsSuperuser
+}
+
+/*
+LDAPSettings contains the configuration of the LDAP or Active Directory server
+used to authenticate the users. Empty attribute names fall back to the standard
+ones, if the groups attribute is not set the groups of the users are not managed
+by the directory
+*/
+type LDAPSettings struct {
+	SingletonModel
+	Enabled            bool   `gorm:"column:enabled; default:false"`
+	Url                string `gorm:"column:url; size:255;"`
+	StartTLS           bool   `gorm:"column:start_tls; default:false"`
+	InsecureSkipVerify bool   `gorm:"column:insecure_skip_verify; default:false"`
+	BindDN             string `gorm:"column:bind_dn; size:255;"`
+	BindPassword       string `gorm:"column:bind_password; size:255;"`
+	UserBaseDN         string `gorm:"column:user_base_dn; size:255;"`
+	UserFilter         string `gorm:"column:user_filter; size:255;"`
+	EmailAttribute     string `gorm:"column:email_attribute; size:255;"`
+	FirstNameAttribute string `gorm:"column:first_name_attribute; size:255;"`
+	LastNameAttribute  string `gorm:"column:last_name_attribute; size:255;"`
+	GroupsAttribute    string `gorm:"column:groups_attribute; size:255;"`
+	AutoProvisionUsers bool   `gorm:"column:auto_provision_users; default:false"`
+}
+
+/*
+GetLDAPConfig returns the configuration used to query the directory
+*/
+func (s *LDAPSettings) GetLDAPConfig() *ldap.Config {
+	config := &ldap.Config{
+		Url:                s.Url,
+		StartTLS:           s.StartTLS,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+		BindDN:             s.BindDN,
+		BindPassword:       s.BindPassword,
+		UserBaseDN:         s.UserBaseDN,
+		UserFilter:         s.UserFilter,
+		EmailAttribute:     s.EmailAttribute,
+		FirstNameAttribute: s.FirstNameAttribute,
+		LastNameAttribute:  s.LastNameAttribute,
+		GroupsAttribute:    s.GroupsAttribute,
+	}
+
+	if config.UserFilter == "" {
+		config.UserFilter = "(mail=%s)"
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = "mail"
+	}
+	if config.FirstNameAttribute == "" {
+		config.FirstNameAttribute = "givenName"
+	}
+	if config.LastNameAttribute == "" {
+		config.LastNameAttribute = "sn"
+	}
+	return config
+}
+
+/*
+IsDirectoryLoginRequired checks if a user must login with the credentials
+of the directory. Local superusers can always login with their password,
+so the instance can be accessed when the directory is not reachable
+*/
+func (s *LDAPSettings) IsDirectoryLoginRequired(user *User) bool {
+	if !s.Enabled {
+		return false
+	}
+	return user == nil || !user.IsSuperuser || user.IsLdapUser()
 }
 
 type AnalyticsConfig struct {
@@ -19,3 +187,21 @@ type AnalyticsConfig struct {
 	LastAttempt            *time.Time `gorm:"column:last_attempt"`
 	LastSuccessfullAttempt *time.Time `gorm:"column:last_successfull_attempt"`
 }
+
+/*
+QuotaSettings contains the quotas applied to all the users,
+they can be overridden for groups and users
+*/
+type QuotaSettings struct {
+	SingletonModel
+	QuotaLimits
+}
+
+/*
+ResourceLimitsSettings contains the maximum resources a workspace can use,
+the limits set on templates and workspaces cannot exceed them
+*/
+type ResourceLimitsSettings struct {
+	SingletonModel
+	MaxResourceLimits ResourceLimits `gorm:"embedded; embeddedPrefix:max_;"`
+}
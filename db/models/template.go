@@ -8,21 +8,28 @@ import (
 )
 
 type WorkspaceTemplate struct {
-	ID          uint           `gorm:"primarykey"  json:"id"`
-	Name        string         `gorm:"column:name; size:255;unique;not null;"  json:"name"`
-	Type        string         `gorm:"column:type; size:255;"  json:"type"`
-	Description string         `gorm:"column:description;" json:"description"`
-	Icon        string         `gorm:"column:icon; type:text;" json:"icon"`
-	CreatedAt   time.Time      `gorm:"index" json:"-"`
-	UpdatedAt   time.Time      `gorm:"index" json:"-"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                    uint         `gorm:"primarykey"  json:"id"`
+	Name                  string       `gorm:"column:name; size:255;unique;not null;"  json:"name"`
+	Type                  string       `gorm:"column:type; size:255;"  json:"type"`
+	Description           string       `gorm:"column:description;" json:"description"`
+	Icon                  string       `gorm:"column:icon; type:text;" json:"icon"`
+	IdleTimeoutMinutes    uint         `gorm:"column:idle_timeout_minutes; default:0;" json:"idle_timeout_minutes"`
+	RequiredRunnerLabels  RunnerLabels `gorm:"column:required_runner_labels; type:text; serializer:json;" json:"required_runner_labels"`
+	PreferredRunnerLabels RunnerLabels `gorm:"column:preferred_runner_labels; type:text; serializer:json;" json:"preferred_runner_labels"`
+	ResourceLimits
+	// restricted templates are visible only to the members of the allowed groups
+	Restricted    bool           `gorm:"column:restricted; default:false;" json:"restricted"`
+	AllowedGroups []Group        `gorm:"many2many:workspace_template_allowed_groups;" json:"allowed_groups"`
+	CreatedAt     time.Time      `gorm:"index" json:"-"`
+	UpdatedAt     time.Time      `gorm:"index" json:"-"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // Retrieve workspace template by id
 // return nil if object is not found
 func RetrieveWorkspaceTemplateByID(id uint) (*WorkspaceTemplate, error) {
 	var wt *WorkspaceTemplate
-	r := dbconn.DB.Model(WorkspaceTemplate{}).Find(&wt, map[string]interface{}{
+	r := dbconn.DB.Model(WorkspaceTemplate{}).Preload("AllowedGroups").Find(&wt, map[string]interface{}{
 		"id": id,
 	})
 
@@ -40,7 +47,7 @@ func RetrieveWorkspaceTemplateByID(id uint) (*WorkspaceTemplate, error) {
 // return nil if object is not found
 func RetrieveWorkspaceTemplateByName(name string) (*WorkspaceTemplate, error) {
 	var wt *WorkspaceTemplate
-	r := dbconn.DB.Model(WorkspaceTemplate{}).Find(&wt, map[string]interface{}{
+	r := dbconn.DB.Model(WorkspaceTemplate{}).Preload("AllowedGroups").Find(&wt, map[string]interface{}{
 		"name": name,
 	})
 
@@ -55,12 +62,19 @@ func RetrieveWorkspaceTemplateByName(name string) (*WorkspaceTemplate, error) {
 }
 
 // create new template
-func CreateWorkspaceTemplate(name string, templateType string, description string, icon string) (*WorkspaceTemplate, error) {
+func CreateWorkspaceTemplate(
+	name string,
+	templateType string,
+	description string,
+	icon string,
+	idleTimeoutMinutes uint,
+) (*WorkspaceTemplate, error) {
 	wt := WorkspaceTemplate{
-		Name:        name,
-		Type:        templateType,
-		Description: description,
-		Icon:        icon,
+		Name:               name,
+		Type:               templateType,
+		Description:        description,
+		Icon:               icon,
+		IdleTimeoutMinutes: idleTimeoutMinutes,
 	}
 
 	err := dbconn.DB.Save(&wt).Error
@@ -99,7 +113,66 @@ func ListWorkspacesByTemplate(wt WorkspaceTemplate) ([]Workspace, error) {
 }
 
 func DeleteTemplate(wt WorkspaceTemplate) error {
-	return dbconn.DB.Unscoped().Delete(&wt).Error
+	return dbconn.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM workspace_template_allowed_groups WHERE workspace_template_id = ?", wt.ID).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&wt).Error
+	})
+}
+
+/*
+ListWorkspaceTemplatesVisibleForUser retrieves the templates that the user can see,
+administrators and template managers can see all the templates
+*/
+func ListWorkspaceTemplatesVisibleForUser(user User) ([]WorkspaceTemplate, error) {
+	templates := []WorkspaceTemplate{}
+	query := dbconn.DB.Preload("AllowedGroups")
+	if !user.IsSuperuser && !user.IsTemplateManager {
+		query = query.Where(
+			"restricted = ? OR id IN (?)",
+			false,
+			dbconn.DB.
+				Table("workspace_template_allowed_groups").
+				Select("workspace_template_allowed_groups.workspace_template_id").
+				Joins("JOIN user_groups ON user_groups.group_id = workspace_template_allowed_groups.group_id").
+				Where("user_groups.user_id = ?", user.ID),
+		)
+	}
+
+	if err := query.Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+/*
+IsVisibleForUser checks if the user can see the template and create workspaces from it,
+restricted templates are visible only to the members of the allowed groups,
+to the administrators and to the template managers
+*/
+func (wt *WorkspaceTemplate) IsVisibleForUser(user User) (bool, error) {
+	if !wt.Restricted || user.IsSuperuser || user.IsTemplateManager {
+		return true, nil
+	}
+
+	var count int64
+	if err := dbconn.DB.
+		Table("user_groups").
+		Joins("JOIN workspace_template_allowed_groups ON workspace_template_allowed_groups.group_id = user_groups.group_id").
+		Where("user_groups.user_id = ? AND workspace_template_allowed_groups.workspace_template_id = ?", user.ID, wt.ID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+/*
+SetWorkspaceTemplateAllowedGroups replaces the groups allowed to see a restricted template
+*/
+func SetWorkspaceTemplateAllowedGroups(wt *WorkspaceTemplate, groups []Group) error {
+	return dbconn.DB.Model(wt).Association("AllowedGroups").Replace(groups)
 }
 
 /*
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gitlab.com/codebox4073715/codebox/utils/targz"
+)
+
+const (
+	TemplateParameterTypeString = "string"
+	TemplateParameterTypeNumber = "number"
+	TemplateParameterTypeBool   = "bool"
+	TemplateParameterTypeEnum   = "enum"
+)
+
+var templateParameterNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// placeholder replaced with the value of a parameter, e.g. {{ parameters.python_version }}
+var templateParameterPlaceholderRegex = regexp.MustCompile(`\{\{\s*parameters\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+/*
+TemplateParameter is a value that is chosen when a workspace is created
+and that is rendered in the files of the template version.
+A parameter without default value is required
+*/
+type TemplateParameter struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Default     *string  `json:"default"`
+	Options     []string `json:"options"` // allowed values of enum parameters
+	Min         *float64 `json:"min"`     // number parameters only
+	Max         *float64 `json:"max"`     // number parameters only
+	Pattern     string   `json:"pattern"` // string parameters only, must match the whole value
+}
+
+type TemplateParameters []TemplateParameter
+
+// values of the parameters of a template version, indexed by name
+type TemplateParameterValues map[string]string
+
+/*
+InvalidTemplateParameterError is returned when a parameter
+definition or the value of a parameter is not valid
+*/
+type InvalidTemplateParameterError struct {
+	Message string
+}
+
+func (e *InvalidTemplateParameterError) Error() string {
+	return e.Message
+}
+
+/*
+compileTemplateParameterPattern compiles the pattern of a
+parameter so that it must match the whole value
+*/
+func compileTemplateParameterPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+/*
+ValidateTemplateParameters checks the parameters declared by a template
+version: names must be unique identifiers and defaults must be valid values
+*/
+func ValidateTemplateParameters(parameters TemplateParameters) error {
+	names := map[string]bool{}
+	for _, p := range parameters {
+		if !templateParameterNameRegex.MatchString(p.Name) {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("invalid parameter name '%s'", p.Name),
+			}
+		}
+
+		if names[p.Name] {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("parameter '%s' is declared more than once", p.Name),
+			}
+		}
+		names[p.Name] = true
+
+		switch p.Type {
+		case TemplateParameterTypeString:
+			if _, err := compileTemplateParameterPattern(p.Pattern); err != nil {
+				return &InvalidTemplateParameterError{
+					Message: fmt.Sprintf("invalid pattern for parameter '%s'", p.Name),
+				}
+			}
+		case TemplateParameterTypeNumber:
+			if p.Min != nil && p.Max != nil && *p.Min > *p.Max {
+				return &InvalidTemplateParameterError{
+					Message: fmt.Sprintf("min is greater than max for parameter '%s'", p.Name),
+				}
+			}
+		case TemplateParameterTypeBool:
+		case TemplateParameterTypeEnum:
+			if len(p.Options) == 0 {
+				return &InvalidTemplateParameterError{
+					Message: fmt.Sprintf("enum parameter '%s' has no options", p.Name),
+				}
+			}
+		default:
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("invalid type '%s' for parameter '%s'", p.Type, p.Name),
+			}
+		}
+
+		if p.Default != nil {
+			if err := p.ValidateValue(*p.Default); err != nil {
+				return &InvalidTemplateParameterError{
+					Message: fmt.Sprintf("invalid default value, %s", err.Error()),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+ValidateValue checks that a value is valid for the parameter,
+values are rendered as they are in the files of the template
+so newlines and control characters are refused for every type
+*/
+func (p TemplateParameter) ValidateValue(value string) error {
+	if strings.IndexFunc(value, unicode.IsControl) != -1 {
+		return &InvalidTemplateParameterError{
+			Message: fmt.Sprintf("value of parameter '%s' must not contain newlines or control characters", p.Name),
+		}
+	}
+
+	switch p.Type {
+	case TemplateParameterTypeString:
+		if p.Pattern != "" {
+			re, err := compileTemplateParameterPattern(p.Pattern)
+			if err != nil || !re.MatchString(value) {
+				return &InvalidTemplateParameterError{
+					Message: fmt.Sprintf("value of parameter '%s' does not match '%s'", p.Name, p.Pattern),
+				}
+			}
+		}
+	case TemplateParameterTypeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("value of parameter '%s' must be a number", p.Name),
+			}
+		}
+
+		if p.Min != nil && n < *p.Min {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("value of parameter '%s' must be at least %g", p.Name, *p.Min),
+			}
+		}
+
+		if p.Max != nil && n > *p.Max {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("value of parameter '%s' must be at most %g", p.Name, *p.Max),
+			}
+		}
+	case TemplateParameterTypeBool:
+		if value != "true" && value != "false" {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("value of parameter '%s' must be true or false", p.Name),
+			}
+		}
+	case TemplateParameterTypeEnum:
+		if !slices.Contains(p.Options, value) {
+			return &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("value of parameter '%s' must be one of %v", p.Name, p.Options),
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+NormalizeTemplateParameterValues converts the values received
+as json (strings, numbers or booleans) to their text representation
+*/
+func NormalizeTemplateParameterValues(values map[string]any) (TemplateParameterValues, error) {
+	normalized := TemplateParameterValues{}
+	for name, value := range values {
+		switch v := value.(type) {
+		case string:
+			normalized[name] = v
+		case float64:
+			normalized[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			normalized[name] = strconv.FormatBool(v)
+		default:
+			return nil, &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("invalid value for parameter '%s'", name),
+			}
+		}
+	}
+	return normalized, nil
+}
+
+/*
+ResolveTemplateParameterValues validates the values of the parameters of
+a template version, parameters without value get the default one.
+Values of parameters that are not declared are refused
+*/
+func ResolveTemplateParameterValues(
+	parameters TemplateParameters,
+	values TemplateParameterValues,
+) (TemplateParameterValues, error) {
+	resolved := TemplateParameterValues{}
+	for name := range values {
+		if !slices.ContainsFunc(parameters, func(p TemplateParameter) bool { return p.Name == name }) {
+			return nil, &InvalidTemplateParameterError{
+				Message: fmt.Sprintf("unknown parameter '%s'", name),
+			}
+		}
+	}
+
+	for _, p := range parameters {
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				return nil, &InvalidTemplateParameterError{
+					Message: fmt.Sprintf("parameter '%s' is required", p.Name),
+				}
+			}
+			value = *p.Default
+		}
+
+		if err := p.ValidateValue(value); err != nil {
+			return nil, err
+		}
+		resolved[p.Name] = value
+	}
+
+	return resolved, nil
+}
+
+/*
+RenderTemplateParameters replaces the placeholders of the parameters
+in a file, placeholders of unknown parameters are left untouched.
+Values are not escaped, they must have been validated with ValidateValue
+*/
+func RenderTemplateParameters(content []byte, values TemplateParameterValues) []byte {
+	return templateParameterPlaceholderRegex.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+		name := templateParameterPlaceholderRegex.FindSubmatch(placeholder)[1]
+		if value, ok := values[string(name)]; ok {
+			return []byte(value)
+		}
+		return placeholder
+	})
+}
+
+/*
+RenderSources writes to destination a copy of the sources of the template
+version with the parameters rendered, binary files are copied as they are
+*/
+func (tv *WorkspaceTemplateVersion) RenderSources(values TemplateParameterValues, destination string) error {
+	if tv.Sources == nil {
+		return fmt.Errorf("template version has no sources")
+	}
+
+	src := targz.TarGZManager{Filepath: tv.Sources.GetAbsolutePath()}
+	entries, err := src.ListEntries()
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range entries {
+		if entry.Type == "file" && utf8.Valid(entry.Content) {
+			entries[i].Content = RenderTemplateParameters(entry.Content, values)
+		}
+	}
+
+	dst := targz.TarGZManager{Filepath: destination}
+	return dst.WriteEntries(entries)
+}
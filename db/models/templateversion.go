@@ -20,6 +20,7 @@ type WorkspaceTemplateVersion struct {
 	Template       *WorkspaceTemplate `gorm:"constraint:OnDelete:CASCADE;not null;" json:"-"`
 	Name           string             `gorm:"column:name; size:255;not null;" json:"name"`
 	ConfigFilePath string             `gorm:"column:config_file_path; type:text;" json:"config_file_relative_path"`
+	Parameters     TemplateParameters `gorm:"column:parameters; type:text; serializer:json;" json:"parameters"`
 	SourcesID      uint               `gorm:"column:sources_id;" json:"-"`
 	Sources        *File              `json:"-"`
 	Published      bool               `gorm:"column:published; default:false" json:"published"`
@@ -172,8 +173,14 @@ func CreateTemplateVersion(template WorkspaceTemplate, name string, user User, c
 		os.RemoveAll(sourceFile.GetAbsolutePath())
 	}
 
+	// parameters are copied from the last version, like the sources
+	parameters := TemplateParameters{}
 	copySourcesFromLastVersion := false
 	if lastTemplateVersion != nil {
+		if lastTemplateVersion.Parameters != nil {
+			parameters = lastTemplateVersion.Parameters
+		}
+
 		if lastTemplateVersion.Sources != nil {
 			copySourcesFromLastVersion = lastTemplateVersion.Sources.Exists()
 		}
@@ -218,6 +225,7 @@ func CreateTemplateVersion(template WorkspaceTemplate, name string, user User, c
 		Template:       &template,
 		Name:           name,
 		ConfigFilePath: configFilePath,
+		Parameters:     parameters,
 		Sources:        &sourceFile,
 		Published:      false,
 		PublishedOn:    nil,
@@ -240,6 +248,7 @@ func UpdateTemplateVersion(
 	published bool,
 	user User,
 	configFilePath string,
+	parameters TemplateParameters,
 ) (*WorkspaceTemplateVersion, error) {
 	// check if template version exists
 	templateVersion, err := RetrieveWorkspaceTemplateVersionsByIdByTemplate(template, tv.ID)
@@ -262,6 +271,7 @@ func UpdateTemplateVersion(
 
 	templateVersion.Name = name
 	templateVersion.Published = published
+	templateVersion.Parameters = parameters
 	templateVersion.EditedByID = user.ID
 	templateVersion.EditedBy = &user
 	templateVersion.EditedOn = time.Now()
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -12,16 +13,20 @@ import (
 var secretKey = []byte("secret-key") // TODO: replace on build
 
 type Token struct {
-	ID                 uint       `gorm:"primarykey"`
-	Token              string     `gorm:"column:token; size:255;unique;"`
-	ExpirationDate     *time.Time `gorm:"column:expiration_date;"`
-	UserID             uint       `gorm:"column:user_id;"`
-	User               User       `gorm:"constraint:OnDelete:CASCADE;"`
-	ImpersonatedUserID *uint      `gorm:"column:impersonated_user_id;"`
-	ImpersonatedUser   *User      `gorm:"constraint:OnDelete:CASCADE;"`
-	CreatedAt          time.Time
-	UpdatedAt          time.Time
-	DeletedAt          gorm.DeletedAt `gorm:"index"`
+	ID                   uint       `gorm:"primarykey"`
+	Token                string     `gorm:"column:token; size:255;unique;"`
+	ExpirationDate       *time.Time `gorm:"column:expiration_date;"`
+	UserID               uint       `gorm:"column:user_id;"`
+	User                 User       `gorm:"constraint:OnDelete:CASCADE;"`
+	ImpersonatedUserID   *uint      `gorm:"column:impersonated_user_id;"`
+	ImpersonatedUser     *User      `gorm:"constraint:OnDelete:CASCADE;"`
+	SecondFactorVerified bool       `gorm:"column:second_factor_verified; default:false; not null;"` // login confirmed with the second factor
+	IpAddress            string     `gorm:"column:ip_address; size:64;"`                             // ip address of the last use, the login one until the token is used
+	UserAgent            string     `gorm:"column:user_agent; size:255;"`                            // user agent of the login
+	LastUsedAt           *time.Time `gorm:"column:last_used_at;"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	DeletedAt            gorm.DeletedAt `gorm:"index"`
 }
 
 func generateJWTToken(userId uint, expiration time.Time) (string, error) {
@@ -44,6 +49,20 @@ func generateJWTToken(userId uint, expiration time.Time) (string, error) {
 CreateToken create a token for a user
 */
 func CreateToken(user User, duration time.Duration) (Token, error) {
+	return CreateSessionToken(user, duration, false, "", "")
+}
+
+/*
+CreateSessionToken create a token for a user that has logged in from a client,
+secondFactorVerified is set if the login has been confirmed with the second factor
+*/
+func CreateSessionToken(
+	user User,
+	duration time.Duration,
+	secondFactorVerified bool,
+	ipAddress string,
+	userAgent string,
+) (Token, error) {
 	tokenExpiration := time.Now().Add(duration)
 
 	jwtToken, err := generateJWTToken(user.ID, tokenExpiration)
@@ -53,9 +72,12 @@ func CreateToken(user User, duration time.Duration) (Token, error) {
 	}
 
 	token := Token{
-		Token:          jwtToken,
-		ExpirationDate: &tokenExpiration,
-		User:           user,
+		Token:                jwtToken,
+		ExpirationDate:       &tokenExpiration,
+		User:                 user,
+		SecondFactorVerified: secondFactorVerified,
+		IpAddress:            truncateString(ipAddress, 64),
+		UserAgent:            truncateString(userAgent, 255),
 	}
 
 	if err := dbconn.DB.Create(&token).Error; err != nil {
@@ -73,6 +95,74 @@ func UpdateToken(token Token) error {
 	return r.Error
 }
 
+func truncateString(s string, length int) string {
+	if len(s) > length {
+		return s[:length]
+	}
+	return s
+}
+
+/*
+TouchToken records the use of the token from the ip address,
+the last use is updated at most once per minute
+*/
+func TouchToken(token *Token, ipAddress string) error {
+	now := time.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < time.Minute && token.IpAddress == ipAddress {
+		return nil
+	}
+
+	ipAddress = truncateString(ipAddress, 64)
+	if err := dbconn.DB.
+		Model(&Token{}).
+		Where("id = ?", token.ID).
+		UpdateColumns(map[string]interface{}{
+			"last_used_at": &now,
+			"ip_address":   ipAddress,
+		}).Error; err != nil {
+		return err
+	}
+	token.LastUsedAt = &now
+	token.IpAddress = ipAddress
+	return nil
+}
+
+/*
+ListActiveTokensForUser retrieves the tokens of the user that have not expired,
+the most recently used first
+*/
+func ListActiveTokensForUser(user User) ([]Token, error) {
+	tokens := []Token{}
+	if err := dbconn.DB.
+		Preload("ImpersonatedUser").
+		Where("user_id = ? AND expiration_date > ?", user.ID, time.Now()).
+		Order("COALESCE(last_used_at, created_at) DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+/*
+RetrieveTokenByUserAndID retrieves a token of the user by id,
+nil is returned if it does not exist
+*/
+func RetrieveTokenByUserAndID(user User, id uint) (*Token, error) {
+	var token Token
+	if err := dbconn.DB.
+		Preload("ImpersonatedUser").
+		First(&token, map[string]interface{}{
+			"id":      id,
+			"user_id": user.ID,
+		}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
 /*
 Delete a token
 */
@@ -81,6 +171,26 @@ func DeleteToken(token *Token) error {
 	return r.Error
 }
 
+/*
+RevokeAllAuthTokensForUser deletes all the authentication tokens of the user
+and the impersonation tokens that target it
+*/
+func RevokeAllAuthTokensForUser(user User) error {
+	return dbconn.DB.Unscoped().
+		Where("user_id = ? OR impersonated_user_id = ?", user.ID, user.ID).
+		Delete(&Token{}).Error
+}
+
+/*
+RevokeOtherAuthTokensForUser deletes the authentication tokens of
+the user except the given one, that is usually the current session
+*/
+func RevokeOtherAuthTokensForUser(user User, current Token) error {
+	return dbconn.DB.Unscoped().
+		Where("user_id = ? AND id <> ?", user.ID, current.ID).
+		Delete(&Token{}).Error
+}
+
 /*
 GetLoginCountPerDayInLast7Days returns an array of login counts for each of the last 7 days.
 The array is ordered from oldest to newest day.
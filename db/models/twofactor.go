@@ -0,0 +1,179 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/utils/totp"
+	"gorm.io/gorm"
+)
+
+// number of recovery codes generated for each user
+const RecoveryCodesCount = 10
+
+/*
+TwoFactorRecoveryCode can be used once instead of the TOTP code,
+only the hash of the code is stored
+*/
+type TwoFactorRecoveryCode struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"column:user_id; not null;"`
+	User      User       `gorm:"constraint:OnDelete:CASCADE;"`
+	CodeHash  string     `gorm:"column:code_hash; size:64; not null; index;"`
+	UsedAt    *time.Time `gorm:"column:used_at;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.ReplaceAll(code, "-", ""), " ", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateRecoveryCode() string {
+	random := make([]byte, 10)
+	if _, err := rand.Read(random); err != nil {
+		panic(err)
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(random))
+	return code[:5] + "-" + code[5:10]
+}
+
+/*
+GenerateRecoveryCodes replaces the recovery codes of the user,
+the returned codes must be shown to the user because they cannot be retrieved later
+*/
+func GenerateRecoveryCodes(user User) ([]string, error) {
+	codes := make([]string, RecoveryCodesCount)
+	err := dbconn.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		for i := range codes {
+			codes[i] = generateRecoveryCode()
+			if err := tx.Create(&TwoFactorRecoveryCode{
+				UserID:   user.ID,
+				CodeHash: hashRecoveryCode(codes[i]),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+/*
+UseRecoveryCode checks if the code is a recovery code of the
+user that has not been used yet, if it is the code is consumed
+*/
+func UseRecoveryCode(user User, code string) (bool, error) {
+	now := time.Now()
+	r := dbconn.DB.Model(&TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", &now)
+	if r.Error != nil {
+		return false, r.Error
+	}
+	return r.RowsAffected > 0, nil
+}
+
+/*
+CountUnusedRecoveryCodes returns the number of recovery codes that can still be used
+*/
+func CountUnusedRecoveryCodes(user User) (int64, error) {
+	var count int64
+	if err := dbconn.DB.Model(&TwoFactorRecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", user.ID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+/*
+NeedsSecondFactor checks if the user must confirm the login with
+the second factor, because it is enabled or required by the settings
+*/
+func (u *User) NeedsSecondFactor(s *AuthenticationSettings) bool {
+	return u.TotpEnabled || s.IsTwoFactorRequired(*u)
+}
+
+/*
+CheckTotpCode checks a TOTP code of the user, codes that have already
+been used are refused. The used code is stored so it cannot be reused
+*/
+func CheckTotpCode(user *User, code string) (bool, error) {
+	if !user.TotpEnabled || user.TotpSecret == "" {
+		return false, nil
+	}
+
+	step, ok := totp.Validate(user.TotpSecret, code, time.Now())
+	if !ok || step <= user.TotpLastUsedStep {
+		return false, nil
+	}
+
+	// the step is updated only if it is still newer than the stored one,
+	// so the same code cannot be accepted by two concurrent requests
+	r := dbconn.DB.Model(&User{}).
+		Where("id = ? AND totp_last_used_step < ?", user.ID, step).
+		UpdateColumn("totp_last_used_step", step)
+	if r.Error != nil {
+		return false, r.Error
+	}
+
+	if r.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TotpLastUsedStep = step
+	return true, nil
+}
+
+/*
+CheckSecondFactor checks a TOTP code or a recovery code of the user
+*/
+func CheckSecondFactor(user *User, code string) (bool, error) {
+	if ok, err := CheckTotpCode(user, code); ok || err != nil {
+		return ok, err
+	}
+
+	if !user.TotpEnabled {
+		return false, nil
+	}
+	return UseRecoveryCode(*user, code)
+}
+
+/*
+EnableTotp enables two-factor authentication with the secret,
+the code used to confirm the secret cannot be used again
+*/
+func EnableTotp(user *User, secret string, step int64) error {
+	user.TotpSecret = secret
+	user.TotpEnabled = true
+	user.TotpLastUsedStep = step
+	return dbconn.DB.Save(user).Error
+}
+
+/*
+DisableTotp disables two-factor authentication and deletes the recovery codes
+*/
+func DisableTotp(user *User) error {
+	user.TotpSecret = ""
+	user.TotpEnabled = false
+	user.TotpLastUsedStep = 0
+	if err := dbconn.DB.Save(user).Error; err != nil {
+		return err
+	}
+	return dbconn.DB.Unscoped().Where("user_id = ?", user.ID).Delete(&TwoFactorRecoveryCode{}).Error
+}
@@ -28,6 +28,12 @@ type User struct {
 	Approved           bool           `gorm:"column:approved; default: false;" json:"-"`
 	DeletionInProgress bool           `gorm:"column:deletion_in_progress;default:false;not null;"`
 	EmailVerified      bool           `gorm:"column:email_verified;default:false;not null;"`
+	TimeZone           string         `gorm:"column:time_zone; size:64; default:'UTC';" json:"time_zone"`
+	LdapDN             string         `gorm:"column:ldap_dn; size:255;" json:"-"`
+	Deactivated        bool           `gorm:"column:deactivated; default:false; not null;" json:"-"`
+	TotpSecret         string         `gorm:"column:totp_secret; size:64;" json:"-"`
+	TotpEnabled        bool           `gorm:"column:totp_enabled; default:false; not null;" json:"-"`
+	TotpLastUsedStep   int64          `gorm:"column:totp_last_used_step; default:0; not null;" json:"-"`
 	CreatedAt          time.Time      `json:"-"`
 	UpdatedAt          time.Time      `json:"-"`
 	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
@@ -88,6 +94,13 @@ func (u *User) CheckPassword(password string) bool {
 	return err == nil
 }
 
+/*
+IsLdapUser checks if the user is managed by the LDAP directory
+*/
+func (u *User) IsLdapUser() bool {
+	return u.LdapDN != ""
+}
+
 /*
 GetLastLogin retrieves the last login time of the user by checking
 the most recent token creation time.
@@ -109,6 +122,22 @@ func (u *User) GetLastLogin() (*time.Time, error) {
 	return &token.CreatedAt, nil
 }
 
+/*
+GetLocation returns the time zone of the user,
+UTC is returned if the time zone is not set or invalid
+*/
+func (u *User) GetLocation() *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 4)
 	return string(bytes), err
@@ -168,6 +197,29 @@ func CreateUser(
 	return &newUser, nil
 }
 
+/*
+ListLdapUsers retrieves the users managed by the LDAP directory
+that have not been deactivated
+*/
+func ListLdapUsers() ([]User, error) {
+	users := []User{}
+	if err := dbconn.DB.Where("ldap_dn <> '' AND deactivated = ?", false).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+/*
+DeactivateUser prevents the user from logging in and revokes its tokens
+*/
+func DeactivateUser(user *User) error {
+	user.Deactivated = true
+	if err := dbconn.DB.Save(user).Error; err != nil {
+		return err
+	}
+	return RevokeAllAuthTokensForUser(*user)
+}
+
 /*
 ListUsers retrieves all users from the database ordered by -CreatedAt.
 Limit specifies the maximum number of users to retrieve.
@@ -182,6 +234,22 @@ func ListUsers(limit int) (users *[]User, err error) {
 	return users, nil
 }
 
+/*
+ListUsersByEmails retrieves the users with the given email addresses,
+users that do not exist are ignored
+*/
+func ListUsersByEmails(emails []string) ([]User, error) {
+	users := []User{}
+	if len(emails) == 0 {
+		return users, nil
+	}
+
+	if err := dbconn.DB.Where("email IN ?", emails).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 /*
 RetrieveUserByEmail retrieves a user by their email address.
 */
@@ -1,7 +1,9 @@
 package models
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -41,6 +43,18 @@ type Workspace struct {
 	GitSourceID          *uint                     `gorm:"column:git_source_id;" json:"-"`
 	GitSource            *GitWorkspaceSource       `gorm:"constraint:OnDelete:CASCADE;" json:"git_source"`
 	EnvironmentVariables []string                  `gorm:"column:environment_variables; serializer:json" json:"environment_variables"`
+	IdleTimeoutMinutes   *uint                     `gorm:"column:idle_timeout_minutes; default:null;" json:"idle_timeout_minutes"` // nil means inherited from template
+	IdleShutdownAt       *time.Time                `gorm:"column:idle_shutdown_at;" json:"idle_shutdown_at"`
+	StartSchedule        string                    `gorm:"column:start_schedule; size:255; default:'';" json:"start_schedule"`
+	StopSchedule         string                    `gorm:"column:stop_schedule; size:255; default:'';" json:"stop_schedule"`
+	ScheduleLastCheck    *time.Time                `gorm:"column:schedule_last_check;" json:"-"`
+	ErrorReason          string                    `gorm:"column:error_reason; type:text;" json:"error_reason"`
+	StartDeadline        *time.Time                `gorm:"column:start_deadline;" json:"-"`     // set while a start is in progress
+	StartHeartbeatAt     *time.Time                `gorm:"column:start_heartbeat_at;" json:"-"` // last time the start job was seen alive
+	StartSubmitted       bool                      `gorm:"column:start_submitted; default:false;" json:"-"`
+	RunnerAccessRevoked  bool                      `gorm:"column:runner_access_revoked; default:false;" json:"runner_access_revoked"` // the owner is no longer allowed to use the runner
+	ResourceLimits                                 // overrides the limits of the template
+	ParameterValues      TemplateParameterValues   `gorm:"column:parameter_values; type:text; serializer:json;" json:"parameter_values"` // values of the template parameters
 	CreatedAt            time.Time                 `json:"created_at"`
 	UpdatedAt            time.Time                 `json:"updated_at"`
 	DeletedAt            gorm.DeletedAt            `gorm:"index" json:"-"`
@@ -104,6 +118,99 @@ func (w *Workspace) RetrieveLogs() (string, error) {
 	return string(fileContent), nil
 }
 
+/*
+WorkspaceLogsChunk is a part of the logs of a workspace, offsets are in bytes.
+NextOffset is the offset to use to read the following chunk and Size
+is the size of the whole logs file
+*/
+type WorkspaceLogsChunk struct {
+	Logs       string
+	Offset     int64
+	NextOffset int64
+	Size       int64
+}
+
+/*
+RetrieveLogsChunk reads the logs starting from the byte offset, at most limit
+bytes are read, zero means no limit. When the chunk is truncated it ends at the
+last complete line, so lines are never split between two chunks. If the offset
+is beyond the end of the file (e.g. the logs have been cleared) logs are read
+from the beginning
+*/
+func (w *Workspace) RetrieveLogsChunk(offset int64, limit int64) (WorkspaceLogsChunk, error) {
+	logsFile, err := w.GetLogsFilePath()
+	if err != nil {
+		return WorkspaceLogsChunk{}, fmt.Errorf("cannot retrieve logs file path, %s", err)
+	}
+
+	f, err := os.Open(logsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return WorkspaceLogsChunk{}, nil
+		}
+		return WorkspaceLogsChunk{}, fmt.Errorf("cannot open logs file, %s", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return WorkspaceLogsChunk{}, fmt.Errorf("cannot read logs from file, %s", err)
+	}
+
+	size := info.Size()
+	if offset < 0 || offset > size {
+		offset = 0
+	}
+
+	length := size - offset
+	if limit > 0 && limit < length {
+		length = limit
+	}
+
+	buffer := make([]byte, length)
+	n, err := f.ReadAt(buffer, offset)
+	if err != nil && err != io.EOF {
+		return WorkspaceLogsChunk{}, fmt.Errorf("cannot read logs from file, %s", err)
+	}
+	buffer = buffer[:n]
+
+	// do not split the last line
+	if offset+int64(n) < size {
+		if i := bytes.LastIndexByte(buffer, '\n'); i >= 0 {
+			buffer = buffer[:i+1]
+		}
+	}
+
+	return WorkspaceLogsChunk{
+		Logs:       string(buffer),
+		Offset:     offset,
+		NextOffset: offset + int64(len(buffer)),
+		Size:       size,
+	}, nil
+}
+
+/*
+RetrieveLogsOffsetFromLine converts a line cursor into the byte
+offset of the line, the size of the logs is returned if the logs
+have less lines than the cursor
+*/
+func (w *Workspace) RetrieveLogsOffsetFromLine(line int64) (int64, error) {
+	logs, err := w.RetrieveLogs()
+	if err != nil {
+		return 0, err
+	}
+
+	offset := 0
+	for i := int64(0); i < line; i++ {
+		next := strings.IndexByte(logs[offset:], '\n')
+		if next < 0 {
+			return int64(len(logs)), nil
+		}
+		offset += next + 1
+	}
+	return int64(offset), nil
+}
+
 /*
 Retrieve list of environement variables to exported by default to workspace
 This variables are informations related to workspace such as workspace name,
@@ -121,6 +228,50 @@ func (w *Workspace) GetDefaultEnvironmentVariables() []string {
 	}
 }
 
+/*
+GetIdleTimeout retrieves the inactivity window after which the workspace
+is stopped automatically. The value set on the workspace overrides the one
+set on the template, zero means that the workspace is never stopped.
+*/
+func (w *Workspace) GetIdleTimeout() time.Duration {
+	if w.IdleTimeoutMinutes != nil {
+		return time.Duration(*w.IdleTimeoutMinutes) * time.Minute
+	}
+
+	if w.TemplateVersion != nil && w.TemplateVersion.Template != nil {
+		return time.Duration(w.TemplateVersion.Template.IdleTimeoutMinutes) * time.Minute
+	}
+
+	return 0
+}
+
+/*
+GetRunnerLabelSelectors retrieves the runner labels required and preferred
+by the workspace, they are declared by the template or by the git source
+*/
+func (w *Workspace) GetRunnerLabelSelectors() (required RunnerLabels, preferred RunnerLabels, err error) {
+	if w.ConfigSource == WorkspaceConfigSourceGit {
+		if w.GitSource == nil {
+			return nil, nil, nil
+		}
+		return w.GitSource.RequiredRunnerLabels, w.GitSource.PreferredRunnerLabels, nil
+	}
+
+	if w.TemplateVersion == nil {
+		return nil, nil, nil
+	}
+
+	template := w.TemplateVersion.Template
+	if template == nil {
+		template, err = RetrieveWorkspaceTemplateByID(w.TemplateVersion.TemplateID)
+		if err != nil || template == nil {
+			return nil, nil, err
+		}
+	}
+
+	return template.RequiredRunnerLabels, template.PreferredRunnerLabels, nil
+}
+
 /*
 Filter workspaces by owner
 */
@@ -312,6 +463,118 @@ func UpdateWorkspace(
 	return workspace, nil
 }
 
+/*
+SetWorkspaceIdleTimeout updates the inactivity window of a workspace,
+if idleTimeoutMinutes is nil the value is inherited from the template
+*/
+func SetWorkspaceIdleTimeout(workspace *Workspace, idleTimeoutMinutes *uint) (*Workspace, error) {
+	workspace.IdleTimeoutMinutes = idleTimeoutMinutes
+	workspace.IdleShutdownAt = nil
+
+	if err := dbconn.DB.Save(&workspace).Error; err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+/*
+SetWorkspaceResourceLimits updates the resource limits of a workspace,
+limits that are nil are inherited from the template
+*/
+func SetWorkspaceResourceLimits(workspace *Workspace, limits ResourceLimits) (*Workspace, error) {
+	workspace.ResourceLimits = limits
+
+	if err := dbconn.DB.Save(&workspace).Error; err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+/*
+SetWorkspaceTemplateParameterValues updates the values of the
+parameters of the template version used by the workspace
+*/
+func SetWorkspaceTemplateParameterValues(workspace *Workspace, values TemplateParameterValues) (*Workspace, error) {
+	workspace.ParameterValues = values
+
+	if err := dbconn.DB.Save(&workspace).Error; err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+/*
+TransferWorkspaceOwnership assigns a workspace to another user. Environment variables
+that override the ones derived from the owner are removed, so the values of the new
+owner are used from the next start. The runner access flag is refreshed for the new owner,
+if the new owner was a collaborator of the workspace the collaborator entry is removed
+*/
+func TransferWorkspaceOwnership(workspace *Workspace, owner User) (*Workspace, error) {
+	environmentVariables := []string{}
+	for _, v := range workspace.EnvironmentVariables {
+		if !strings.HasPrefix(v, "CODEBOX_WORKSPACE_OWNER_") {
+			environmentVariables = append(environmentVariables, v)
+		}
+	}
+
+	runnerAccessRevoked := false
+	if workspace.Runner != nil {
+		allowed, err := workspace.Runner.IsAllowedForUser(owner)
+		if err != nil {
+			return nil, err
+		}
+		runnerAccessRevoked = !allowed
+	}
+
+	// the new owner does not need to be a collaborator anymore
+	if err := dbconn.DB.
+		Where("workspace_id = ? AND user_id = ?", workspace.ID, owner.ID).
+		Delete(&WorkspaceCollaborator{}).Error; err != nil {
+		return nil, err
+	}
+
+	workspace.UserID = owner.ID
+	workspace.User = &owner
+	workspace.EnvironmentVariables = environmentVariables
+	workspace.RunnerAccessRevoked = runnerAccessRevoked
+
+	if err := dbconn.DB.Save(&workspace).Error; err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+/*
+ListWorkspacesByStatus retrieves all the workspaces with the given status
+*/
+func ListWorkspacesByStatus(status string) ([]Workspace, error) {
+	workspaces := []Workspace{}
+	r := dbconn.DB.
+		Preload("GitSource").
+		Preload("GitSource.Sources").
+		Preload("TemplateVersion").
+		Preload("TemplateVersion.Sources").
+		Preload("TemplateVersion.Template").
+		Preload("Runner").
+		Preload("User").
+		Find(
+			&workspaces,
+			map[string]interface{}{
+				"status": status,
+			},
+		)
+
+	if r.Error != nil {
+		return []Workspace{}, r.Error
+	}
+
+	return workspaces, nil
+}
+
 /*
 CountAllWorkspaces counts the total number of workspaces in the database.
 */
@@ -335,3 +598,67 @@ func CountAllOnlineWorkspaces() (int64, error) {
 	}
 	return count, nil
 }
+
+/*
+RetrieveWorkspaceStatus retrieves only the current status of a workspace,
+it's used by long running tasks to detect changes made in the meantime
+*/
+func RetrieveWorkspaceStatus(id uint) (string, error) {
+	var statuses []string
+	if err := dbconn.DB.
+		Model(&Workspace{}).
+		Where("id = ?", id).
+		Pluck("status", &statuses).Error; err != nil {
+		return "", err
+	}
+
+	if len(statuses) == 0 {
+		return "", gorm.ErrRecordNotFound
+	}
+	return statuses[0], nil
+}
+
+/*
+UpdateWorkspaceStartProgress stores the progress of an in-flight start,
+only the start related columns are updated so that concurrent changes
+to the workspace (e.g. a stop request) are not overwritten
+*/
+func UpdateWorkspaceStartProgress(workspace *Workspace) error {
+	return dbconn.DB.
+		Model(&Workspace{}).
+		Where("id = ?", workspace.ID).
+		UpdateColumns(map[string]interface{}{
+			"start_deadline":     workspace.StartDeadline,
+			"start_heartbeat_at": workspace.StartHeartbeatAt,
+			"start_submitted":    workspace.StartSubmitted,
+			"error_reason":       workspace.ErrorReason,
+		}).Error
+}
+
+/*
+UpdateWorkspaceStartHeartbeat records that the start job of
+the workspace is alive, only the heartbeat column is updated
+*/
+func UpdateWorkspaceStartHeartbeat(workspaceID uint, at time.Time) error {
+	return dbconn.DB.
+		Model(&Workspace{}).
+		Where("id = ?", workspaceID).
+		UpdateColumn("start_heartbeat_at", &at).Error
+}
+
+/*
+ListStalledWorkspaceStarts returns the workspaces with a start in progress
+whose start job has not been seen alive since the given time,
+e.g. because the server has been restarted
+*/
+func ListStalledWorkspaceStarts(since time.Time) ([]Workspace, error) {
+	var workspaces []Workspace
+	if err := dbconn.DB.
+		Where("start_deadline IS NOT NULL").
+		Where("status IN ?", []string{WorkspaceStatusStarting, WorkspaceStatusStopping}).
+		Where("start_heartbeat_at IS NULL OR start_heartbeat_at < ?", since).
+		Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gorm.io/gorm"
+)
+
+// roles of a user in a workspace, each role includes the permissions of the previous ones
+const (
+	WorkspaceRoleViewer    = "viewer"    // status, logs and events
+	WorkspaceRoleDeveloper = "developer" // terminal, ssh, filesystem and private ports
+	WorkspaceRoleAdmin     = "admin"     // start, stop and configuration
+	WorkspaceRoleOwner     = "owner"     // deletion and collaborators, not assignable
+)
+
+var workspaceRoleLevels = map[string]int{
+	WorkspaceRoleViewer:    1,
+	WorkspaceRoleDeveloper: 2,
+	WorkspaceRoleAdmin:     3,
+	WorkspaceRoleOwner:     4,
+}
+
+/*
+WorkspaceCollaborator grants to a user other than the
+owner access to a workspace with the given role
+*/
+type WorkspaceCollaborator struct {
+	ID          uint       `gorm:"primarykey"`
+	WorkspaceID uint       `gorm:"column:workspace_id; not null; uniqueIndex:idx_workspace_collaborators_user,priority:1;"`
+	Workspace   *Workspace `gorm:"constraint:OnDelete:CASCADE;"`
+	UserID      uint       `gorm:"column:user_id; not null; uniqueIndex:idx_workspace_collaborators_user,priority:2;"`
+	User        *User      `gorm:"constraint:OnDelete:CASCADE;"`
+	Role        string     `gorm:"column:role; size:30; not null;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+/*
+IsValidWorkspaceCollaboratorRole checks if a role can be assigned to a collaborator
+*/
+func IsValidWorkspaceCollaboratorRole(role string) bool {
+	return role == WorkspaceRoleViewer ||
+		role == WorkspaceRoleDeveloper ||
+		role == WorkspaceRoleAdmin
+}
+
+/*
+WorkspaceRoleAllows checks if a role includes the permissions of the required one,
+an empty role means no access to the workspace
+*/
+func WorkspaceRoleAllows(role string, required string) bool {
+	level, ok := workspaceRoleLevels[role]
+	if !ok {
+		return false
+	}
+	return level >= workspaceRoleLevels[required]
+}
+
+/*
+ListWorkspaceCollaborators retrieves the collaborators of a workspace
+*/
+func ListWorkspaceCollaborators(workspace Workspace) ([]WorkspaceCollaborator, error) {
+	collaborators := []WorkspaceCollaborator{}
+	if err := dbconn.DB.
+		Preload("User").
+		Where("workspace_id = ?", workspace.ID).
+		Order("created_at ASC").
+		Find(&collaborators).Error; err != nil {
+		return nil, err
+	}
+	return collaborators, nil
+}
+
+/*
+RetrieveWorkspaceCollaborator retrieves the collaborator entry of a user
+in a workspace, nil is returned if the user is not a collaborator
+*/
+func RetrieveWorkspaceCollaborator(workspace Workspace, user User) (*WorkspaceCollaborator, error) {
+	var collaborator WorkspaceCollaborator
+	if err := dbconn.DB.
+		Preload("User").
+		First(&collaborator, map[string]interface{}{
+			"workspace_id": workspace.ID,
+			"user_id":      user.ID,
+		}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+/*
+SetWorkspaceCollaborator adds a user to the collaborators of a workspace
+or updates their role if they are already a collaborator
+*/
+func SetWorkspaceCollaborator(workspace Workspace, user User, role string) (*WorkspaceCollaborator, error) {
+	collaborator, err := RetrieveWorkspaceCollaborator(workspace, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if collaborator == nil {
+		collaborator = &WorkspaceCollaborator{
+			WorkspaceID: workspace.ID,
+			UserID:      user.ID,
+			User:        &user,
+		}
+	}
+	collaborator.Role = role
+
+	if err := dbconn.DB.Save(collaborator).Error; err != nil {
+		return nil, err
+	}
+	return collaborator, nil
+}
+
+/*
+DeleteWorkspaceCollaborator removes a user from the collaborators of a workspace
+*/
+func DeleteWorkspaceCollaborator(collaborator *WorkspaceCollaborator) error {
+	return dbconn.DB.Delete(collaborator).Error
+}
+
+/*
+RetrieveWorkspaceRoleForUser returns the role of a user in a workspace,
+an empty string is returned if the user cannot access the workspace
+*/
+func RetrieveWorkspaceRoleForUser(workspace Workspace, user User) (string, error) {
+	if workspace.UserID == user.ID {
+		return WorkspaceRoleOwner, nil
+	}
+
+	collaborator, err := RetrieveWorkspaceCollaborator(workspace, user)
+	if err != nil {
+		return "", err
+	}
+
+	if collaborator == nil {
+		return "", nil
+	}
+	return collaborator.Role, nil
+}
+
+/*
+RetrieveWorkspaceWithRoleByUserAndId retrieves a workspace owned by the user or shared
+with them, together with the role of the user in the workspace.
+If the workspace does not exist or the user cannot access it nil is returned
+*/
+func RetrieveWorkspaceWithRoleByUserAndId(user User, id uint) (*Workspace, string, error) {
+	workspace, err := RetrieveWorkspaceById(id)
+	if err != nil || workspace == nil {
+		return nil, "", err
+	}
+
+	role, err := RetrieveWorkspaceRoleForUser(*workspace, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if role == "" {
+		return nil, "", nil
+	}
+	return workspace, role, nil
+}
+
+/*
+ListSharedWorkspaces retrieves the collaborator entries of a user,
+each one with the workspace that has been shared with the user
+*/
+func ListSharedWorkspaces(user User) ([]WorkspaceCollaborator, error) {
+	collaborators := []WorkspaceCollaborator{}
+	if err := dbconn.DB.
+		Joins("JOIN workspaces ON workspaces.id = workspace_collaborators.workspace_id AND workspaces.deleted_at IS NULL").
+		Preload("Workspace").
+		Preload("Workspace.GitSource").
+		Preload("Workspace.GitSource.Sources").
+		Preload("Workspace.TemplateVersion").
+		Preload("Workspace.TemplateVersion.Sources").
+		Preload("Workspace.Runner").
+		Preload("Workspace.User").
+		Where("workspace_collaborators.user_id = ?", user.ID).
+		Find(&collaborators).Error; err != nil {
+		return nil, err
+	}
+	return collaborators, nil
+}
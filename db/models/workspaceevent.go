@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gorm.io/gorm"
+)
+
+// source of a workspace event
+const (
+	WorkspaceEventSourceUser       = "user"
+	WorkspaceEventSourceAdmin      = "admin"
+	WorkspaceEventSourceScheduler  = "scheduler"
+	WorkspaceEventSourceReconciler = "reconciler"
+	WorkspaceEventSourceSystem     = "system"
+)
+
+/*
+WorkspaceEvent records a status transition of a workspace,
+the actor is the user who requested the transition, if any
+*/
+type WorkspaceEvent struct {
+	ID          uint       `gorm:"primarykey"`
+	WorkspaceID uint       `gorm:"column:workspace_id; not null;"`
+	Workspace   *Workspace `gorm:"constraint:OnDelete:CASCADE;"`
+	FromStatus  string     `gorm:"column:from_status; size:30;"`
+	ToStatus    string     `gorm:"column:to_status; size:30; not null;"`
+	Source      string     `gorm:"column:source; size:30; not null;"`
+	ActorID     *uint      `gorm:"column:actor_id;"`
+	Actor       *User      `gorm:"constraint:OnDelete:SET NULL;"`
+	Message     string     `gorm:"column:message; type:text;"`
+	ErrorReason string     `gorm:"column:error_reason; type:text;"`
+	CreatedAt   time.Time  `gorm:"column:created_at; index;"`
+}
+
+/*
+CreateWorkspaceEvent records the transition of a workspace from the given
+status to its current status, the error reason is stored if the workspace
+has moved to error. Nothing is recorded if the status has not changed
+*/
+func CreateWorkspaceEvent(
+	workspace Workspace,
+	fromStatus string,
+	source string,
+	actor *User,
+	message string,
+) (*WorkspaceEvent, error) {
+	if fromStatus == workspace.Status {
+		return nil, nil
+	}
+
+	event := WorkspaceEvent{
+		WorkspaceID: workspace.ID,
+		FromStatus:  fromStatus,
+		ToStatus:    workspace.Status,
+		Source:      source,
+		Message:     message,
+	}
+
+	if actor != nil && actor.ID > 0 {
+		event.ActorID = &actor.ID
+	}
+
+	if workspace.Status == WorkspaceStatusError {
+		event.ErrorReason = workspace.ErrorReason
+	}
+
+	if err := dbconn.DB.Create(&event).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+/*
+CreateWorkspaceTransitionEvent records the completion of a transition performed
+by a background task, e.g. stopping -> stopped. Source and actor are inherited from
+the event that started the transition, so the whole transition is attributed to
+who requested it
+*/
+func CreateWorkspaceTransitionEvent(workspace Workspace, fromStatus string, message string) (*WorkspaceEvent, error) {
+	source := WorkspaceEventSourceSystem
+	var actor *User
+
+	latest, err := RetrieveLatestWorkspaceEvent(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	if latest != nil {
+		source = latest.Source
+		actor = latest.Actor
+	}
+
+	return CreateWorkspaceEvent(workspace, fromStatus, source, actor, message)
+}
+
+/*
+RetrieveLatestWorkspaceEvent retrieves the latest event of a workspace,
+nil is returned if the workspace has no events
+*/
+func RetrieveLatestWorkspaceEvent(workspace Workspace) (*WorkspaceEvent, error) {
+	var event WorkspaceEvent
+	if err := dbconn.DB.
+		Preload("Actor").
+		Where("workspace_id = ?", workspace.ID).
+		Order("created_at DESC, id DESC").
+		First(&event).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &event, nil
+}
+
+/*
+ListWorkspaceEvents retrieves the events of a workspace,
+the most recent first. A limit of -1 means no limit
+*/
+func ListWorkspaceEvents(workspace Workspace, limit int, offset int) ([]WorkspaceEvent, error) {
+	events := []WorkspaceEvent{}
+	if err := dbconn.DB.
+		Preload("Actor").
+		Where("workspace_id = ?", workspace.ID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+/*
+CountWorkspaceEvents counts the events of a workspace
+*/
+func CountWorkspaceEvents(workspace Workspace) (int64, error) {
+	var count int64
+	if err := dbconn.DB.
+		Model(&WorkspaceEvent{}).
+		Where("workspace_id = ?", workspace.ID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
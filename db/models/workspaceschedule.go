@@ -0,0 +1,130 @@
+package models
+
+import (
+	"time"
+
+	"github.com/robfig/cron"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gorm.io/gorm"
+)
+
+// scheduled actions
+const WorkspaceScheduleActionStart = "start"
+const WorkspaceScheduleActionStop = "stop"
+
+type WorkspaceScheduleSkippedRun struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	WorkspaceID uint           `gorm:"column:workspace_id; not null;" json:"-"`
+	Workspace   Workspace      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Action      string         `gorm:"column:action; size:20; not null;" json:"action"`
+	ScheduledAt time.Time      `gorm:"column:scheduled_at; not null;" json:"scheduled_at"`
+	Reason      string         `gorm:"column:reason; type:text;" json:"reason"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+/*
+ParseWorkspaceSchedule parses a schedule expression, expressions use
+the standard cron format (minute, hour, day of month, month, day of week)
+or one of the descriptors supported by cron (e.g. @daily).
+An empty expression means that no schedule is set, in that case nil is returned
+*/
+func ParseWorkspaceSchedule(expression string) (cron.Schedule, error) {
+	if expression == "" {
+		return nil, nil
+	}
+	return cron.ParseStandard(expression)
+}
+
+/*
+NextScheduledRun returns the first time after 'after' at which the schedule
+expression fires, times are evaluated in the given location.
+Nil is returned if the expression is empty or invalid
+*/
+func NextScheduledRun(expression string, after time.Time, location *time.Location) *time.Time {
+	schedule, err := ParseWorkspaceSchedule(expression)
+	if err != nil || schedule == nil {
+		return nil
+	}
+
+	next := schedule.Next(after.In(location))
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+/*
+SetWorkspaceSchedule updates start and stop schedules of a workspace,
+expressions must be validated with ParseWorkspaceSchedule before calling this function
+*/
+func SetWorkspaceSchedule(workspace *Workspace, startSchedule, stopSchedule string) (*Workspace, error) {
+	now := time.Now()
+	workspace.StartSchedule = startSchedule
+	workspace.StopSchedule = stopSchedule
+	// runs planned before the update must not be executed
+	workspace.ScheduleLastCheck = &now
+
+	if err := dbconn.DB.Save(workspace).Error; err != nil {
+		return nil, err
+	}
+	return workspace, nil
+}
+
+/*
+ListScheduledWorkspaces returns all the workspaces that have
+at least one start or stop schedule
+*/
+func ListScheduledWorkspaces() ([]Workspace, error) {
+	var workspaces []Workspace
+	if err := dbconn.DB.
+		Preload("Runner").
+		Preload("User").
+		Where("start_schedule <> '' OR stop_schedule <> ''").
+		Find(&workspaces).Error; err != nil {
+		return nil, err
+	}
+	return workspaces, nil
+}
+
+/*
+CreateWorkspaceScheduleSkippedRun records a scheduled action
+that has not been performed
+*/
+func CreateWorkspaceScheduleSkippedRun(
+	workspace Workspace,
+	action string,
+	scheduledAt time.Time,
+	reason string,
+) (*WorkspaceScheduleSkippedRun, error) {
+	run := WorkspaceScheduleSkippedRun{
+		WorkspaceID: workspace.ID,
+		Action:      action,
+		ScheduledAt: scheduledAt,
+		Reason:      reason,
+	}
+
+	if err := dbconn.DB.Create(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+/*
+ListWorkspaceScheduleSkippedRuns returns the latest skipped runs of a workspace,
+most recent first
+*/
+func ListWorkspaceScheduleSkippedRuns(workspace Workspace, limit int) ([]WorkspaceScheduleSkippedRun, error) {
+	var runs []WorkspaceScheduleSkippedRun
+	if err := dbconn.DB.
+		Where(map[string]interface{}{
+			"workspace_id": workspace.ID,
+		}).
+		Order("scheduled_at DESC").
+		Limit(limit).
+		Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
@@ -8,6 +8,7 @@ import (
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/db/models"
 	docs "gitlab.com/codebox4073715/codebox/docs"
 	runnerapis "gitlab.com/codebox4073715/codebox/httpserver/api/runner"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/admin"
@@ -19,6 +20,7 @@ import (
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/common"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/notifications"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/runners"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/secrets"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/templates"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
 	"gitlab.com/codebox4073715/codebox/httpserver/permissions"
@@ -51,7 +53,7 @@ func V1ApiRoutes(router *gin.Engine) {
 			)
 			authApis.POST(
 				"/logout",
-				permissions.AuthenticationRequiredRoute(auth.HandleLogout),
+				permissions.SessionRequiredRoute(auth.HandleLogout),
 			)
 			authApis.POST(
 				"/signup",
@@ -61,6 +63,38 @@ func V1ApiRoutes(router *gin.Engine) {
 					60,
 				),
 			)
+			authApis.GET(
+				"/login/two-factor",
+				auth.HandleRetrieveTwoFactorChallenge,
+			)
+			authApis.POST(
+				"/login/two-factor",
+				permissions.IPRateLimitedRoute(
+					auth.HandleTwoFactorLogin,
+					10,
+					60,
+				),
+			)
+			authApis.GET(
+				"/two-factor",
+				permissions.SessionRequiredRoute(auth.HandleRetrieveTwoFactorStatus),
+			)
+			authApis.POST(
+				"/two-factor/enroll",
+				permissions.SessionRequiredRoute(auth.HandleEnrollTwoFactor),
+			)
+			authApis.POST(
+				"/two-factor/activate",
+				permissions.SessionRequiredRoute(auth.HandleActivateTwoFactor),
+			)
+			authApis.POST(
+				"/two-factor/disable",
+				permissions.SessionRequiredRoute(auth.HandleDisableTwoFactor),
+			)
+			authApis.POST(
+				"/two-factor/recovery-codes",
+				permissions.SessionRequiredRoute(auth.HandleRegenerateRecoveryCodes),
+			)
 			authApis.GET(
 				"/user-details",
 				permissions.AuthenticationRequiredRoute(auth.HandleRetriveUserDetails),
@@ -75,11 +109,11 @@ func V1ApiRoutes(router *gin.Engine) {
 			)
 			authApis.POST(
 				"/change-password",
-				permissions.AuthenticationRequiredRoute(auth.HandleChangePassword),
+				permissions.SessionRequiredRoute(auth.HandleChangePassword),
 			)
 			authApis.GET(
 				"/subdomains/authorize",
-				permissions.AuthenticationRequiredRoute(auth.HandleSubdomainLoginAuthorize),
+				permissions.SessionRequiredRoute(auth.HandleSubdomainLoginAuthorize),
 			)
 			authApis.GET(
 				fmt.Sprintf("/subdomains/callback-%s", url.PathEscape(config.Environment.AuthCookieName)),
@@ -87,12 +121,56 @@ func V1ApiRoutes(router *gin.Engine) {
 			)
 			authApis.POST(
 				"/cli-login",
-				permissions.AuthenticationRequiredRoute(auth.HandleCliLogin),
+				permissions.SessionRequiredRoute(auth.HandleCliLogin),
+			)
+			authApis.GET(
+				"/tokens",
+				permissions.SessionRequiredRoute(auth.HandleListPersonalAccessTokens),
+			)
+			authApis.POST(
+				"/tokens",
+				permissions.SessionRequiredRoute(auth.HandleCreatePersonalAccessToken),
+			)
+			authApis.DELETE(
+				"/tokens/:tokenId",
+				permissions.SessionRequiredRoute(auth.HandleDeletePersonalAccessToken),
+			)
+			authApis.GET(
+				"/sessions",
+				permissions.SessionRequiredRoute(auth.HandleListSessions),
+			)
+			authApis.DELETE(
+				"/sessions",
+				permissions.SessionRequiredRoute(auth.HandleRevokeOtherSessions),
+			)
+			authApis.DELETE(
+				"/sessions/:sessionId",
+				permissions.SessionRequiredRoute(auth.HandleRevokeSession),
 			)
 			authApis.GET(
 				"/is-signup-open",
 				auth.HandleIsSignUpOpen,
 			)
+			authApis.GET(
+				"/login-options",
+				auth.HandleRetrieveLoginOptions,
+			)
+			authApis.GET(
+				"/oidc/login",
+				permissions.IPRateLimitedRoute(
+					auth.HandleOIDCLogin,
+					20,
+					60,
+				),
+			)
+			authApis.GET(
+				"/oidc/callback",
+				permissions.IPRateLimitedRoute(
+					auth.HandleOIDCCallback,
+					20,
+					60,
+				),
+			)
 			authApis.POST(
 				"/verify-email-address",
 				auth.HandleVerifyEmailAddress,
@@ -146,6 +224,18 @@ func V1ApiRoutes(router *gin.Engine) {
 				"/:workspaceId/logs",
 				permissions.AuthenticationRequiredRoute(workspaces.HandleRetrieveWorkspaceLogs),
 			)
+			workspaceApis.GET(
+				"/:workspaceId/logs/stream",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleStreamWorkspaceLogs),
+			)
+			workspaceApis.GET(
+				"/:workspaceId/events",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleListWorkspaceEvents),
+			)
+			workspaceApis.POST(
+				"/:workspaceId/clone",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleCloneWorkspace),
+			)
 			workspaceApis.POST(
 				"/:workspaceId/start",
 				permissions.AuthenticationRequiredRoute(workspaces.HandleStartWorkspace),
@@ -166,6 +256,54 @@ func V1ApiRoutes(router *gin.Engine) {
 				"/:workspaceId/set-runner",
 				permissions.AuthenticationRequiredRoute(workspaces.HandleSetRunnerForWorkspace),
 			)
+			workspaceApis.POST(
+				"/:workspaceId/set-idle-timeout",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleSetIdleTimeoutForWorkspace),
+			)
+			workspaceApis.POST(
+				"/:workspaceId/set-resource-limits",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleSetResourceLimitsForWorkspace),
+			)
+			workspaceApis.GET(
+				"/:workspaceId/schedule",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleRetrieveWorkspaceSchedule),
+			)
+			workspaceApis.PUT(
+				"/:workspaceId/schedule",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleUpdateWorkspaceSchedule),
+			)
+			workspaceApis.DELETE(
+				"/:workspaceId/schedule",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleDeleteWorkspaceSchedule),
+			)
+			workspaceApis.GET(
+				"/:workspaceId/collaborators",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleListWorkspaceCollaborators),
+			)
+			workspaceApis.PUT(
+				"/:workspaceId/collaborators/:email",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleSetWorkspaceCollaborator),
+			)
+			workspaceApis.DELETE(
+				"/:workspaceId/collaborators/:email",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleDeleteWorkspaceCollaborator),
+			)
+			workspaceApis.GET(
+				"/:workspaceId/secrets",
+				permissions.AuthenticationRequiredRoute(secrets.HandleListWorkspaceSecrets),
+			)
+			workspaceApis.POST(
+				"/:workspaceId/secrets",
+				permissions.AuthenticationRequiredRoute(secrets.HandleCreateWorkspaceSecret),
+			)
+			workspaceApis.PUT(
+				"/:workspaceId/secrets/:secretId",
+				permissions.AuthenticationRequiredRoute(secrets.HandleUpdateWorkspaceSecret),
+			)
+			workspaceApis.DELETE(
+				"/:workspaceId/secrets/:secretId",
+				permissions.AuthenticationRequiredRoute(secrets.HandleDeleteWorkspaceSecret),
+			)
 			// container related apis
 			workspaceApis.GET(
 				"/:workspaceId/container",
@@ -191,13 +329,42 @@ func V1ApiRoutes(router *gin.Engine) {
 				"/:workspaceId/container/:containerName/port/:portNumber",
 				permissions.AuthenticationRequiredRoute(workspaces.HandleDeleteContainerPortByWorkspaceContainer),
 			)
+			workspaceApis.GET(
+				"/:workspaceId/container/:containerName/port/:portNumber/share-tokens",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleListPortShareTokens),
+			)
+			workspaceApis.POST(
+				"/:workspaceId/container/:containerName/port/:portNumber/share-tokens",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleCreatePortShareToken),
+			)
+			workspaceApis.DELETE(
+				"/:workspaceId/container/:containerName/port/:portNumber/share-tokens/:tokenId",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleDeletePortShareToken),
+			)
 			workspaceApis.Any(
 				"/:workspaceId/container/:containerName/forward-ssh",
-				permissions.AuthenticationRequiredRoute(workspaces.HandleForwardSsh),
+				permissions.ScopeRequiredRoute(
+					permissions.AuthenticationRequiredRoute(workspaces.HandleForwardSsh),
+					models.PersonalAccessTokenScopeSsh,
+				),
+			)
+			workspaceApis.GET(
+				"/:workspaceId/container/:containerName/forward-tcp/:portNumber",
+				permissions.ScopeRequiredRoute(
+					permissions.AuthenticationRequiredRoute(workspaces.HandleForwardTcp),
+					models.PersonalAccessTokenScopeWorkspacesWrite,
+				),
+			)
+			workspaceApis.GET(
+				"/:workspaceId/container/:containerName/tcp-tunnels",
+				permissions.AuthenticationRequiredRoute(workspaces.HandleListTcpTunnels),
 			)
 			workspaceApis.Any(
 				"/:workspaceId/container/:containerName/terminal",
-				permissions.AuthenticationRequiredRoute(workspaces.HandleTerminal),
+				permissions.ScopeRequiredRoute(
+					permissions.AuthenticationRequiredRoute(workspaces.HandleTerminal),
+					models.PersonalAccessTokenScopeWorkspacesWrite,
+				),
 			)
 			// fs related apis
 			workspaceApis.GET(
@@ -296,6 +463,15 @@ func V1ApiRoutes(router *gin.Engine) {
 			)
 		}
 
+		// secrets related apis
+		secretsApis := v1.Group("/secrets")
+		{
+			secretsApis.GET("", permissions.AuthenticationRequiredRoute(secrets.HandleListUserSecrets))
+			secretsApis.POST("", permissions.AuthenticationRequiredRoute(secrets.HandleCreateUserSecret))
+			secretsApis.PUT(":secretId", permissions.AuthenticationRequiredRoute(secrets.HandleUpdateUserSecret))
+			secretsApis.DELETE(":secretId", permissions.AuthenticationRequiredRoute(secrets.HandleDeleteUserSecret))
+		}
+
 		// runners related apis
 		runnersApis := v1.Group("/runners")
 		{
@@ -318,7 +494,7 @@ func V1ApiRoutes(router *gin.Engine) {
 		// impersonation related apis
 		v1.POST(
 			"stop-impersonation",
-			permissions.AuthenticationRequiredRoute(auth.HandleStopImpersonation),
+			permissions.SessionRequiredRoute(auth.HandleStopImpersonation),
 		)
 
 		// notifications related apis
@@ -356,10 +532,18 @@ func V1ApiRoutes(router *gin.Engine) {
 				"runners/:runnerId",
 				permissions.AdminRequiredRoute(admin.HandleAdminDeleteRunner),
 			)
+			adminApis.POST(
+				"runners/:runnerId/reconcile",
+				permissions.AdminRequiredRoute(admin.HandleAdminReconcileRunner),
+			)
 			adminApis.GET(
 				"recommended-runner-version",
 				permissions.AdminRequiredRoute(admin.HandleRetrieveRecommendedRunnerVersion),
 			)
+			adminApis.POST(
+				"workspaces/:workspaceId/transfer",
+				permissions.AdminRequiredRoute(admin.HandleAdminTransferWorkspace),
+			)
 			adminApis.GET(
 				"users",
 				permissions.AdminRequiredRoute(admin.HandleAdminListUsers),
@@ -384,14 +568,113 @@ func V1ApiRoutes(router *gin.Engine) {
 				"users/:email/set-password",
 				permissions.AdminRequiredRoute(admin.HandleAdminSetUserPassword),
 			)
+			adminApis.DELETE(
+				"users/:email/two-factor",
+				permissions.AdminRequiredRoute(admin.HandleAdminResetUserTwoFactor),
+			)
 			adminApis.POST(
 				"users/:email/impersonate",
-				permissions.AdminRequiredRoute(admin.HandleAdminImpersonateUser),
+				permissions.SessionRequiredRoute(
+					permissions.AdminRequiredRoute(admin.HandleAdminImpersonateUser),
+				),
 			)
 			adminApis.GET(
 				"users/:email/impersonation-logs",
 				permissions.AdminRequiredRoute(admin.HandleAdminListImpersonationLogsByUser),
 			)
+			adminApis.GET(
+				"users/:email/sessions",
+				permissions.AdminRequiredRoute(admin.HandleAdminListUserSessions),
+			)
+			adminApis.DELETE(
+				"users/:email/sessions",
+				permissions.AdminRequiredRoute(admin.HandleAdminRevokeUserSessions),
+			)
+			adminApis.DELETE(
+				"users/:email/sessions/:sessionId",
+				permissions.AdminRequiredRoute(admin.HandleAdminRevokeUserSession),
+			)
+			// groups related apis
+			adminApis.GET(
+				"groups",
+				permissions.AdminRequiredRoute(admin.HandleAdminListGroups),
+			)
+			adminApis.POST(
+				"groups",
+				permissions.AdminRequiredRoute(admin.HandleAdminCreateGroup),
+			)
+			adminApis.POST(
+				"groups/bulk-assign",
+				permissions.AdminRequiredRoute(admin.HandleAdminBulkAssignGroups),
+			)
+			adminApis.GET(
+				"groups/:groupId",
+				permissions.AdminRequiredRoute(admin.HandleAdminRetrieveGroup),
+			)
+			adminApis.PUT(
+				"groups/:groupId",
+				permissions.AdminRequiredRoute(admin.HandleAdminUpdateGroup),
+			)
+			adminApis.DELETE(
+				"groups/:groupId",
+				permissions.AdminRequiredRoute(admin.HandleAdminDeleteGroup),
+			)
+			adminApis.GET(
+				"groups/:groupId/members",
+				permissions.AdminRequiredRoute(admin.HandleAdminListGroupMembers),
+			)
+			adminApis.POST(
+				"groups/:groupId/members",
+				permissions.AdminRequiredRoute(admin.HandleAdminAddGroupMembers),
+			)
+			adminApis.PUT(
+				"groups/:groupId/members",
+				permissions.AdminRequiredRoute(admin.HandleAdminSetGroupMembers),
+			)
+			adminApis.DELETE(
+				"groups/:groupId/members/:email",
+				permissions.AdminRequiredRoute(admin.HandleAdminRemoveGroupMember),
+			)
+			adminApis.GET(
+				"groups/:groupId/quota",
+				permissions.AdminRequiredRoute(admin.HandleAdminRetrieveGroupQuota),
+			)
+			adminApis.PUT(
+				"groups/:groupId/quota",
+				permissions.AdminRequiredRoute(admin.HandleAdminUpdateGroupQuota),
+			)
+			adminApis.DELETE(
+				"groups/:groupId/quota",
+				permissions.AdminRequiredRoute(admin.HandleAdminDeleteGroupQuota),
+			)
+			adminApis.GET(
+				"groups/:groupId/secrets",
+				permissions.AdminRequiredRoute(secrets.HandleAdminListGroupSecrets),
+			)
+			adminApis.POST(
+				"groups/:groupId/secrets",
+				permissions.AdminRequiredRoute(secrets.HandleAdminCreateGroupSecret),
+			)
+			adminApis.PUT(
+				"groups/:groupId/secrets/:secretId",
+				permissions.AdminRequiredRoute(secrets.HandleAdminUpdateGroupSecret),
+			)
+			adminApis.DELETE(
+				"groups/:groupId/secrets/:secretId",
+				permissions.AdminRequiredRoute(secrets.HandleAdminDeleteGroupSecret),
+			)
+			adminApis.GET(
+				"users/:email/quota",
+				permissions.AdminRequiredRoute(admin.HandleAdminRetrieveUserQuota),
+			)
+			adminApis.PUT(
+				"users/:email/quota",
+				permissions.AdminRequiredRoute(admin.HandleAdminUpdateUserQuota),
+			)
+			adminApis.DELETE(
+				"users/:email/quota",
+				permissions.AdminRequiredRoute(admin.HandleAdminDeleteUserQuota),
+			)
 			// instance settings related apis
 			adminApis.GET(
 				"authentication-settings",
@@ -401,6 +684,38 @@ func V1ApiRoutes(router *gin.Engine) {
 				"authentication-settings",
 				permissions.AdminRequiredRoute(settings.HandleUpdateAuthenticationSettings),
 			)
+			adminApis.GET(
+				"oidc-settings",
+				permissions.AdminRequiredRoute(settings.HandleRetrieveOIDCSettings),
+			)
+			adminApis.PUT(
+				"oidc-settings",
+				permissions.AdminRequiredRoute(settings.HandleUpdateOIDCSettings),
+			)
+			adminApis.GET(
+				"ldap-settings",
+				permissions.AdminRequiredRoute(settings.HandleRetrieveLDAPSettings),
+			)
+			adminApis.PUT(
+				"ldap-settings",
+				permissions.AdminRequiredRoute(settings.HandleUpdateLDAPSettings),
+			)
+			adminApis.GET(
+				"quota-settings",
+				permissions.AdminRequiredRoute(settings.HandleRetrieveQuotaSettings),
+			)
+			adminApis.PUT(
+				"quota-settings",
+				permissions.AdminRequiredRoute(settings.HandleUpdateQuotaSettings),
+			)
+			adminApis.GET(
+				"resource-limits-settings",
+				permissions.AdminRequiredRoute(settings.HandleRetrieveResourceLimitsSettings),
+			)
+			adminApis.PUT(
+				"resource-limits-settings",
+				permissions.AdminRequiredRoute(settings.HandleUpdateResourceLimitsSettings),
+			)
 			adminApis.GET(
 				"email-service-configured",
 				permissions.AdminRequiredRoute(common.HandleAdminEmailServiceConfigured),
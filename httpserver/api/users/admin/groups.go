@@ -0,0 +1,454 @@
+package admin
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleAdminListGroups godoc
+// @Summary List groups
+// @Schemes
+// @Description List all groups ordered by name
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.AdminGroupSerializer
+// @Router /api/v1/admin/groups [get]
+func HandleAdminListGroups(c *gin.Context) {
+	groups, err := models.ListGroups()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleAdminGroupSerializer(groups))
+}
+
+// HandleAdminRetrieveGroup godoc
+// @Summary Retrieve a group
+// @Schemes
+// @Description Retrieve a group by its id
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.AdminGroupSerializer
+// @Router /api/v1/admin/groups/:groupId [get]
+func HandleAdminRetrieveGroup(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadAdminGroupSerializer(group))
+}
+
+type AdminCreateGroupRequestBody struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// HandleAdminCreateGroup godoc
+// @Summary Create a group
+// @Schemes
+// @Description Create a group
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AdminCreateGroupRequestBody true "Group details"
+// @Success 201 {object} serializers.AdminGroupSerializer
+// @Router /api/v1/admin/groups [post]
+func HandleAdminCreateGroup(c *gin.Context) {
+	var reqBody AdminCreateGroupRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid or missing argument")
+		return
+	}
+
+	name := strings.TrimSpace(reqBody.Name)
+	if name == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid or missing argument")
+		return
+	}
+
+	existing, err := models.RetrieveGroupByName(name)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if existing != nil {
+		utils.ErrorResponse(c, http.StatusConflict, "another group with the same name already exists")
+		return
+	}
+
+	group, err := models.CreateGroup(name)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializers.LoadAdminGroupSerializer(group))
+}
+
+type AdminUpdateGroupRequestBody struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// HandleAdminUpdateGroup godoc
+// @Summary Rename a group
+// @Schemes
+// @Description Rename a group
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AdminUpdateGroupRequestBody true "Group details"
+// @Success 200 {object} serializers.AdminGroupSerializer
+// @Router /api/v1/admin/groups/:groupId [put]
+func HandleAdminUpdateGroup(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	var reqBody AdminUpdateGroupRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid or missing argument")
+		return
+	}
+
+	name := strings.TrimSpace(reqBody.Name)
+	if name == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid or missing argument")
+		return
+	}
+
+	existing, err := models.RetrieveGroupByName(name)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if existing != nil && existing.ID != group.ID {
+		utils.ErrorResponse(c, http.StatusConflict, "another group with the same name already exists")
+		return
+	}
+
+	group.Name = name
+	if err := models.UpdateGroup(*group); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadAdminGroupSerializer(group))
+}
+
+// HandleAdminDeleteGroup godoc
+// @Summary Delete a group
+// @Schemes
+// @Description Delete a group, workspaces on runners restricted to
+// @Description the group are flagged if their owner loses the access
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/admin/groups/:groupId [delete]
+func HandleAdminDeleteGroup(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	runners, err := models.ListRunnersByAllowedGroup(*group)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if err := models.DeleteGroup(*group); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	refreshRunnerAccessFlags(runners)
+
+	c.JSON(http.StatusNoContent, gin.H{"detail": "group has been deleted"})
+}
+
+// HandleAdminListGroupMembers godoc
+// @Summary List the members of a group
+// @Schemes
+// @Description List the members of a group
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.AdminUserSerializer
+// @Router /api/v1/admin/groups/:groupId/members [get]
+func HandleAdminListGroupMembers(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	members, err := models.ListGroupMembers(*group)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleAdminUserSerializer(members))
+}
+
+type AdminGroupMembersRequestBody struct {
+	Emails []string `json:"emails" binding:"required"`
+}
+
+// HandleAdminAddGroupMembers godoc
+// @Summary Add members to a group
+// @Schemes
+// @Description Add one or more users to a group, users that are already members are ignored
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AdminGroupMembersRequestBody true "Emails of the users"
+// @Success 200 {object} []serializers.AdminUserSerializer
+// @Router /api/v1/admin/groups/:groupId/members [post]
+func HandleAdminAddGroupMembers(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	users := getUsersFromRequestBody(c)
+	if users == nil {
+		return
+	}
+
+	if err := models.AddUsersToGroup(*group, users); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	refreshRunnerAccessFlagsForGroup(*group)
+	respondWithGroupMembers(c, *group)
+}
+
+// HandleAdminSetGroupMembers godoc
+// @Summary Set the members of a group
+// @Schemes
+// @Description Replace the members of a group with the given users,
+// @Description workspaces on runners restricted to the group are flagged
+// @Description if their owner loses the access
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AdminGroupMembersRequestBody true "Emails of the users"
+// @Success 200 {object} []serializers.AdminUserSerializer
+// @Router /api/v1/admin/groups/:groupId/members [put]
+func HandleAdminSetGroupMembers(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	users := getUsersFromRequestBody(c)
+	if users == nil {
+		return
+	}
+
+	if err := models.SetGroupMembers(*group, users); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	refreshRunnerAccessFlagsForGroup(*group)
+	respondWithGroupMembers(c, *group)
+}
+
+// HandleAdminRemoveGroupMember godoc
+// @Summary Remove a member from a group
+// @Schemes
+// @Description Remove a user from a group, workspaces on runners restricted
+// @Description to the group are flagged if the user loses the access
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.AdminUserSerializer
+// @Router /api/v1/admin/groups/:groupId/members/:email [delete]
+func HandleAdminRemoveGroupMember(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	email, _ := c.Params.Get("email")
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err := models.RemoveUserFromGroup(*group, *user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	refreshRunnerAccessFlagsForGroup(*group)
+	respondWithGroupMembers(c, *group)
+}
+
+type AdminBulkAssignGroupsRequestBody struct {
+	Emails   []string `json:"emails" binding:"required"`
+	GroupIDs []uint   `json:"group_ids" binding:"required"`
+}
+
+// HandleAdminBulkAssignGroups godoc
+// @Summary Assign users to groups
+// @Schemes
+// @Description Add each of the users to each of the groups
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AdminBulkAssignGroupsRequestBody true "Users and groups"
+// @Success 200 {object} []serializers.AdminGroupSerializer
+// @Router /api/v1/admin/groups/bulk-assign [post]
+func HandleAdminBulkAssignGroups(c *gin.Context) {
+	var reqBody AdminBulkAssignGroupsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid or missing argument")
+		return
+	}
+
+	groups, err := retrieveGroupsByIDs(reqBody.GroupIDs)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := retrieveUsersByEmails(reqBody.Emails)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, group := range groups {
+		if err := models.AddUsersToGroup(group, users); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+		refreshRunnerAccessFlagsForGroup(group)
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleAdminGroupSerializer(groups))
+}
+
+/*
+Retrieve the group from the id in the path, an error
+response is sent and nil is returned if the group does not exist
+*/
+func getGroupFromContext(c *gin.Context) *models.Group {
+	id, err := utils.GetUIntParamFromContext(c, "groupId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "group not found")
+		return nil
+	}
+
+	group, err := models.RetrieveGroupByID(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if group == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "group not found")
+		return nil
+	}
+	return group
+}
+
+/*
+Retrieve the users listed in the request body, an error response
+is sent and nil is returned if the body is invalid or a user does not exist
+*/
+func getUsersFromRequestBody(c *gin.Context) []models.User {
+	var reqBody AdminGroupMembersRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid or missing argument")
+		return nil
+	}
+
+	users, err := retrieveUsersByEmails(reqBody.Emails)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return nil
+	}
+	return users
+}
+
+/*
+Retrieve the users with the given emails,
+an error is returned if some of the users do not exist
+*/
+func retrieveUsersByEmails(emails []string) ([]models.User, error) {
+	users, err := models.ListUsersByEmails(emails)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, email := range emails {
+		found := false
+		for _, user := range users {
+			if user.Email == email {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("user '%s' not found", email)
+		}
+	}
+	return users, nil
+}
+
+func respondWithGroupMembers(c *gin.Context, group models.Group) {
+	members, err := models.ListGroupMembers(group)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleAdminUserSerializer(members))
+}
+
+/*
+Update the flags of the workspaces on the runners restricted to the group,
+members added to or removed from the group may have gained or lost the
+access to the runners
+*/
+func refreshRunnerAccessFlagsForGroup(group models.Group) {
+	runners, err := models.ListRunnersByAllowedGroup(group)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	refreshRunnerAccessFlags(runners)
+}
+
+func refreshRunnerAccessFlags(runners []models.Runner) {
+	for _, runner := range runners {
+		if _, err := models.RefreshRunnerAccessFlags(runner); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/admin"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Create a group, add and remove a member, then delete the group
+*/
+func TestGroupManagement(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		adminUser, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || adminUser == nil {
+			t.Fatalf("Failed to retrieve admin user: '%s'", err)
+		}
+
+		// create the group
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/admin/groups",
+			"POST",
+			admin.AdminCreateGroupRequestBody{Name: "developers"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		group, err := serializers.AdminGroupSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse group: '%s'", err)
+		}
+		assert.Equal(t, "developers", group.Name)
+
+		// another group with the same name cannot be created
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/admin/groups",
+			"POST",
+			admin.AdminCreateGroupRequestBody{Name: "developers"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// add a member
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			fmt.Sprintf("/api/v1/admin/groups/%d/members", group.ID),
+			"POST",
+			admin.AdminGroupMembersRequestBody{Emails: []string{"user1@user.com"}},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var members []serializers.AdminUserSerializer
+		if err := json.Unmarshal(w.Body.Bytes(), &members); err != nil {
+			t.Fatalf("Failed to parse members: '%s'", err)
+		}
+		assert.Len(t, members, 1)
+		assert.Equal(t, "user1@user.com", members[0].Email)
+		assert.Len(t, members[0].Groups, 1)
+
+		// the members are counted in the list of the groups
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/admin/groups", "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var groups []serializers.AdminGroupSerializer
+		if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+			t.Fatalf("Failed to parse groups: '%s'", err)
+		}
+		for _, g := range groups {
+			if g.ID == group.ID {
+				assert.Equal(t, int64(1), g.MembersCount)
+			}
+		}
+
+		// unknown users are rejected
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			fmt.Sprintf("/api/v1/admin/groups/%d/members", group.ID),
+			"POST",
+			admin.AdminGroupMembersRequestBody{Emails: []string{"unknown@user.com"}},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// remove the member
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			fmt.Sprintf("/api/v1/admin/groups/%d/members/user1@user.com", group.ID),
+			"DELETE",
+			nil,
+		)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "[]", w.Body.String())
+
+		// delete the group
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			fmt.Sprintf("/api/v1/admin/groups/%d", group.ID),
+			"DELETE",
+			nil,
+		)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		deletedGroup, err := models.RetrieveGroupByID(group.ID)
+		assert.Nil(t, err)
+		assert.Nil(t, deletedGroup)
+
+		// regular users cannot manage groups
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/admin/groups", "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+/*
+Restricted templates are visible only to the members of the allowed groups
+*/
+func TestTemplateVisibilityByGroup(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		member, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || member == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		other, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || other == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		group, err := models.CreateGroup("frontend")
+		if err != nil {
+			t.Fatalf("Failed to create group: '%s'", err)
+		}
+
+		if err := models.AddUsersToGroup(*group, []models.User{*member}); err != nil {
+			t.Fatalf("Failed to add the member: '%s'", err)
+		}
+
+		template, err := models.CreateWorkspaceTemplate("frontend-template", "docker_compose", "", "", 0)
+		if err != nil {
+			t.Fatalf("Failed to create template: '%s'", err)
+		}
+
+		template.Restricted = true
+		if err := models.UpdateWorkspaceTemplate(*template); err != nil {
+			t.Fatalf("Failed to update template: '%s'", err)
+		}
+
+		if err := models.SetWorkspaceTemplateAllowedGroups(template, []models.Group{*group}); err != nil {
+			t.Fatalf("Failed to set the allowed groups: '%s'", err)
+		}
+
+		listTemplates := func(user models.User) []models.WorkspaceTemplate {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, "/api/v1/templates", "GET", nil)
+			testutils.AuthenticateHttpRequest(t, req, user)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var templates []models.WorkspaceTemplate
+			if err := json.Unmarshal(w.Body.Bytes(), &templates); err != nil {
+				t.Fatalf("Failed to parse templates: '%s'", err)
+			}
+			return templates
+		}
+
+		retrieveTemplate := func(user models.User) int {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, fmt.Sprintf("/api/v1/templates/%d", template.ID), "GET", nil)
+			testutils.AuthenticateHttpRequest(t, req, user)
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		containsTemplate := func(templates []models.WorkspaceTemplate) bool {
+			for _, wt := range templates {
+				if wt.ID == template.ID {
+					return true
+				}
+			}
+			return false
+		}
+
+		assert.True(t, containsTemplate(listTemplates(*member)))
+		assert.Equal(t, http.StatusOK, retrieveTemplate(*member))
+
+		assert.False(t, containsTemplate(listTemplates(*other)))
+		assert.Equal(t, http.StatusNotFound, retrieveTemplate(*other))
+	})
+}
@@ -0,0 +1,234 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/admin/settings"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleAdminRetrieveGroupQuota godoc
+// @Summary Retrieve the quota of a group
+// @Schemes
+// @Description Retrieve the quota of a group, null means that the limit is inherited
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.QuotaLimitsSerializer
+// @Router /api/v1/admin/groups/:groupId/quota [get]
+func HandleAdminRetrieveGroupQuota(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	quota, err := models.RetrieveGroupQuota(*group)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	limits := models.QuotaLimits{}
+	if quota != nil {
+		limits = quota.QuotaLimits
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadQuotaLimitsSerializer(limits))
+}
+
+// HandleAdminUpdateGroupQuota godoc
+// @Summary Update the quota of a group
+// @Schemes
+// @Description Update the quota of a group, null means that the limit is inherited.
+// @Description If a user is member of multiple groups the highest limit is applied
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body settings.QuotaLimitsRequestBody true "Quotas"
+// @Success 200 {object} serializers.QuotaLimitsSerializer
+// @Router /api/v1/admin/groups/:groupId/quota [put]
+func HandleAdminUpdateGroupQuota(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	var reqBody settings.QuotaLimitsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	quota, err := models.SetGroupQuota(*group, reqBody.ToQuotaLimits())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadQuotaLimitsSerializer(quota.QuotaLimits))
+}
+
+// HandleAdminDeleteGroupQuota godoc
+// @Summary Delete the quota of a group
+// @Schemes
+// @Description Delete the quota of a group, the members inherit the global limits
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/admin/groups/:groupId/quota [delete]
+func HandleAdminDeleteGroupQuota(c *gin.Context) {
+	group := getGroupFromContext(c)
+	if group == nil {
+		return
+	}
+
+	quota, err := models.RetrieveGroupQuota(*group)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if quota != nil {
+		if err := models.DeleteWorkspaceQuota(*quota); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"detail": "quota has been deleted"})
+}
+
+// HandleAdminRetrieveUserQuota godoc
+// @Summary Retrieve the quota of a user
+// @Schemes
+// @Description Retrieve the quota override of a user, null means that the limit is inherited
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.QuotaLimitsSerializer
+// @Router /api/v1/admin/users/:email/quota [get]
+func HandleAdminRetrieveUserQuota(c *gin.Context) {
+	user := getUserFromEmailParam(c)
+	if user == nil {
+		return
+	}
+
+	quota, err := models.RetrieveUserQuota(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	limits := models.QuotaLimits{}
+	if quota != nil {
+		limits = quota.QuotaLimits
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadQuotaLimitsSerializer(limits))
+}
+
+// HandleAdminUpdateUserQuota godoc
+// @Summary Update the quota of a user
+// @Schemes
+// @Description Update the quota override of a user, null means that the limit is inherited
+// @Description from the groups of the user or from the global quotas
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body settings.QuotaLimitsRequestBody true "Quotas"
+// @Success 200 {object} serializers.QuotaLimitsSerializer
+// @Router /api/v1/admin/users/:email/quota [put]
+func HandleAdminUpdateUserQuota(c *gin.Context) {
+	user := getUserFromEmailParam(c)
+	if user == nil {
+		return
+	}
+
+	var reqBody settings.QuotaLimitsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	quota, err := models.SetUserQuota(*user, reqBody.ToQuotaLimits())
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadQuotaLimitsSerializer(quota.QuotaLimits))
+}
+
+// HandleAdminDeleteUserQuota godoc
+// @Summary Delete the quota of a user
+// @Schemes
+// @Description Delete the quota override of a user
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/admin/users/:email/quota [delete]
+func HandleAdminDeleteUserQuota(c *gin.Context) {
+	user := getUserFromEmailParam(c)
+	if user == nil {
+		return
+	}
+
+	quota, err := models.RetrieveUserQuota(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if quota != nil {
+		if err := models.DeleteWorkspaceQuota(*quota); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"detail": "quota has been deleted"})
+}
+
+/*
+Retrieve the user from the email in the path, an error
+response is sent and nil is returned if the user does not exist
+*/
+func getUserFromEmailParam(c *gin.Context) *models.User {
+	email, _ := c.Params.Get("email")
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if user == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "user not found")
+		return nil
+	}
+	return user
+}
+
+// respondQuotaError sends the response for an error returned by a quota check:
+// 403 with the explanation if a quota has been exceeded, 500 otherwise.
+// It returns false if there is no error.
+func respondQuotaError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var quotaErr *models.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		utils.ErrorResponse(c, http.StatusForbidden, quotaErr.Error())
+		return true
+	}
+
+	utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+	return true
+}
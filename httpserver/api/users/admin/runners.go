@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -41,7 +42,7 @@ func HandleAdminListRunners(c *gin.Context) {
 		return
 	}
 
-	runners, err := models.ListRunners(parsedLimit, 0)
+	runners, err := models.ListRunnersWithAllowedGroups(parsedLimit, 0)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
 		return
@@ -83,10 +84,13 @@ func HandleAdminRetrieveRunners(c *gin.Context) {
 }
 
 type HandleAdminCreateRunnerRequestBody struct {
-	Name         string `json:"name" binding:"required"`
-	Type         string `json:"type" binding:"required"`
-	UsePublicUrl bool   `json:"use_public_url"`
-	PublicUrl    string `json:"public_url"`
+	Name          string              `json:"name" binding:"required"`
+	Type          string              `json:"type" binding:"required"`
+	UsePublicUrl  bool                `json:"use_public_url"`
+	PublicUrl     string              `json:"public_url"`
+	Labels        models.RunnerLabels `json:"labels"`
+	Restricted    bool                `json:"restricted"`
+	AllowedGroups []uint              `json:"allowed_groups"` // ids of the groups allowed to use a restricted runner
 }
 
 // HandleAdminCreateRunner godoc
@@ -133,6 +137,17 @@ func HandleAdminCreateRunner(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateRunnerLabels(parsedBody.Labels); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	allowedGroups, err := retrieveGroupsByIDs(parsedBody.AllowedGroups)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	if parsedBody.UsePublicUrl {
 		if parsedBody.PublicUrl == "" {
 			utils.ErrorResponse(c, http.StatusConflict, "'public_url' is required")
@@ -169,6 +184,24 @@ func HandleAdminCreateRunner(c *gin.Context) {
 		return
 	}
 
+	if len(parsedBody.Labels) > 0 || parsedBody.Restricted {
+		runner.Labels = parsedBody.Labels
+		runner.Restricted = parsedBody.Restricted
+		if err := models.UpdateRunner(*runner); err != nil {
+			log.Println(err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	if len(allowedGroups) > 0 {
+		if err := models.SetRunnerAllowedGroups(runner, allowedGroups); err != nil {
+			log.Println(err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
 	bgtasks.BgTasksEnqueuer.Enqueue("ping_runners", work.Q{})
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -178,10 +211,13 @@ func HandleAdminCreateRunner(c *gin.Context) {
 }
 
 type AdminUpdateRunnerRequestBody struct {
-	Name         string `json:"name" binding:"required"`
-	Type         string `json:"type" binding:"required"`
-	UsePublicUrl *bool  `json:"use_public_url" binding:"required"`
-	PublicUrl    string `json:"public_url" binding:"required"`
+	Name          string              `json:"name" binding:"required"`
+	Type          string              `json:"type" binding:"required"`
+	UsePublicUrl  *bool               `json:"use_public_url" binding:"required"`
+	PublicUrl     string              `json:"public_url" binding:"required"`
+	Labels        models.RunnerLabels `json:"labels"`         // if nil the labels are not changed
+	Restricted    *bool               `json:"restricted"`     // if nil the restriction is not changed
+	AllowedGroups []uint              `json:"allowed_groups"` // if nil the allowed groups are not changed
 }
 
 // HandleAdminCreateRunner godoc
@@ -225,14 +261,79 @@ func HandleAdminUpdateRunner(c *gin.Context) {
 	runner.UsePublicUrl = *reqBody.UsePublicUrl
 	runner.PublicUrl = reqBody.PublicUrl
 
+	if reqBody.Labels != nil {
+		if err := models.ValidateRunnerLabels(reqBody.Labels); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		runner.Labels = reqBody.Labels
+	}
+
+	if reqBody.Restricted != nil {
+		runner.Restricted = *reqBody.Restricted
+	}
+
+	var allowedGroups []models.Group
+	if reqBody.AllowedGroups != nil {
+		allowedGroups, err = retrieveGroupsByIDs(reqBody.AllowedGroups)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
 	if err := models.UpdateRunner(*runner); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
+	if allowedGroups != nil {
+		if err := models.SetRunnerAllowedGroups(runner, allowedGroups); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	// flag the workspaces whose owner has lost the access to the runner
+	if _, err := models.RefreshRunnerAccessFlags(*runner); err != nil {
+		log.Println(err)
+	}
+
+	runner, err = models.RetrieveRunnerByID(runner.ID)
+	if err != nil || runner == nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
 	c.JSON(http.StatusOK, serializers.LoadAdminRunnerSerializer(runner))
 }
 
+/*
+Retrieve the groups with the given ids,
+an error is returned if some of the groups do not exist
+*/
+func retrieveGroupsByIDs(ids []uint) ([]models.Group, error) {
+	groups, err := models.ListGroupsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		found := false
+		for _, group := range groups {
+			if group.ID == id {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("group %d not found", id)
+		}
+	}
+	return groups, nil
+}
+
 // HandleAdminDeleteRunner godoc
 // @Summary Delete a runner
 // @Schemes
@@ -280,6 +381,55 @@ func HandleAdminDeleteRunner(c *gin.Context) {
 	)
 }
 
+// HandleAdminReconcileRunner godoc
+// @Summary Reconcile the workspaces of a runner
+// @Schemes
+// @Description Align status, containers and ports of the workspaces stored in the db
+// @Description with the state reported by the runner. The operation runs in background,
+// @Description corrections are written to the logs of the workspaces
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 202
+// @Router /api/v1/admin/runners/:id/reconcile [post]
+func HandleAdminReconcileRunner(c *gin.Context) {
+	runnerId, _ := c.Params.Get("runnerId")
+
+	id, err := strconv.Atoi(runnerId)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "runner not found")
+		return
+	}
+
+	runner, err := models.RetrieveRunnerByID(uint(id))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if runner == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "runner not found")
+		return
+	}
+
+	if runner.DeletionInProgress {
+		utils.ErrorResponse(c, http.StatusConflict, "this runner is about to be deleted")
+		return
+	}
+
+	if !runner.IsOnline() {
+		utils.ErrorResponse(c, http.StatusFailedDependency, "runner is offline")
+		return
+	}
+
+	bgtasks.BgTasksEnqueuer.Enqueue("reconcile_runner", work.Q{"runner_id": runner.ID})
+
+	c.JSON(
+		http.StatusAccepted,
+		gin.H{"detail": "reconciliation has been started"},
+	)
+}
+
 // HandleRetrieveRecommendedRunnerVersion godoc
 // @Summary Retrieve recommended runner version
 // @Schemes
@@ -0,0 +1,134 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleAdminListUserSessions godoc
+// @Summary Admin list user sessions
+// @Schemes
+// @Description List the active sessions of a user with the ip address
+// @Description and the time of the last use, and the user agent used to login
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} []serializers.SessionSerializer
+// @Failure 404 "User not found"
+// @Router /api/v1/admin/users/{email}/sessions [get]
+func HandleAdminListUserSessions(c *gin.Context) {
+	email, _ := c.Params.Get("email")
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	tokens, err := models.ListActiveTokensForUser(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// the admin may be looking at its own sessions
+	var currentID uint
+	if current, err := utils.GetTokenFromContext(c); err == nil {
+		currentID = current.ID
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleSessionSerializer(tokens, currentID))
+}
+
+// HandleAdminRevokeUserSession godoc
+// @Summary Admin revoke a user session
+// @Schemes
+// @Description Revoke a session of a user
+// @Tags Admin
+// @Produce json
+// @Success 204
+// @Failure 404 "User or session not found"
+// @Router /api/v1/admin/users/{email}/sessions/{sessionId} [delete]
+func HandleAdminRevokeUserSession(c *gin.Context) {
+	email, _ := c.Params.Get("email")
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	id, err := utils.GetUIntParamFromContext(c, "sessionId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "session not found")
+		return
+	}
+
+	token, err := models.RetrieveTokenByUserAndID(*user, id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if token == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "session not found")
+		return
+	}
+
+	if err := models.DeleteToken(token); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleAdminRevokeUserSessions godoc
+// @Summary Admin revoke all user sessions
+// @Schemes
+// @Description Revoke all the sessions of a user, including the impersonation sessions that target the user,
+// @Description and its personal access tokens, including the ones created by the cli login
+// @Tags Admin
+// @Produce json
+// @Success 204
+// @Failure 404 "User not found"
+// @Router /api/v1/admin/users/{email}/sessions [delete]
+func HandleAdminRevokeUserSessions(c *gin.Context) {
+	email, _ := c.Params.Get("email")
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if err := models.RevokeAllAuthTokensForUser(*user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if err := models.RevokeAllPersonalAccessTokensForUser(*user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -34,18 +34,21 @@ func HandleRetrieveAuthenticationSettings(c *gin.Context) {
 }
 
 type HandleUpdateServerSettingsRequestBody struct {
-	IsSignUpOpen                *bool   `json:"is_signup_open" binding:"required"`
-	IsSignUpRestricted          *bool   `json:"is_signup_restricted" binding:"required"`
-	AllowedEmailRegex           *string `json:"allowed_emails_regex" binding:"required"`
-	BlockedEmailRegex           *string `json:"blocked_emails_regex" binding:"required"`
-	UsersMustBeApproved         *bool   `json:"users_must_be_approved" binding:"required"`
-	ApprovedByDefaultEmailRegex *string `json:"approved_by_default_emails_regex" binding:"required"`
+	IsSignUpOpen                         *bool   `json:"is_signup_open" binding:"required"`
+	IsSignUpRestricted                   *bool   `json:"is_signup_restricted" binding:"required"`
+	AllowedEmailRegex                    *string `json:"allowed_emails_regex" binding:"required"`
+	BlockedEmailRegex                    *string `json:"blocked_emails_regex" binding:"required"`
+	UsersMustBeApproved                  *bool   `json:"users_must_be_approved" binding:"required"`
+	ApprovedByDefaultEmailRegex          *string `json:"approved_by_default_emails_regex" binding:"required"`
+	TwoFactorRequiredForSuperusers       *bool   `json:"two_factor_required_for_superusers"`        // null keeps the current value
+	TwoFactorRequiredForTemplateManagers *bool   `json:"two_factor_required_for_template_managers"` // null keeps the current value
 }
 
 // HandleUpdateAuthenticationSettings godoc
 // @Summary Update authentication settings
 // @Schemes
-// @Description Update authentication settings, this api is available only to administrators
+// @Description Update authentication settings, this api is available only to administrators.
+// @Description When two-factor authentication is required the users enroll at their next login
 // @Tags Templates
 // @Accept json
 // @Produce json
@@ -89,6 +92,12 @@ func HandleUpdateAuthenticationSettings(c *gin.Context) {
 	s.BlockedEmailRegex = *parsedBody.BlockedEmailRegex
 	s.UsersMustBeApproved = *parsedBody.UsersMustBeApproved
 	s.ApprovedByDefaultEmailRegex = *parsedBody.ApprovedByDefaultEmailRegex
+	if parsedBody.TwoFactorRequiredForSuperusers != nil {
+		s.TwoFactorRequiredForSuperusers = *parsedBody.TwoFactorRequiredForSuperusers
+	}
+	if parsedBody.TwoFactorRequiredForTemplateManagers != nil {
+		s.TwoFactorRequiredForTemplateManagers = *parsedBody.TwoFactorRequiredForTemplateManagers
+	}
 	models.SaveSingletonModel(s)
 
 	c.JSON(http.StatusOK, serializers.LoadAuthenticationSettingsSerializer(s))
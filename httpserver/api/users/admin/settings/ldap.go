@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/utils/ldap"
+)
+
+// HandleRetrieveLDAPSettings godoc
+// @Summary Retrieve LDAP settings
+// @Schemes
+// @Description Retrieve the configuration of the LDAP or Active Directory server, the bind password is never returned.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.LDAPSettingsSerializer
+// @Router /api/v1/admin/ldap-settings [get]
+func HandleRetrieveLDAPSettings(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.LDAPSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadLDAPSettingsSerializer(s))
+}
+
+type LDAPSettingsRequestBody struct {
+	Enabled            *bool   `json:"enabled" binding:"required"`
+	Url                string  `json:"url"`
+	StartTLS           *bool   `json:"start_tls" binding:"required"`
+	InsecureSkipVerify *bool   `json:"insecure_skip_verify" binding:"required"`
+	BindDN             string  `json:"bind_dn"`
+	BindPassword       *string `json:"bind_password"` // null keeps the current password
+	UserBaseDN         string  `json:"user_base_dn"`
+	UserFilter         string  `json:"user_filter"`
+	EmailAttribute     string  `json:"email_attribute"`
+	FirstNameAttribute string  `json:"first_name_attribute"`
+	LastNameAttribute  string  `json:"last_name_attribute"`
+	GroupsAttribute    string  `json:"groups_attribute"`
+	AutoProvisionUsers *bool   `json:"auto_provision_users" binding:"required"`
+}
+
+// HandleUpdateLDAPSettings godoc
+// @Summary Update LDAP settings
+// @Schemes
+// @Description Update the configuration of the LDAP or Active Directory server. When LDAP is enabled
+// @Description the server is contacted with the bind DN to check the configuration. The user filter
+// @Description must contain %s, that is replaced with the login of the user.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body LDAPSettingsRequestBody true "LDAP settings"
+// @Success 200 {object} serializers.LDAPSettingsSerializer
+// @Failure 400 "Bad request or server not reachable"
+// @Router /api/v1/admin/ldap-settings [put]
+func HandleUpdateLDAPSettings(c *gin.Context) {
+	var parsedBody LDAPSettingsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&parsedBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	s, err := models.GetSingletonModelInstance[models.LDAPSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	s.Enabled = *parsedBody.Enabled
+	s.Url = strings.TrimSpace(parsedBody.Url)
+	s.StartTLS = *parsedBody.StartTLS
+	s.InsecureSkipVerify = *parsedBody.InsecureSkipVerify
+	s.BindDN = strings.TrimSpace(parsedBody.BindDN)
+	if parsedBody.BindPassword != nil {
+		s.BindPassword = *parsedBody.BindPassword
+	}
+	s.UserBaseDN = strings.TrimSpace(parsedBody.UserBaseDN)
+	s.UserFilter = strings.TrimSpace(parsedBody.UserFilter)
+	s.EmailAttribute = strings.TrimSpace(parsedBody.EmailAttribute)
+	s.FirstNameAttribute = strings.TrimSpace(parsedBody.FirstNameAttribute)
+	s.LastNameAttribute = strings.TrimSpace(parsedBody.LastNameAttribute)
+	s.GroupsAttribute = strings.TrimSpace(parsedBody.GroupsAttribute)
+	s.AutoProvisionUsers = *parsedBody.AutoProvisionUsers
+
+	if s.UserFilter != "" && !strings.Contains(s.UserFilter, "%s") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "the user filter must contain %s")
+		return
+	}
+
+	if s.Enabled {
+		if err := ldap.ValidateUrl(s.Url); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if s.UserBaseDN == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "missing user base dn")
+			return
+		}
+
+		if err := s.GetLDAPConfig().CheckConnection(); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := models.SaveSingletonModel(s); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadLDAPSettingsSerializer(s))
+}
@@ -0,0 +1,116 @@
+package settings
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/utils/oidc"
+)
+
+// HandleRetrieveOIDCSettings godoc
+// @Summary Retrieve single sign-on settings
+// @Schemes
+// @Description Retrieve the configuration of the OpenID Connect provider, the client secret is never returned.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.OIDCSettingsSerializer
+// @Router /api/v1/admin/oidc-settings [get]
+func HandleRetrieveOIDCSettings(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadOIDCSettingsSerializer(s))
+}
+
+type OIDCSettingsRequestBody struct {
+	Enabled               *bool   `json:"enabled" binding:"required"`
+	ProviderName          string  `json:"provider_name"`
+	IssuerUrl             string  `json:"issuer_url"`
+	ClientID              string  `json:"client_id"`
+	ClientSecret          *string `json:"client_secret"` // null keeps the current secret
+	Scopes                string  `json:"scopes"`
+	EmailClaim            string  `json:"email_claim"`
+	FirstNameClaim        string  `json:"first_name_claim"`
+	LastNameClaim         string  `json:"last_name_claim"`
+	GroupsClaim           string  `json:"groups_claim"`
+	AutoProvisionUsers    *bool   `json:"auto_provision_users" binding:"required"`
+	PasswordLoginDisabled *bool   `json:"password_login_disabled" binding:"required"`
+}
+
+// HandleUpdateOIDCSettings godoc
+// @Summary Update single sign-on settings
+// @Schemes
+// @Description Update the configuration of the OpenID Connect provider. When single sign-on is enabled the
+// @Description configuration of the provider is retrieved from the issuer to check it. Password login can
+// @Description be disabled only for the users that are not administrators.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body OIDCSettingsRequestBody true "single sign-on settings"
+// @Success 200 {object} serializers.OIDCSettingsSerializer
+// @Failure 400 "Bad request or provider not reachable"
+// @Router /api/v1/admin/oidc-settings [put]
+func HandleUpdateOIDCSettings(c *gin.Context) {
+	var parsedBody OIDCSettingsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&parsedBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	s, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	s.Enabled = *parsedBody.Enabled
+	s.ProviderName = strings.TrimSpace(parsedBody.ProviderName)
+	s.IssuerUrl = strings.TrimSpace(parsedBody.IssuerUrl)
+	s.ClientID = strings.TrimSpace(parsedBody.ClientID)
+	if parsedBody.ClientSecret != nil {
+		s.ClientSecret = *parsedBody.ClientSecret
+	}
+	s.Scopes = strings.TrimSpace(parsedBody.Scopes)
+	s.EmailClaim = strings.TrimSpace(parsedBody.EmailClaim)
+	s.FirstNameClaim = strings.TrimSpace(parsedBody.FirstNameClaim)
+	s.LastNameClaim = strings.TrimSpace(parsedBody.LastNameClaim)
+	s.GroupsClaim = strings.TrimSpace(parsedBody.GroupsClaim)
+	s.AutoProvisionUsers = *parsedBody.AutoProvisionUsers
+	s.PasswordLoginDisabled = *parsedBody.PasswordLoginDisabled
+
+	if s.Enabled {
+		issuerUrl, err := url.Parse(s.IssuerUrl)
+		if err != nil || (issuerUrl.Scheme != "http" && issuerUrl.Scheme != "https") || issuerUrl.Host == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid issuer url")
+			return
+		}
+
+		if s.ClientID == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "missing client id")
+			return
+		}
+
+		if _, err := oidc.Discover(nil, s.IssuerUrl); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if err := models.SaveSingletonModel(s); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadOIDCSettingsSerializer(s))
+}
@@ -0,0 +1,77 @@
+package settings
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleRetrieveQuotaSettings godoc
+// @Summary Retrieve global quotas
+// @Schemes
+// @Description Retrieve the quotas applied to all the users, null means no limit.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.QuotaLimitsSerializer
+// @Router /api/v1/admin/quota-settings [get]
+func HandleRetrieveQuotaSettings(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.QuotaSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadQuotaLimitsSerializer(s.QuotaLimits))
+}
+
+type QuotaLimitsRequestBody struct {
+	MaxWorkspaces             *uint `json:"max_workspaces"`
+	MaxRunningWorkspaces      *uint `json:"max_running_workspaces"`
+	MaxContainersPerWorkspace *uint `json:"max_containers_per_workspace"`
+}
+
+func (b QuotaLimitsRequestBody) ToQuotaLimits() models.QuotaLimits {
+	return models.QuotaLimits{
+		MaxWorkspaces:             b.MaxWorkspaces,
+		MaxRunningWorkspaces:      b.MaxRunningWorkspaces,
+		MaxContainersPerWorkspace: b.MaxContainersPerWorkspace,
+	}
+}
+
+// HandleUpdateQuotaSettings godoc
+// @Summary Update global quotas
+// @Schemes
+// @Description Update the quotas applied to all the users, null means no limit.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body QuotaLimitsRequestBody true "Quotas"
+// @Success 200 {object} serializers.QuotaLimitsSerializer
+// @Router /api/v1/admin/quota-settings [put]
+func HandleUpdateQuotaSettings(c *gin.Context) {
+	var reqBody QuotaLimitsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	s, err := models.GetSingletonModelInstance[models.QuotaSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	s.QuotaLimits = reqBody.ToQuotaLimits()
+	if err := models.SaveSingletonModel(s); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadQuotaLimitsSerializer(s.QuotaLimits))
+}
@@ -0,0 +1,90 @@
+package settings
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleRetrieveResourceLimitsSettings godoc
+// @Summary Retrieve maximum resource limits
+// @Schemes
+// @Description Retrieve the maximum resources a workspace can use, null means no limit.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.ResourceLimitsSerializer
+// @Router /api/v1/admin/resource-limits-settings [get]
+func HandleRetrieveResourceLimitsSettings(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.ResourceLimitsSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadResourceLimitsSerializer(s.MaxResourceLimits))
+}
+
+type ResourceLimitsRequestBody struct {
+	CPULimit      *float64 `json:"cpu_limit"`
+	MemoryLimitMB *uint    `json:"memory_limit_mb"`
+	DiskLimitMB   *uint    `json:"disk_limit_mb"`
+}
+
+func (b ResourceLimitsRequestBody) ToResourceLimits() models.ResourceLimits {
+	return models.ResourceLimits{
+		CPULimit:      b.CPULimit,
+		MemoryLimitMB: b.MemoryLimitMB,
+		DiskLimitMB:   b.DiskLimitMB,
+	}
+}
+
+// HandleUpdateResourceLimitsSettings godoc
+// @Summary Update maximum resource limits
+// @Schemes
+// @Description Update the maximum resources a workspace can use, null means no limit.
+// @Description Limits set on templates and workspaces are bounded by these values.
+// @Description This api is available only to administrators
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body ResourceLimitsRequestBody true "Maximum resource limits"
+// @Success 200 {object} serializers.ResourceLimitsSerializer
+// @Router /api/v1/admin/resource-limits-settings [put]
+func HandleUpdateResourceLimitsSettings(c *gin.Context) {
+	var reqBody ResourceLimitsRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	s, err := models.GetSingletonModelInstance[models.ResourceLimitsSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	limits := reqBody.ToResourceLimits()
+	if err := models.ValidateMaxResourceLimits(limits); err != nil {
+		var limitsErr *models.InvalidResourceLimitsError
+		if errors.As(err, &limitsErr) {
+			utils.ErrorResponse(c, http.StatusBadRequest, limitsErr.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	s.MaxResourceLimits = limits
+	if err := models.SaveSingletonModel(s); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadResourceLimitsSerializer(s.MaxResourceLimits))
+}
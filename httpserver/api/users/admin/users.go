@@ -1,6 +1,7 @@
 package admin
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -232,11 +233,16 @@ func HandleAdminUpdateUser(c *gin.Context) {
 // HandleAdminDeleteUser godoc
 // @Summary Admin delete user
 // @Schemes
-// @Description Admin delete user
+// @Description Admin delete user, the workspaces of the user are deleted too.
+// @Description If transfer_to is set the workspaces are transferred to that user instead,
+// @Description none of them can be changing status and the quotas of that user must not be exceeded
 // @Tags Admin
 // @Accept json
 // @Produce json
+// @Param transfer_to query string false "Email of the user that receives the workspaces"
 // @Success 204
+// @Failure 403 "Quota of the new owner exceeded"
+// @Failure 409 "A workspace is changing status"
 // @Router /api/v1/admin/users/{email} [delete]
 func HandleAdminDeleteUser(c *gin.Context) {
 	currentUser, _ := utils.GetUserFromContext(c)
@@ -257,10 +263,44 @@ func HandleAdminDeleteUser(c *gin.Context) {
 		utils.ErrorResponse(c, 400, "you cannot delete yourself")
 	}
 
+	jobArgs := work.Q{"user_email": user.Email}
+	if transferTo := c.Query("transfer_to"); transferTo != "" {
+		owner, err := models.RetrieveUserByEmail(transferTo)
+		if err != nil {
+			utils.ErrorResponse(c, 500, "internal server error")
+			return
+		}
+
+		if owner == nil || owner.ID == user.ID || owner.DeletionInProgress {
+			utils.ErrorResponse(c, 400, "invalid user for the transfer of the workspaces")
+			return
+		}
+
+		// the transfer is validated before scheduling the deletion, so that
+		// the job does not stop halfway with part of the workspaces transferred
+		workspaces, err := models.ListUserWorkspaces(*user)
+		if err != nil {
+			utils.ErrorResponse(c, 500, "internal server error")
+			return
+		}
+
+		for _, workspace := range workspaces {
+			if err := bgtasks.CheckWorkspaceTransferAllowed(workspace); err != nil {
+				utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("workspace '%s': %s", workspace.Name, err.Error()))
+				return
+			}
+		}
+
+		if respondQuotaError(c, models.CheckTransferWorkspacesQuota(*owner, workspaces)) {
+			return
+		}
+		jobArgs["transfer_to"] = owner.Email
+	}
+
 	user.DeletionInProgress = true
 	models.UpdateUser(user)
 
-	bgtasks.BgTasksEnqueuer.Enqueue("delete_user", work.Q{"user_email": user.Email})
+	bgtasks.BgTasksEnqueuer.Enqueue("delete_user", jobArgs)
 
 	c.JSON(http.StatusNoContent, gin.H{
 		"detail": "user deletion has been scheduled",
@@ -323,6 +363,43 @@ func HandleAdminSetUserPassword(c *gin.Context) {
 	})
 }
 
+// HandleAdminResetUserTwoFactor godoc
+// @Summary Admin reset user two-factor authentication
+// @Schemes
+// @Description Disable two-factor authentication of a user that has lost the authenticator app and the
+// @Description recovery codes, the sessions of the user are revoked. If two-factor authentication is
+// @Description required for the user it will enroll again at the next login
+// @Tags Admin
+// @Success 204
+// @Failure 404 "User not found"
+// @Router /api/v1/admin/users/{email}/two-factor [delete]
+func HandleAdminResetUserTwoFactor(c *gin.Context) {
+	email, _ := c.Params.Get("email")
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(c, 404, "user not found")
+		return
+	}
+
+	if err := models.DisableTotp(user); err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	if err := models.RevokeAllAuthTokensForUser(*user); err != nil {
+		utils.ErrorResponse(c, 500, "internal server error")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // HandleAdminImpersonateUser godoc
 // @Summary API for admins to impersonate a user
 // @Schemes
@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/bgtasks"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+type AdminTransferWorkspaceRequestBody struct {
+	UserEmail string `json:"user_email" binding:"required"`
+}
+
+// HandleAdminTransferWorkspace godoc
+// @Summary Transfer a workspace to another user
+// @Schemes
+// @Description Assign a workspace to another user, a running workspace is restarted so that the
+// @Description environment variables derived from the owner and the secrets are regenerated.
+// @Description The quotas of the new owner must not be exceeded. The response contains
+// @Description warnings about the consequences of the transfer, e.g. git operations are authenticated
+// @Description with the SSH key of the new owner
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body AdminTransferWorkspaceRequestBody true "New owner"
+// @Success 200 {object} serializers.WorkspaceTransferSerializer
+// @Failure 403 "Quota of the new owner exceeded"
+// @Failure 409 "Workspace changing status"
+// @Router /api/v1/admin/workspaces/{workspaceId}/transfer [post]
+func HandleAdminTransferWorkspace(c *gin.Context) {
+	currentUser, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	id, err := utils.GetUIntParamFromContext(c, "workspaceId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "workspace not found")
+		return
+	}
+
+	var reqBody AdminTransferWorkspaceRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	workspace, err := models.RetrieveWorkspaceById(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if workspace == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "workspace not found")
+		return
+	}
+
+	owner, err := models.RetrieveUserByEmail(reqBody.UserEmail)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if owner == nil || owner.DeletionInProgress {
+		utils.ErrorResponse(c, http.StatusBadRequest, "user not found")
+		return
+	}
+
+	if owner.ID == workspace.UserID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "the user already owns the workspace")
+		return
+	}
+
+	if respondQuotaError(c, models.CheckTransferWorkspacesQuota(*owner, []models.Workspace{*workspace})) {
+		return
+	}
+
+	warnings, err := bgtasks.TransferWorkspace(workspace, *owner, &currentUser)
+	if err != nil {
+		if errors.Is(err, bgtasks.ErrWorkspaceTransferNotAllowed) {
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadWorkspaceTransferSerializer(workspace, warnings))
+}
@@ -0,0 +1,217 @@
+package admin_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/admin"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Transfer a workspace to another user, workspaces that are
+changing status cannot be transferred
+*/
+func TestTransferWorkspace(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		adminUser, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || adminUser == nil {
+			t.Fatalf("Failed to retrieve admin user: '%s'", err)
+		}
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		gitSource, err := models.CreateGitWorkspaceSource(
+			"https://github.com/davidebianchi03/codebox.git",
+			"main",
+			"/path/to/config",
+		)
+		if err != nil {
+			t.Fatalf("Failed to create git source: '%s'", err)
+		}
+
+		workspace, err := models.CreateWorkspace(
+			"Test Workspace",
+			user,
+			"docker_compose",
+			&runners[0],
+			models.WorkspaceConfigSourceGit,
+			nil,
+			gitSource,
+			[]string{"VAR1=value1", "CODEBOX_WORKSPACE_OWNER_EMAIL=user1@user.com"},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create workspace: '%s'", err)
+		}
+
+		transferUrl := fmt.Sprintf("/api/v1/admin/workspaces/%d/transfer", workspace.ID)
+		body := admin.AdminTransferWorkspaceRequestBody{UserEmail: otherUser.Email}
+
+		// the workspace is starting
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, transferUrl, "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		if err := dbconn.DB.
+			Model(&models.Workspace{}).
+			Where("id = ?", workspace.ID).
+			Update("status", models.WorkspaceStatusStopped).Error; err != nil {
+			t.Fatalf("Failed to update workspace: '%s'", err)
+		}
+
+		// common users cannot transfer workspaces
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, transferUrl, "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.NotEqual(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, transferUrl, "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		transfer, err := serializers.WorkspaceTransferSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+
+		assert.Equal(t, models.WorkspaceStatusStopped, transfer.Workspace.Status)
+		assert.Equal(t, []string{"VAR1=value1"}, transfer.Workspace.EnvironmentVariables)
+		assert.Contains(t, transfer.Warnings[0], "SSH key of 'user2@user.com'")
+
+		userWorkspaces, err := models.ListUserWorkspaces(*user)
+		if err != nil {
+			t.Fatalf("Failed to list workspaces: '%s'", err)
+		}
+		assert.Len(t, userWorkspaces, 0)
+
+		otherUserWorkspaces, err := models.ListUserWorkspaces(*otherUser)
+		if err != nil {
+			t.Fatalf("Failed to list workspaces: '%s'", err)
+		}
+		assert.Len(t, otherUserWorkspaces, 1)
+
+		// the workspace already belongs to the user
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, transferUrl, "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+/*
+The deletion of a user with the transfer of the workspaces is refused
+if a workspace is changing status or if the quota of the new owner
+would be exceeded
+*/
+func TestDeleteUserWithWorkspacesTransfer(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		adminUser, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || adminUser == nil {
+			t.Fatalf("Failed to retrieve admin user: '%s'", err)
+		}
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		gitSource, err := models.CreateGitWorkspaceSource(
+			"https://github.com/davidebianchi03/codebox.git",
+			"main",
+			"/path/to/config",
+		)
+		if err != nil {
+			t.Fatalf("Failed to create git source: '%s'", err)
+		}
+
+		workspace, err := models.CreateWorkspace(
+			"Test Workspace",
+			user,
+			"docker_compose",
+			&runners[0],
+			models.WorkspaceConfigSourceGit,
+			nil,
+			gitSource,
+			[]string{},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create workspace: '%s'", err)
+		}
+
+		deleteUrl := fmt.Sprintf("/api/v1/admin/users/%s?transfer_to=%s", user.Email, otherUser.Email)
+
+		// the workspace is starting
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, deleteUrl, "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		if err := dbconn.DB.
+			Model(&models.Workspace{}).
+			Where("id = ?", workspace.ID).
+			Update("status", models.WorkspaceStatusStopped).Error; err != nil {
+			t.Fatalf("Failed to update workspace: '%s'", err)
+		}
+
+		// the new owner cannot have more workspaces
+		maxWorkspaces := uint(0)
+		if _, err := models.SetUserQuota(*otherUser, models.QuotaLimits{MaxWorkspaces: &maxWorkspaces}); err != nil {
+			t.Fatalf("Failed to set quota: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, deleteUrl, "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *adminUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "quota exceeded")
+
+		// the deletion has not been scheduled
+		user, err = models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+		assert.False(t, user.DeletionInProgress)
+	})
+}
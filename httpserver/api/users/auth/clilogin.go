@@ -9,7 +9,21 @@ import (
 	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
 )
 
-// TODO: replace with api keys
+// name of the personal access tokens created for the cli
+const cliTokenName = "Codebox CLI"
+
+// personal access tokens created for the cli last 90 days
+const cliTokenDurationDays = 90
+
+// HandleCliLogin godoc
+// @Summary Cli login
+// @Schemes
+// @Description Create a personal access token for the cli, it can manage the workspaces and
+// @Description connect to them using ssh but it cannot use the admin apis
+// @Tags Authentication
+// @Produce json
+// @Success 200 ""
+// @Router /api/v1/auth/cli-login [post]
 func HandleCliLogin(c *gin.Context) {
 	user, err := utils.GetUserFromContext(c)
 	if err != nil {
@@ -26,7 +40,47 @@ func HandleCliLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := models.CreateToken(user, time.Duration(time.Hour*24*90))
+	// the cli gets a new long lived token, the session must have passed the second factor
+	sessionToken, err := utils.GetTokenFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"detail": "missing or invalid authorization token",
+		})
+		return
+	}
+
+	secondFactorSatisfied, err := isSecondFactorSatisfied(sessionToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"detail": "internal server error",
+		})
+		return
+	}
+
+	if !secondFactorSatisfied {
+		c.JSON(http.StatusForbidden, gin.H{
+			"detail": "two-factor authentication is required, login again",
+		})
+		return
+	}
+
+	if sessionToken.ImpersonatedUser != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"detail": "personal access tokens cannot be managed while impersonating a user",
+		})
+		return
+	}
+
+	token, value, err := models.CreatePersonalAccessToken(
+		user,
+		cliTokenName,
+		[]string{
+			models.PersonalAccessTokenScopeWorkspacesRead,
+			models.PersonalAccessTokenScopeWorkspacesWrite,
+			models.PersonalAccessTokenScopeSsh,
+		},
+		time.Now().AddDate(0, 0, cliTokenDurationDays),
+	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"detail": "internal server error",
@@ -35,7 +89,7 @@ func HandleCliLogin(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token":      token.Token,
-		"expiration": token.ExpirationDate,
+		"token":      value,
+		"expiration": token.ExpiresAt,
 	})
 }
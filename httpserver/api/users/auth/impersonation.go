@@ -13,6 +13,11 @@ import (
 Method that returns if the user is being impersonated
 */
 func UserIsBeingImpersonated(c *gin.Context) (bool, error) {
+	// personal access tokens cannot be used to impersonate users
+	if utils.GetAuthorizedPersonalAccessToken(c) != nil {
+		return false, nil
+	}
+
 	t, err := utils.GetTokenFromContext(c)
 	if err != nil {
 		return false, err
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/logging"
+	"gitlab.com/codebox4073715/codebox/utils/ldap"
+)
+
+/*
+authenticateLDAPUser checks the credentials against the directory and returns the
+user of the entry that has been found, creating it if it does not exist and auto
+provisioning is enabled. The fields of the user and, if the groups attribute is
+configured, its groups are updated with the values of the directory.
+If the user cannot login nil is returned together with the status and the message of the error
+*/
+func authenticateLDAPUser(s *models.LDAPSettings, login string, password string) (*models.User, int, string) {
+	config := s.GetLDAPConfig()
+	entry, err := config.Authenticate(login, password)
+	if err != nil {
+		if errors.Is(err, ldap.ErrInvalidCredentials) {
+			return nil, http.StatusBadRequest, "invalid credentials"
+		}
+		logging.Error("ldap login failed: %s", err)
+		return nil, http.StatusBadGateway, "the directory is not reachable, try again later"
+	}
+
+	// the login is not used as email, it may be a username
+	email := entry.Email
+	if email == "" {
+		return nil, http.StatusForbidden, "your directory account has no email address, contact an administrator"
+	}
+
+	as, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+	if err != nil {
+		return nil, http.StatusInternalServerError, "internal server error"
+	}
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "internal server error"
+	}
+
+	if user == nil {
+		if !s.AutoProvisionUsers {
+			return nil, http.StatusForbidden, "your account does not exist, contact an administrator"
+		}
+
+		user, err = provisionExternalUser(as, email, entry.FirstName, entry.LastName)
+		if err != nil {
+			return nil, http.StatusInternalServerError, "internal server error"
+		}
+	} else if (user.IsSuperuser && !user.IsLdapUser()) ||
+		(user.IsLdapUser() && !strings.EqualFold(user.LdapDN, entry.DN)) {
+		// local administrators can login only with their password and
+		// a user cannot be taken over by another entry with the same email
+		return nil, http.StatusForbidden, "your directory account cannot be used to login, contact an administrator"
+	}
+
+	// users removed from the directory and then added again are reactivated
+	user.LdapDN = entry.DN
+	user.EmailVerified = true
+	user.Deactivated = false
+	if entry.FirstName != "" {
+		user.FirstName = entry.FirstName
+	}
+	if entry.LastName != "" {
+		user.LastName = entry.LastName
+	}
+
+	if err := models.UpdateUser(user); err != nil {
+		return nil, http.StatusInternalServerError, "internal server error"
+	}
+
+	if config.GroupsAttribute != "" {
+		if err := models.SetUserGroupsByName(*user, ldap.GroupNames(entry.Groups)); err != nil {
+			return nil, http.StatusInternalServerError, "internal server error"
+		}
+	}
+
+	return user, 0, ""
+}
@@ -0,0 +1,92 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/auth"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+When the directory is not reachable the users cannot login,
+local administrators can still login with their password
+*/
+func TestLDAPLoginBreakGlass(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		if err := models.SaveSingletonModel(&models.LDAPSettings{
+			Enabled:    true,
+			Url:        "ldap://127.0.0.1:1",
+			UserBaseDN: "dc=example,dc=com",
+		}); err != nil {
+			t.Fatalf("Failed to save ldap settings: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "user1@user.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadGateway, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "admin@admin.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login-options", "GET", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"ldap_enabled":true`)
+	})
+}
+
+/*
+Deactivated users cannot use their tokens and cannot login
+*/
+func TestDeactivatedUser(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		if err := models.DeactivateUser(user); err != nil {
+			t.Fatalf("Failed to deactivate user: '%s'", err)
+		}
+
+		// tokens are revoked on deactivation, the ones created later are refused
+		token, err := models.CreateToken(*user, time.Hour)
+		if err != nil {
+			t.Fatalf("Failed to create token: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/user-details", "GET", nil)
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "user1@user.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
@@ -22,17 +22,21 @@ type LoginRequestBody struct {
 // Login godoc
 // @Summary Login
 // @Schemes
-// @Description Login
+// @Description Login, when LDAP is enabled the credentials are checked against the directory,
+// @Description except for local administrators that always login with their password
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body LoginRequestBody true "Credentials"
 // @Success 200 {object} serializers.TokenSerializer
+// @Success 202 {object} serializers.TwoFactorChallengeSerializer "Second factor required, complete the login with /api/v1/auth/login/two-factor"
 // @Failure 400 "Invalid credentials or already logged in"
+// @Failure 403 "Password login disabled or account deactivated"
 // @Failure 406 "User not approved"
 // @Failure 412 "Email not verified"
 // @Failure 429 "Ratelimit exceeded"
 // @Failure 500 "Internal server error"
+// @Failure 502 "Directory not reachable"
 // @Router /api/v1/auth/login [post]
 func HandleLogin(c *gin.Context) {
 	_, err := utils.GetUserFromContext(c)
@@ -77,17 +81,65 @@ func HandleLogin(c *gin.Context) {
 		return
 	}
 
-	if user == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"detail": "invalid credentials",
-		})
+	ldapSettings, err := models.GetSingletonModelInstance[models.LDAPSettings]()
+	if err != nil {
+		utils.ErrorResponse(
+			c,
+			http.StatusInternalServerError,
+			"internal server error",
+		)
 		return
 	}
 
-	if !user.CheckPassword(requestBody.Password) {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"detail": "invalid credentials",
-		})
+	if ldapSettings.IsDirectoryLoginRequired(user) {
+		var status int
+		var message string
+		user, status, message = authenticateLDAPUser(ldapSettings, requestBody.Email, requestBody.Password)
+		if user == nil {
+			utils.ErrorResponse(c, status, message)
+			return
+		}
+	} else {
+		if user == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"detail": "invalid credentials",
+			})
+			return
+		}
+
+		if !user.CheckPassword(requestBody.Password) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"detail": "invalid credentials",
+			})
+			return
+		}
+	}
+
+	if user.Deactivated {
+		utils.ErrorResponse(
+			c,
+			http.StatusForbidden,
+			"your account has been deactivated",
+		)
+		return
+	}
+
+	oidcSettings, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		utils.ErrorResponse(
+			c,
+			http.StatusInternalServerError,
+			"internal server error",
+		)
+		return
+	}
+
+	if !oidcSettings.IsPasswordLoginAllowed(*user) {
+		utils.ErrorResponse(
+			c,
+			http.StatusForbidden,
+			"password login is disabled, use single sign-on",
+		)
 		return
 	}
 
@@ -138,9 +190,32 @@ func HandleLogin(c *gin.Context) {
 			http.StatusNotAcceptable,
 			"user not approved, your account need approval from ad admin",
 		)
+		return
 	}
 
-	token, err := models.CreateToken(*user, time.Duration(time.Hour*24*20))
+	// the token is created by the second step of the login
+	if user.NeedsSecondFactor(s) {
+		challenge, err := createTwoFactorChallenge(*user, requestBody.RememberMe, !user.TotpEnabled)
+		if err != nil {
+			utils.ErrorResponse(
+				c,
+				http.StatusInternalServerError,
+				"internal server error",
+			)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, challenge)
+		return
+	}
+
+	token, err := models.CreateSessionToken(
+		*user,
+		time.Duration(time.Hour*24*20),
+		false,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"detail": "internal server error",
@@ -148,14 +223,18 @@ func HandleLogin(c *gin.Context) {
 		return
 	}
 
-	// Set auth cookie,
-	// duration = 0 means that cookie expires when browser session ends
-	cookieDuration := 0
-	if requestBody.RememberMe {
-		cookieDuration = 3600 * 24 * 20
-	}
-
-	SetAuthCookie(c, token.Token, cookieDuration)
+	SetAuthCookie(c, token.Token, getLoginCookieDuration(requestBody.RememberMe))
 
 	c.JSON(http.StatusOK, serializers.LoadTokenSerializer(&token))
 }
+
+/*
+getLoginCookieDuration returns the duration of the auth cookie,
+0 means that the cookie expires when the browser session ends
+*/
+func getLoginCookieDuration(rememberMe bool) int {
+	if rememberMe {
+		return 3600 * 24 * 20
+	}
+	return 0
+}
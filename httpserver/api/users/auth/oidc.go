@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/cache"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	httperrors "gitlab.com/codebox4073715/codebox/httpserver/errors"
+	"gitlab.com/codebox4073715/codebox/logging"
+	"gitlab.com/codebox4073715/codebox/utils/oidc"
+)
+
+// cookie that binds the login started by a browser to the callback
+const oidcStateCookieName = "codebox_oidc_state"
+
+// seconds available to complete the login on the provider
+const oidcStateTTLSeconds = 600
+
+// oidcLoginState is stored in cache while the user authenticates on the provider
+type oidcLoginState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	Next         string `json:"next"`
+}
+
+func oidcStateCacheKey(state string) string {
+	return fmt.Sprintf("oidc-state-%s", state)
+}
+
+/*
+getOIDCClient returns a client for the configured provider,
+the configuration of the provider is retrieved from the issuer
+*/
+func getOIDCClient(s *models.OIDCSettings) (*oidc.Client, error) {
+	provider, err := oidc.Discover(nil, s.IssuerUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oidc.Client{
+		ClientID:     s.ClientID,
+		ClientSecret: s.ClientSecret,
+		RedirectUrl:  s.GetRedirectUrl(),
+		Scopes:       s.GetScopes(),
+		Provider:     provider,
+	}, nil
+}
+
+/*
+getSafeRedirectPath returns next if it is a path of codebox,
+otherwise the home page, to avoid open redirects
+*/
+func getSafeRedirectPath(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+// HandleRetrieveLoginOptions godoc
+// @Summary Retrieve login options
+// @Schemes
+// @Description Retrieve the available login methods, password login (local or LDAP) and single sign-on
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.LoginOptionsSerializer
+// @Router /api/v1/auth/login-options [get]
+func HandleRetrieveLoginOptions(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ldapSettings, err := models.GetSingletonModelInstance[models.LDAPSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadLoginOptionsSerializer(s, ldapSettings))
+}
+
+// HandleOIDCLogin godoc
+// @Summary Start single sign-on
+// @Schemes
+// @Description Redirect the user to the OpenID Connect provider using the authorization code flow with PKCE,
+// @Description after the login the user is redirected to next, that must be a path of codebox
+// @Tags Authentication
+// @Param next query string false "Path to open after the login"
+// @Success 307
+// @Router /api/v1/auth/oidc/login [get]
+func HandleOIDCLogin(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if !s.Enabled {
+		httperrors.RenderError(c, http.StatusNotFound, "Single sign-on is not enabled")
+		return
+	}
+
+	client, err := getOIDCClient(s)
+	if err != nil {
+		logging.Error("cannot retrieve the configuration of the oidc provider: %s", err)
+		httperrors.RenderError(c, http.StatusBadGateway, "The identity provider is not reachable")
+		return
+	}
+
+	state := oidc.GenerateRandomString()
+	loginState := oidcLoginState{
+		CodeVerifier: oidc.GenerateRandomString(),
+		Nonce:        oidc.GenerateRandomString(),
+		Next:         getSafeRedirectPath(c.Query("next")),
+	}
+
+	value, err := json.Marshal(loginState)
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if err := cache.SetKeyToCache(oidcStateCacheKey(state), value, oidcStateTTLSeconds); err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookieName, state, oidcStateTTLSeconds, "/api/v1/auth/oidc", "", false, true)
+
+	c.Redirect(
+		http.StatusTemporaryRedirect,
+		client.AuthorizationUrl(state, loginState.Nonce, loginState.CodeVerifier),
+	)
+}
+
+// HandleOIDCCallback godoc
+// @Summary Complete single sign-on
+// @Schemes
+// @Description Endpoint the OpenID Connect provider redirects the user to after the authentication.
+// @Description Users that do not exist are created if auto provisioning is enabled, if the groups claim
+// @Description is configured the groups of the user are replaced with the groups in the claim. If the user
+// @Description needs the second factor it is redirected to /login/two-factor with the challenge of the login
+// @Tags Authentication
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 307
+// @Router /api/v1/auth/oidc/callback [get]
+func HandleOIDCCallback(c *gin.Context) {
+	s, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	if !s.Enabled {
+		httperrors.RenderError(c, http.StatusNotFound, "Single sign-on is not enabled")
+		return
+	}
+
+	// the state must match the one of the browser that started the login
+	state := c.Query("state")
+	stateCookie, err := c.Cookie(oidcStateCookieName)
+	if err != nil || state == "" || stateCookie != state {
+		httperrors.RenderError(c, http.StatusBadRequest, "Invalid login request, try again")
+		return
+	}
+	c.SetCookie(oidcStateCookieName, "", -1, "/api/v1/auth/oidc", "", false, true)
+
+	value, err := cache.GetKeyFromCache(oidcStateCacheKey(state))
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	cache.DeleteKeyFromCache(oidcStateCacheKey(state))
+
+	var loginState oidcLoginState
+	if value == nil || json.Unmarshal(value, &loginState) != nil {
+		httperrors.RenderError(c, http.StatusBadRequest, "The login request has expired, try again")
+		return
+	}
+
+	if providerError := c.Query("error"); providerError != "" {
+		httperrors.RenderError(c, http.StatusUnauthorized, "The identity provider refused the login")
+		return
+	}
+
+	client, err := getOIDCClient(s)
+	if err != nil {
+		logging.Error("cannot retrieve the configuration of the oidc provider: %s", err)
+		httperrors.RenderError(c, http.StatusBadGateway, "The identity provider is not reachable")
+		return
+	}
+
+	token, err := client.ExchangeCode(c.Query("code"), loginState.CodeVerifier)
+	if err != nil {
+		logging.Error("oidc login failed: %s", err)
+		httperrors.RenderError(c, http.StatusUnauthorized, "Authentication with the identity provider failed")
+		return
+	}
+
+	claims, err := client.VerifyIDToken(token.IDToken, loginState.Nonce)
+	if err != nil {
+		logging.Error("oidc login failed: %s", err)
+		httperrors.RenderError(c, http.StatusUnauthorized, "Authentication with the identity provider failed")
+		return
+	}
+
+	// claims that are not in the id token, like groups for some providers, are read from userinfo
+	if client.Provider.UserinfoEndpoint != "" && token.AccessToken != "" {
+		userInfo, err := client.RetrieveUserInfo(token.AccessToken)
+		if err != nil {
+			logging.Error("oidc login failed: %s", err)
+			httperrors.RenderError(c, http.StatusUnauthorized, "Authentication with the identity provider failed")
+			return
+		}
+
+		if userInfo.GetString("sub") == claims.GetString("sub") {
+			for name, value := range userInfo {
+				if _, found := claims[name]; !found {
+					claims[name] = value
+				}
+			}
+		}
+	}
+
+	user, status, message := retrieveOrProvisionOIDCUser(s, claims)
+	if user == nil {
+		httperrors.RenderError(c, status, message)
+		return
+	}
+
+	if s.GroupsClaim != "" {
+		if err := models.SetUserGroupsByName(*user, claims.GetStringList(s.GroupsClaim)); err != nil {
+			httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+	}
+
+	as, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	// the login is completed by the login page with the second factor
+	if user.NeedsSecondFactor(as) {
+		challenge, err := createTwoFactorChallenge(*user, false, !user.TotpEnabled)
+		if err != nil {
+			httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+			return
+		}
+
+		c.Redirect(
+			http.StatusTemporaryRedirect,
+			fmt.Sprintf(
+				"/login/two-factor?challenge=%s&next=%s",
+				url.QueryEscape(challenge.Challenge),
+				url.QueryEscape(loginState.Next),
+			),
+		)
+		return
+	}
+
+	authToken, err := models.CreateSessionToken(
+		*user,
+		time.Duration(time.Hour*24*20),
+		false,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	SetAuthCookie(c, authToken.Token, 0)
+	c.Redirect(http.StatusTemporaryRedirect, loginState.Next)
+}
+
+/*
+isOIDCEmailVerified checks if the provider has verified the email address,
+a missing email_verified claim means that the address is not verified
+*/
+func isOIDCEmailVerified(claims oidc.Claims) bool {
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		return verified
+	case string:
+		return verified == "true"
+	}
+	return false
+}
+
+/*
+retrieveOrProvisionOIDCUser retrieves the user authenticated by the provider, creating it
+if it does not exist and auto provisioning is enabled. If the user cannot login nil is
+returned together with the status and the message of the error
+*/
+func retrieveOrProvisionOIDCUser(s *models.OIDCSettings, claims oidc.Claims) (*models.User, int, string) {
+	email := strings.TrimSpace(claims.GetString(s.GetEmailClaim()))
+	if email == "" {
+		return nil, http.StatusBadRequest, "The identity provider did not return an email address"
+	}
+
+	// users are matched by email address, so the address is trusted only if the
+	// provider states that it has been verified, otherwise anyone able to set that
+	// address on the provider could take over the existing account
+	if !isOIDCEmailVerified(claims) {
+		return nil, http.StatusForbidden, "The email address has not been verified by the identity provider"
+	}
+
+	as, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+
+	user, err := models.RetrieveUserByEmail(email)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Internal server error"
+	}
+
+	firstName := claims.GetString(s.GetFirstNameClaim())
+	lastName := claims.GetString(s.GetLastNameClaim())
+
+	if user == nil {
+		if !s.AutoProvisionUsers {
+			return nil, http.StatusForbidden, "Your account does not exist, contact an administrator"
+		}
+
+		user, err = provisionExternalUser(as, email, firstName, lastName)
+		if err != nil {
+			return nil, http.StatusInternalServerError, "Internal server error"
+		}
+	} else if !user.EmailVerified || (firstName != "" && firstName != user.FirstName) || (lastName != "" && lastName != user.LastName) {
+		user.EmailVerified = true
+		if firstName != "" {
+			user.FirstName = firstName
+		}
+		if lastName != "" {
+			user.LastName = lastName
+		}
+
+		if err := models.UpdateUser(user); err != nil {
+			return nil, http.StatusInternalServerError, "Internal server error"
+		}
+	}
+
+	if as.UsersMustBeApproved && !user.Approved {
+		return nil, http.StatusNotAcceptable, "Your account needs the approval of an administrator"
+	}
+
+	return user, 0, ""
+}
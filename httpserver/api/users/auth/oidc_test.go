@@ -0,0 +1,382 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/config"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/auth"
+	"gitlab.com/codebox4073715/codebox/testutils"
+	"gitlab.com/codebox4073715/codebox/utils/oidc/oidctest"
+)
+
+func configureOIDC(t *testing.T, provider *oidctest.Provider, passwordLoginDisabled bool) {
+	if err := models.SaveSingletonModel(&models.OIDCSettings{
+		Enabled:               true,
+		ProviderName:          "Mock",
+		IssuerUrl:             provider.Issuer(),
+		ClientID:              provider.ClientID,
+		ClientSecret:          provider.ClientSecret,
+		GroupsClaim:           "groups",
+		AutoProvisionUsers:    true,
+		PasswordLoginDisabled: passwordLoginDisabled,
+	}); err != nil {
+		t.Fatalf("Failed to save oidc settings: '%s'", err)
+	}
+}
+
+/*
+The test database is created by the migrations, the settings must be
+stored in the migrated table and read by every password login
+*/
+func TestOIDCSettingsMigratedTable(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		assert.True(t, dbconn.DB.Migrator().HasTable(&models.OIDCSettings{}))
+
+		if err := models.SaveSingletonModel(&models.OIDCSettings{
+			ProviderName: "Mock",
+		}); err != nil {
+			t.Fatalf("Failed to save oidc settings: '%s'", err)
+		}
+
+		settings, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+		if err != nil {
+			t.Fatalf("Failed to retrieve oidc settings: '%s'", err)
+		}
+		assert.Equal(t, "Mock", settings.ProviderName)
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "user1@user.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+/*
+Login through the mock provider, the returned cookie must
+authenticate the user created from the claims
+*/
+func TestOIDCLogin(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		provider := oidctest.NewProvider("codebox", "secret")
+		defer provider.Close()
+		provider.Claims = map[string]any{
+			"sub":            "42",
+			"email":          "sso@user.com",
+			"email_verified": true,
+			"given_name":     "Single",
+			"family_name":    "Sign-On",
+			"groups":         []string{"developers", "not-a-codebox-group"},
+		}
+		configureOIDC(t, provider, false)
+
+		if _, err := models.CreateGroup("developers"); err != nil {
+			t.Fatalf("Failed to create group: '%s'", err)
+		}
+
+		// start the login
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/oidc/login?next=/workspaces", "GET", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+		var stateCookie *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "codebox_oidc_state" {
+				stateCookie = cookie
+			}
+		}
+		if stateCookie == nil {
+			t.Fatalf("The state cookie has not been set")
+		}
+
+		// authenticate on the provider
+		httpClient := &http.Client{
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		res, err := httpClient.Get(w.Header().Get("Location"))
+		if err != nil {
+			t.Fatalf("Failed to reach the provider: '%s'", err)
+		}
+		res.Body.Close()
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+
+		callbackUrl, err := url.Parse(res.Header.Get("Location"))
+		if err != nil {
+			t.Fatalf("Invalid callback url: '%s'", err)
+		}
+		assert.Equal(t, "/api/v1/auth/oidc/callback", callbackUrl.Path)
+
+		// the callback cannot be used without the state cookie
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, callbackUrl.RequestURI(), "GET", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, callbackUrl.RequestURI(), "GET", nil)
+		req.AddCookie(stateCookie)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+		assert.Equal(t, "/workspaces", w.Header().Get("Location"))
+
+		var authCookie *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == config.Environment.AuthCookieName {
+				authCookie = cookie
+			}
+		}
+		if authCookie == nil || authCookie.Value == "" {
+			t.Fatalf("The authentication cookie has not been set")
+		}
+
+		// the user has been provisioned with the groups of the claim that exist
+		user, err := models.RetrieveUserByEmail("sso@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("The user has not been created: '%s'", err)
+		}
+		assert.Equal(t, "Single", user.FirstName)
+		assert.True(t, user.EmailVerified)
+
+		groups, err := user.GetGroups()
+		if err != nil {
+			t.Fatalf("Failed to retrieve groups: '%s'", err)
+		}
+		if assert.Len(t, groups, 1) {
+			assert.Equal(t, "developers", groups[0].Name)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/user-details", "GET", nil)
+		req.AddCookie(authCookie)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "sso@user.com")
+
+		// the state cannot be reused
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, callbackUrl.RequestURI(), "GET", nil)
+		req.AddCookie(stateCookie)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+/*
+When password login is disabled only administrators can login with the password
+*/
+func TestOIDCPasswordLoginDisabled(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		provider := oidctest.NewProvider("codebox", "secret")
+		defer provider.Close()
+		configureOIDC(t, provider, true)
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "user1@user.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "admin@admin.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login-options", "GET", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"password_login_enabled":false`)
+		assert.Contains(t, w.Body.String(), `"oidc_enabled":true`)
+	})
+}
+
+// completes a login through the mock provider and returns the response of the callback
+func oidcLogin(t *testing.T, router http.Handler) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/oidc/login", "GET", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+	var stateCookie *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "codebox_oidc_state" {
+			stateCookie = cookie
+		}
+	}
+	if stateCookie == nil {
+		t.Fatalf("The state cookie has not been set")
+	}
+
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := httpClient.Get(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Failed to reach the provider: '%s'", err)
+	}
+	res.Body.Close()
+
+	callbackUrl, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid callback url: '%s'", err)
+	}
+
+	w = httptest.NewRecorder()
+	req = testutils.CreateRequestWithJSONBody(t, callbackUrl.RequestURI(), "GET", nil)
+	req.AddCookie(stateCookie)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+/*
+When the provider removes the user from the group allowed to use a
+restricted runner, the workspaces on that runner cannot be started anymore
+*/
+func TestOIDCGroupRemovalRevokesRunnerAccess(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		provider := oidctest.NewProvider("codebox", "secret")
+		defer provider.Close()
+		provider.Claims = map[string]any{
+			"sub":            "42",
+			"email":          "sso@user.com",
+			"email_verified": true,
+			"groups":         []string{"developers"},
+		}
+		configureOIDC(t, provider, false)
+
+		group, err := models.CreateGroup("developers")
+		if err != nil {
+			t.Fatalf("Failed to create group: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+		runner := runners[0]
+		runner.Restricted = true
+		if err := models.UpdateRunner(runner); err != nil {
+			t.Fatalf("Failed to update runner: '%s'", err)
+		}
+		if err := models.SetRunnerAllowedGroups(&runner, []models.Group{*group}); err != nil {
+			t.Fatalf("Failed to set runner allowed groups: '%s'", err)
+		}
+
+		w := oidcLogin(t, router)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+		user, err := models.RetrieveUserByEmail("sso@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("The user has not been created: '%s'", err)
+		}
+
+		workspace, err := models.CreateWorkspace(
+			"Test Workspace",
+			user,
+			"docker_compose",
+			&runner,
+			models.WorkspaceConfigSourceGit,
+			nil,
+			nil,
+			[]string{},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create workspace: '%s'", err)
+		}
+		if _, err := models.UpdateWorkspace(
+			workspace,
+			workspace.Name,
+			models.WorkspaceStatusStopped,
+			&runner,
+			workspace.ConfigSource,
+			nil,
+			nil,
+			workspace.EnvironmentVariables,
+		); err != nil {
+			t.Fatalf("Failed to stop workspace: '%s'", err)
+		}
+
+		// the provider removes the user from the group
+		provider.Claims["groups"] = []string{}
+		w = oidcLogin(t, router)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+
+		workspace, err = models.RetrieveWorkspaceByUserAndId(*user, workspace.ID)
+		if err != nil || workspace == nil {
+			t.Fatalf("Failed to retrieve workspace: '%s'", err)
+		}
+		assert.True(t, workspace.RunnerAccessRevoked)
+
+		w = httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, fmt.Sprintf("/api/v1/workspace/%d/start", workspace.ID), "POST", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+/*
+Users are matched by email address, the provider must state that the
+address has been verified to login, otherwise the account could be taken over
+*/
+func TestOIDCLoginRequiresVerifiedEmail(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		provider := oidctest.NewProvider("codebox", "secret")
+		defer provider.Close()
+		provider.Claims = map[string]any{
+			"sub":        "42",
+			"email":      "user1@user.com",
+			"given_name": "Attacker",
+		}
+		configureOIDC(t, provider, false)
+
+		// the claim is missing
+		w := oidcLogin(t, router)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		provider.Claims["email_verified"] = false
+		w = oidcLogin(t, router)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// the existing account has not been changed
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+		assert.NotEqual(t, "Attacker", user.FirstName)
+
+		provider.Claims["email_verified"] = "true"
+		w = oidcLogin(t, router)
+		assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	})
+}
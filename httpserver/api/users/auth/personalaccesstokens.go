@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+/*
+getPersonalAccessTokensOwner returns the user that is managing its personal access tokens,
+impersonators cannot create tokens that would keep working after the impersonation ends
+*/
+func getPersonalAccessTokensOwner(c *gin.Context) (*models.Token, *models.User, bool) {
+	token, err := utils.GetTokenFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "missing or invalid authorization token")
+		return nil, nil, false
+	}
+
+	if token.ImpersonatedUser != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "personal access tokens cannot be managed while impersonating a user")
+		return nil, nil, false
+	}
+
+	return &token, &token.User, true
+}
+
+// HandleListPersonalAccessTokens godoc
+// @Summary List personal access tokens
+// @Schemes
+// @Description List the personal access tokens of the current user, the values of the tokens are not returned
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} []serializers.PersonalAccessTokenSerializer
+// @Router /api/v1/auth/tokens [get]
+func HandleListPersonalAccessTokens(c *gin.Context) {
+	_, user, ok := getPersonalAccessTokensOwner(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := models.ListPersonalAccessTokens(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultiplePersonalAccessTokenSerializer(tokens))
+}
+
+type CreatePersonalAccessTokenRequestBody struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays uint     `json:"expires_in_days" binding:"required"`
+}
+
+// HandleCreatePersonalAccessToken godoc
+// @Summary Create a personal access token
+// @Schemes
+// @Description Create a token to use the apis with the 'Authorization: Bearer' header, it can be used only
+// @Description for the apis allowed by its scopes. The value of the token is returned only once
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body CreatePersonalAccessTokenRequestBody true "Personal access token options"
+// @Success 201 {object} serializers.PersonalAccessTokenSerializer
+// @Router /api/v1/auth/tokens [post]
+func HandleCreatePersonalAccessToken(c *gin.Context) {
+	sessionToken, user, ok := getPersonalAccessTokensOwner(c)
+	if !ok {
+		return
+	}
+
+	var reqBody CreatePersonalAccessTokenRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid request argument")
+		return
+	}
+
+	if len(reqBody.Name) > 255 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "the name cannot be longer than 255 characters")
+		return
+	}
+
+	if reqBody.ExpiresInDays > models.PersonalAccessTokenMaxDurationDays {
+		utils.ErrorResponse(c, http.StatusBadRequest, "personal access tokens cannot last more than 365 days")
+		return
+	}
+
+	scopes, err := models.ValidatePersonalAccessTokenScopes(reqBody.Scopes)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	for _, scope := range scopes {
+		if scope == models.PersonalAccessTokenScopeAdmin && !user.IsSuperuser {
+			utils.ErrorResponse(c, http.StatusForbidden, "only superusers can create tokens with the admin scope")
+			return
+		}
+		if scope == models.PersonalAccessTokenScopeTemplatesWrite && !user.IsTemplateManager && !user.IsSuperuser {
+			utils.ErrorResponse(c, http.StatusForbidden, "only template managers can create tokens with the templates:write scope")
+			return
+		}
+	}
+
+	// like the cli login, the session must have passed the second factor
+	secondFactorSatisfied, err := isSecondFactorSatisfied(*sessionToken)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if !secondFactorSatisfied {
+		utils.ErrorResponse(c, http.StatusForbidden, "two-factor authentication is required, login again")
+		return
+	}
+
+	token, value, err := models.CreatePersonalAccessToken(
+		*user,
+		reqBody.Name,
+		scopes,
+		time.Now().AddDate(0, 0, int(reqBody.ExpiresInDays)),
+	)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializers.LoadPersonalAccessTokenSerializer(token, value))
+}
+
+// HandleDeletePersonalAccessToken godoc
+// @Summary Revoke a personal access token
+// @Schemes
+// @Description Revoke a personal access token of the current user
+// @Tags Authentication
+// @Produce json
+// @Success 204
+// @Router /api/v1/auth/tokens/:tokenId [delete]
+func HandleDeletePersonalAccessToken(c *gin.Context) {
+	_, user, ok := getPersonalAccessTokensOwner(c)
+	if !ok {
+		return
+	}
+
+	id, err := utils.GetUIntParamFromContext(c, "tokenId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "personal access token not found")
+		return
+	}
+
+	token, err := models.RetrievePersonalAccessTokenByID(*user, id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if token == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "personal access token not found")
+		return
+	}
+
+	if err := models.DeletePersonalAccessToken(token); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/auth"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Personal access tokens can be used only for the apis allowed by
+their scopes, they cannot manage the account and can be revoked
+*/
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		// users that are not superusers cannot get the admin scope
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/tokens", "POST", auth.CreatePersonalAccessTokenRequestBody{
+			Name:          "ci",
+			Scopes:        []string{models.PersonalAccessTokenScopeAdmin},
+			ExpiresInDays: 30,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/tokens", "POST", auth.CreatePersonalAccessTokenRequestBody{
+			Name:          "ci",
+			Scopes:        []string{"unknown"},
+			ExpiresInDays: 30,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/tokens", "POST", auth.CreatePersonalAccessTokenRequestBody{
+			Name:          "ci",
+			Scopes:        []string{models.PersonalAccessTokenScopeWorkspacesRead},
+			ExpiresInDays: 30,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		pat, err := serializers.PersonalAccessTokenSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+		assert.True(t, strings.HasPrefix(pat.Token, models.PersonalAccessTokenPrefix))
+		assert.True(t, strings.HasPrefix(pat.Token, pat.TokenPrefix))
+
+		withPat := func(url, method string) int {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, url, method, nil)
+			req.Header.Set("Authorization", "Bearer "+pat.Token)
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, withPat("/api/v1/workspace", "GET"))
+		assert.Equal(t, http.StatusOK, withPat("/api/v1/auth/user-details", "GET"))
+		assert.Equal(t, http.StatusForbidden, withPat("/api/v1/workspace", "POST"))
+		assert.Equal(t, http.StatusForbidden, withPat("/api/v1/auth/tokens", "GET"))
+		assert.Equal(t, http.StatusForbidden, withPat("/api/v1/auth/change-password", "POST"))
+
+		// the value of the token is not returned anymore and the last use is tracked
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/tokens", "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), pat.Token)
+		assert.NotContains(t, w.Body.String(), `"last_used_at":null`)
+
+		// revoked tokens cannot be used
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, fmt.Sprintf("/api/v1/auth/tokens/%d", pat.ID), "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		assert.Equal(t, http.StatusUnauthorized, withPat("/api/v1/workspace", "GET"))
+	})
+}
+
+/*
+The admin scope gives access only to the admin apis
+*/
+func TestPersonalAccessTokenAdminScope(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		admin, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || admin == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/tokens", "POST", auth.CreatePersonalAccessTokenRequestBody{
+			Name:          "automation",
+			Scopes:        []string{models.PersonalAccessTokenScopeAdmin},
+			ExpiresInDays: 1,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *admin)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		pat, err := serializers.PersonalAccessTokenSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+
+		withPat := func(url, method string) int {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, url, method, nil)
+			req.Header.Set("Authorization", "Bearer "+pat.Token)
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, withPat("/api/v1/admin/users", "GET"))
+		assert.Equal(t, http.StatusForbidden, withPat("/api/v1/workspace", "GET"))
+		assert.Equal(t, http.StatusForbidden, withPat("/api/v1/admin/users/user1@user.com/impersonate", "POST"))
+	})
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+/*
+getSessionsOwner returns the current session and the user that owns it,
+impersonators cannot see or revoke the sessions of the impersonated users
+*/
+func getSessionsOwner(c *gin.Context) (*models.Token, *models.User, bool) {
+	token, err := utils.GetTokenFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "missing or invalid authorization token")
+		return nil, nil, false
+	}
+
+	if token.ImpersonatedUser != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "sessions cannot be managed while impersonating a user")
+		return nil, nil, false
+	}
+
+	return &token, &token.User, true
+}
+
+// HandleListSessions godoc
+// @Summary List sessions
+// @Schemes
+// @Description List the active sessions of the current user with the ip address
+// @Description and the time of the last use, and the user agent used to login
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} []serializers.SessionSerializer
+// @Router /api/v1/auth/sessions [get]
+func HandleListSessions(c *gin.Context) {
+	current, user, ok := getSessionsOwner(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := models.ListActiveTokensForUser(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleSessionSerializer(tokens, current.ID))
+}
+
+// HandleRevokeSession godoc
+// @Summary Revoke a session
+// @Schemes
+// @Description Revoke a session of the current user, the current session cannot be revoked, logout instead
+// @Tags Authentication
+// @Produce json
+// @Success 204
+// @Failure 404 "Session not found"
+// @Router /api/v1/auth/sessions/:sessionId [delete]
+func HandleRevokeSession(c *gin.Context) {
+	current, user, ok := getSessionsOwner(c)
+	if !ok {
+		return
+	}
+
+	id, err := utils.GetUIntParamFromContext(c, "sessionId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "session not found")
+		return
+	}
+
+	if id == current.ID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "the current session cannot be revoked, logout instead")
+		return
+	}
+
+	token, err := models.RetrieveTokenByUserAndID(*user, id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if token == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "session not found")
+		return
+	}
+
+	if err := models.DeleteToken(token); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleRevokeOtherSessions godoc
+// @Summary Revoke the other sessions
+// @Schemes
+// @Description Revoke all the sessions of the current user except the current one,
+// @Description the personal access tokens, including the ones created by the cli login, are revoked as well
+// @Tags Authentication
+// @Produce json
+// @Success 204
+// @Router /api/v1/auth/sessions [delete]
+func HandleRevokeOtherSessions(c *gin.Context) {
+	current, user, ok := getSessionsOwner(c)
+	if !ok {
+		return
+	}
+
+	if err := models.RevokeOtherAuthTokensForUser(*user, *current); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if err := models.RevokeAllPersonalAccessTokensForUser(*user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
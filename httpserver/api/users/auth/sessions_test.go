@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/auth"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Users can list their sessions and revoke the other ones,
+changing the password revokes the other sessions and the personal access tokens
+*/
+func TestSessionsListingAndRevocation(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		// the requests are built without a remote address, the one of a client is set
+		const clientIP = "192.0.2.10"
+
+		login := func(userAgent string) string {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+				Email:    "user1@user.com",
+				Password: "password",
+			})
+			req.RemoteAddr = clientIP + ":41000"
+			req.Header.Set("User-Agent", userAgent)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var token serializers.TokenSerializer
+			if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil {
+				t.Fatalf("Failed to parse response: '%s'", err)
+			}
+			return token.Token
+		}
+
+		request := func(token, url, method string, body interface{}) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, url, method, body)
+			req.RemoteAddr = clientIP + ":41000"
+			req.Header.Set("Authorization", "Bearer "+token)
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		browser := login("browser")
+		laptop := login("laptop")
+
+		w := request(browser, "/api/v1/auth/sessions", "GET", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		sessions, err := serializers.MultipleSessionSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+		assert.Len(t, sessions, 2)
+
+		var laptopSessionID uint
+		for _, session := range sessions {
+			if session.UserAgent == "browser" {
+				assert.True(t, session.Current)
+				assert.NotNil(t, session.LastUsedAt)
+			} else {
+				assert.Equal(t, "laptop", session.UserAgent)
+				assert.False(t, session.Current)
+				laptopSessionID = session.ID
+			}
+			assert.Equal(t, clientIP, session.IpAddress)
+		}
+
+		// the current session cannot be revoked
+		for _, session := range sessions {
+			if session.Current {
+				w = request(browser, fmt.Sprintf("/api/v1/auth/sessions/%d", session.ID), "DELETE", nil)
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			}
+		}
+
+		w = request(browser, fmt.Sprintf("/api/v1/auth/sessions/%d", laptopSessionID), "DELETE", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, request(laptop, "/api/v1/auth/user-details", "GET", nil).Code)
+
+		// revoke all the other sessions
+		laptop = login("laptop")
+		w = request(browser, "/api/v1/auth/sessions", "DELETE", nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, request(laptop, "/api/v1/auth/user-details", "GET", nil).Code)
+		assert.Equal(t, http.StatusOK, request(browser, "/api/v1/auth/user-details", "GET", nil).Code)
+
+		// changing the password revokes the other sessions and the personal access tokens by default
+		laptop = login("laptop")
+		w = request(browser, "/api/v1/auth/tokens", "POST", auth.CreatePersonalAccessTokenRequestBody{
+			Name:          "ci",
+			Scopes:        []string{models.PersonalAccessTokenScopeWorkspacesRead},
+			ExpiresInDays: 30,
+		})
+		assert.Equal(t, http.StatusCreated, w.Code)
+		pat, err := serializers.PersonalAccessTokenSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+		assert.Equal(t, http.StatusOK, request(pat.Token, "/api/v1/workspace", "GET", nil).Code)
+
+		w = request(browser, "/api/v1/auth/change-password", "POST", auth.ChangePasswordRequestBody{
+			CurrentPassword: "password",
+			NewPassword:     "NewPassword!1",
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, request(laptop, "/api/v1/auth/user-details", "GET", nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, request(pat.Token, "/api/v1/workspace", "GET", nil).Code)
+		assert.Equal(t, http.StatusOK, request(browser, "/api/v1/auth/user-details", "GET", nil).Code)
+	})
+}
+
+/*
+Admins can list and revoke the sessions of a user
+*/
+func TestAdminUserSessions(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		admin, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || admin == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "user1@user.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var token serializers.TokenSerializer
+		if err := json.Unmarshal(w.Body.Bytes(), &token); err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/admin/users/user1@user.com/sessions", "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *admin)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		sessions, err := serializers.MultipleSessionSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+		assert.Len(t, sessions, 1)
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+		_, pat, err := models.CreatePersonalAccessToken(
+			*user, "Codebox CLI", []string{models.PersonalAccessTokenScopeWorkspacesRead}, time.Now().Add(time.Hour),
+		)
+		if err != nil {
+			t.Fatalf("Failed to create personal access token: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/admin/users/user1@user.com/sessions", "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *admin)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/user-details", "GET", nil)
+		req.Header.Set("Authorization", "Bearer "+token.Token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// the personal access tokens, like the cli logins, are revoked too
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "GET", nil)
+		req.Header.Set("Authorization", "Bearer "+pat)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
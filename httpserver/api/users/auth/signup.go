@@ -10,6 +10,7 @@ import (
 	"gitlab.com/codebox4073715/codebox/emails"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/utils/oidc"
 )
 
 // HandleRetrieveInitialUserExists godoc
@@ -57,6 +58,37 @@ func IsEmailMatchingARegex(email string, regEx []string) bool {
 	return false
 }
 
+/*
+provisionExternalUser creates a user authenticated by the single sign-on provider
+or by the directory. Users are approved automatically as the ones that sign up,
+the password is random since these users do not use it
+*/
+func provisionExternalUser(
+	as *models.AuthenticationSettings,
+	email string,
+	firstName string,
+	lastName string,
+) (*models.User, error) {
+	autoApproved := false
+	if len(strings.TrimSpace(as.ApprovedByDefaultEmailRegex)) > 0 {
+		autoApproved = IsEmailMatchingARegex(
+			email,
+			strings.Split(as.ApprovedByDefaultEmailRegex, "\n"),
+		)
+	}
+
+	return models.CreateUser(
+		email,
+		firstName,
+		lastName,
+		oidc.GenerateRandomString(),
+		false,
+		false,
+		true,
+		autoApproved,
+	)
+}
+
 type SignUpRequestBody struct {
 	Email     string `json:"email" binding:"required,email"`
 	FirstName string `json:"first_name"  binding:"required"`
@@ -109,7 +141,20 @@ func HandleSignup(c *gin.Context) {
 		return
 	}
 
-	if usersCount > 0 && !s.IsSignUpOpen {
+	oidcSettings, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		utils.ErrorResponse(
+			c,
+			http.StatusInternalServerError,
+			"internal server error",
+		)
+		return
+	}
+
+	// accounts with a password cannot be used if password login is disabled
+	passwordLoginDisabled := oidcSettings.Enabled && oidcSettings.PasswordLoginDisabled
+
+	if usersCount > 0 && (!s.IsSignUpOpen || passwordLoginDisabled) {
 		utils.ErrorResponse(
 			c,
 			http.StatusNotAcceptable,
@@ -261,8 +306,20 @@ func HandleIsSignUpOpen(c *gin.Context) {
 		return
 	}
 
+	oidcSettings, err := models.GetSingletonModelInstance[models.OIDCSettings]()
+	if err != nil {
+		utils.ErrorResponse(
+			c,
+			http.StatusInternalServerError,
+			"internal server error",
+		)
+		return
+	}
+
 	c.JSON(
 		http.StatusOK,
-		serializers.LoadIsSignUpOpenSerializer(s.IsSignUpOpen),
+		serializers.LoadIsSignUpOpenSerializer(
+			s.IsSignUpOpen && !(oidcSettings.Enabled && oidcSettings.PasswordLoginDisabled),
+		),
 	)
 }
@@ -44,6 +44,26 @@ func HandleSubdomainLoginAuthorize(c *gin.Context) {
 		return
 	}
 
+	// workspaces are reachable only by sessions that have passed the second factor
+	secondFactorSatisfied, err := isSecondFactorSatisfied(token)
+	if err != nil {
+		httperrors.RenderError(
+			c,
+			http.StatusInternalServerError,
+			"Internal server error",
+		)
+		return
+	}
+
+	if !secondFactorSatisfied {
+		httperrors.RenderError(
+			c,
+			http.StatusForbidden,
+			"Two-factor authentication is required, logout and login again",
+		)
+		return
+	}
+
 	authorizationCode, err := models.GenerateAuthorizationCode(token, time.Now().Add(2*time.Minute))
 	if err != nil {
 		httperrors.RenderError(
@@ -0,0 +1,543 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/cache"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/utils/oidc"
+	"gitlab.com/codebox4073715/codebox/utils/totp"
+)
+
+// issuer shown by the authenticator apps
+const totpIssuer = "Codebox"
+
+// seconds available to complete the second step of the login
+const twoFactorChallengeTTLSeconds = 300
+
+// wrong codes accepted before the login must be started again
+const twoFactorMaxAttempts = 5
+
+/*
+twoFactorChallenge is stored in cache between the first step of the login,
+that checks the credentials, and the second one that checks the code
+*/
+type twoFactorChallenge struct {
+	Email            string    `json:"email"`
+	RememberMe       bool      `json:"remember_me"`
+	EnrollmentSecret string    `json:"enrollment_secret"` // set if the user must enroll during the login
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+func twoFactorChallengeCacheKey(id string) string {
+	return fmt.Sprintf("two-factor-challenge-%s", id)
+}
+
+// the attempts are counted in a separate key so they can be incremented atomically
+func twoFactorChallengeAttemptsCacheKey(id string) string {
+	return fmt.Sprintf("two-factor-challenge-attempts-%s", id)
+}
+
+// the attempts are not deleted but expire with the challenge, otherwise a request
+// that has already loaded the challenge would start counting again from zero
+func deleteTwoFactorChallenge(id string) {
+	cache.DeleteKeyFromCache(twoFactorChallengeCacheKey(id))
+}
+
+func saveTwoFactorChallenge(id string, challenge twoFactorChallenge) error {
+	ttl := int(time.Until(challenge.ExpiresAt).Seconds())
+	if ttl <= 0 {
+		cache.DeleteKeyFromCache(twoFactorChallengeCacheKey(id))
+		return nil
+	}
+
+	value, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+	return cache.SetKeyToCache(twoFactorChallengeCacheKey(id), value, ttl)
+}
+
+// retrieveTwoFactorChallenge returns nil if the challenge does not exist or has expired
+func retrieveTwoFactorChallenge(id string) (*twoFactorChallenge, error) {
+	if id == "" {
+		return nil, nil
+	}
+
+	value, err := cache.GetKeyFromCache(twoFactorChallengeCacheKey(id))
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	var challenge twoFactorChallenge
+	if err := json.Unmarshal(value, &challenge); err != nil || time.Now().After(challenge.ExpiresAt) {
+		return nil, nil
+	}
+	return &challenge, nil
+}
+
+func loadTwoFactorChallengeSerializer(id string, challenge twoFactorChallenge) *serializers.TwoFactorChallengeSerializer {
+	s := &serializers.TwoFactorChallengeSerializer{
+		TwoFactorRequired:  true,
+		Challenge:          id,
+		EnrollmentRequired: challenge.EnrollmentSecret != "",
+	}
+
+	if s.EnrollmentRequired {
+		s.Enrollment = &serializers.TwoFactorEnrollmentSerializer{
+			Secret:          challenge.EnrollmentSecret,
+			ProvisioningUri: totp.ProvisioningUri(totpIssuer, challenge.Email, challenge.EnrollmentSecret),
+		}
+	}
+	return s
+}
+
+/*
+createTwoFactorChallenge starts the second step of the login of a user whose
+credentials have been checked. If enroll is true a new secret is generated,
+it is enabled when the user completes the login with a code of the secret
+*/
+func createTwoFactorChallenge(user models.User, rememberMe bool, enroll bool) (*serializers.TwoFactorChallengeSerializer, error) {
+	id := oidc.GenerateRandomString()
+	challenge := twoFactorChallenge{
+		Email:      user.Email,
+		RememberMe: rememberMe,
+		ExpiresAt:  time.Now().Add(twoFactorChallengeTTLSeconds * time.Second),
+	}
+	if enroll {
+		challenge.EnrollmentSecret = totp.GenerateSecret()
+	}
+
+	if err := saveTwoFactorChallenge(id, challenge); err != nil {
+		return nil, err
+	}
+	return loadTwoFactorChallengeSerializer(id, challenge), nil
+}
+
+/*
+isSecondFactorSatisfied checks that the token has been created with
+the second factor, if the user that has logged in needs it
+*/
+func isSecondFactorSatisfied(token models.Token) (bool, error) {
+	if token.SecondFactorVerified {
+		return true, nil
+	}
+
+	s, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+	if err != nil {
+		return false, err
+	}
+	return !token.User.NeedsSecondFactor(s), nil
+}
+
+// HandleRetrieveTwoFactorChallenge godoc
+// @Summary Retrieve a two-factor login challenge
+// @Schemes
+// @Description Retrieve a pending second step of the login, it is used after single sign-on
+// @Description to know if the user must enroll the authenticator app
+// @Tags Authentication
+// @Produce json
+// @Param challenge query string true "Challenge"
+// @Success 200 {object} serializers.TwoFactorChallengeSerializer
+// @Failure 404 "Challenge not found or expired"
+// @Router /api/v1/auth/login/two-factor [get]
+func HandleRetrieveTwoFactorChallenge(c *gin.Context) {
+	id := c.Query("challenge")
+	challenge, err := retrieveTwoFactorChallenge(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if challenge == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "the login request has expired, login again")
+		return
+	}
+
+	c.JSON(http.StatusOK, loadTwoFactorChallengeSerializer(id, *challenge))
+}
+
+type TwoFactorLoginRequestBody struct {
+	Challenge string `json:"challenge" binding:"required"`
+	Code      string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// HandleTwoFactorLogin godoc
+// @Summary Complete the login with the second factor
+// @Schemes
+// @Description Complete the login with a TOTP code or a recovery code. If the user has enrolled during
+// @Description the login the TOTP code enables two-factor authentication and the recovery codes are returned.
+// @Description After 5 wrong codes the login must be started again
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorLoginRequestBody true "Challenge and code"
+// @Success 200 {object} serializers.TwoFactorLoginSerializer
+// @Failure 400 "Invalid code or expired challenge"
+// @Failure 429 "Ratelimit exceeded"
+// @Router /api/v1/auth/login/two-factor [post]
+func HandleTwoFactorLogin(c *gin.Context) {
+	var requestBody TwoFactorLoginRequestBody
+	if err := c.ShouldBindBodyWithJSON(&requestBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	challenge, err := retrieveTwoFactorChallenge(requestBody.Challenge)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if challenge == nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "the login request has expired, login again")
+		return
+	}
+
+	user, err := models.RetrieveUserByEmail(challenge.Email)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil || user.Deactivated {
+		deleteTwoFactorChallenge(requestBody.Challenge)
+		utils.ErrorResponse(c, http.StatusBadRequest, "the login request has expired, login again")
+		return
+	}
+
+	// the attempt is counted before checking the code, so that
+	// concurrent requests cannot check more codes than allowed
+	attempts, err := cache.IncrementKeyInCache(
+		twoFactorChallengeAttemptsCacheKey(requestBody.Challenge),
+		int(time.Until(challenge.ExpiresAt).Seconds())+1,
+	)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if attempts > twoFactorMaxAttempts {
+		deleteTwoFactorChallenge(requestBody.Challenge)
+		utils.ErrorResponse(c, http.StatusBadRequest, "too many invalid codes, login again")
+		return
+	}
+
+	valid := false
+	if challenge.EnrollmentSecret != "" {
+		var step int64
+		step, valid = totp.Validate(challenge.EnrollmentSecret, requestBody.Code, time.Now())
+		if valid {
+			err = models.EnableTotp(user, challenge.EnrollmentSecret, step)
+		}
+	} else {
+		valid, err = models.CheckSecondFactor(user, requestBody.Code)
+	}
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if !valid {
+		if attempts >= twoFactorMaxAttempts {
+			deleteTwoFactorChallenge(requestBody.Challenge)
+			utils.ErrorResponse(c, http.StatusBadRequest, "too many invalid codes, login again")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid code")
+		return
+	}
+
+	deleteTwoFactorChallenge(requestBody.Challenge)
+
+	var recoveryCodes []string
+	if challenge.EnrollmentSecret != "" {
+		recoveryCodes, err = models.GenerateRecoveryCodes(*user)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	token, err := models.CreateSessionToken(
+		*user,
+		time.Duration(time.Hour*24*20),
+		true,
+		c.ClientIP(),
+		c.Request.UserAgent(),
+	)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	SetAuthCookie(c, token.Token, getLoginCookieDuration(challenge.RememberMe))
+
+	c.JSON(http.StatusOK, serializers.LoadTwoFactorLoginSerializer(&token, recoveryCodes))
+}
+
+/*
+getTwoFactorUser returns the user that is managing its second factor,
+impersonators cannot change the second factor of the impersonated users
+*/
+func getTwoFactorUser(c *gin.Context) (*models.Token, *models.User, bool) {
+	token, err := utils.GetTokenFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "missing or invalid authorization token")
+		return nil, nil, false
+	}
+
+	if token.ImpersonatedUser != nil {
+		utils.ErrorResponse(c, http.StatusForbidden, "two-factor authentication cannot be managed while impersonating a user")
+		return nil, nil, false
+	}
+
+	return &token, &token.User, true
+}
+
+// HandleRetrieveTwoFactorStatus godoc
+// @Summary Retrieve two-factor authentication status
+// @Schemes
+// @Description Retrieve if two-factor authentication is enabled or required for the current user
+// @Description and the number of recovery codes that can still be used
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} serializers.TwoFactorStatusSerializer
+// @Router /api/v1/auth/two-factor [get]
+func HandleRetrieveTwoFactorStatus(c *gin.Context) {
+	_, user, ok := getTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	s, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	recoveryCodesLeft, err := models.CountUnusedRecoveryCodes(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadTwoFactorStatusSerializer(user, s.IsTwoFactorRequired(*user), recoveryCodesLeft))
+}
+
+// HandleEnrollTwoFactor godoc
+// @Summary Start two-factor authentication enrollment
+// @Schemes
+// @Description Generate a new TOTP secret to add to the authenticator app,
+// @Description two-factor authentication is enabled when a code is confirmed with /api/v1/auth/two-factor/activate
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} serializers.TwoFactorEnrollmentSerializer
+// @Failure 409 "Two-factor authentication already enabled"
+// @Router /api/v1/auth/two-factor/enroll [post]
+func HandleEnrollTwoFactor(c *gin.Context) {
+	_, user, ok := getTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TotpEnabled {
+		utils.ErrorResponse(c, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	user.TotpSecret = totp.GenerateSecret()
+	if err := models.UpdateUser(user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.TwoFactorEnrollmentSerializer{
+		Secret:          user.TotpSecret,
+		ProvisioningUri: totp.ProvisioningUri(totpIssuer, user.Email, user.TotpSecret),
+	})
+}
+
+type TwoFactorCodeRequestBody struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// HandleActivateTwoFactor godoc
+// @Summary Enable two-factor authentication
+// @Schemes
+// @Description Confirm the enrollment with a code of the authenticator app, the recovery codes are returned
+// @Description only by this api, they must be saved by the user
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequestBody true "TOTP code"
+// @Success 200 {object} serializers.RecoveryCodesSerializer
+// @Failure 400 "Enrollment not started"
+// @Failure 409 "Two-factor authentication already enabled"
+// @Failure 417 "Invalid code"
+// @Router /api/v1/auth/two-factor/activate [post]
+func HandleActivateTwoFactor(c *gin.Context) {
+	var requestBody TwoFactorCodeRequestBody
+	if err := c.ShouldBindBodyWithJSON(&requestBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	token, user, ok := getTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	if user.TotpEnabled {
+		utils.ErrorResponse(c, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+
+	if user.TotpSecret == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "the enrollment has not been started")
+		return
+	}
+
+	step, valid := totp.Validate(user.TotpSecret, requestBody.Code, time.Now())
+	if !valid {
+		utils.ErrorResponse(c, http.StatusExpectationFailed, "invalid code")
+		return
+	}
+
+	if err := models.EnableTotp(user, user.TotpSecret, step); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	recoveryCodes, err := models.GenerateRecoveryCodes(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// the user has just proved to own the second factor
+	token.SecondFactorVerified = true
+	if err := models.UpdateToken(*token); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.RecoveryCodesSerializer{RecoveryCodes: recoveryCodes})
+}
+
+// HandleDisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Schemes
+// @Description Disable two-factor authentication, a TOTP code or a recovery code is required.
+// @Description It cannot be disabled if it is required for the user by the authentication settings
+// @Tags Authentication
+// @Accept json
+// @Param request body TwoFactorCodeRequestBody true "TOTP code or recovery code"
+// @Success 204
+// @Failure 400 "Two-factor authentication not enabled"
+// @Failure 403 "Two-factor authentication required"
+// @Failure 417 "Invalid code"
+// @Router /api/v1/auth/two-factor/disable [post]
+func HandleDisableTwoFactor(c *gin.Context) {
+	var requestBody TwoFactorCodeRequestBody
+	if err := c.ShouldBindBodyWithJSON(&requestBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	_, user, ok := getTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TotpEnabled {
+		utils.ErrorResponse(c, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	s, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if s.IsTwoFactorRequired(*user) {
+		utils.ErrorResponse(c, http.StatusForbidden, "two-factor authentication is required for your account")
+		return
+	}
+
+	valid, err := models.CheckSecondFactor(user, requestBody.Code)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if !valid {
+		utils.ErrorResponse(c, http.StatusExpectationFailed, "invalid code")
+		return
+	}
+
+	if err := models.DisableTotp(user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// HandleRegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Schemes
+// @Description Replace the recovery codes of the current user, a TOTP code is required.
+// @Description The previous recovery codes cannot be used anymore
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequestBody true "TOTP code"
+// @Success 200 {object} serializers.RecoveryCodesSerializer
+// @Failure 400 "Two-factor authentication not enabled"
+// @Failure 417 "Invalid code"
+// @Router /api/v1/auth/two-factor/recovery-codes [post]
+func HandleRegenerateRecoveryCodes(c *gin.Context) {
+	var requestBody TwoFactorCodeRequestBody
+	if err := c.ShouldBindBodyWithJSON(&requestBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	_, user, ok := getTwoFactorUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.TotpEnabled {
+		utils.ErrorResponse(c, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	valid, err := models.CheckTotpCode(user, requestBody.Code)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if !valid {
+		utils.ErrorResponse(c, http.StatusExpectationFailed, "invalid code")
+		return
+	}
+
+	recoveryCodes, err := models.GenerateRecoveryCodes(*user)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.RecoveryCodesSerializer{RecoveryCodes: recoveryCodes})
+}
@@ -0,0 +1,312 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/auth"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/testutils"
+	"gitlab.com/codebox4073715/codebox/utils/totp"
+)
+
+func generateTotpCode(t *testing.T, secret string, at time.Time) string {
+	code, err := totp.GenerateCode(secret, at)
+	if err != nil {
+		t.Fatalf("Failed to generate totp code: '%s'", err)
+	}
+	return code
+}
+
+/*
+Enroll the authenticator app, then login with the second factor
+using a TOTP code and a recovery code
+*/
+func TestTwoFactorEnrollmentAndLogin(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		// enroll
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/two-factor/enroll", "POST", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		enrollment, err := serializers.TwoFactorEnrollmentSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+
+		// wrong codes do not enable two-factor authentication
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/two-factor/activate", "POST", auth.TwoFactorCodeRequestBody{
+			Code: "000000",
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusExpectationFailed, w.Code)
+
+		now := time.Now()
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/two-factor/activate", "POST", auth.TwoFactorCodeRequestBody{
+			Code: generateTotpCode(t, enrollment.Secret, now),
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		recoveryCodes, err := serializers.RecoveryCodesSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+		assert.Len(t, recoveryCodes.RecoveryCodes, models.RecoveryCodesCount)
+
+		login := func() serializers.TwoFactorChallengeSerializer {
+			w := httptest.NewRecorder()
+			req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+				Email:    "user1@user.com",
+				Password: "password",
+			})
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusAccepted, w.Code)
+			assert.NotContains(t, w.Body.String(), `"token"`)
+
+			challenge, err := serializers.TwoFactorChallengeSerializerFromJSON(w.Body.String())
+			if err != nil {
+				t.Fatalf("Failed to parse response: '%s'", err)
+			}
+			assert.False(t, challenge.EnrollmentRequired)
+			return challenge
+		}
+
+		// the code used for the activation cannot be reused
+		challenge := login()
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      generateTotpCode(t, enrollment.Secret, now),
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      generateTotpCode(t, enrollment.Secret, now.Add(totp.Period*time.Second)),
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"token"`)
+
+		// the challenge cannot be reused
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      recoveryCodes.RecoveryCodes[0],
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// recovery codes can be used once
+		challenge = login()
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      recoveryCodes.RecoveryCodes[0],
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		challenge = login()
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      recoveryCodes.RecoveryCodes[0],
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		left, err := models.CountUnusedRecoveryCodes(*user)
+		if err != nil {
+			t.Fatalf("Failed to count recovery codes: '%s'", err)
+		}
+		assert.Equal(t, int64(models.RecoveryCodesCount-1), left)
+	})
+}
+
+/*
+When two-factor authentication is required for superusers they enroll during
+the login, sessions created without the second factor cannot be used by the cli
+*/
+func TestTwoFactorRequiredForSuperusers(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		s, err := models.GetSingletonModelInstance[models.AuthenticationSettings]()
+		if err != nil {
+			t.Fatalf("Failed to retrieve authentication settings: '%s'", err)
+		}
+		s.TwoFactorRequiredForSuperusers = true
+		if err := models.SaveSingletonModel(s); err != nil {
+			t.Fatalf("Failed to save authentication settings: '%s'", err)
+		}
+
+		admin, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || admin == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		// a session created before the second factor was required
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/cli-login", "POST", nil)
+		testutils.AuthenticateHttpRequest(t, req, *admin)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// users that are not superusers are not affected
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/cli-login", "POST", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// the admin enrolls during the login
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "admin@admin.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		challenge, err := serializers.TwoFactorChallengeSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+		assert.True(t, challenge.EnrollmentRequired)
+		if challenge.Enrollment == nil {
+			t.Fatalf("The enrollment secret has not been returned")
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      generateTotpCode(t, challenge.Enrollment.Secret, time.Now()),
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"recovery_codes"`)
+
+		var sessionCookie *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Value != "" {
+				sessionCookie = cookie
+			}
+		}
+		if sessionCookie == nil {
+			t.Fatalf("The authentication cookie has not been set")
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/cli-login", "POST", nil)
+		req.AddCookie(sessionCookie)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// the second factor cannot be disabled while it is required
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/two-factor/disable", "POST", auth.TwoFactorCodeRequestBody{
+			Code: "000000",
+		})
+		req.AddCookie(sessionCookie)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+/*
+The wrong codes are counted atomically, concurrent requests
+cannot check more codes than allowed for a login
+*/
+func TestTwoFactorLoginConcurrentAttempts(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		secret := totp.GenerateSecret()
+		if err := models.EnableTotp(user, secret, 0); err != nil {
+			t.Fatalf("Failed to enable two-factor authentication: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login", "POST", auth.LoginRequestBody{
+			Email:    "user1@user.com",
+			Password: "password",
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+
+		challenge, err := serializers.TwoFactorChallengeSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		invalidCodes := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				req := testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+					Challenge: challenge.Challenge,
+					Code:      "000000",
+				})
+				router.ServeHTTP(w, req)
+
+				mu.Lock()
+				defer mu.Unlock()
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+				if strings.Contains(w.Body.String(), `"invalid code"`) {
+					invalidCodes++
+				}
+			}()
+		}
+		wg.Wait()
+
+		// the last allowed attempt cancels the login
+		assert.Equal(t, 4, invalidCodes)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/auth/login/two-factor", "POST", auth.TwoFactorLoginRequestBody{
+			Challenge: challenge.Challenge,
+			Code:      generateTotpCode(t, secret, time.Now()),
+		})
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "login again")
+	})
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
@@ -51,6 +52,7 @@ func HandleRetriveUserDetails(c *gin.Context) {
 type HandleUpdateUserDetailsRequestBody struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
+	TimeZone  string `json:"time_zone"`
 }
 
 // HandleUpdateUserDetails godoc
@@ -89,6 +91,19 @@ func HandleUpdateUserDetails(c *gin.Context) {
 		return
 	}
 
+	// the time zone is optional, if it is not provided the current one is kept
+	if requestBody.TimeZone != "" {
+		if _, err := time.LoadLocation(requestBody.TimeZone); err != nil {
+			utils.ErrorResponse(
+				c,
+				http.StatusBadRequest,
+				"invalid time zone",
+			)
+			return
+		}
+		user.TimeZone = requestBody.TimeZone
+	}
+
 	user.FirstName = requestBody.FirstName
 	user.LastName = requestBody.LastName
 	dbconn.DB.Save(&user)
@@ -130,18 +145,22 @@ func HandleRetrieveUserPublicKey(c *gin.Context) {
 type ChangePasswordRequestBody struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
 	NewPassword     string `json:"new_password" binding:"required"`
+	// the other sessions and the personal access tokens of the user are revoked unless it is false
+	RevokeOtherSessions *bool `json:"revoke_other_sessions"`
 }
 
 // HandleChangePassword godoc
 // @Summary Change user password
 // @Schemes
 // @Description Change password of the currently authenticated user.
+// @Description The other sessions and the personal access tokens of the user are revoked
+// @Description unless revoke_other_sessions is false.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body ChangePasswordRequestBody true "Request Data"
 // @Success 200 "Password changed successfully"
-// @Failure 400 "Missing or invalid field"
+// @Failure 400 "Missing or invalid field or password managed by the directory"
 // @Failure 401 "Unauthorized"
 // @Failure 417 "Invalid current password"
 // @Failure 500 "Internal server error"
@@ -168,6 +187,15 @@ func HandleChangePassword(c *gin.Context) {
 		return
 	}
 
+	if user.IsLdapUser() {
+		utils.ErrorResponse(
+			c,
+			http.StatusBadRequest,
+			"the password is managed by the directory",
+		)
+		return
+	}
+
 	// check if current password is correct
 	// the current password is used to check that the user that
 	// is trying to perform this operation is the owner of the account
@@ -204,6 +232,38 @@ func HandleChangePassword(c *gin.Context) {
 	}
 
 	dbconn.DB.Save(&user)
+
+	if parsedBody.RevokeOtherSessions == nil || *parsedBody.RevokeOtherSessions {
+		token, err := utils.GetTokenFromContext(c)
+		if err != nil {
+			utils.ErrorResponse(
+				c,
+				http.StatusInternalServerError,
+				"internal server error",
+			)
+			return
+		}
+
+		if err := models.RevokeOtherAuthTokensForUser(user, token); err != nil {
+			utils.ErrorResponse(
+				c,
+				http.StatusInternalServerError,
+				"internal server error",
+			)
+			return
+		}
+
+		// the cli logins are personal access tokens
+		if err := models.RevokeAllPersonalAccessTokensForUser(user); err != nil {
+			utils.ErrorResponse(
+				c,
+				http.StatusInternalServerError,
+				"internal server error",
+			)
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"detail": "password changed",
 	})
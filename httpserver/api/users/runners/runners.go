@@ -12,14 +12,22 @@ import (
 // List runners godoc
 // @Summary List runners
 // @Schemes
-// @Description List runners
+// @Description List the runners that the current user is allowed to use
 // @Tags Runners
 // @Accept json
 // @Produce json
 // @Success 200 {object} []serializers.RunnerSerializer
 // @Router /api/v1/runners [get]
 func HandleListRunners(c *gin.Context) {
-	runners, err := models.ListRunners(-1, 0)
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(
+			c, http.StatusInternalServerError, "internal server error",
+		)
+		return
+	}
+
+	runners, err := models.ListRunnersAllowedForUser(user)
 	if err != nil {
 		utils.ErrorResponse(
 			c, http.StatusInternalServerError, "internal server error",
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+/*
+Retrieve the secrets scope of the group referenced by the groupId param,
+an error response is sent and nil is returned if the group does not exist
+*/
+func getGroupSecretsScope(c *gin.Context) *secretsScope {
+	id, err := utils.GetUIntParamFromContext(c, "groupId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "group not found")
+		return nil
+	}
+
+	group, err := models.RetrieveGroupByID(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if group == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "group not found")
+		return nil
+	}
+
+	return &secretsScope{
+		list: func() ([]models.Secret, error) {
+			return models.ListGroupSecrets(*group)
+		},
+		retrieve: func(id uint) (*models.Secret, error) {
+			return models.RetrieveGroupSecretByID(*group, id)
+		},
+		create: func(name string, value string) (*models.Secret, error) {
+			return models.CreateGroupSecret(*group, name, value)
+		},
+	}
+}
+
+// HandleAdminListGroupSecrets godoc
+// @Summary List group secrets
+// @Schemes
+// @Description List the secrets of a group, values are never returned
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.SecretSerializer
+// @Router /api/v1/admin/groups/:groupId/secrets [get]
+func HandleAdminListGroupSecrets(c *gin.Context) {
+	if scope := getGroupSecretsScope(c); scope != nil {
+		listSecrets(c, *scope)
+	}
+}
+
+// HandleAdminCreateGroupSecret godoc
+// @Summary Create a group secret
+// @Schemes
+// @Description Create a secret injected in the workspaces of all the members of a group
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body CreateSecretRequestBody true "Secret"
+// @Success 201 {object} serializers.SecretSerializer
+// @Router /api/v1/admin/groups/:groupId/secrets [post]
+func HandleAdminCreateGroupSecret(c *gin.Context) {
+	if scope := getGroupSecretsScope(c); scope != nil {
+		createSecret(c, *scope)
+	}
+}
+
+// HandleAdminUpdateGroupSecret godoc
+// @Summary Update a group secret
+// @Schemes
+// @Description Replace the value of a secret of a group
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param request body UpdateSecretRequestBody true "Secret"
+// @Success 200 {object} serializers.SecretSerializer
+// @Router /api/v1/admin/groups/:groupId/secrets/:secretId [put]
+func HandleAdminUpdateGroupSecret(c *gin.Context) {
+	if scope := getGroupSecretsScope(c); scope != nil {
+		updateSecret(c, *scope)
+	}
+}
+
+// HandleAdminDeleteGroupSecret godoc
+// @Summary Delete a group secret
+// @Schemes
+// @Description Delete a secret of a group
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/admin/groups/:groupId/secrets/:secretId [delete]
+func HandleAdminDeleteGroupSecret(c *gin.Context) {
+	if scope := getGroupSecretsScope(c); scope != nil {
+		deleteSecret(c, *scope)
+	}
+}
@@ -0,0 +1,144 @@
+package secrets
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/utils/secrets"
+)
+
+type CreateSecretRequestBody struct {
+	Name  string `json:"name" binding:"required"`
+	Value string `json:"value"`
+}
+
+type UpdateSecretRequestBody struct {
+	Value string `json:"value"`
+}
+
+/*
+secretsScope contains the functions used to access
+the secrets of a user, of a group or of a workspace
+*/
+type secretsScope struct {
+	list     func() ([]models.Secret, error)
+	retrieve func(id uint) (*models.Secret, error)
+	create   func(name string, value string) (*models.Secret, error)
+}
+
+/*
+Send an error response for an error returned while creating
+or updating a secret
+*/
+func respondSecretError(c *gin.Context, err error) {
+	var invalidSecretErr *models.InvalidSecretError
+	if errors.As(err, &invalidSecretErr) {
+		utils.ErrorResponse(c, http.StatusBadRequest, invalidSecretErr.Error())
+		return
+	}
+
+	if errors.Is(err, models.ErrSecretAlreadyExists) {
+		utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		return
+	}
+
+	if errors.Is(err, secrets.ErrMasterKeyNotConfigured) {
+		utils.ErrorResponse(
+			c,
+			http.StatusFailedDependency,
+			"secrets are not enabled, ask the administrator to set CODEBOX_SECRETS_MASTER_KEY",
+		)
+		return
+	}
+
+	utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+}
+
+func listSecrets(c *gin.Context, scope secretsScope) {
+	secretsList, err := scope.list()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadMultipleSecretSerializer(secretsList))
+}
+
+func createSecret(c *gin.Context, scope secretsScope) {
+	var reqBody CreateSecretRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	secret, err := scope.create(reqBody.Name, reqBody.Value)
+	if err != nil {
+		respondSecretError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, serializers.LoadSecretSerializer(secret))
+}
+
+func updateSecret(c *gin.Context, scope secretsScope) {
+	secret := getSecretFromContext(c, scope)
+	if secret == nil {
+		return
+	}
+
+	var reqBody UpdateSecretRequestBody
+	if err := c.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "missing or invalid field")
+		return
+	}
+
+	secret, err := models.UpdateSecretValue(secret, reqBody.Value)
+	if err != nil {
+		respondSecretError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, serializers.LoadSecretSerializer(secret))
+}
+
+func deleteSecret(c *gin.Context, scope secretsScope) {
+	secret := getSecretFromContext(c, scope)
+	if secret == nil {
+		return
+	}
+
+	if err := models.DeleteSecret(*secret); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{"detail": "secret has been deleted"})
+}
+
+/*
+Retrieve the secret referenced by the secretId param, an error
+response is sent and nil is returned if the secret does not exist
+*/
+func getSecretFromContext(c *gin.Context, scope secretsScope) *models.Secret {
+	id, err := utils.GetUIntParamFromContext(c, "secretId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "secret not found")
+		return nil
+	}
+
+	secret, err := scope.retrieve(id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if secret == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "secret not found")
+		return nil
+	}
+	return secret
+}
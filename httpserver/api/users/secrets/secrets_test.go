@@ -0,0 +1,173 @@
+package secrets_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/secrets"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Create, list, update and delete a secret of the current user,
+the value must never be returned and must be stored encrypted
+*/
+func TestUserSecrets(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		masterKey := config.Environment.SecretsMasterKey
+		config.Environment.SecretsMasterKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+		defer func() { config.Environment.SecretsMasterKey = masterKey }()
+
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		// create the secret
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/secrets",
+			"POST",
+			secrets.CreateSecretRequestBody{Name: "GITHUB_TOKEN", Value: "ghp_secret-value"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.False(t, strings.Contains(w.Body.String(), "ghp_secret-value"))
+
+		// a secret with the same name cannot be created
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/secrets",
+			"POST",
+			secrets.CreateSecretRequestBody{Name: "GITHUB_TOKEN", Value: "other"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		// invalid names are rejected
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/secrets",
+			"POST",
+			secrets.CreateSecretRequestBody{Name: "INVALID-NAME", Value: "value"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// list secrets
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/secrets", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.False(t, strings.Contains(w.Body.String(), "ghp_secret-value"))
+
+		secretsList, err := serializers.MultipleSecretSerializersFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse secrets: '%s'", err)
+		}
+		assert.Len(t, secretsList, 1)
+		assert.Equal(t, "GITHUB_TOKEN", secretsList[0].Name)
+		assert.Equal(t, models.SecretScopeUser, secretsList[0].Scope)
+
+		// the value is stored encrypted
+		secret, err := models.RetrieveUserSecretByID(*user, secretsList[0].ID)
+		if err != nil || secret == nil {
+			t.Fatalf("Failed to retrieve secret: '%s'", err)
+		}
+		assert.NotEqual(t, "ghp_secret-value", secret.EncryptedValue)
+
+		value, err := secret.GetValue()
+		assert.Nil(t, err)
+		assert.Equal(t, "ghp_secret-value", value)
+
+		// other users cannot access the secret
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			fmt.Sprintf("/api/v1/secrets/%d", secret.ID),
+			"PUT",
+			secrets.UpdateSecretRequestBody{Value: "new-value"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// update the value
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			fmt.Sprintf("/api/v1/secrets/%d", secret.ID),
+			"PUT",
+			secrets.UpdateSecretRequestBody{Value: "new-value"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		secret, _ = models.RetrieveUserSecretByID(*user, secret.ID)
+		value, _ = secret.GetValue()
+		assert.Equal(t, "new-value", value)
+
+		// delete the secret
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", fmt.Sprintf("/api/v1/secrets/%d", secret.ID), nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		secret, err = models.RetrieveUserSecretByID(*user, secret.ID)
+		assert.Nil(t, err)
+		assert.Nil(t, secret)
+	})
+}
+
+/*
+Secrets cannot be created if the master key is not set
+*/
+func TestCreateSecretWithoutMasterKey(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		masterKey := config.Environment.SecretsMasterKey
+		config.Environment.SecretsMasterKey = ""
+		defer func() { config.Environment.SecretsMasterKey = masterKey }()
+
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/secrets",
+			"POST",
+			secrets.CreateSecretRequestBody{Name: "GITHUB_TOKEN", Value: "value"},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusFailedDependency, w.Code)
+	})
+}
@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+/*
+Retrieve the secrets scope of the current user, an error
+response is sent and nil is returned if the user cannot be retrieved
+*/
+func getUserSecretsScope(c *gin.Context) *secretsScope {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	return &secretsScope{
+		list: func() ([]models.Secret, error) {
+			return models.ListUserSecrets(user)
+		},
+		retrieve: func(id uint) (*models.Secret, error) {
+			return models.RetrieveUserSecretByID(user, id)
+		},
+		create: func(name string, value string) (*models.Secret, error) {
+			return models.CreateUserSecret(user, name, value)
+		},
+	}
+}
+
+// HandleListUserSecrets godoc
+// @Summary List user secrets
+// @Schemes
+// @Description List the secrets of the current user, values are never returned
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.SecretSerializer
+// @Router /api/v1/secrets [get]
+func HandleListUserSecrets(c *gin.Context) {
+	if scope := getUserSecretsScope(c); scope != nil {
+		listSecrets(c, *scope)
+	}
+}
+
+// HandleCreateUserSecret godoc
+// @Summary Create a user secret
+// @Schemes
+// @Description Create a secret injected in all the workspaces of the current user
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param request body CreateSecretRequestBody true "Secret"
+// @Success 201 {object} serializers.SecretSerializer
+// @Router /api/v1/secrets [post]
+func HandleCreateUserSecret(c *gin.Context) {
+	if scope := getUserSecretsScope(c); scope != nil {
+		createSecret(c, *scope)
+	}
+}
+
+// HandleUpdateUserSecret godoc
+// @Summary Update a user secret
+// @Schemes
+// @Description Replace the value of a secret of the current user
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param request body UpdateSecretRequestBody true "Secret"
+// @Success 200 {object} serializers.SecretSerializer
+// @Router /api/v1/secrets/:secretId [put]
+func HandleUpdateUserSecret(c *gin.Context) {
+	if scope := getUserSecretsScope(c); scope != nil {
+		updateSecret(c, *scope)
+	}
+}
+
+// HandleDeleteUserSecret godoc
+// @Summary Delete a user secret
+// @Schemes
+// @Description Delete a secret of the current user
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/secrets/:secretId [delete]
+func HandleDeleteUserSecret(c *gin.Context) {
+	if scope := getUserSecretsScope(c); scope != nil {
+		deleteSecret(c, *scope)
+	}
+}
@@ -0,0 +1,119 @@
+package secrets
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+/*
+Retrieve the secrets scope of the workspace referenced by the workspaceId
+param, an error response is sent and nil is returned if the workspace does not exist
+or if the current user is not its owner or one of its collaborators with the admin role
+*/
+func getWorkspaceSecretsScope(c *gin.Context) *secretsScope {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	id, err := utils.GetUIntParamFromContext(c, "workspaceId")
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "workspace not found")
+		return nil
+	}
+
+	workspace, role, err := models.RetrieveWorkspaceWithRoleByUserAndId(user, id)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if workspace == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "workspace not found")
+		return nil
+	}
+
+	if !models.WorkspaceRoleAllows(role, models.WorkspaceRoleAdmin) {
+		utils.ErrorResponse(c, http.StatusForbidden, "you don't have the permission to perform this action")
+		return nil
+	}
+
+	return &secretsScope{
+		list: func() ([]models.Secret, error) {
+			return models.ListWorkspaceSecrets(*workspace)
+		},
+		retrieve: func(id uint) (*models.Secret, error) {
+			return models.RetrieveWorkspaceSecretByID(*workspace, id)
+		},
+		create: func(name string, value string) (*models.Secret, error) {
+			return models.CreateWorkspaceSecret(*workspace, name, value)
+		},
+	}
+}
+
+// HandleListWorkspaceSecrets godoc
+// @Summary List workspace secrets
+// @Schemes
+// @Description List the secrets of a workspace, values are never returned
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.SecretSerializer
+// @Router /api/v1/workspace/:workspaceId/secrets [get]
+func HandleListWorkspaceSecrets(c *gin.Context) {
+	if scope := getWorkspaceSecretsScope(c); scope != nil {
+		listSecrets(c, *scope)
+	}
+}
+
+// HandleCreateWorkspaceSecret godoc
+// @Summary Create a workspace secret
+// @Schemes
+// @Description Create a secret injected in a workspace, it overrides user and group
+// @Description secrets with the same name. It is applied on the next start of the workspace
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param request body CreateSecretRequestBody true "Secret"
+// @Success 201 {object} serializers.SecretSerializer
+// @Router /api/v1/workspace/:workspaceId/secrets [post]
+func HandleCreateWorkspaceSecret(c *gin.Context) {
+	if scope := getWorkspaceSecretsScope(c); scope != nil {
+		createSecret(c, *scope)
+	}
+}
+
+// HandleUpdateWorkspaceSecret godoc
+// @Summary Update a workspace secret
+// @Schemes
+// @Description Replace the value of a secret of a workspace
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Param request body UpdateSecretRequestBody true "Secret"
+// @Success 200 {object} serializers.SecretSerializer
+// @Router /api/v1/workspace/:workspaceId/secrets/:secretId [put]
+func HandleUpdateWorkspaceSecret(c *gin.Context) {
+	if scope := getWorkspaceSecretsScope(c); scope != nil {
+		updateSecret(c, *scope)
+	}
+}
+
+// HandleDeleteWorkspaceSecret godoc
+// @Summary Delete a workspace secret
+// @Schemes
+// @Description Delete a secret of a workspace
+// @Tags Secrets
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/workspace/:workspaceId/secrets/:secretId [delete]
+func HandleDeleteWorkspaceSecret(c *gin.Context) {
+	if scope := getWorkspaceSecretsScope(c); scope != nil {
+		deleteSecret(c, *scope)
+	}
+}
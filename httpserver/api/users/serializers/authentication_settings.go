@@ -7,12 +7,14 @@ import (
 )
 
 type AuthenticationSettingsSerializer struct {
-	IsSignUpOpen                bool   `json:"is_signup_open"`
-	IsSignUpRestricted          bool   `json:"is_signup_restricted"`
-	AllowedEmailRegex           string `json:"allowed_emails_regex"`
-	BlockedEmailRegex           string `json:"blocked_emails_regex"`
-	UsersMustBeApproved         bool   `json:"users_must_be_approved"`
-	ApprovedByDefaultEmailRegex string `json:"approved_by_default_emails_regex"`
+	IsSignUpOpen                         bool   `json:"is_signup_open"`
+	IsSignUpRestricted                   bool   `json:"is_signup_restricted"`
+	AllowedEmailRegex                    string `json:"allowed_emails_regex"`
+	BlockedEmailRegex                    string `json:"blocked_emails_regex"`
+	UsersMustBeApproved                  bool   `json:"users_must_be_approved"`
+	ApprovedByDefaultEmailRegex          string `json:"approved_by_default_emails_regex"`
+	TwoFactorRequiredForSuperusers       bool   `json:"two_factor_required_for_superusers"`
+	TwoFactorRequiredForTemplateManagers bool   `json:"two_factor_required_for_template_managers"`
 }
 
 func LoadAuthenticationSettingsSerializer(s *models.AuthenticationSettings) *AuthenticationSettingsSerializer {
@@ -21,12 +23,14 @@ func LoadAuthenticationSettingsSerializer(s *models.AuthenticationSettings) *Aut
 	}
 
 	return &AuthenticationSettingsSerializer{
-		IsSignUpOpen:                s.IsSignUpOpen,
-		IsSignUpRestricted:          s.IsSignUpRestricted,
-		AllowedEmailRegex:           s.AllowedEmailRegex,
-		BlockedEmailRegex:           s.BlockedEmailRegex,
-		UsersMustBeApproved:         s.UsersMustBeApproved,
-		ApprovedByDefaultEmailRegex: s.ApprovedByDefaultEmailRegex,
+		IsSignUpOpen:                         s.IsSignUpOpen,
+		IsSignUpRestricted:                   s.IsSignUpRestricted,
+		AllowedEmailRegex:                    s.AllowedEmailRegex,
+		BlockedEmailRegex:                    s.BlockedEmailRegex,
+		UsersMustBeApproved:                  s.UsersMustBeApproved,
+		ApprovedByDefaultEmailRegex:          s.ApprovedByDefaultEmailRegex,
+		TwoFactorRequiredForSuperusers:       s.TwoFactorRequiredForSuperusers,
+		TwoFactorRequiredForTemplateManagers: s.TwoFactorRequiredForTemplateManagers,
 	}
 }
 
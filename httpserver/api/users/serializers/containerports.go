@@ -33,7 +33,7 @@ func LoadWorkspaceContainerPort(port *models.WorkspaceContainerPort) *WorkspaceC
 		)
 	} else {
 		portUrl = fmt.Sprintf(
-			"%s/views/port-forward/workspace/%d/container/%s/port/%d",
+			"%s/p/%d/%s/%d/",
 			config.Environment.ExternalUrl,
 			port.Container.WorkspaceID,
 			port.Container.ContainerName,
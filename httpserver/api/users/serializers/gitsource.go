@@ -3,9 +3,11 @@ package serializers
 import "gitlab.com/codebox4073715/codebox/db/models"
 
 type GitWorkspaceSourceSerializer struct {
-	RepositoryURL  string `json:"repository_url"`
-	RefName        string `json:"ref_name"`
-	ConfigFilePath string `json:"config_file_relative_path"`
+	RepositoryURL         string              `json:"repository_url"`
+	RefName               string              `json:"ref_name"`
+	ConfigFilePath        string              `json:"config_file_relative_path"`
+	RequiredRunnerLabels  models.RunnerLabels `json:"required_runner_labels"`
+	PreferredRunnerLabels models.RunnerLabels `json:"preferred_runner_labels"`
 }
 
 func LoadGitWorkspaceSourceSerializer(gitSource *models.GitWorkspaceSource) *GitWorkspaceSourceSerializer {
@@ -14,9 +16,11 @@ func LoadGitWorkspaceSourceSerializer(gitSource *models.GitWorkspaceSource) *Git
 	}
 
 	return &GitWorkspaceSourceSerializer{
-		RepositoryURL:  gitSource.RepositoryURL,
-		RefName:        gitSource.RefName,
-		ConfigFilePath: gitSource.ConfigFilePath,
+		RepositoryURL:         gitSource.RepositoryURL,
+		RefName:               gitSource.RefName,
+		ConfigFilePath:        gitSource.ConfigFilePath,
+		RequiredRunnerLabels:  gitSource.RequiredRunnerLabels,
+		PreferredRunnerLabels: gitSource.PreferredRunnerLabels,
 	}
 }
 
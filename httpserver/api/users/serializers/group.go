@@ -0,0 +1,84 @@
+package serializers
+
+import (
+	"encoding/json"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type GroupSerializer struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+func LoadGroupSerializer(group *models.Group) *GroupSerializer {
+	if group == nil {
+		return nil
+	}
+
+	return &GroupSerializer{
+		ID:   group.ID,
+		Name: group.Name,
+	}
+}
+
+func LoadMultipleGroupSerializer(groups []models.Group) []GroupSerializer {
+	serializers := make([]GroupSerializer, len(groups))
+	for i, group := range groups {
+		serializers[i] = *LoadGroupSerializer(&group)
+	}
+	return serializers
+}
+
+// admin
+type AdminGroupSerializer struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	MembersCount int64  `json:"members_count"`
+	CreatedAt    string `json:"created_at"`
+}
+
+func LoadAdminGroupSerializer(group *models.Group) *AdminGroupSerializer {
+	if group == nil {
+		return nil
+	}
+
+	membersCount, err := models.CountGroupMembers(*group)
+	if err != nil {
+		membersCount = 0
+	}
+
+	return loadAdminGroupSerializerWithMembersCount(group, membersCount)
+}
+
+func loadAdminGroupSerializerWithMembersCount(group *models.Group, membersCount int64) *AdminGroupSerializer {
+	return &AdminGroupSerializer{
+		ID:           group.ID,
+		Name:         group.Name,
+		MembersCount: membersCount,
+		CreatedAt:    group.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func LoadMultipleAdminGroupSerializer(groups []models.Group) []AdminGroupSerializer {
+	// the members of all the groups are counted at once
+	membersCounts, err := models.CountMembersByGroup(groups)
+	if err != nil {
+		membersCounts = map[uint]int64{}
+	}
+
+	serializers := make([]AdminGroupSerializer, len(groups))
+	for i, group := range groups {
+		serializers[i] = *loadAdminGroupSerializerWithMembersCount(&group, membersCounts[group.ID])
+	}
+	return serializers
+}
+
+func AdminGroupSerializerFromJSON(data string) (AdminGroupSerializer, error) {
+	var group AdminGroupSerializer
+	if err := json.Unmarshal([]byte(data), &group); err != nil {
+		return AdminGroupSerializer{}, err
+	}
+	return group, nil
+}
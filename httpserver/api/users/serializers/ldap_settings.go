@@ -0,0 +1,53 @@
+package serializers
+
+import (
+	"encoding/json"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type LDAPSettingsSerializer struct {
+	Enabled            bool   `json:"enabled"`
+	Url                string `json:"url"`
+	StartTLS           bool   `json:"start_tls"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	BindDN             string `json:"bind_dn"`
+	BindPasswordSet    bool   `json:"bind_password_set"` // the password is never returned
+	UserBaseDN         string `json:"user_base_dn"`
+	UserFilter         string `json:"user_filter"`
+	EmailAttribute     string `json:"email_attribute"`
+	FirstNameAttribute string `json:"first_name_attribute"`
+	LastNameAttribute  string `json:"last_name_attribute"`
+	GroupsAttribute    string `json:"groups_attribute"`
+	AutoProvisionUsers bool   `json:"auto_provision_users"`
+}
+
+func LoadLDAPSettingsSerializer(s *models.LDAPSettings) *LDAPSettingsSerializer {
+	if s == nil {
+		return nil
+	}
+
+	return &LDAPSettingsSerializer{
+		Enabled:            s.Enabled,
+		Url:                s.Url,
+		StartTLS:           s.StartTLS,
+		InsecureSkipVerify: s.InsecureSkipVerify,
+		BindDN:             s.BindDN,
+		BindPasswordSet:    s.BindPassword != "",
+		UserBaseDN:         s.UserBaseDN,
+		UserFilter:         s.UserFilter,
+		EmailAttribute:     s.EmailAttribute,
+		FirstNameAttribute: s.FirstNameAttribute,
+		LastNameAttribute:  s.LastNameAttribute,
+		GroupsAttribute:    s.GroupsAttribute,
+		AutoProvisionUsers: s.AutoProvisionUsers,
+	}
+}
+
+func LDAPSettingsSerializerFromJSON(data string) (LDAPSettingsSerializer, error) {
+	var s LDAPSettingsSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return LDAPSettingsSerializer{}, err
+	}
+	return s, nil
+}
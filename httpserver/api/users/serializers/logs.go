@@ -0,0 +1,32 @@
+package serializers
+
+import (
+	"encoding/json"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// offsets are in bytes
+type WorkspaceLogsSerializer struct {
+	Logs       string `json:"logs"`
+	Offset     int64  `json:"offset"`
+	NextOffset int64  `json:"next_offset"`
+	Size       int64  `json:"size"`
+}
+
+func LoadWorkspaceLogsSerializer(chunk models.WorkspaceLogsChunk) WorkspaceLogsSerializer {
+	return WorkspaceLogsSerializer{
+		Logs:       chunk.Logs,
+		Offset:     chunk.Offset,
+		NextOffset: chunk.NextOffset,
+		Size:       chunk.Size,
+	}
+}
+
+func WorkspaceLogsSerializerFromJSON(data string) (WorkspaceLogsSerializer, error) {
+	var logs WorkspaceLogsSerializer
+	if err := json.Unmarshal([]byte(data), &logs); err != nil {
+		return WorkspaceLogsSerializer{}, err
+	}
+	return logs, nil
+}
@@ -0,0 +1,79 @@
+package serializers
+
+import (
+	"encoding/json"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type OIDCSettingsSerializer struct {
+	Enabled               bool   `json:"enabled"`
+	ProviderName          string `json:"provider_name"`
+	IssuerUrl             string `json:"issuer_url"`
+	ClientID              string `json:"client_id"`
+	ClientSecretSet       bool   `json:"client_secret_set"` // the secret is never returned
+	Scopes                string `json:"scopes"`
+	EmailClaim            string `json:"email_claim"`
+	FirstNameClaim        string `json:"first_name_claim"`
+	LastNameClaim         string `json:"last_name_claim"`
+	GroupsClaim           string `json:"groups_claim"`
+	AutoProvisionUsers    bool   `json:"auto_provision_users"`
+	PasswordLoginDisabled bool   `json:"password_login_disabled"`
+	RedirectUrl           string `json:"redirect_url"`
+}
+
+func LoadOIDCSettingsSerializer(s *models.OIDCSettings) *OIDCSettingsSerializer {
+	if s == nil {
+		return nil
+	}
+
+	return &OIDCSettingsSerializer{
+		Enabled:               s.Enabled,
+		ProviderName:          s.ProviderName,
+		IssuerUrl:             s.IssuerUrl,
+		ClientID:              s.ClientID,
+		ClientSecretSet:       s.ClientSecret != "",
+		Scopes:                s.Scopes,
+		EmailClaim:            s.EmailClaim,
+		FirstNameClaim:        s.FirstNameClaim,
+		LastNameClaim:         s.LastNameClaim,
+		GroupsClaim:           s.GroupsClaim,
+		AutoProvisionUsers:    s.AutoProvisionUsers,
+		PasswordLoginDisabled: s.PasswordLoginDisabled,
+		RedirectUrl:           s.GetRedirectUrl(),
+	}
+}
+
+func OIDCSettingsSerializerFromJSON(data string) (OIDCSettingsSerializer, error) {
+	var s OIDCSettingsSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return OIDCSettingsSerializer{}, err
+	}
+	return s, nil
+}
+
+type LoginOptionsSerializer struct {
+	PasswordLoginEnabled bool   `json:"password_login_enabled"`
+	OIDCEnabled          bool   `json:"oidc_enabled"`
+	OIDCProviderName     string `json:"oidc_provider_name"`
+	OIDCLoginUrl         string `json:"oidc_login_url"`
+	LDAPEnabled          bool   `json:"ldap_enabled"` // the password is the one of the directory
+}
+
+func LoadLoginOptionsSerializer(s *models.OIDCSettings, ldapSettings *models.LDAPSettings) *LoginOptionsSerializer {
+	if s == nil || ldapSettings == nil {
+		return nil
+	}
+
+	serializer := &LoginOptionsSerializer{
+		PasswordLoginEnabled: !s.Enabled || !s.PasswordLoginDisabled,
+		OIDCEnabled:          s.Enabled,
+		LDAPEnabled:          ldapSettings.Enabled,
+	}
+
+	if s.Enabled {
+		serializer.OIDCProviderName = s.ProviderName
+		serializer.OIDCLoginUrl = "/api/v1/auth/oidc/login"
+	}
+	return serializer
+}
@@ -0,0 +1,56 @@
+package serializers
+
+import (
+	"encoding/json"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type PersonalAccessTokenSerializer struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Token       string     `json:"token,omitempty"` // returned only when the token is created
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+/*
+LoadPersonalAccessTokenSerializer serializes a personal access token,
+the value of the token is set only when it has just been created
+*/
+func LoadPersonalAccessTokenSerializer(token *models.PersonalAccessToken, value string) *PersonalAccessTokenSerializer {
+	if token == nil {
+		return nil
+	}
+
+	return &PersonalAccessTokenSerializer{
+		ID:          token.ID,
+		Name:        token.Name,
+		TokenPrefix: token.TokenPrefix,
+		Token:       value,
+		Scopes:      token.GetScopes(),
+		ExpiresAt:   token.ExpiresAt,
+		LastUsedAt:  token.LastUsedAt,
+		CreatedAt:   token.CreatedAt,
+	}
+}
+
+func LoadMultiplePersonalAccessTokenSerializer(tokens []models.PersonalAccessToken) []PersonalAccessTokenSerializer {
+	serializers := make([]PersonalAccessTokenSerializer, len(tokens))
+	for i, token := range tokens {
+		serializers[i] = *LoadPersonalAccessTokenSerializer(&token, "")
+	}
+	return serializers
+}
+
+func PersonalAccessTokenSerializerFromJSON(data string) (PersonalAccessTokenSerializer, error) {
+	var s PersonalAccessTokenSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return PersonalAccessTokenSerializer{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,75 @@
+package serializers
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type PortShareTokenSerializer struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Token       string    `json:"token"`
+	ShareUrl    string    `json:"share_url"`
+	HasPassword bool      `json:"has_password"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	MaxUses     *uint     `json:"max_uses"`
+	Uses        uint      `json:"uses"`
+	CreatedBy   *string   `json:"created_by"` // email of the user who created the token
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+/*
+LoadPortShareTokenSerializer serializes a share token of a port,
+the url of the port is used to build the link to share
+*/
+func LoadPortShareTokenSerializer(token *models.PortShareToken, port *models.WorkspaceContainerPort) *PortShareTokenSerializer {
+	if token == nil || port == nil {
+		return nil
+	}
+
+	shareUrl := ""
+	if u, err := url.Parse(LoadWorkspaceContainerPort(port).PortUrl); err == nil {
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		u.RawQuery = url.Values{models.PortShareTokenQueryParam: {token.Token}}.Encode()
+		shareUrl = u.String()
+	}
+
+	var createdBy *string
+	if token.CreatedBy != nil {
+		createdBy = &token.CreatedBy.Email
+	}
+
+	return &PortShareTokenSerializer{
+		ID:          token.ID,
+		Name:        token.Name,
+		Token:       token.Token,
+		ShareUrl:    shareUrl,
+		HasPassword: token.HasPassword(),
+		ExpiresAt:   token.ExpiresAt,
+		MaxUses:     token.MaxUses,
+		Uses:        token.Uses,
+		CreatedBy:   createdBy,
+		CreatedAt:   token.CreatedAt,
+	}
+}
+
+func LoadMultiplePortShareTokenSerializer(tokens []models.PortShareToken, port *models.WorkspaceContainerPort) []PortShareTokenSerializer {
+	serializers := make([]PortShareTokenSerializer, len(tokens))
+	for i, token := range tokens {
+		serializers[i] = *LoadPortShareTokenSerializer(&token, port)
+	}
+	return serializers
+}
+
+func PortShareTokenSerializerFromJSON(data string) (PortShareTokenSerializer, error) {
+	var token PortShareTokenSerializer
+	if err := json.Unmarshal([]byte(data), &token); err != nil {
+		return PortShareTokenSerializer{}, err
+	}
+	return token, nil
+}
@@ -0,0 +1,81 @@
+package serializers
+
+import (
+	"encoding/json"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type QuotaLimitsSerializer struct {
+	MaxWorkspaces             *uint `json:"max_workspaces"`
+	MaxRunningWorkspaces      *uint `json:"max_running_workspaces"`
+	MaxContainersPerWorkspace *uint `json:"max_containers_per_workspace"`
+}
+
+func LoadQuotaLimitsSerializer(limits models.QuotaLimits) *QuotaLimitsSerializer {
+	return &QuotaLimitsSerializer{
+		MaxWorkspaces:             limits.MaxWorkspaces,
+		MaxRunningWorkspaces:      limits.MaxRunningWorkspaces,
+		MaxContainersPerWorkspace: limits.MaxContainersPerWorkspace,
+	}
+}
+
+func QuotaLimitsSerializerFromJSON(data string) (QuotaLimitsSerializer, error) {
+	var limits QuotaLimitsSerializer
+	if err := json.Unmarshal([]byte(data), &limits); err != nil {
+		return QuotaLimitsSerializer{}, err
+	}
+	return limits, nil
+}
+
+// limit applied to the current user, limit is null if there is no limit
+type QuotaUsageSerializer struct {
+	Limit  *uint  `json:"limit"`
+	Source string `json:"source"`
+	Used   int64  `json:"used"`
+}
+
+type UserQuotaSerializer struct {
+	Workspaces             QuotaUsageSerializer `json:"workspaces"`
+	RunningWorkspaces      QuotaUsageSerializer `json:"running_workspaces"`
+	ContainersPerWorkspace QuotaUsageSerializer `json:"containers_per_workspace"`
+}
+
+func LoadUserQuotaSerializer(user *models.User) *UserQuotaSerializer {
+	if user == nil || user.ID == 0 {
+		return nil
+	}
+
+	quota, err := models.GetEffectiveQuota(*user)
+	if err != nil {
+		return nil
+	}
+
+	workspaces, err := models.CountUserWorkspaces(*user)
+	if err != nil {
+		workspaces = 0
+	}
+
+	runningWorkspaces, err := models.CountUserRunningWorkspaces(*user, 0)
+	if err != nil {
+		runningWorkspaces = 0
+	}
+
+	return &UserQuotaSerializer{
+		Workspaces: QuotaUsageSerializer{
+			Limit:  quota.MaxWorkspaces.Limit,
+			Source: quota.MaxWorkspaces.Source,
+			Used:   workspaces,
+		},
+		RunningWorkspaces: QuotaUsageSerializer{
+			Limit:  quota.MaxRunningWorkspaces.Limit,
+			Source: quota.MaxRunningWorkspaces.Source,
+			Used:   runningWorkspaces,
+		},
+		// the usage depends on the workspace, it is not reported here
+		ContainersPerWorkspace: QuotaUsageSerializer{
+			Limit:  quota.MaxContainersPerWorkspace.Limit,
+			Source: quota.MaxContainersPerWorkspace.Source,
+		},
+	}
+}
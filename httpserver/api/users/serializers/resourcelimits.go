@@ -0,0 +1,29 @@
+package serializers
+
+import (
+	"encoding/json"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type ResourceLimitsSerializer struct {
+	CPULimit      *float64 `json:"cpu_limit"`
+	MemoryLimitMB *uint    `json:"memory_limit_mb"`
+	DiskLimitMB   *uint    `json:"disk_limit_mb"`
+}
+
+func LoadResourceLimitsSerializer(limits models.ResourceLimits) *ResourceLimitsSerializer {
+	return &ResourceLimitsSerializer{
+		CPULimit:      limits.CPULimit,
+		MemoryLimitMB: limits.MemoryLimitMB,
+		DiskLimitMB:   limits.DiskLimitMB,
+	}
+}
+
+func ResourceLimitsSerializerFromJSON(data string) (ResourceLimitsSerializer, error) {
+	var limits ResourceLimitsSerializer
+	if err := json.Unmarshal([]byte(data), &limits); err != nil {
+		return ResourceLimitsSerializer{}, err
+	}
+	return limits, nil
+}
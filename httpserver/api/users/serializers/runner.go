@@ -8,10 +8,12 @@ import (
 )
 
 type RunnerSerializer struct {
-	ID          uint       `json:"id"`
-	Name        string     `json:"name"`
-	Type        string     `json:"type"`
-	LastContact *time.Time `json:"last_contact"`
+	ID           uint                `json:"id"`
+	Name         string              `json:"name"`
+	Type         string              `json:"type"`
+	LastContact  *time.Time          `json:"last_contact"`
+	Labels       models.RunnerLabels `json:"labels"`
+	Capabilities []string            `json:"capabilities"`
 }
 
 func LoadRunnerSerializer(runner *models.Runner) *RunnerSerializer {
@@ -19,10 +21,12 @@ func LoadRunnerSerializer(runner *models.Runner) *RunnerSerializer {
 		return nil
 	}
 	return &RunnerSerializer{
-		ID:          runner.ID,
-		Name:        runner.Name,
-		Type:        runner.Type,
-		LastContact: runner.LastContact,
+		ID:           runner.ID,
+		Name:         runner.Name,
+		Type:         runner.Type,
+		LastContact:  runner.LastContact,
+		Labels:       runner.Labels,
+		Capabilities: runner.GetCapabilities(),
 	}
 }
 
@@ -36,14 +40,18 @@ func LoadMultipleRunnerSerializer(runners []models.Runner) []RunnerSerializer {
 
 // AdminRunnersSerializer is used for admin-specific runner information
 type AdminRunnersSerializer struct {
-	ID                 uint       `json:"id"`
-	Name               string     `json:"name"`
-	Type               string     `json:"type"`
-	LastContact        *time.Time `json:"last_contact"`
-	UsePublicUrl       bool       `json:"use_public_url"`
-	PublicUrl          string     `json:"public_url"`
-	DeletionInProgress bool       `json:"deletion_in_progress"`
-	Version            string     `json:"version"`
+	ID                 uint                `json:"id"`
+	Name               string              `json:"name"`
+	Type               string              `json:"type"`
+	LastContact        *time.Time          `json:"last_contact"`
+	UsePublicUrl       bool                `json:"use_public_url"`
+	PublicUrl          string              `json:"public_url"`
+	DeletionInProgress bool                `json:"deletion_in_progress"`
+	Version            string              `json:"version"`
+	Labels             models.RunnerLabels `json:"labels"`
+	Restricted         bool                `json:"restricted"`
+	AllowedGroups      []GroupSerializer   `json:"allowed_groups"`
+	Capabilities       []string            `json:"capabilities"`
 }
 
 func LoadAdminRunnerSerializer(runner *models.Runner) *AdminRunnersSerializer {
@@ -59,6 +67,10 @@ func LoadAdminRunnerSerializer(runner *models.Runner) *AdminRunnersSerializer {
 		PublicUrl:          runner.PublicUrl,
 		DeletionInProgress: runner.DeletionInProgress,
 		Version:            runner.Version,
+		Labels:             runner.Labels,
+		Restricted:         runner.Restricted,
+		AllowedGroups:      LoadMultipleGroupSerializer(runner.AllowedGroups),
+		Capabilities:       runner.GetCapabilities(),
 	}
 }
 
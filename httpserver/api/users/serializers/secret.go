@@ -0,0 +1,47 @@
+package serializers
+
+import (
+	"encoding/json"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// the value of a secret is never returned
+type SecretSerializer struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Scope     string `json:"scope"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+func LoadSecretSerializer(secret *models.Secret) *SecretSerializer {
+	if secret == nil {
+		return nil
+	}
+
+	return &SecretSerializer{
+		ID:        secret.ID,
+		Name:      secret.Name,
+		Scope:     secret.Scope,
+		CreatedAt: secret.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: secret.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func LoadMultipleSecretSerializer(secrets []models.Secret) []SecretSerializer {
+	serializers := make([]SecretSerializer, len(secrets))
+	for i, secret := range secrets {
+		serializers[i] = *LoadSecretSerializer(&secret)
+	}
+	return serializers
+}
+
+func MultipleSecretSerializersFromJSON(data string) ([]SecretSerializer, error) {
+	var secrets []SecretSerializer
+	if err := json.Unmarshal([]byte(data), &secrets); err != nil {
+		return []SecretSerializer{}, err
+	}
+	return secrets, nil
+}
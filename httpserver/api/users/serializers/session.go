@@ -0,0 +1,65 @@
+package serializers
+
+import (
+	"encoding/json"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type SessionSerializer struct {
+	ID               uint       `json:"id"`
+	IpAddress        string     `json:"ip_address"`
+	UserAgent        string     `json:"user_agent"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	ImpersonatedUser *string    `json:"impersonated_user"` // email of the user impersonated by the session
+	Current          bool       `json:"current"`
+}
+
+/*
+LoadSessionSerializer serializes a session, current is set
+if it is the session used to perform the request
+*/
+func LoadSessionSerializer(token *models.Token, current bool) *SessionSerializer {
+	if token == nil {
+		return nil
+	}
+
+	var impersonatedUser *string
+	if token.ImpersonatedUser != nil {
+		impersonatedUser = &token.ImpersonatedUser.Email
+	}
+
+	return &SessionSerializer{
+		ID:               token.ID,
+		IpAddress:        token.IpAddress,
+		UserAgent:        token.UserAgent,
+		CreatedAt:        token.CreatedAt,
+		LastUsedAt:       token.LastUsedAt,
+		ExpiresAt:        token.ExpirationDate,
+		ImpersonatedUser: impersonatedUser,
+		Current:          current,
+	}
+}
+
+/*
+LoadMultipleSessionSerializer serializes the sessions of a user,
+currentID is the id of the session used to perform the request
+*/
+func LoadMultipleSessionSerializer(tokens []models.Token, currentID uint) []SessionSerializer {
+	serializers := make([]SessionSerializer, len(tokens))
+	for i, token := range tokens {
+		serializers[i] = *LoadSessionSerializer(&token, token.ID == currentID)
+	}
+	return serializers
+}
+
+func MultipleSessionSerializerFromJSON(data string) ([]SessionSerializer, error) {
+	var s []SessionSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,41 @@
+package serializers
+
+import (
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/httpserver/tunnels"
+)
+
+type TcpTunnelSerializer struct {
+	ID            uint64    `json:"id"`
+	PortNumber    uint      `json:"port_number"`
+	UserEmail     string    `json:"user_email"`
+	RemoteAddr    string    `json:"remote_addr"`
+	BytesSent     uint64    `json:"bytes_sent"`
+	BytesReceived uint64    `json:"bytes_received"`
+	StartedAt     time.Time `json:"started_at"`
+}
+
+func LoadTcpTunnelSerializer(tunnel *tunnels.Tunnel) *TcpTunnelSerializer {
+	if tunnel == nil {
+		return nil
+	}
+
+	return &TcpTunnelSerializer{
+		ID:            tunnel.ID,
+		PortNumber:    tunnel.PortNumber,
+		UserEmail:     tunnel.UserEmail,
+		RemoteAddr:    tunnel.RemoteAddr,
+		BytesSent:     tunnel.BytesSent(),
+		BytesReceived: tunnel.BytesReceived(),
+		StartedAt:     tunnel.StartedAt,
+	}
+}
+
+func LoadMultipleTcpTunnelSerializer(tunnels []*tunnels.Tunnel) []TcpTunnelSerializer {
+	serializers := make([]TcpTunnelSerializer, len(tunnels))
+	for i, tunnel := range tunnels {
+		serializers[i] = *LoadTcpTunnelSerializer(tunnel)
+	}
+	return serializers
+}
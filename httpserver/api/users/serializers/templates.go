@@ -3,10 +3,11 @@ package serializers
 import "gitlab.com/codebox4073715/codebox/db/models"
 
 type WorkspaceTemplateVersionSerializer struct {
-	ID         uint   `json:"id"`
-	TemplateID uint   `json:"template_id"`
-	Name       string `json:"name"`
-	Published  bool   `json:"published"`
+	ID         uint                      `json:"id"`
+	TemplateID uint                      `json:"template_id"`
+	Name       string                    `json:"name"`
+	Published  bool                      `json:"published"`
+	Parameters models.TemplateParameters `json:"parameters"`
 }
 
 func LoadWorkspaceTemplateVersionSerializer(templateVersion *models.WorkspaceTemplateVersion) *WorkspaceTemplateVersionSerializer {
@@ -18,6 +19,7 @@ func LoadWorkspaceTemplateVersionSerializer(templateVersion *models.WorkspaceTem
 		TemplateID: templateVersion.TemplateID,
 		Name:       templateVersion.Name,
 		Published:  templateVersion.Published,
+		Parameters: templateVersion.Parameters,
 	}
 }
 
@@ -0,0 +1,87 @@
+package serializers
+
+import (
+	"encoding/json"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type TwoFactorStatusSerializer struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+func LoadTwoFactorStatusSerializer(user *models.User, required bool, recoveryCodesLeft int64) *TwoFactorStatusSerializer {
+	return &TwoFactorStatusSerializer{
+		Enabled:           user.TotpEnabled,
+		Required:          required,
+		RecoveryCodesLeft: recoveryCodesLeft,
+	}
+}
+
+func TwoFactorStatusSerializerFromJSON(data string) (TwoFactorStatusSerializer, error) {
+	var s TwoFactorStatusSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return TwoFactorStatusSerializer{}, err
+	}
+	return s, nil
+}
+
+// secret to add to the authenticator app
+type TwoFactorEnrollmentSerializer struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioning_uri"`
+}
+
+func TwoFactorEnrollmentSerializerFromJSON(data string) (TwoFactorEnrollmentSerializer, error) {
+	var s TwoFactorEnrollmentSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return TwoFactorEnrollmentSerializer{}, err
+	}
+	return s, nil
+}
+
+// recovery codes are returned only when they are generated
+type RecoveryCodesSerializer struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func RecoveryCodesSerializerFromJSON(data string) (RecoveryCodesSerializer, error) {
+	var s RecoveryCodesSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return RecoveryCodesSerializer{}, err
+	}
+	return s, nil
+}
+
+/*
+TwoFactorChallengeSerializer is returned by the login when the second factor is
+needed, if the user must enroll the secret to add to the authenticator app is returned
+*/
+type TwoFactorChallengeSerializer struct {
+	TwoFactorRequired  bool                           `json:"two_factor_required"`
+	Challenge          string                         `json:"challenge"`
+	EnrollmentRequired bool                           `json:"enrollment_required"`
+	Enrollment         *TwoFactorEnrollmentSerializer `json:"enrollment"`
+}
+
+func TwoFactorChallengeSerializerFromJSON(data string) (TwoFactorChallengeSerializer, error) {
+	var s TwoFactorChallengeSerializer
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return TwoFactorChallengeSerializer{}, err
+	}
+	return s, nil
+}
+
+type TwoFactorLoginSerializer struct {
+	TokenSerializer
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // only when the user has enrolled during the login
+}
+
+func LoadTwoFactorLoginSerializer(token *models.Token, recoveryCodes []string) *TwoFactorLoginSerializer {
+	return &TwoFactorLoginSerializer{
+		TokenSerializer: *LoadTokenSerializer(token),
+		RecoveryCodes:   recoveryCodes,
+	}
+}
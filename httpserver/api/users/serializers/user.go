@@ -8,14 +8,17 @@ import (
 
 // current user
 type CurrentUserSerializer struct {
-	Email             string  `json:"email"`
-	FirstName         string  `json:"first_name"`
-	LastName          string  `json:"last_name"`
-	IsSuperUser       bool    `json:"is_superuser"`
-	IsTemplateManager bool    `json:"is_template_manager"`
-	LastLogin         *string `json:"last_login"`
-	CreatedAt         string  `json:"created_at"`
-	Impersonated      bool    `json:"impersonated"`
+	Email             string               `json:"email"`
+	FirstName         string               `json:"first_name"`
+	LastName          string               `json:"last_name"`
+	IsSuperUser       bool                 `json:"is_superuser"`
+	IsTemplateManager bool                 `json:"is_template_manager"`
+	TimeZone          string               `json:"time_zone"`
+	LastLogin         *string              `json:"last_login"`
+	CreatedAt         string               `json:"created_at"`
+	Impersonated      bool                 `json:"impersonated"`
+	Groups            []GroupSerializer    `json:"groups"`
+	Quota             *UserQuotaSerializer `json:"quota"`
 }
 
 func LoadCurrentUserSerializer(user *models.User, impersonated bool) *CurrentUserSerializer {
@@ -32,15 +35,23 @@ func LoadCurrentUserSerializer(user *models.User, impersonated bool) *CurrentUse
 		}
 	}
 
+	groups, err := user.GetGroups()
+	if err != nil {
+		groups = []models.Group{}
+	}
+
 	return &CurrentUserSerializer{
 		Email:             user.Email,
 		FirstName:         user.FirstName,
 		LastName:          user.LastName,
 		IsSuperUser:       user.IsSuperuser,
 		IsTemplateManager: user.IsTemplateManager,
+		TimeZone:          user.GetLocation().String(),
 		LastLogin:         lastLoginPtr,
 		CreatedAt:         user.CreatedAt.Format(time.RFC3339),
 		Impersonated:      impersonated,
+		Groups:            LoadMultipleGroupSerializer(groups),
+		Quota:             LoadUserQuotaSerializer(user),
 	}
 }
 
@@ -82,16 +93,20 @@ func LoadMultipleUserSerializer(users []models.User) []UserSerializer {
 
 // admin
 type AdminUserSerializer struct {
-	Email              string  `json:"email"`
-	FirstName          string  `json:"first_name"`
-	LastName           string  `json:"last_name"`
-	IsSuperUser        bool    `json:"is_superuser"`
-	IsTemplateManager  bool    `json:"is_template_manager"`
-	DeletionInProgress bool    `json:"deletion_in_progress"`
-	EmailVerified      bool    `json:"email_verified"`
-	Approved           bool    `json:"approved"`
-	LastLogin          *string `json:"last_login"`
-	CreatedAt          string  `json:"created_at"`
+	Email              string            `json:"email"`
+	FirstName          string            `json:"first_name"`
+	LastName           string            `json:"last_name"`
+	IsSuperUser        bool              `json:"is_superuser"`
+	IsTemplateManager  bool              `json:"is_template_manager"`
+	DeletionInProgress bool              `json:"deletion_in_progress"`
+	EmailVerified      bool              `json:"email_verified"`
+	Approved           bool              `json:"approved"`
+	Deactivated        bool              `json:"deactivated"`
+	LdapManaged        bool              `json:"ldap_managed"`
+	TwoFactorEnabled   bool              `json:"two_factor_enabled"`
+	LastLogin          *string           `json:"last_login"`
+	CreatedAt          string            `json:"created_at"`
+	Groups             []GroupSerializer `json:"groups"`
 }
 
 func LoadAdminUserSerializer(user *models.User) *AdminUserSerializer {
@@ -108,6 +123,11 @@ func LoadAdminUserSerializer(user *models.User) *AdminUserSerializer {
 		}
 	}
 
+	groups, err := user.GetGroups()
+	if err != nil {
+		groups = []models.Group{}
+	}
+
 	return &AdminUserSerializer{
 		Email:              user.Email,
 		FirstName:          user.FirstName,
@@ -116,9 +136,13 @@ func LoadAdminUserSerializer(user *models.User) *AdminUserSerializer {
 		IsTemplateManager:  user.IsTemplateManager,
 		EmailVerified:      user.EmailVerified,
 		Approved:           user.Approved,
+		Deactivated:        user.Deactivated,
+		LdapManaged:        user.IsLdapUser(),
+		TwoFactorEnabled:   user.TotpEnabled,
 		LastLogin:          lastLoginPtr,
 		CreatedAt:          user.CreatedAt.Format(time.RFC3339),
 		DeletionInProgress: user.DeletionInProgress,
+		Groups:             LoadMultipleGroupSerializer(groups),
 	}
 }
 
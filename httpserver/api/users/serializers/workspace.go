@@ -8,39 +8,100 @@ import (
 )
 
 type WorkspaceSerializer struct {
-	ID                   uint                                `json:"id"`
-	Name                 string                              `json:"name"`
-	User                 *UserSerializer                     `json:"user"`
-	Status               string                              `json:"status"`
-	Type                 string                              `json:"type"`
-	Runner               *RunnerSerializer                   `json:"runner"`
-	ConfigSource         string                              `json:"config_source"`
-	TemplateVersion      *WorkspaceTemplateVersionSerializer `json:"template_version"`
-	GitSource            *GitWorkspaceSourceSerializer       `json:"git_source"`
-	EnvironmentVariables []string                            `json:"environment_variables"`
-	CreatedAt            time.Time                           `json:"created_at"`
-	UpdatedAt            time.Time                           `json:"updated_at"`
+	ID                     uint                                `json:"id"`
+	Name                   string                              `json:"name"`
+	User                   *UserSerializer                     `json:"user"`
+	Status                 string                              `json:"status"`
+	ErrorReason            string                              `json:"error_reason"`
+	Type                   string                              `json:"type"`
+	Runner                 *RunnerSerializer                   `json:"runner"`
+	ConfigSource           string                              `json:"config_source"`
+	TemplateVersion        *WorkspaceTemplateVersionSerializer `json:"template_version"`
+	GitSource              *GitWorkspaceSourceSerializer       `json:"git_source"`
+	EnvironmentVariables   []string                            `json:"environment_variables"`
+	IdleTimeoutMinutes     *uint                               `json:"idle_timeout_minutes"`
+	IdleShutdownAt         *time.Time                          `json:"idle_shutdown_at"`
+	StartSchedule          string                              `json:"start_schedule"`
+	StopSchedule           string                              `json:"stop_schedule"`
+	RunnerAccessRevoked    bool                                `json:"runner_access_revoked"`
+	ResourceLimits         *ResourceLimitsSerializer           `json:"resource_limits"`          // limits applied to the workspace, null in the lists
+	ResourceLimitsOverride *ResourceLimitsSerializer           `json:"resource_limits_override"` // limits set on the workspace
+	ParameterValues        models.TemplateParameterValues      `json:"parameter_values"`
+	Shared                 bool                                `json:"shared"`      // the workspace is owned by another user
+	SharedRole             string                              `json:"shared_role"` // role of the current user in a shared workspace
+	CreatedAt              time.Time                           `json:"created_at"`
+	UpdatedAt              time.Time                           `json:"updated_at"`
 }
 
 func LoadWorkspaceSerializer(workspace *models.Workspace) *WorkspaceSerializer {
+	serializer := loadWorkspaceSerializerWithoutLimits(workspace)
+	if serializer == nil {
+		return nil
+	}
+
+	// the limits are reported as not set if they cannot be computed
+	limits, err := workspace.GetResourceLimits()
+	if err != nil {
+		limits = models.ResourceLimits{}
+	}
+	serializer.ResourceLimits = LoadResourceLimitsSerializer(limits)
+	return serializer
+}
+
+/*
+loadWorkspaceSerializerWithoutLimits serializes a workspace without computing the
+limits applied to it, that requires additional queries for each workspace of a list
+*/
+func loadWorkspaceSerializerWithoutLimits(workspace *models.Workspace) *WorkspaceSerializer {
 	if workspace == nil {
 		return nil
 	}
 
 	return &WorkspaceSerializer{
-		ID:                   workspace.ID,
-		Name:                 workspace.Name,
-		User:                 LoadUserSerializer(workspace.User),
-		Status:               workspace.Status,
-		Type:                 workspace.Type,
-		Runner:               LoadRunnerSerializer(workspace.Runner),
-		ConfigSource:         workspace.ConfigSource,
-		TemplateVersion:      LoadWorkspaceTemplateVersionSerializer(workspace.TemplateVersion),
-		GitSource:            LoadGitWorkspaceSourceSerializer(workspace.GitSource),
-		EnvironmentVariables: workspace.EnvironmentVariables,
-		CreatedAt:            workspace.CreatedAt,
-		UpdatedAt:            workspace.UpdatedAt,
+		ID:                     workspace.ID,
+		Name:                   workspace.Name,
+		User:                   LoadUserSerializer(workspace.User),
+		Status:                 workspace.Status,
+		ErrorReason:            workspace.ErrorReason,
+		Type:                   workspace.Type,
+		Runner:                 LoadRunnerSerializer(workspace.Runner),
+		ConfigSource:           workspace.ConfigSource,
+		TemplateVersion:        LoadWorkspaceTemplateVersionSerializer(workspace.TemplateVersion),
+		GitSource:              LoadGitWorkspaceSourceSerializer(workspace.GitSource),
+		EnvironmentVariables:   workspace.EnvironmentVariables,
+		IdleTimeoutMinutes:     workspace.IdleTimeoutMinutes,
+		IdleShutdownAt:         workspace.IdleShutdownAt,
+		StartSchedule:          workspace.StartSchedule,
+		StopSchedule:           workspace.StopSchedule,
+		RunnerAccessRevoked:    workspace.RunnerAccessRevoked,
+		ResourceLimitsOverride: LoadResourceLimitsSerializer(workspace.ResourceLimits),
+		ParameterValues:        workspace.ParameterValues,
+		CreatedAt:              workspace.CreatedAt,
+		UpdatedAt:              workspace.UpdatedAt,
+	}
+}
+
+/*
+LoadSharedWorkspaceSerializer serializes a workspace that has
+been shared with the current user with the given role,
+the environment variables may contain secrets so they are
+hidden to the collaborators that cannot open a terminal
+*/
+func LoadSharedWorkspaceSerializer(workspace *models.Workspace, role string) *WorkspaceSerializer {
+	return shareWorkspaceSerializer(LoadWorkspaceSerializer(workspace), role)
+}
+
+func shareWorkspaceSerializer(serializer *WorkspaceSerializer, role string) *WorkspaceSerializer {
+	if serializer == nil {
+		return nil
+	}
+
+	serializer.Shared = true
+	serializer.SharedRole = role
+	if !models.WorkspaceRoleAllows(role, models.WorkspaceRoleDeveloper) {
+		serializer.EnvironmentVariables = []string{}
 	}
+	return serializer
 }
 
 func WorkspaceSerializerFromJSON(data string) (WorkspaceSerializer, error) {
@@ -54,7 +115,18 @@ func WorkspaceSerializerFromJSON(data string) (WorkspaceSerializer, error) {
 func LoadMultipleWorkspaceSerializer(workspaces []models.Workspace) []WorkspaceSerializer {
 	serializers := make([]WorkspaceSerializer, len(workspaces))
 	for i, workspace := range workspaces {
-		serializers[i] = *LoadWorkspaceSerializer(&workspace)
+		serializers[i] = *loadWorkspaceSerializerWithoutLimits(&workspace)
+	}
+	return serializers
+}
+
+func LoadMultipleSharedWorkspaceSerializer(collaborators []models.WorkspaceCollaborator) []WorkspaceSerializer {
+	serializers := []WorkspaceSerializer{}
+	for _, collaborator := range collaborators {
+		s := shareWorkspaceSerializer(loadWorkspaceSerializerWithoutLimits(collaborator.Workspace), collaborator.Role)
+		if s != nil {
+			serializers = append(serializers, *s)
+		}
 	}
 	return serializers
 }
@@ -66,3 +138,23 @@ func MultipleWorkspaceSerializersFromJSON(data string) ([]WorkspaceSerializer, e
 	}
 	return workspaces, nil
 }
+
+type WorkspaceTransferSerializer struct {
+	Workspace *WorkspaceSerializer `json:"workspace"`
+	Warnings  []string             `json:"warnings"`
+}
+
+func LoadWorkspaceTransferSerializer(workspace *models.Workspace, warnings []string) *WorkspaceTransferSerializer {
+	return &WorkspaceTransferSerializer{
+		Workspace: LoadWorkspaceSerializer(workspace),
+		Warnings:  warnings,
+	}
+}
+
+func WorkspaceTransferSerializerFromJSON(data string) (WorkspaceTransferSerializer, error) {
+	var transfer WorkspaceTransferSerializer
+	if err := json.Unmarshal([]byte(data), &transfer); err != nil {
+		return WorkspaceTransferSerializer{}, err
+	}
+	return transfer, nil
+}
@@ -0,0 +1,48 @@
+package serializers
+
+import (
+	"encoding/json"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type WorkspaceCollaboratorSerializer struct {
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func LoadWorkspaceCollaboratorSerializer(collaborator *models.WorkspaceCollaborator) *WorkspaceCollaboratorSerializer {
+	if collaborator == nil || collaborator.User == nil {
+		return nil
+	}
+
+	return &WorkspaceCollaboratorSerializer{
+		Email:     collaborator.User.Email,
+		FirstName: collaborator.User.FirstName,
+		LastName:  collaborator.User.LastName,
+		Role:      collaborator.Role,
+		CreatedAt: collaborator.CreatedAt,
+	}
+}
+
+func LoadMultipleWorkspaceCollaboratorSerializer(collaborators []models.WorkspaceCollaborator) []WorkspaceCollaboratorSerializer {
+	serializers := []WorkspaceCollaboratorSerializer{}
+	for _, collaborator := range collaborators {
+		if s := LoadWorkspaceCollaboratorSerializer(&collaborator); s != nil {
+			serializers = append(serializers, *s)
+		}
+	}
+	return serializers
+}
+
+func WorkspaceCollaboratorSerializerFromJSON(data string) (WorkspaceCollaboratorSerializer, error) {
+	var collaborator WorkspaceCollaboratorSerializer
+	if err := json.Unmarshal([]byte(data), &collaborator); err != nil {
+		return WorkspaceCollaboratorSerializer{}, err
+	}
+	return collaborator, nil
+}
@@ -14,6 +14,10 @@ type WorkspaceContainerSerializer struct {
 	ContainerUserName string     `json:"container_user_name"`
 	AgentLastContact  *time.Time `json:"agent_last_contact"`
 	WorkspacePath     string     `json:"workspace_path"`
+	LastActivity      time.Time  `json:"last_activity"`
+	CPULimit          *float64   `json:"cpu_limit"`
+	MemoryLimitMB     *uint      `json:"memory_limit_mb"`
+	DiskLimitMB       *uint      `json:"disk_limit_mb"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
@@ -31,6 +35,10 @@ func LoadWorkspaceContainerSerializer(container *models.WorkspaceContainer) *Wor
 		ContainerUserName: container.ContainerUserName,
 		AgentLastContact:  container.AgentLastContact,
 		WorkspacePath:     container.WorkspacePath,
+		LastActivity:      container.GetLastActivity(),
+		CPULimit:          container.CPULimit,
+		MemoryLimitMB:     container.MemoryLimitMB,
+		DiskLimitMB:       container.DiskLimitMB,
 		CreatedAt:         container.CreatedAt,
 		UpdatedAt:         container.UpdatedAt,
 	}
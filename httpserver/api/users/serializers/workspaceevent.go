@@ -0,0 +1,70 @@
+package serializers
+
+import (
+	"encoding/json"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type WorkspaceEventActorSerializer struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+func LoadWorkspaceEventActorSerializer(user *models.User) *WorkspaceEventActorSerializer {
+	if user == nil {
+		return nil
+	}
+
+	return &WorkspaceEventActorSerializer{
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
+}
+
+type WorkspaceEventSerializer struct {
+	ID          uint                           `json:"id"`
+	FromStatus  string                         `json:"from_status"`
+	ToStatus    string                         `json:"to_status"`
+	Source      string                         `json:"source"`
+	Actor       *WorkspaceEventActorSerializer `json:"actor"`
+	Message     string                         `json:"message"`
+	ErrorReason string                         `json:"error_reason"`
+	CreatedAt   time.Time                      `json:"created_at"`
+}
+
+func LoadWorkspaceEventSerializer(event *models.WorkspaceEvent) *WorkspaceEventSerializer {
+	if event == nil {
+		return nil
+	}
+
+	return &WorkspaceEventSerializer{
+		ID:          event.ID,
+		FromStatus:  event.FromStatus,
+		ToStatus:    event.ToStatus,
+		Source:      event.Source,
+		Actor:       LoadWorkspaceEventActorSerializer(event.Actor),
+		Message:     event.Message,
+		ErrorReason: event.ErrorReason,
+		CreatedAt:   event.CreatedAt,
+	}
+}
+
+func LoadMultipleWorkspaceEventSerializer(events []models.WorkspaceEvent) []WorkspaceEventSerializer {
+	serializers := make([]WorkspaceEventSerializer, len(events))
+	for i, event := range events {
+		serializers[i] = *LoadWorkspaceEventSerializer(&event)
+	}
+	return serializers
+}
+
+func MultipleWorkspaceEventSerializersFromJSON(data string) ([]WorkspaceEventSerializer, error) {
+	var events []WorkspaceEventSerializer
+	if err := json.Unmarshal([]byte(data), &events); err != nil {
+		return []WorkspaceEventSerializer{}, err
+	}
+	return events, nil
+}
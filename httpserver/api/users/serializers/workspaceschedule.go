@@ -0,0 +1,51 @@
+package serializers
+
+import (
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+type WorkspaceScheduleSkippedRunSerializer struct {
+	Action      string    `json:"action"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Reason      string    `json:"reason"`
+}
+
+type WorkspaceScheduleSerializer struct {
+	StartSchedule string                                  `json:"start_schedule"`
+	StopSchedule  string                                  `json:"stop_schedule"`
+	TimeZone      string                                  `json:"time_zone"`
+	NextStart     *time.Time                              `json:"next_start"`
+	NextStop      *time.Time                              `json:"next_stop"`
+	SkippedRuns   []WorkspaceScheduleSkippedRunSerializer `json:"skipped_runs"`
+}
+
+func LoadWorkspaceScheduleSerializer(
+	workspace *models.Workspace,
+	location *time.Location,
+	skippedRuns []models.WorkspaceScheduleSkippedRun,
+) *WorkspaceScheduleSerializer {
+	if workspace == nil {
+		return nil
+	}
+
+	now := time.Now()
+	serializedRuns := make([]WorkspaceScheduleSkippedRunSerializer, len(skippedRuns))
+	for i, run := range skippedRuns {
+		serializedRuns[i] = WorkspaceScheduleSkippedRunSerializer{
+			Action:      run.Action,
+			ScheduledAt: run.ScheduledAt.In(location),
+			Reason:      run.Reason,
+		}
+	}
+
+	return &WorkspaceScheduleSerializer{
+		StartSchedule: workspace.StartSchedule,
+		StopSchedule:  workspace.StopSchedule,
+		TimeZone:      location.String(),
+		NextStart:     models.NextScheduledRun(workspace.StartSchedule, now, location),
+		NextStop:      models.NextScheduledRun(workspace.StopSchedule, now, location),
+		SkippedRuns:   serializedRuns,
+	}
+}
@@ -38,6 +38,10 @@ func HandleListTemplateVersionsByTemplate(c *gin.Context) {
 		return
 	}
 
+	if !checkTemplateVisibility(c, wt) {
+		return
+	}
+
 	tv, err := models.ListWorkspaceTemplateVersionsByTemplate(*wt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -86,6 +90,10 @@ func HandleRetrieveTemplateVersionByTemplate(c *gin.Context) {
 		return
 	}
 
+	if !checkTemplateVisibility(c, wt) {
+		return
+	}
+
 	tv, err := models.RetrieveWorkspaceTemplateVersionsByIdByTemplate(*wt, uint(tvi))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -132,6 +140,10 @@ func HandleRetrieveLatestTemplateVersionByTemplate(c *gin.Context) {
 		return
 	}
 
+	if !checkTemplateVisibility(c, wt) {
+		return
+	}
+
 	tv, err := models.RetrieveLatestTemplateVersionByTemplate(*wt)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -154,12 +166,17 @@ type UpdateTemplateVersionRequestBody struct {
 	Name           string `json:"name" binding:"required,min=1"`
 	Published      bool   `json:"published"`
 	ConfigFilePath string `json:"config_file_path"`
+	// parameters rendered in the files as {{ parameters.<name> }},
+	// if not set the parameters of the version are kept
+	Parameters *models.TemplateParameters `json:"parameters"`
 }
 
 // UpdateTemplateversionByTemplate godoc
 // @Summary Update a template version
 // @Schemes
-// @Description Update a template version
+// @Description Update a template version, the parameters of the version are rendered
+// @Description in the files of the template when a workspace is started.
+// @Description A parameter is referenced in the files as {{ parameters.<name> }}
 // @Tags Templates
 // @Accept json
 // @Produce json
@@ -202,6 +219,10 @@ func HandleUpdateTemplateVersionByTemplate(c *gin.Context) {
 		return
 	}
 
+	if !checkTemplateVisibility(c, wt) {
+		return
+	}
+
 	tv, err := models.RetrieveWorkspaceTemplateVersionsByIdByTemplate(*wt, uint(tvi))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -232,6 +253,18 @@ func HandleUpdateTemplateVersionByTemplate(c *gin.Context) {
 		return
 	}
 
+	parameters := tv.Parameters
+	if requestBody.Parameters != nil {
+		parameters = *requestBody.Parameters
+	}
+
+	if err := models.ValidateTemplateParameters(parameters); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"details": err.Error(),
+		})
+		return
+	}
+
 	tv, err = models.UpdateTemplateVersion(
 		*wt,
 		*tv,
@@ -239,6 +272,7 @@ func HandleUpdateTemplateVersionByTemplate(c *gin.Context) {
 		requestBody.Published,
 		user,
 		requestBody.ConfigFilePath,
+		parameters,
 	)
 
 	if err != nil {
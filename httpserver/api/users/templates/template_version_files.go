@@ -42,6 +42,10 @@ func getTemplateVersionFromContext(c *gin.Context) *models.WorkspaceTemplateVers
 		return nil
 	}
 
+	if !checkTemplateVisibility(c, wt) {
+		return nil
+	}
+
 	tv, err := models.RetrieveWorkspaceTemplateVersionsByIdByTemplate(*wt, uint(tvi))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1,6 +1,7 @@
 package templates
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,25 +9,91 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gitlab.com/codebox4073715/codebox/config"
-	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
 	"gitlab.com/codebox4073715/codebox/db/models"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
 	"gitlab.com/codebox4073715/codebox/utils/randomnames"
 	"gitlab.com/codebox4073715/codebox/utils/targz"
 )
 
+// check if the template exists and the current user can see it,
+// this function writes http responses
+func checkTemplateVisibility(c *gin.Context, wt *models.WorkspaceTemplate) bool {
+	if wt == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"details": "template not found",
+		})
+		return false
+	}
+
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"details": "internal server error",
+		})
+		return false
+	}
+
+	visible, err := wt.IsVisibleForUser(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"details": "internal server error",
+		})
+		return false
+	}
+
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{
+			"details": "template not found",
+		})
+		return false
+	}
+	return true
+}
+
+// retrieve the groups with the given ids, an error is returned if a group does not exist
+func retrieveGroupsByIDs(ids []uint) ([]models.Group, error) {
+	groups, err := models.ListGroupsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		found := false
+		for _, group := range groups {
+			if group.ID == id {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("group %d not found", id)
+		}
+	}
+	return groups, nil
+}
+
 // TemplatesList godoc
 // @Summary List templates
 // @Schemes
-// @Description List all templates
+// @Description List the templates visible to the current user,
+// @Description restricted templates are visible only to the members of the allowed groups
 // @Tags Templates
 // @Accept json
 // @Produce json
 // @Success 200 {object} []models.WorkspaceTemplate
 // @Router /api/v1/templates [get]
 func HandleListTemplates(c *gin.Context) {
-	var templates *[]models.WorkspaceTemplate
-	if err := dbconn.DB.Find(&templates).Error; err != nil {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"detail": "internal server error",
+		})
+		return
+	}
+
+	templates, err := models.ListWorkspaceTemplatesVisibleForUser(user)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"detail": "internal server error",
 		})
@@ -65,10 +132,7 @@ func HandleRetrieveTemplate(c *gin.Context) {
 		return
 	}
 
-	if template == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"details": "template not found",
-		})
+	if !checkTemplateVisibility(c, template) {
 		return
 	}
 
@@ -95,10 +159,7 @@ func HandleRetrieveTemplateByName(c *gin.Context) {
 		return
 	}
 
-	if template == nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"details": "template not found",
-		})
+	if !checkTemplateVisibility(c, template) {
 		return
 	}
 
@@ -106,10 +167,17 @@ func HandleRetrieveTemplateByName(c *gin.Context) {
 }
 
 type CreateTemplateRequestBody struct {
-	Name        string `json:"name" binding:"required"`
-	Type        string `json:"type" binding:"required"`
-	Description string `json:"description"`
-	Icon        string `json:"icon"`
+	Name                  string              `json:"name" binding:"required"`
+	Type                  string              `json:"type" binding:"required"`
+	Description           string              `json:"description"`
+	Icon                  string              `json:"icon"`
+	IdleTimeoutMinutes    uint                `json:"idle_timeout_minutes"`
+	RequiredRunnerLabels  models.RunnerLabels `json:"required_runner_labels"`
+	PreferredRunnerLabels models.RunnerLabels `json:"preferred_runner_labels"`
+	// cpu_limit, memory_limit_mb and disk_limit_mb, null means no limit
+	models.ResourceLimits
+	Restricted    bool   `json:"restricted"`
+	AllowedGroups []uint `json:"allowed_groups"` // ids of the groups allowed to see a restricted template
 }
 
 // TemplateCreate godoc
@@ -180,12 +248,50 @@ func HandleCreateTemplate(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateRunnerLabels(requestBody.RequiredRunnerLabels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := models.ValidateRunnerLabels(requestBody.PreferredRunnerLabels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := models.ValidateResourceLimits(requestBody.ResourceLimits); err != nil {
+		var limitsErr *models.InvalidResourceLimitsError
+		if errors.As(err, &limitsErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"details": limitsErr.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"details": "internal server error",
+		})
+		return
+	}
+
+	allowedGroups, err := retrieveGroupsByIDs(requestBody.AllowedGroups)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// add template
 	wt, err = models.CreateWorkspaceTemplate(
 		requestBody.Name,
 		requestBody.Type,
 		requestBody.Description,
 		requestBody.Icon,
+		requestBody.IdleTimeoutMinutes,
 	)
 
 	if err != nil {
@@ -195,6 +301,24 @@ func HandleCreateTemplate(c *gin.Context) {
 		return
 	}
 
+	wt.RequiredRunnerLabels = requestBody.RequiredRunnerLabels
+	wt.PreferredRunnerLabels = requestBody.PreferredRunnerLabels
+	wt.ResourceLimits = requestBody.ResourceLimits
+	wt.Restricted = requestBody.Restricted
+	if err := models.UpdateWorkspaceTemplate(*wt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"details": "internal server error",
+		})
+		return
+	}
+
+	if err := models.SetWorkspaceTemplateAllowedGroups(wt, allowedGroups); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"details": "internal server error",
+		})
+		return
+	}
+
 	// create the first version
 	tv, err := models.CreateTemplateVersion(
 		*wt,
@@ -232,9 +356,16 @@ func HandleCreateTemplate(c *gin.Context) {
 }
 
 type UpdateTemplateRequestBody struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
-	Icon        string `json:"icon"`
+	Name                  string              `json:"name" binding:"required"`
+	Description           string              `json:"description"`
+	Icon                  string              `json:"icon"`
+	IdleTimeoutMinutes    uint                `json:"idle_timeout_minutes"`
+	RequiredRunnerLabels  models.RunnerLabels `json:"required_runner_labels"`
+	PreferredRunnerLabels models.RunnerLabels `json:"preferred_runner_labels"`
+	// cpu_limit, memory_limit_mb and disk_limit_mb, null means no limit
+	models.ResourceLimits
+	Restricted    *bool  `json:"restricted"`     // if nil the restriction is not changed
+	AllowedGroups []uint `json:"allowed_groups"` // if nil the allowed groups are not changed
 }
 
 // TemplateUpdate godoc
@@ -301,9 +432,56 @@ func HandleUpdateTemplate(c *gin.Context) {
 		}
 	}
 
+	if err := models.ValidateRunnerLabels(requestBody.RequiredRunnerLabels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := models.ValidateRunnerLabels(requestBody.PreferredRunnerLabels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := models.ValidateResourceLimits(requestBody.ResourceLimits); err != nil {
+		var limitsErr *models.InvalidResourceLimitsError
+		if errors.As(err, &limitsErr) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"details": limitsErr.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"details": "internal server error",
+		})
+		return
+	}
+
+	var allowedGroups []models.Group
+	if requestBody.AllowedGroups != nil {
+		allowedGroups, err = retrieveGroupsByIDs(requestBody.AllowedGroups)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
 	wt.Name = requestBody.Name
 	wt.Description = requestBody.Description
 	wt.Icon = requestBody.Icon
+	wt.IdleTimeoutMinutes = requestBody.IdleTimeoutMinutes
+	wt.RequiredRunnerLabels = requestBody.RequiredRunnerLabels
+	wt.PreferredRunnerLabels = requestBody.PreferredRunnerLabels
+	wt.ResourceLimits = requestBody.ResourceLimits
+	if requestBody.Restricted != nil {
+		wt.Restricted = *requestBody.Restricted
+	}
 
 	if err := models.UpdateWorkspaceTemplate(*wt); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -312,6 +490,15 @@ func HandleUpdateTemplate(c *gin.Context) {
 		return
 	}
 
+	if allowedGroups != nil {
+		if err := models.SetWorkspaceTemplateAllowedGroups(wt, allowedGroups); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"details": "internal server error",
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, wt)
 }
 
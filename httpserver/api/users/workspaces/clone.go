@@ -0,0 +1,260 @@
+package workspaces
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gocraft/work"
+	"gitlab.com/codebox4073715/codebox/bgtasks"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+type CloneWorkspaceRequestBody struct {
+	Name       string `json:"name"`         // defaults to the name of the cloned workspace
+	GitRefName string `json:"git_ref_name"` // git sources only, defaults to the ref of the cloned workspace
+	RunnerID   uint   `json:"runner_id"`    // 0 means the runner of the cloned workspace
+	UserEmail  string `json:"user_email"`   // owner of the new workspace, administrators only
+}
+
+// HandleCloneWorkspace godoc
+// @Summary Clone a workspace
+// @Schemes
+// @Description Create a new workspace with the type, runner, configuration source and environment
+// @Description variables of an existing workspace, then start it. Name, git ref and runner can be overridden.
+// @Description Administrators can clone any workspace and set user_email to create it for another user
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param request body CloneWorkspaceRequestBody false "Overrides"
+// @Success 201 {object} serializers.WorkspaceSerializer
+// @Router /api/v1/workspace/:workspaceId/clone [post]
+func HandleCloneWorkspace(ctx *gin.Context) {
+	currentUser, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
+		return
+	}
+
+	// the body is optional, an empty body means no overrides
+	var reqBody CloneWorkspaceRequestBody
+	if ctx.Request.Body != nil && ctx.Request.Body != http.NoBody {
+		if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil && !errors.Is(err, io.EOF) {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+			return
+		}
+	}
+
+	// administrators can clone the workspaces of the other users
+	var source *models.Workspace
+	role := models.WorkspaceRoleOwner
+	if currentUser.IsSuperuser {
+		source, err = models.RetrieveWorkspaceById(id)
+	} else {
+		source, role, err = models.RetrieveWorkspaceWithRoleByUserAndId(currentUser, id)
+	}
+
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if source == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
+		return
+	}
+
+	if !models.WorkspaceRoleAllows(role, models.WorkspaceRoleAdmin) {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "you don't have the permission to perform this action")
+		return
+	}
+
+	owner := currentUser
+	if reqBody.UserEmail != "" && reqBody.UserEmail != currentUser.Email {
+		if !currentUser.IsSuperuser {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "only administrators can clone a workspace for another user")
+			return
+		}
+
+		user, err := models.RetrieveUserByEmail(reqBody.UserEmail)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if user == nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "user not found")
+			return
+		}
+		owner = *user
+	}
+
+	if reqBody.GitRefName != "" && source.ConfigSource != models.WorkspaceConfigSourceGit {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "git_ref_name can be set only for git sources")
+		return
+	}
+
+	// the owner of the clone must be allowed to use the template of the workspace
+	if source.ConfigSource == models.WorkspaceConfigSourceTemplate && source.TemplateVersion != nil {
+		template, err := models.RetrieveWorkspaceTemplateByID(source.TemplateVersion.TemplateID)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if template == nil {
+			utils.ErrorResponse(ctx, http.StatusFailedDependency, "the template of the workspace does not exist")
+			return
+		}
+
+		visible, err := template.IsVisibleForUser(owner)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if !visible {
+			utils.ErrorResponse(ctx, http.StatusForbidden, "the owner of the clone cannot use the template of the workspace")
+			return
+		}
+	}
+
+	// the clone is started after it has been created
+	if respondQuotaError(ctx, models.CheckCreateWorkspaceQuota(owner)) {
+		return
+	}
+
+	if respondQuotaError(ctx, models.CheckStartWorkspaceQuota(owner, models.Workspace{})) {
+		return
+	}
+
+	runner := source.Runner
+	if reqBody.RunnerID != 0 {
+		runner, err = models.RetrieveRunnerByID(reqBody.RunnerID)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if runner == nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "runner not found")
+			return
+		}
+	}
+
+	if runner == nil {
+		utils.ErrorResponse(ctx, http.StatusFailedDependency, "no runner selected")
+		return
+	}
+
+	requiredLabels, preferredLabels, err := source.GetRunnerLabelSelectors()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	limits, err := source.GetRequestedResourceLimits()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// the owner of the clone may not be allowed to use the runner of the workspace
+	if !checkRunnerForWorkspace(ctx, *runner, owner, source.Type, requiredLabels, limits) {
+		return
+	}
+
+	var gitSource *models.GitWorkspaceSource
+	if source.ConfigSource == models.WorkspaceConfigSourceGit {
+		if source.GitSource == nil {
+			utils.ErrorResponse(ctx, http.StatusFailedDependency, "the workspace has no git source")
+			return
+		}
+
+		refName := source.GitSource.RefName
+		if reqBody.GitRefName != "" {
+			refName = reqBody.GitRefName
+		}
+
+		gitSource, err = models.CreateGitWorkspaceSource(
+			source.GitSource.RepositoryURL,
+			refName,
+			source.GitSource.ConfigFilePath,
+		)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if len(requiredLabels) > 0 || len(preferredLabels) > 0 {
+			if err := models.SetGitWorkspaceSourceRunnerLabels(
+				gitSource,
+				requiredLabels,
+				preferredLabels,
+			); err != nil {
+				utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+				return
+			}
+		}
+	}
+
+	name := source.Name
+	if reqBody.Name != "" {
+		name = reqBody.Name
+	}
+
+	workspace, err := models.CreateWorkspace(
+		name,
+		&owner,
+		source.Type,
+		runner,
+		source.ConfigSource,
+		source.TemplateVersion,
+		gitSource,
+		append([]string{}, source.EnvironmentVariables...),
+	)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if source.IdleTimeoutMinutes != nil {
+		workspace, err = models.SetWorkspaceIdleTimeout(workspace, source.IdleTimeoutMinutes)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	if source.ResourceLimits.IsSet() {
+		workspace, err = models.SetWorkspaceResourceLimits(workspace, source.ResourceLimits)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	if len(source.ParameterValues) > 0 {
+		workspace, err = models.SetWorkspaceTemplateParameterValues(workspace, source.ParameterValues)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	workspace.AppendLogs("Creating workspace...")
+	recordWorkspaceEvent(ctx, workspace, "", fmt.Sprintf("Workspace cloned from '%s'", source.Name))
+	bgtasks.BgTasksEnqueuer.Enqueue("start_workspace", work.Q{"workspace_id": workspace.ID})
+
+	ctx.JSON(http.StatusCreated, serializers.LoadWorkspaceSerializer(workspace))
+}
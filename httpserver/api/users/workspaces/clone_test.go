@@ -0,0 +1,135 @@
+package workspaces_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Clone a workspace overriding name and git ref, then try to clone
+it for another user as a common user and as an administrator
+*/
+func TestCloneWorkspace(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		admin, err := models.RetrieveUserByEmail("admin@admin.com")
+		if err != nil || admin == nil {
+			t.Fatalf("Failed to retrieve test admin: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// create a new workspace
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{"VAR1=value1"},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		cloneUrl := fmt.Sprintf("/api/v1/workspace/%d/clone", createdWorkspace.ID)
+
+		// clone with overrides
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, cloneUrl, "POST", workspaces.CloneWorkspaceRequestBody{
+			Name:       "Feature Workspace",
+			GitRefName: "feature",
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		clone, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse cloned workspace: '%s'", err)
+		}
+
+		assert.NotEqual(t, createdWorkspace.ID, clone.ID)
+		assert.Equal(t, "Feature Workspace", clone.Name)
+		assert.Equal(t, models.WorkspaceStatusStarting, clone.Status)
+		assert.Equal(t, createdWorkspace.Runner.ID, clone.Runner.ID)
+		assert.Equal(t, []string{"VAR1=value1"}, clone.EnvironmentVariables)
+		if assert.NotNil(t, clone.GitSource) {
+			assert.Equal(t, "feature", clone.GitSource.RefName)
+			assert.Equal(t, createdWorkspace.GitSource.RepositoryURL, clone.GitSource.RepositoryURL)
+		}
+
+		// common users cannot clone into another account
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, cloneUrl, "POST", workspaces.CloneWorkspaceRequestBody{
+			UserEmail: otherUser.Email,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// other users cannot clone the workspace
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, cloneUrl, "POST", workspaces.CloneWorkspaceRequestBody{})
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// administrators can clone into another account
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, cloneUrl, "POST", workspaces.CloneWorkspaceRequestBody{
+			UserEmail: otherUser.Email,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *admin)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		clone, err = serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse cloned workspace: '%s'", err)
+		}
+
+		assert.Equal(t, createdWorkspace.Name, clone.Name)
+		otherUserWorkspaces, err := models.ListUserWorkspaces(*otherUser)
+		if err != nil {
+			t.Fatalf("Failed to list workspaces: '%s'", err)
+		}
+		assert.Equal(t, 1, len(otherUserWorkspaces))
+	})
+}
@@ -0,0 +1,150 @@
+package workspaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleListWorkspaceCollaborators godoc
+// @Summary List the collaborators of a workspace
+// @Schemes
+// @Description List the users the workspace has been shared with and their roles
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.WorkspaceCollaboratorSerializer
+// @Router /api/v1/workspace/:workspaceId/collaborators [get]
+func HandleListWorkspaceCollaborators(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
+	if workspace == nil {
+		return
+	}
+
+	collaborators, err := models.ListWorkspaceCollaborators(*workspace)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.LoadMultipleWorkspaceCollaboratorSerializer(collaborators))
+}
+
+type SetWorkspaceCollaboratorRequestBody struct {
+	Role string `json:"role" binding:"required"` // viewer, developer or admin
+}
+
+// HandleSetWorkspaceCollaborator godoc
+// @Summary Share a workspace with a user
+// @Schemes
+// @Description Add a user to the collaborators of the workspace or change their role, only the owner
+// @Description can manage the collaborators. Viewers can see status, logs and events, developers can also
+// @Description use terminal, ssh, filesystem and private ports, admins can also start, stop and configure it
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param request body SetWorkspaceCollaboratorRequestBody true "Role of the collaborator"
+// @Success 200 {object} serializers.WorkspaceCollaboratorSerializer
+// @Router /api/v1/workspace/:workspaceId/collaborators/:email [put]
+func HandleSetWorkspaceCollaborator(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleOwner)
+	if workspace == nil {
+		return
+	}
+
+	var reqBody SetWorkspaceCollaboratorRequestBody
+	if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+		return
+	}
+
+	if !models.IsValidWorkspaceCollaboratorRole(reqBody.Role) {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid role, allowed roles are viewer, developer and admin")
+		return
+	}
+
+	user, err := models.RetrieveUserByEmail(ctx.Param("email"))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "user not found")
+		return
+	}
+
+	if user.ID == workspace.UserID {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "the owner cannot be a collaborator of the workspace")
+		return
+	}
+
+	collaborator, err := models.SetWorkspaceCollaborator(*workspace, *user, reqBody.Role)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.LoadWorkspaceCollaboratorSerializer(collaborator))
+}
+
+// HandleDeleteWorkspaceCollaborator godoc
+// @Summary Stop sharing a workspace with a user
+// @Schemes
+// @Description Remove a user from the collaborators of the workspace, the owner can remove
+// @Description any collaborator, collaborators can remove themselves
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/workspace/:workspaceId/collaborators/:email [delete]
+func HandleDeleteWorkspaceCollaborator(ctx *gin.Context) {
+	currentUser, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// collaborators can leave the workspace, the owner can remove anyone
+	role := models.WorkspaceRoleOwner
+	if ctx.Param("email") == currentUser.Email {
+		role = models.WorkspaceRoleViewer
+	}
+
+	workspace := getWorkspaceFromContext(ctx, role)
+	if workspace == nil {
+		return
+	}
+
+	user, err := models.RetrieveUserByEmail(ctx.Param("email"))
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if user == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "collaborator not found")
+		return
+	}
+
+	collaborator, err := models.RetrieveWorkspaceCollaborator(*workspace, *user)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if collaborator == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "collaborator not found")
+		return
+	}
+
+	if err := models.DeleteWorkspaceCollaborator(collaborator); err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,187 @@
+package workspaces_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Share a workspace with another user, the actions that the
+collaborator can perform depend on the assigned role
+*/
+func TestWorkspaceCollaborators(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// create a new workspace
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{"API_KEY=secret"},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		workspaceUrl := fmt.Sprintf("/api/v1/workspace/%d", createdWorkspace.ID)
+		collaboratorUrl := fmt.Sprintf("%s/collaborators/%s", workspaceUrl, otherUser.Email)
+		idleTimeoutUrl := fmt.Sprintf("%s/set-idle-timeout", workspaceUrl)
+
+		// the workspace is not visible before it is shared
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, workspaceUrl, "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		// invalid role
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, collaboratorUrl, "PUT", workspaces.SetWorkspaceCollaboratorRequestBody{
+			Role: "owner",
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, collaboratorUrl, "PUT", workspaces.SetWorkspaceCollaboratorRequestBody{
+			Role: models.WorkspaceRoleViewer,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// shared workspaces are listed and marked as shared
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		sharedWorkspaces, err := serializers.MultipleWorkspaceSerializersFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse workspaces: '%s'", err)
+		}
+
+		if assert.Len(t, sharedWorkspaces, 1) {
+			assert.Equal(t, createdWorkspace.ID, sharedWorkspaces[0].ID)
+			assert.True(t, sharedWorkspaces[0].Shared)
+			assert.Equal(t, models.WorkspaceRoleViewer, sharedWorkspaces[0].SharedRole)
+			assert.Empty(t, sharedWorkspaces[0].EnvironmentVariables)
+		}
+
+		// the environment variables are hidden to the viewers
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, workspaceUrl, "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		sharedWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse workspace: '%s'", err)
+		}
+		assert.Empty(t, sharedWorkspace.EnvironmentVariables)
+
+		// viewers cannot change the workspace
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, idleTimeoutUrl, "POST", workspaces.SetIdleTimeoutForWorkspaceBody{})
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, collaboratorUrl, "PUT", workspaces.SetWorkspaceCollaboratorRequestBody{
+			Role: models.WorkspaceRoleAdmin,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, idleTimeoutUrl, "POST", workspaces.SetIdleTimeoutForWorkspaceBody{})
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, workspaceUrl, "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		sharedWorkspace, err = serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse workspace: '%s'", err)
+		}
+		assert.Equal(t, []string{"API_KEY=secret"}, sharedWorkspace.EnvironmentVariables)
+
+		// only the owner can delete the workspace and manage the collaborators
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, workspaceUrl, "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, collaboratorUrl, "PUT", workspaces.SetWorkspaceCollaboratorRequestBody{
+			Role: models.WorkspaceRoleAdmin,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// collaborators can leave the workspace
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, collaboratorUrl, "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, workspaceUrl, "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
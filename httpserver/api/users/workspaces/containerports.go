@@ -19,7 +19,7 @@ import (
 // @Success 200 {object} []serializers.WorkspaceContainerPort
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/port [get]
 func ListContainerPortsByWorkspaceContainer(c *gin.Context) {
-	container, err := retrieveContainerByWorkspaceAndName(c)
+	container, err := retrieveContainerByWorkspaceAndName(c, models.WorkspaceRoleViewer)
 	if err != nil {
 		return
 	}
@@ -45,7 +45,7 @@ func ListContainerPortsByWorkspaceContainer(c *gin.Context) {
 // @Success 200 {object} serializers.WorkspaceContainerPort
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/port/:portNumber [get]
 func RetrieveContainerPortsByWorkspaceContainer(ctx *gin.Context) {
-	container, err := retrieveContainerByWorkspaceAndName(ctx)
+	container, err := retrieveContainerByWorkspaceAndName(ctx, models.WorkspaceRoleViewer)
 	if err != nil {
 		return
 	}
@@ -94,7 +94,7 @@ type CreateContainerPortRequestBody struct {
 // @Success 201 {object} serializers.WorkspaceContainerPort
 // @Router/api/v1/workspace/:workspaceId/container/:containerName/port [post]
 func HandleCreateContainerPortByWorkspaceContainer(c *gin.Context) {
-	container, err := retrieveContainerByWorkspaceAndName(c)
+	container, err := retrieveContainerByWorkspaceAndName(c, models.WorkspaceRoleAdmin)
 	if err != nil {
 		return
 	}
@@ -160,7 +160,7 @@ func HandleCreateContainerPortByWorkspaceContainer(c *gin.Context) {
 // @Success 204
 // @Router/api/v1/workspace/:workspaceId/container/:containerName/port/:portNumber [delete]
 func HandleDeleteContainerPortByWorkspaceContainer(c *gin.Context) {
-	container, err := retrieveContainerByWorkspaceAndName(c)
+	container, err := retrieveContainerByWorkspaceAndName(c, models.WorkspaceRoleAdmin)
 	if err != nil {
 		return
 	}
@@ -21,33 +21,8 @@ import (
 // @Success 200 {object} []serializers.WorkspaceContainerSerializer
 // @Router /api/v1/workspace/:workspaceId/container [get]
 func ListWorkspaceContainersByWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
-		return
-	}
-
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
 	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
 		return
 	}
 
@@ -67,25 +42,10 @@ func ListWorkspaceContainersByWorkspace(ctx *gin.Context) {
 
 /*
 RetrieveWorkspaceContainerFromContext is a helper function that retrieves
-a workspace container from the context.
+a workspace container from the context, the current user must have
+at least the required role in the workspace.
 */
-func retrieveWorkspaceContainerFromContext(c *gin.Context) (*models.WorkspaceContainer, error) {
-	user, err := utils.GetUserFromContext(c)
-	if err != nil {
-		utils.ErrorResponse(
-			c, http.StatusInternalServerError, "internal server error",
-		)
-		return nil, err
-	}
-
-	workspaceId, err := utils.GetUIntParamFromContext(c, "workspaceId")
-	if err != nil {
-		utils.ErrorResponse(
-			c, http.StatusNotFound, "workspace not found",
-		)
-		return nil, errors.New("workspace id not found in path")
-	}
-
+func retrieveWorkspaceContainerFromContext(c *gin.Context, role string) (*models.WorkspaceContainer, error) {
 	containerName, found := c.Params.Get("containerName")
 	if !found {
 		utils.ErrorResponse(
@@ -94,18 +54,8 @@ func retrieveWorkspaceContainerFromContext(c *gin.Context) (*models.WorkspaceCon
 		return nil, errors.New("container name not found in path")
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, workspaceId)
-	if err != nil {
-		utils.ErrorResponse(
-			c, http.StatusInternalServerError, "internal server error",
-		)
-		return nil, err
-	}
-
+	workspace := getWorkspaceFromContext(c, role)
 	if workspace == nil {
-		utils.ErrorResponse(
-			c, http.StatusNotFound, "workspace not found",
-		)
 		return nil, errors.New("workspace not found")
 	}
 
@@ -137,7 +87,7 @@ func retrieveWorkspaceContainerFromContext(c *gin.Context) (*models.WorkspaceCon
 // @Success 200 {object} serializers.WorkspaceContainerSerializer
 // @Router /api/v1/workspace/:workspaceId/container/:containerName [get]
 func RetrieveWorkspaceContainersByWorkspace(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleViewer)
 	if err != nil {
 		return
 	}
@@ -163,7 +113,7 @@ func RetrieveWorkspaceContainersByWorkspace(c *gin.Context) {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/list-directory [get]
 func WorkspaceContainerListDirectory(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -228,7 +178,7 @@ func WorkspaceContainerListDirectory(c *gin.Context) {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/get-item-info [get]
 func WorkspaceContainerGetItemInfo(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -298,7 +248,7 @@ type CreateDirectoryRequest struct {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/create-directory [post]
 func WorkspaceContainerCreateDirectory(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -368,7 +318,7 @@ func WorkspaceContainerCreateDirectory(c *gin.Context) {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/delete-item [delete]
 func WorkspaceContainerDeleteItem(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -433,7 +383,7 @@ type RenameItemRequest struct {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/rename-item [post]
 func WorkspaceContainerRenameItem(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -511,7 +461,7 @@ func WorkspaceContainerRenameItem(c *gin.Context) {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/read-file [get]
 func WorkspaceContainerReadFile(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -583,7 +533,7 @@ type WriteFileRequest struct {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/write-file [post]
 func WorkspaceContainerWriteFile(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -663,7 +613,7 @@ type ExecuteCommandRequest struct {
 // @Failure 500 {object} serializers.ErrorSerializer "Internal server error"
 // @Router /api/v1/workspace/:workspaceId/container/:containerName/fs/execute-command [post]
 func WorkspaceContainerExecuteCommand(c *gin.Context) {
-	container, err := retrieveWorkspaceContainerFromContext(c)
+	container, err := retrieveWorkspaceContainerFromContext(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -0,0 +1,58 @@
+package workspaces
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// HandleListWorkspaceEvents godoc
+// @Summary List workspace events
+// @Schemes
+// @Description List the status transitions of a workspace, the most recent first.
+// @Description Each event reports who requested the transition and where it comes from
+// @Description (user, admin, scheduler, reconciler or system)
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param limit query int false "Max number of events, -1 means no limit"
+// @Param offset query int false "Number of events to skip"
+// @Success 200 {object} []serializers.WorkspaceEventSerializer
+// @Router /api/v1/workspace/:workspaceId/events [get]
+func HandleListWorkspaceEvents(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
+	if workspace == nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "-1"))
+	if err != nil || limit < -1 || limit == 0 {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid limit")
+		return
+	}
+
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid offset")
+		return
+	}
+
+	events, err := models.ListWorkspaceEvents(*workspace, limit, offset)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	count, err := models.CountWorkspaceEvents(*workspace)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.Header("X-Total-Count", strconv.FormatInt(count, 10))
+	ctx.JSON(http.StatusOK, serializers.LoadMultipleWorkspaceEventSerializer(events))
+}
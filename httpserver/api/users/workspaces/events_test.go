@@ -0,0 +1,121 @@
+package workspaces_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Status transitions requested by the user are recorded
+with the actor and listed from the most recent
+*/
+func TestWorkspaceEvents(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// create a new workspace
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		eventsUrl := fmt.Sprintf("/api/v1/workspace/%d/events", createdWorkspace.ID)
+
+		// the creation is recorded
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", eventsUrl, nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		events, err := serializers.MultipleWorkspaceEventSerializersFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse events: '%s'", err)
+		}
+		assert.Len(t, events, 1)
+		assert.Equal(t, "", events[0].FromStatus)
+		assert.Equal(t, models.WorkspaceStatusStarting, events[0].ToStatus)
+		assert.Equal(t, models.WorkspaceEventSourceUser, events[0].Source)
+
+		// stop the workspace
+		dbconn.DB.Model(&models.Workspace{}).
+			Where("id = ?", createdWorkspace.ID).
+			Update("status", models.WorkspaceStatusRunning)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("POST", fmt.Sprintf("/api/v1/workspace/%d/stop", createdWorkspace.ID), nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", eventsUrl+"?limit=1", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-Total-Count"))
+
+		events, err = serializers.MultipleWorkspaceEventSerializersFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse events: '%s'", err)
+		}
+		assert.Len(t, events, 1)
+		assert.Equal(t, models.WorkspaceStatusRunning, events[0].FromStatus)
+		assert.Equal(t, models.WorkspaceStatusStopping, events[0].ToStatus)
+		if assert.NotNil(t, events[0].Actor) {
+			assert.Equal(t, "user1@user.com", events[0].Actor.Email)
+		}
+
+		// other users cannot list the events
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", eventsUrl, nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -13,7 +13,7 @@ import (
 Endpoint that handles ssh connection
 */
 func HandleForwardSsh(c *gin.Context) {
-	container, err := retrieveContainerByWorkspaceAndName(c)
+	container, err := retrieveContainerByWorkspaceAndName(c, models.WorkspaceRoleDeveloper)
 	if err != nil {
 		return
 	}
@@ -34,6 +34,9 @@ func HandleForwardSsh(c *gin.Context) {
 	ri := runnerinterface.RunnerInterface{
 		Runner: runner,
 	}
+	stopTracking := utils.TrackContainerActivity(container, models.ContainerActivitySsh)
+	defer stopTracking()
+
 	if err := ri.AgentForwardSsh(&workspace, container, c.Writer, c.Request); err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
 		return
@@ -0,0 +1,139 @@
+package workspaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/httpserver/tunnels"
+	"gitlab.com/codebox4073715/codebox/logging"
+	"gitlab.com/codebox4073715/codebox/runnerinterface"
+)
+
+var tcpTunnelUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Accepting all requests
+	},
+}
+
+// HandleForwardTcp godoc
+// @Summary Forward a TCP port of a container
+// @Schemes ws wss
+// @Description Open a WebSocket that tunnels a raw TCP stream to a port of the container through the runner,
+// @Description the stream is carried by binary messages. Any port of the container can be reached, the same
+// @Description permissions of the private ports are required
+// @Tags Workspaces
+// @Success 101
+// @Failure 409 "The workspace has no runner or the runner does not support tcp forwarding"
+// @Router /api/v1/workspace/:workspaceId/container/:containerName/forward-tcp/:portNumber [get]
+func HandleForwardTcp(c *gin.Context) {
+	user, err := utils.GetUserFromContext(c)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	// private ports are accessible by the owner and by the developers of the workspace
+	container, err := retrieveContainerByWorkspaceAndName(c, models.WorkspaceRoleDeveloper)
+	if err != nil {
+		return
+	}
+
+	portNumber, err := utils.GetUIntParamFromContext(c, "portNumber")
+	if err != nil || portNumber == 0 || portNumber >= 65536 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid port number")
+		return
+	}
+
+	workspace := container.Workspace
+	if workspace.RunnerID == nil {
+		utils.ErrorResponse(c, http.StatusConflict, "the workspace has no runner")
+		return
+	}
+
+	runner, err := models.RetrieveRunnerByID(*workspace.RunnerID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if runner == nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "runner not found")
+		return
+	}
+
+	// older runners cannot forward raw tcp streams to the agents
+	if !runner.HasCapability(models.RunnerCapabilityTcpForwarding) {
+		utils.ErrorResponse(c, http.StatusConflict, "the runner of the workspace does not support tcp forwarding")
+		return
+	}
+
+	ri := runnerinterface.RunnerInterface{
+		Runner: runner,
+	}
+
+	// the agent is reached before upgrading the connection, so errors can be returned to the client
+	agentConn, err := ri.AgentDialTcp(&workspace, container, portNumber)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadGateway, "cannot connect to the port")
+		return
+	}
+
+	clientConn, err := tcpTunnelUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		agentConn.Close()
+		return
+	}
+
+	tunnel := &tunnels.Tunnel{
+		WorkspaceID:   workspace.ID,
+		ContainerName: container.ContainerName,
+		PortNumber:    portNumber,
+		UserEmail:     user.Email,
+		RemoteAddr:    c.ClientIP(),
+	}
+	unregister := tunnels.Register(tunnel)
+	defer unregister()
+
+	stopTracking := utils.TrackContainerActivity(container, models.ContainerActivityPortForward)
+	defer stopTracking()
+
+	tunnels.Pipe(tunnel, clientConn, agentConn)
+
+	logging.Info(
+		"tcp tunnel %d to port %d of container %s of workspace %d closed, %d bytes sent, %d bytes received",
+		tunnel.ID,
+		portNumber,
+		container.ContainerName,
+		workspace.ID,
+		tunnel.BytesSent(),
+		tunnel.BytesReceived(),
+	)
+}
+
+// HandleListTcpTunnels godoc
+// @Summary List the active TCP tunnels of a container
+// @Schemes
+// @Description List the active TCP tunnels to the ports of a container with the bytes transferred
+// @Description in each direction, bytes_sent are sent by the client to the container
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.TcpTunnelSerializer
+// @Router /api/v1/workspace/:workspaceId/container/:containerName/tcp-tunnels [get]
+func HandleListTcpTunnels(c *gin.Context) {
+	container, err := retrieveContainerByWorkspaceAndName(c, models.WorkspaceRoleAdmin)
+	if err != nil {
+		return
+	}
+
+	c.JSON(
+		http.StatusOK,
+		serializers.LoadMultipleTcpTunnelSerializer(
+			tunnels.ListByContainer(container.WorkspaceID, container.ContainerName),
+		),
+	)
+}
@@ -1,56 +1,266 @@
 package workspaces
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
 )
 
+// interval between two reads of the logs file while streaming
+const logsStreamPollInterval = 500 * time.Millisecond
+
+// max number of bytes sent in a single message while streaming
+const logsStreamChunkSize = 64 * 1024
+
+var logsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // Accepting all requests
+	},
+}
+
 // HandleRetrieveWorkspaceLogs godoc
 // @Summary Retrieve workspace logs
 // @Schemes
-// @Description Retrieve workspace logs
+// @Description Retrieve workspace logs. Use offset and limit (in bytes) to retrieve a part of the logs,
+// @Description a truncated chunk always ends with a complete line. Set format=text to download the logs as text/plain
 // @Tags Workspaces
 // @Accept json
 // @Produce json
-// @Success 200
+// @Param offset query int false "Offset in bytes"
+// @Param limit query int false "Max number of bytes, 0 means no limit"
+// @Param format query string false "json (default) or text"
+// @Success 200 {object} serializers.WorkspaceLogsSerializer
 // @Router /api/v1/workspace/:workspaceId/logs [get]
 func HandleRetrieveWorkspaceLogs(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
+	if workspace == nil {
 		return
 	}
 
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
+	offset, limit, ok := getLogsRangeFromQuery(ctx)
+	if !ok {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid offset or limit")
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
+	chunk, err := workspace.RetrieveLogsChunk(offset, limit)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if ctx.Query("format") == "text" {
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=workspace_%d.log", workspace.ID))
+		ctx.Header("X-Logs-Next-Offset", strconv.FormatInt(chunk.NextOffset, 10))
+		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(chunk.Logs))
 		return
 	}
 
+	ctx.JSON(http.StatusOK, serializers.LoadWorkspaceLogsSerializer(chunk))
+}
+
+// HandleStreamWorkspaceLogs godoc
+// @Summary Stream workspace logs
+// @Schemes ws wss http https
+// @Description Stream workspace logs over WebSocket, or as server-sent events if the request is not a
+// @Description WebSocket upgrade. Logs are streamed from the byte offset or from the line cursor and
+// @Description new lines are pushed as they are written. The stream is closed when the workspace
+// @Description is neither starting nor stopping, an 'end' message is sent before closing
+// @Tags Workspaces
+// @Param offset query int false "Offset in bytes"
+// @Param line query int false "Line cursor, used if offset is not set"
+// @Success 101 {object} serializers.WorkspaceLogsSerializer
+// @Success 200 {object} serializers.WorkspaceLogsSerializer
+// @Router /api/v1/workspace/:workspaceId/logs/stream [get]
+func HandleStreamWorkspaceLogs(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
 	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
+		return
+	}
+
+	offset, ok := getLogsStreamOffsetFromQuery(ctx, workspace)
+	if !ok {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid offset or line")
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(ctx.Request) {
+		streamWorkspaceLogsWebSocket(ctx, workspace, offset)
+	} else {
+		streamWorkspaceLogsSSE(ctx, workspace, offset)
+	}
+}
+
+/*
+Retrieve offset and limit from the query string,
+false is returned if one of them is invalid
+*/
+func getLogsRangeFromQuery(ctx *gin.Context) (offset int64, limit int64, ok bool) {
+	offset, err := strconv.ParseInt(ctx.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, 0, false
+	}
+
+	limit, err = strconv.ParseInt(ctx.DefaultQuery("limit", "0"), 10, 64)
+	if err != nil || limit < 0 {
+		return 0, 0, false
+	}
+	return offset, limit, true
+}
+
+/*
+Retrieve the offset from which the logs are streamed, the byte
+offset has the precedence over the line cursor
+*/
+func getLogsStreamOffsetFromQuery(ctx *gin.Context, workspace *models.Workspace) (int64, bool) {
+	if value, found := ctx.GetQuery("offset"); found {
+		offset, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || offset < 0 {
+			return 0, false
+		}
+		return offset, true
+	}
+
+	if value, found := ctx.GetQuery("line"); found {
+		line, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || line < 0 {
+			return 0, false
+		}
+
+		offset, err := workspace.RetrieveLogsOffsetFromLine(line)
+		if err != nil {
+			return 0, false
+		}
+		return offset, true
+	}
+
+	return 0, true
+}
+
+/*
+Check if the logs of a workspace can still change, logs are
+streamed only while the workspace is starting or stopping
+*/
+func isWorkspaceLogsStreamOpen(workspace *models.Workspace) bool {
+	status, err := models.RetrieveWorkspaceStatus(workspace.ID)
+	if err != nil {
+		return false
+	}
+	return status == models.WorkspaceStatusStarting || status == models.WorkspaceStatusStopping
+}
+
+/*
+Tail the logs of a workspace, send is called for each new chunk. The function returns
+when the workspace leaves the starting/stopping status and all the logs have been sent,
+when send fails or when done is closed
+*/
+func tailWorkspaceLogs(
+	workspace *models.Workspace,
+	offset int64,
+	done <-chan struct{},
+	send func(chunk models.WorkspaceLogsChunk) error,
+) (int64, error) {
+	ticker := time.NewTicker(logsStreamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// the status is checked before reading, so the lines written
+		// before the status change are always sent
+		open := isWorkspaceLogsStreamOpen(workspace)
+
+		for {
+			chunk, err := workspace.RetrieveLogsChunk(offset, logsStreamChunkSize)
+			if err != nil {
+				return offset, err
+			}
+
+			if chunk.NextOffset == offset && chunk.Offset == offset {
+				break
+			}
+
+			if err := send(chunk); err != nil {
+				return offset, err
+			}
+			offset = chunk.NextOffset
+		}
+
+		if !open {
+			return offset, nil
+		}
+
+		select {
+		case <-done:
+			return offset, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func streamWorkspaceLogsWebSocket(ctx *gin.Context, workspace *models.Workspace, offset int64) {
+	wsConn, err := logsUpgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "cannot upgrade ws connection")
+		return
+	}
+	defer wsConn.Close()
+
+	// the client is not expected to send messages, reading is
+	// required to detect when the connection is closed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := wsConn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	offset, err = tailWorkspaceLogs(workspace, offset, done, func(chunk models.WorkspaceLogsChunk) error {
+		return wsConn.WriteJSON(gin.H{
+			"type": "logs",
+			"data": serializers.LoadWorkspaceLogsSerializer(chunk),
 		})
+	})
+	if err != nil {
 		return
 	}
 
-	logs, _ := workspace.RetrieveLogs()
-	ctx.JSON(http.StatusOK, gin.H{
-		"logs": logs,
+	wsConn.WriteJSON(gin.H{
+		"type": "end",
+		"data": gin.H{"next_offset": offset},
 	})
+	wsConn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "workspace logs completed"),
+		time.Now().Add(time.Second),
+	)
+}
+
+func streamWorkspaceLogsSSE(ctx *gin.Context, workspace *models.Workspace, offset int64) {
+	// same value set by gin when the events are written
+	ctx.Header("Content-Type", "text/event-stream;charset=utf-8")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	offset, err := tailWorkspaceLogs(workspace, offset, ctx.Request.Context().Done(), func(chunk models.WorkspaceLogsChunk) error {
+		ctx.SSEvent("logs", serializers.LoadWorkspaceLogsSerializer(chunk))
+		ctx.Writer.Flush()
+		return ctx.Request.Context().Err()
+	})
+	if err != nil {
+		return
+	}
+
+	ctx.SSEvent("end", gin.H{"next_offset": offset})
+	ctx.Writer.Flush()
 }
@@ -0,0 +1,157 @@
+package workspaces_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Retrieve the logs of a workspace in chunks, as text
+and as a stream of server-sent events
+*/
+func TestWorkspaceLogs(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// create a new workspace
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		workspace, err := models.RetrieveWorkspaceById(createdWorkspace.ID)
+		if err != nil || workspace == nil {
+			t.Fatalf("Failed to retrieve workspace: '%s'", err)
+		}
+
+		workspace.ClearLogs()
+		defer workspace.ClearLogs()
+		workspace.AppendLogs("first line")
+		workspace.AppendLogs("second line")
+		workspace.AppendLogs("third line")
+
+		logs, _ := workspace.RetrieveLogs()
+		firstLineLength := int64(strings.Index(logs, "\n") + 1)
+
+		// the first chunk ends with a complete line
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(
+			"GET",
+			fmt.Sprintf("/api/v1/workspace/%d/logs?limit=%d", workspace.ID, firstLineLength+5),
+			nil,
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		chunk, err := serializers.WorkspaceLogsSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse logs: '%s'", err)
+		}
+		assert.Contains(t, chunk.Logs, "first line")
+		assert.NotContains(t, chunk.Logs, "second line")
+		assert.Equal(t, firstLineLength, chunk.NextOffset)
+		assert.Equal(t, int64(len(logs)), chunk.Size)
+
+		// the following chunk
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(
+			"GET",
+			fmt.Sprintf("/api/v1/workspace/%d/logs?offset=%d", workspace.ID, chunk.NextOffset),
+			nil,
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		chunk, err = serializers.WorkspaceLogsSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse logs: '%s'", err)
+		}
+		assert.NotContains(t, chunk.Logs, "first line")
+		assert.Contains(t, chunk.Logs, "third line")
+		assert.Equal(t, int64(len(logs)), chunk.NextOffset)
+
+		// invalid offset
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/workspace/%d/logs?offset=-1", workspace.ID), nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// download as text
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/workspace/%d/logs?format=text", workspace.ID), nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+		assert.Equal(t, logs, w.Body.String())
+
+		// the stream is closed since the workspace is not starting
+		dbconn.DB.Model(workspace).Update("status", models.WorkspaceStatusStopped)
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/workspace/%d/logs/stream?line=1", workspace.ID), nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream"))
+		assert.NotContains(t, w.Body.String(), "first line")
+		assert.Contains(t, w.Body.String(), "second line")
+		assert.Contains(t, w.Body.String(), "event:end")
+
+		// other users cannot read the logs
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", fmt.Sprintf("/api/v1/workspace/%d/logs/stream", workspace.ID), nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,169 @@
+package workspaces
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// share tokens cannot last more than 30 days
+const maxPortShareTokenDurationMinutes = 30 * 24 * 60
+
+// retrievePortFromContext retrieves the port referenced by the portNumber param,
+// the current user must have at least the required role in the workspace.
+// An error response is sent and nil is returned if it does not exist.
+func retrievePortFromContext(ctx *gin.Context, role string) *models.WorkspaceContainerPort {
+	container, err := retrieveContainerByWorkspaceAndName(ctx, role)
+	if err != nil {
+		return nil
+	}
+
+	portNumber, err := utils.GetUIntParamFromContext(ctx, "portNumber")
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "port not found")
+		return nil
+	}
+
+	port, err := models.RetrieveContainerPortByPortNumber(*container, portNumber)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if port == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "port not found")
+		return nil
+	}
+	return port
+}
+
+// HandleListPortShareTokens godoc
+// @Summary List the share tokens of a port
+// @Schemes
+// @Description List the tokens that give access to a private port without being a collaborator of the workspace
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 200 {object} []serializers.PortShareTokenSerializer
+// @Router /api/v1/workspace/:workspaceId/container/:containerName/port/:portNumber/share-tokens [get]
+func HandleListPortShareTokens(ctx *gin.Context) {
+	port := retrievePortFromContext(ctx, models.WorkspaceRoleAdmin)
+	if port == nil {
+		return
+	}
+
+	tokens, err := models.ListPortShareTokens(*port)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.LoadMultiplePortShareTokenSerializer(tokens, port))
+}
+
+type CreatePortShareTokenRequestBody struct {
+	Name             string `json:"name"`
+	ExpiresInMinutes uint   `json:"expires_in_minutes" binding:"required"`
+	Password         string `json:"password"` // empty means that no password is required
+	MaxUses          *uint  `json:"max_uses"` // null means unlimited
+}
+
+// HandleCreatePortShareToken godoc
+// @Summary Create a share token for a port
+// @Schemes
+// @Description Create a token that gives access to a private port until it expires, the port can be opened
+// @Description with the share_url, then a cookie scoped to the port is set. If a password is set it is asked
+// @Description before setting the cookie, max_uses limits how many times the link can be opened
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param request body CreatePortShareTokenRequestBody true "Share token options"
+// @Success 201 {object} serializers.PortShareTokenSerializer
+// @Router /api/v1/workspace/:workspaceId/container/:containerName/port/:portNumber/share-tokens [post]
+func HandleCreatePortShareToken(ctx *gin.Context) {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	port := retrievePortFromContext(ctx, models.WorkspaceRoleAdmin)
+	if port == nil {
+		return
+	}
+
+	var reqBody CreatePortShareTokenRequestBody
+	if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+		return
+	}
+
+	if reqBody.ExpiresInMinutes > maxPortShareTokenDurationMinutes {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "share tokens cannot last more than 30 days")
+		return
+	}
+
+	if reqBody.MaxUses != nil && *reqBody.MaxUses == 0 {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "max_uses must be greater than zero")
+		return
+	}
+
+	token, err := models.CreatePortShareToken(
+		*port,
+		user,
+		reqBody.Name,
+		time.Now().Add(time.Duration(reqBody.ExpiresInMinutes)*time.Minute),
+		reqBody.Password,
+		reqBody.MaxUses,
+	)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, serializers.LoadPortShareTokenSerializer(token, port))
+}
+
+// HandleDeletePortShareToken godoc
+// @Summary Revoke a share token of a port
+// @Schemes
+// @Description Revoke a share token, the link and the cookies set using it stop working
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/workspace/:workspaceId/container/:containerName/port/:portNumber/share-tokens/:tokenId [delete]
+func HandleDeletePortShareToken(ctx *gin.Context) {
+	port := retrievePortFromContext(ctx, models.WorkspaceRoleAdmin)
+	if port == nil {
+		return
+	}
+
+	id, err := utils.GetUIntParamFromContext(ctx, "tokenId")
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "share token not found")
+		return
+	}
+
+	token, err := models.RetrievePortShareTokenByID(*port, id)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if token == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "share token not found")
+		return
+	}
+
+	if err := models.DeletePortShareToken(token); err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusNoContent, gin.H{})
+}
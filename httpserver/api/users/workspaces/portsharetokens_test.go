@@ -0,0 +1,149 @@
+package workspaces_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Create, list and revoke the share tokens of a private port
+*/
+func TestPortShareTokens(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		otherUser, err := models.RetrieveUserByEmail("user2@user.com")
+		if err != nil || otherUser == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// create a new workspace
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		container := models.WorkspaceContainer{
+			WorkspaceID:   createdWorkspace.ID,
+			ContainerName: "development",
+		}
+		if err := dbconn.DB.Create(&container).Error; err != nil {
+			t.Fatalf("Failed to create container: '%s'", err)
+		}
+
+		if _, err := models.CreateContainerPort(container, "web", 8080, false); err != nil {
+			t.Fatalf("Failed to create port: '%s'", err)
+		}
+
+		tokensUrl := fmt.Sprintf(
+			"/api/v1/workspace/%d/container/%s/port/%d/share-tokens",
+			createdWorkspace.ID,
+			container.ContainerName,
+			8080,
+		)
+
+		// share tokens cannot last more than 30 days
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, tokensUrl, "POST", workspaces.CreatePortShareTokenRequestBody{
+			ExpiresInMinutes: 31 * 24 * 60,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		maxUses := uint(0)
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, tokensUrl, "POST", workspaces.CreatePortShareTokenRequestBody{
+			ExpiresInMinutes: 60,
+			MaxUses:          &maxUses,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		maxUses = 3
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, tokensUrl, "POST", workspaces.CreatePortShareTokenRequestBody{
+			Name:             "Review",
+			ExpiresInMinutes: 60,
+			Password:         "password",
+			MaxUses:          &maxUses,
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		token, err := serializers.PortShareTokenSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse share token: '%s'", err)
+		}
+		assert.True(t, token.HasPassword)
+		assert.Contains(t, token.ShareUrl, token.Token)
+
+		// users that cannot access the workspace cannot manage the tokens
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, tokensUrl, "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *otherUser)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, tokensUrl, "GET", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), token.Token)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, fmt.Sprintf("%s/%d", tokensUrl, token.ID), "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, fmt.Sprintf("%s/%d", tokensUrl, token.ID), "DELETE", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
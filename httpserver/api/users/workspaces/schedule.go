@@ -0,0 +1,121 @@
+package workspaces
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// max number of skipped runs returned with the schedule
+const maxScheduleSkippedRuns = 20
+
+// HandleRetrieveWorkspaceSchedule godoc
+// @Summary Retrieve the schedule of a workspace
+// @Schemes
+// @Description Retrieve start/stop schedules of a workspace, the next planned runs
+// @Description and the latest runs that have been skipped
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 200 {object} serializers.WorkspaceScheduleSerializer
+// @Router /api/v1/workspace/:workspaceId/schedule [get]
+func HandleRetrieveWorkspaceSchedule(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
+	if workspace == nil {
+		return
+	}
+
+	skippedRuns, err := models.ListWorkspaceScheduleSkippedRuns(*workspace, maxScheduleSkippedRuns)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		serializers.LoadWorkspaceScheduleSerializer(workspace, workspace.User.GetLocation(), skippedRuns),
+	)
+}
+
+type UpdateWorkspaceScheduleRequestBody struct {
+	StartSchedule string `json:"start_schedule"`
+	StopSchedule  string `json:"stop_schedule"`
+}
+
+// HandleUpdateWorkspaceSchedule godoc
+// @Summary Update the schedule of a workspace
+// @Schemes
+// @Description Set start/stop schedules of a workspace, schedules use the standard cron format
+// @Description (e.g. '0 8 * * 1-5') and are evaluated in the time zone of the owner.
+// @Description An empty value removes the schedule
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param request body UpdateWorkspaceScheduleRequestBody true "Request body"
+// @Success 200 {object} serializers.WorkspaceScheduleSerializer
+// @Router /api/v1/workspace/:workspaceId/schedule [put]
+func HandleUpdateWorkspaceSchedule(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
+		return
+	}
+
+	var reqBody UpdateWorkspaceScheduleRequestBody
+	if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+		return
+	}
+
+	if _, err := models.ParseWorkspaceSchedule(reqBody.StartSchedule); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid value for 'start_schedule'")
+		return
+	}
+
+	if _, err := models.ParseWorkspaceSchedule(reqBody.StopSchedule); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "invalid value for 'stop_schedule'")
+		return
+	}
+
+	workspace, err := models.SetWorkspaceSchedule(workspace, reqBody.StartSchedule, reqBody.StopSchedule)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	skippedRuns, err := models.ListWorkspaceScheduleSkippedRuns(*workspace, maxScheduleSkippedRuns)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(
+		http.StatusOK,
+		serializers.LoadWorkspaceScheduleSerializer(workspace, workspace.User.GetLocation(), skippedRuns),
+	)
+}
+
+// HandleDeleteWorkspaceSchedule godoc
+// @Summary Remove the schedule of a workspace
+// @Schemes
+// @Description Remove start/stop schedules of a workspace
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Success 204
+// @Router /api/v1/workspace/:workspaceId/schedule [delete]
+func HandleDeleteWorkspaceSchedule(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
+		return
+	}
+
+	if _, err := models.SetWorkspaceSchedule(workspace, "", ""); err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
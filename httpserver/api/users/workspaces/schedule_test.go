@@ -0,0 +1,130 @@
+package workspaces_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+/*
+Set, retrieve and remove the schedule of a workspace
+*/
+func TestWorkspaceSchedule(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// create a new workspace
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		scheduleUrl := fmt.Sprintf("/api/v1/workspace/%d/schedule", createdWorkspace.ID)
+
+		// set an invalid schedule (should fail)
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			scheduleUrl,
+			"PUT",
+			workspaces.UpdateWorkspaceScheduleRequestBody{
+				StartSchedule: "not a cron expression",
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		// set a valid schedule
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(
+			t,
+			scheduleUrl,
+			"PUT",
+			workspaces.UpdateWorkspaceScheduleRequestBody{
+				StartSchedule: "0 8 * * 1-5",
+				StopSchedule:  "0 19 * * 1-5",
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// retrieve the schedule
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", scheduleUrl, nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var schedule serializers.WorkspaceScheduleSerializer
+		if err := json.Unmarshal(w.Body.Bytes(), &schedule); err != nil {
+			t.Fatalf("Failed to parse schedule: '%s'", err)
+		}
+		assert.Equal(t, "0 8 * * 1-5", schedule.StartSchedule)
+		assert.Equal(t, "0 19 * * 1-5", schedule.StopSchedule)
+		assert.NotNil(t, schedule.NextStart)
+		assert.NotNil(t, schedule.NextStop)
+		assert.Equal(t, 0, len(schedule.SkippedRuns))
+
+		// remove the schedule
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("DELETE", scheduleUrl, nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		workspace, err := models.RetrieveWorkspaceByUserAndId(*user, createdWorkspace.ID)
+		if err != nil || workspace == nil {
+			t.Fatalf("Failed to retrieve workspace: '%s'", err)
+		}
+		assert.Equal(t, "", workspace.StartSchedule)
+		assert.Equal(t, "", workspace.StopSchedule)
+
+		// retrieve the schedule of a workspace that does not exist
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/api/v1/workspace/104/schedule", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -10,22 +10,6 @@ import (
 )
 
 func HandleTerminal(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
-	workspaceId, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
-		return
-	}
-
 	containerName, found := ctx.Params.Get("containerName")
 	if !found {
 		ctx.JSON(http.StatusNotFound, gin.H{
@@ -34,18 +18,8 @@ func HandleTerminal(ctx *gin.Context) {
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, workspaceId)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleDeveloper)
 	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
 		return
 	}
 
@@ -68,6 +42,9 @@ func HandleTerminal(ctx *gin.Context) {
 		Runner: workspace.Runner,
 	}
 
+	stopTracking := utils.TrackContainerActivity(container, models.ContainerActivityTerminal)
+	defer stopTracking()
+
 	if err := ri.AgentForwardTerminal(
 		workspace,
 		container,
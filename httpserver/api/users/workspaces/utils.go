@@ -5,36 +5,26 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/config"
 	"gitlab.com/codebox4073715/codebox/db/models"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/runnerscheduler"
 )
 
 // retrieveContainerByWorkspaceAndName retrieves a container by
-// workspace ID and container name from the context.
+// workspace ID and container name from the context, the current user
+// must have at least the required role in the workspace.
 // It returns the container if found, or an error if not
 // found or if there is an internal error.
-func retrieveContainerByWorkspaceAndName(ctx *gin.Context) (*models.WorkspaceContainer, error) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		utils.ErrorResponse(ctx, http.StatusNotFound, "internal server error")
-		return nil, errors.New("unknown error")
-	}
-
-	workspaceId, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
-		return nil, errors.New("workspace not found")
-	}
-
+func retrieveContainerByWorkspaceAndName(ctx *gin.Context, role string) (*models.WorkspaceContainer, error) {
 	containerName, found := ctx.Params.Get("containerName")
 	if !found {
 		utils.ErrorResponse(ctx, http.StatusNotFound, "container not found")
 		return nil, errors.New("container not found")
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, workspaceId)
-	if err != nil || workspace == nil {
-		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
+	workspace := getWorkspaceFromContext(ctx, role)
+	if workspace == nil {
 		return nil, errors.New("workspace not found")
 	}
 
@@ -46,3 +36,166 @@ func retrieveContainerByWorkspaceAndName(ctx *gin.Context) (*models.WorkspaceCon
 
 	return container, nil
 }
+
+// respondQuotaError sends the response for an error returned by a quota check:
+// 403 with the explanation if a quota has been exceeded, 500 otherwise.
+// It returns false if there is no error.
+func respondQuotaError(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var quotaErr *models.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		utils.ErrorResponse(ctx, http.StatusForbidden, quotaErr.Error())
+		return true
+	}
+
+	utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	return true
+}
+
+// respondResourceLimitsError sends the response for an error returned by a
+// resource limits check: 400 with the explanation if the limits are not valid
+// or cannot be enforced by the runner, 500 otherwise.
+// It returns false if there is no error.
+func respondResourceLimitsError(ctx *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var limitsErr *models.InvalidResourceLimitsError
+	if errors.As(err, &limitsErr) {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, limitsErr.Error())
+		return true
+	}
+
+	utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+	return true
+}
+
+// checkRunnerForWorkspace checks that the runner can host a workspace of the given
+// type for the user: the runner must support the type, be allowed for the user, have
+// the required labels and be able to enforce the resource limits.
+// An error response is sent and false is returned if it cannot.
+func checkRunnerForWorkspace(
+	ctx *gin.Context,
+	runner models.Runner,
+	user models.User,
+	workspaceType string,
+	requiredLabels models.RunnerLabels,
+	limits models.ResourceLimits,
+) bool {
+	if config.RetrieveRunnerTypeByID(runner.Type) == nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "runner matching runner_id and type not found")
+		return false
+	}
+
+	// check if the runner supports the requested workspace type
+	if !runnerscheduler.SupportsWorkspaceType(runner, workspaceType) {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "runner does not support the requested workspace type")
+		return false
+	}
+
+	// check if the user is allowed to use the runner
+	allowed, err := runner.IsAllowedForUser(user)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return false
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "you are not allowed to use the requested runner")
+		return false
+	}
+
+	// check if the runner satisfies the placement constraints
+	if err := models.CheckRunnerLabels(runner, requiredLabels); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return false
+	}
+
+	return !respondResourceLimitsError(ctx, models.CheckRunnerResourceLimitsSupport(runner, limits))
+}
+
+// resolveTemplateParameterValues merges the values received in the request with
+// the current ones and validates them against the parameters of the template version.
+// An error response is sent if the values are not valid.
+func resolveTemplateParameterValues(
+	ctx *gin.Context,
+	templateVersion models.WorkspaceTemplateVersion,
+	current models.TemplateParameterValues,
+	values map[string]any,
+) (models.TemplateParameterValues, error) {
+	requested, err := models.NormalizeTemplateParameterValues(values)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return nil, err
+	}
+
+	// values of parameters removed from the template version are dropped
+	merged := models.TemplateParameterValues{}
+	for _, p := range templateVersion.Parameters {
+		if value, ok := current[p.Name]; ok {
+			merged[p.Name] = value
+		}
+	}
+	for name, value := range requested {
+		merged[name] = value
+	}
+
+	resolved, err := models.ResolveTemplateParameterValues(templateVersion.Parameters, merged)
+	if err != nil {
+		var parameterErr *models.InvalidTemplateParameterError
+		if errors.As(err, &parameterErr) {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, parameterErr.Error())
+			return nil, err
+		}
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
+// getWorkspaceFromContext retrieves the workspace referenced by the workspaceId
+// param among the workspaces owned by the current user or shared with them.
+// An error response is sent and nil is returned if it does not exist or
+// if the user does not have at least the required role.
+func getWorkspaceFromContext(ctx *gin.Context, role string) *models.Workspace {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
+		return nil
+	}
+
+	workspace, userRole, err := models.RetrieveWorkspaceWithRoleByUserAndId(user, id)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return nil
+	}
+
+	if workspace == nil {
+		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
+		return nil
+	}
+
+	if !models.WorkspaceRoleAllows(userRole, role) {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "you don't have the permission to perform this action")
+		return nil
+	}
+	return workspace
+}
+
+// recordWorkspaceEvent records the transition of a workspace requested
+// through the api, the actor is retrieved from the request context.
+func recordWorkspaceEvent(ctx *gin.Context, workspace *models.Workspace, fromStatus string, message string) {
+	source, actor := utils.GetWorkspaceEventActorFromContext(ctx)
+	models.CreateWorkspaceEvent(*workspace, fromStatus, source, actor, message)
+}
@@ -1,8 +1,9 @@
 package workspaces
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,12 +13,14 @@ import (
 	"gitlab.com/codebox4073715/codebox/db/models"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/runnerscheduler"
 )
 
 // HandleListWorkspaces godoc
 // @Summary List workspaces
 // @Schemes
-// @Description List workspaces created by the current user
+// @Description List workspaces created by the current user followed by the workspaces
+// @Description shared with them, shared workspaces are marked with the role of the user
 // @Tags Workspaces
 // @Accept json
 // @Produce json
@@ -39,7 +42,17 @@ func HandleListWorkspaces(ctx *gin.Context) {
 		})
 		return
 	}
-	ctx.JSON(http.StatusOK, serializers.LoadMultipleWorkspaceSerializer(workspaces))
+
+	shared, err := models.ListSharedWorkspaces(user)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, append(
+		serializers.LoadMultipleWorkspaceSerializer(workspaces),
+		serializers.LoadMultipleSharedWorkspaceSerializer(shared)...,
+	))
 }
 
 // HandleRetrieveWorkspace godoc
@@ -54,40 +67,23 @@ func HandleListWorkspaces(ctx *gin.Context) {
 func HandleRetrieveWorkspace(ctx *gin.Context) {
 	user, err := utils.GetUserFromContext(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
-	id, found := ctx.Params.Get("workspaceId")
-	if !found {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
-	workspaceId, err := strconv.Atoi(id)
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspce not found",
-		})
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleViewer)
+	if workspace == nil {
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, uint(workspaceId))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
+	if workspace.UserID != user.ID {
+		role, err := models.RetrieveWorkspaceRoleForUser(*workspace, user)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
 
-	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
+		ctx.JSON(http.StatusOK, *serializers.LoadSharedWorkspaceSerializer(workspace, role))
 		return
 	}
 
@@ -97,19 +93,28 @@ func HandleRetrieveWorkspace(ctx *gin.Context) {
 type CreateWorkspaceRequestBody struct {
 	Name                 string   `json:"name" binding:"required"`
 	Type                 string   `json:"type" binding:"required"`
-	RunnerID             uint     `json:"runner_id" binding:"required"`
+	RunnerID             uint     `json:"runner_id"` // 0 means that the runner is chosen automatically
 	ConfigSource         string   `json:"config_source" binding:"required"`
 	TemplateVersionID    uint     `json:"template_version_id"`
 	GitRepoUrl           string   `json:"git_repo_url"`
 	GitRefName           string   `json:"git_ref_name"`
 	ConfigSourceFilePath string   `json:"config_source_path"`
 	EnvironmentVariables []string `json:"environment_variables" binding:"required"`
+	IdleTimeoutMinutes   *uint    `json:"idle_timeout_minutes"`
+	// runner placement constraints, used only for git sources,
+	// workspaces created from templates use the ones of the template
+	RequiredRunnerLabels  models.RunnerLabels `json:"required_runner_labels"`
+	PreferredRunnerLabels models.RunnerLabels `json:"preferred_runner_labels"`
+	// cpu_limit, memory_limit_mb and disk_limit_mb override the limits of the template
+	models.ResourceLimits
+	// values of the parameters of the template version, used only for templates
+	ParameterValues map[string]any `json:"parameter_values"`
 }
 
 // HandleRetrieveWorkspace godoc
 // @Summary Create a workspace
 // @Schemes
-// @Description Create a new workspace
+// @Description Create a new workspace, if runner_id is not set the runner is chosen automatically
 // @Tags Workspaces
 // @Accept json
 // @Produce json
@@ -142,50 +147,20 @@ func HandleCreateWorkspace(c *gin.Context) {
 		return
 	}
 
-	// validate runner
-	runner, err := models.RetrieveRunnerByID(requestBody.RunnerID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
-	if runner == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"detail": "runner matching runner_id and type not found",
-		})
+	// the workspace is started after it has been created
+	if respondQuotaError(c, models.CheckCreateWorkspaceQuota(currentUser)) {
 		return
 	}
 
-	rt := config.RetrieveRunnerTypeByID(runner.Type)
-	if rt == nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"detail": "runner matching runner_id and type not found",
-		})
-		return
-	}
-
-	// check if the runner supports the requested workspace type
-	supported := false
-	for _, supportedType := range rt.SupportedTypes {
-		if supportedType.ID == wt.ID {
-			supported = true
-			break
-		}
-	}
-
-	if !supported {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"detail": "runner does not support the requested workspace type",
-		})
+	if respondQuotaError(c, models.CheckStartWorkspaceQuota(currentUser, models.Workspace{})) {
 		return
 	}
 
-	// TODO: check if user is allowed to use requested runner
 	// validate workspace configuration source
 	var gitSource *models.GitWorkspaceSource
 	var templateVersion *models.WorkspaceTemplateVersion
+	var requiredLabels, preferredLabels models.RunnerLabels
+	var parameterValues models.TemplateParameterValues
 	if requestBody.ConfigSource == models.WorkspaceConfigSourceGit {
 		if requestBody.GitRepoUrl == "" {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -200,16 +175,21 @@ func HandleCreateWorkspace(c *gin.Context) {
 			return
 		}
 
-		gitSource, err = models.CreateGitWorkspaceSource(
-			requestBody.GitRepoUrl,
-			requestBody.GitRefName,
-			requestBody.ConfigSourceFilePath,
-		)
+		if err := models.ValidateRunnerLabels(requestBody.RequiredRunnerLabels); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"detail": "internal server error",
-			})
+		if err := models.ValidateRunnerLabels(requestBody.PreferredRunnerLabels); err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		requiredLabels = requestBody.RequiredRunnerLabels
+		preferredLabels = requestBody.PreferredRunnerLabels
+
+		if len(requestBody.ParameterValues) > 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "template parameters can be set only for templates")
 			return
 		}
 	} else if requestBody.ConfigSource == models.WorkspaceConfigSourceTemplate {
@@ -234,6 +214,35 @@ func HandleCreateWorkspace(c *gin.Context) {
 			})
 			return
 		}
+
+		// restricted templates can be used only by the members of the allowed groups
+		visible, err := templateVersion.Template.IsVisibleForUser(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"detail": "internal server error",
+			})
+			return
+		}
+
+		if !visible {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"detail": "requested template version does not exist",
+			})
+			return
+		}
+
+		requiredLabels = templateVersion.Template.RequiredRunnerLabels
+		preferredLabels = templateVersion.Template.PreferredRunnerLabels
+
+		parameterValues, err = resolveTemplateParameterValues(
+			c,
+			*templateVersion,
+			models.TemplateParameterValues{},
+			requestBody.ParameterValues,
+		)
+		if err != nil {
+			return
+		}
 	} else {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"detail": "invalid value for 'config_source'",
@@ -241,6 +250,103 @@ func HandleCreateWorkspace(c *gin.Context) {
 		return
 	}
 
+	if respondResourceLimitsError(c, models.ValidateResourceLimits(requestBody.ResourceLimits)) {
+		return
+	}
+
+	// limits requested for the workspace, used to pick a runner able to enforce them
+	limits, err := (&models.Workspace{
+		ConfigSource:    requestBody.ConfigSource,
+		TemplateVersion: templateVersion,
+		ResourceLimits:  requestBody.ResourceLimits,
+	}).GetRequestedResourceLimits()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	var runner *models.Runner
+	runnerSelectionReason := ""
+	if requestBody.RunnerID == 0 {
+		// choose the runner automatically
+		runner, runnerSelectionReason, err = runnerscheduler.SelectRunner(
+			currentUser,
+			wt.ID,
+			requiredLabels,
+			preferredLabels,
+			limits,
+		)
+		if err != nil {
+			if errors.Is(err, runnerscheduler.ErrNoRunnerAvailable) {
+				detail := "no runner available for the requested workspace type"
+				if len(requiredLabels) > 0 {
+					detail = fmt.Sprintf(
+						"no runner available for the requested workspace type with the required labels: %s",
+						requiredLabels.String(),
+					)
+				}
+				if limits.IsSet() {
+					detail = fmt.Sprintf("%s and able to enforce resource limits", detail)
+				}
+				utils.ErrorResponse(c, http.StatusFailedDependency, detail)
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"detail": "internal server error",
+			})
+			return
+		}
+	} else {
+		// validate runner
+		runner, err = models.RetrieveRunnerByID(requestBody.RunnerID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"detail": "internal server error",
+			})
+			return
+		}
+
+		if runner == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"detail": "runner matching runner_id and type not found",
+			})
+			return
+		}
+
+		if !checkRunnerForWorkspace(c, *runner, currentUser, wt.ID, requiredLabels, limits) {
+			return
+		}
+	}
+
+	if requestBody.ConfigSource == models.WorkspaceConfigSourceGit {
+		gitSource, err = models.CreateGitWorkspaceSource(
+			requestBody.GitRepoUrl,
+			requestBody.GitRefName,
+			requestBody.ConfigSourceFilePath,
+		)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"detail": "internal server error",
+			})
+			return
+		}
+
+		if len(requiredLabels) > 0 || len(preferredLabels) > 0 {
+			if err := models.SetGitWorkspaceSourceRunnerLabels(
+				gitSource,
+				requiredLabels,
+				preferredLabels,
+			); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"detail": "internal server error",
+				})
+				return
+			}
+		}
+	}
+
 	workspace, err := models.CreateWorkspace(
 		requestBody.Name,
 		&currentUser,
@@ -259,7 +365,37 @@ func HandleCreateWorkspace(c *gin.Context) {
 		return
 	}
 
+	if requestBody.IdleTimeoutMinutes != nil {
+		workspace, err = models.SetWorkspaceIdleTimeout(workspace, requestBody.IdleTimeoutMinutes)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"detail": "internal server error",
+			})
+			return
+		}
+	}
+
+	if len(parameterValues) > 0 {
+		workspace, err = models.SetWorkspaceTemplateParameterValues(workspace, parameterValues)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
+	if requestBody.ResourceLimits.IsSet() {
+		workspace, err = models.SetWorkspaceResourceLimits(workspace, requestBody.ResourceLimits)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	}
+
 	workspace.AppendLogs("Creating workspace...")
+	recordWorkspaceEvent(c, workspace, "", "Workspace created")
+	if runnerSelectionReason != "" {
+		workspace.AppendLogs(runnerSelectionReason)
+	}
 	bgtasks.BgTasksEnqueuer.Enqueue("start_workspace", work.Q{"workspace_id": workspace.ID})
 
 	c.JSON(http.StatusCreated, serializers.LoadWorkspaceSerializer(workspace))
@@ -268,42 +404,30 @@ func HandleCreateWorkspace(c *gin.Context) {
 // HandleStopWorkspace godoc
 // @Summary Stop a workspace
 // @Schemes
-// @Description Stop a workspace, only running workspaces can be stopped
+// @Description Stop a workspace, only running workspaces can be stopped.
+// @Description Stopping a starting workspace cancels the start
 // @Tags Workspaces
 // @Accept json
 // @Produce json
 // @Success 200
 // @Router /api/v1/workspace/:id/stop [post]
 func HandleStopWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
-		return
-	}
-
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
-		return
-	}
-
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-	if err != nil {
-		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
-		return
-	}
-
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
 	if workspace == nil {
-		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
 		return
 	}
 
-	if workspace.Status != models.WorkspaceStatusRunning && workspace.Status != models.WorkspaceStatusError {
+	if workspace.Status != models.WorkspaceStatusRunning &&
+		workspace.Status != models.WorkspaceStatusError &&
+		workspace.Status != models.WorkspaceStatusStarting {
 		utils.ErrorResponse(ctx, http.StatusConflict, "workspace is not running")
 		return
 	}
 
-	workspace, err = models.UpdateWorkspace(
+	cancelStart := workspace.Status == models.WorkspaceStatusStarting
+
+	previousStatus := workspace.Status
+	workspace, err := models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
 		models.WorkspaceStatusStopping,
@@ -319,8 +443,18 @@ func HandleStopWorkspace(ctx *gin.Context) {
 		return
 	}
 
+	// the start job detects the cancellation and
+	// takes care of stopping the workspace
+	if cancelStart {
+		workspace.AppendLogs("Cancelling workspace start...")
+		recordWorkspaceEvent(ctx, workspace, previousStatus, "Workspace start cancelled")
+		ctx.JSON(http.StatusOK, serializers.LoadWorkspaceSerializer(workspace))
+		return
+	}
+
 	workspace.ClearLogs()
 	workspace.AppendLogs("Stopping workspace...")
+	recordWorkspaceEvent(ctx, workspace, previousStatus, "Workspace stop requested")
 
 	// start bg task
 	bgtasks.BgTasksEnqueuer.Enqueue("stop_workspace", work.Q{"workspace_id": workspace.ID})
@@ -338,49 +472,44 @@ func HandleStopWorkspace(ctx *gin.Context) {
 // @Success 200 {object} serializers.WorkspaceSerializer
 // @Router /api/v1/workspace/:id/start [post]
 func HandleStartWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
 		return
 	}
 
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
+	if workspace.Runner == nil {
+		utils.ErrorResponse(ctx, http.StatusFailedDependency, "no runner selected")
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
+	if workspace.Status != models.WorkspaceStatusStopped {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"detail": "workspace is already running",
 		})
 		return
 	}
 
-	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
+	if workspace.RunnerAccessRevoked {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "you are no longer allowed to use the runner of this workspace")
 		return
 	}
 
-	if workspace.Runner == nil {
-		utils.ErrorResponse(ctx, http.StatusFailedDependency, "no runner selected")
+	if respondQuotaError(ctx, models.CheckStartWorkspaceQuota(*workspace.User, *workspace)) {
 		return
 	}
 
-	if workspace.Status != models.WorkspaceStatusStopped {
-		ctx.JSON(http.StatusConflict, gin.H{
-			"detail": "workspace is already running",
-		})
+	// the limits may have been changed since the runner has been chosen
+	limits, err := workspace.GetRequestedResourceLimits()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
+	if respondResourceLimitsError(ctx, models.CheckRunnerResourceLimitsSupport(*workspace.Runner, limits)) {
+		return
+	}
+
+	previousStatus := workspace.Status
 	workspace, err = models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
@@ -401,6 +530,7 @@ func HandleStartWorkspace(ctx *gin.Context) {
 
 	workspace.ClearLogs()
 	workspace.AppendLogs("Starting workspace...")
+	recordWorkspaceEvent(ctx, workspace, previousStatus, "Workspace start requested")
 
 	// start bg task
 	bgtasks.BgTasksEnqueuer.Enqueue("start_workspace", work.Q{"workspace_id": workspace.ID})
@@ -418,45 +548,39 @@ func HandleStartWorkspace(ctx *gin.Context) {
 // @Success 200 {object} serializers.WorkspaceSerializer
 // @Router /api/v1/workspace/:id/restart [post]
 func HandleRestartWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
 		return
 	}
 
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
+	// Only running workspaces can be restarted
+	if workspace.Status != models.WorkspaceStatusRunning {
+		ctx.JSON(http.StatusConflict, gin.H{
+			"detail": "workspace is not running",
 		})
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
+	if workspace.RunnerAccessRevoked {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "you are no longer allowed to use the runner of this workspace")
+		return
+	}
+
+	containers, err := models.ListWorkspaceContainersByWorkspace(*workspace)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
 		return
 	}
 
-	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
+	if respondQuotaError(ctx, models.CheckStartWorkspaceQuota(*workspace.User, *workspace)) {
 		return
 	}
 
-	// Only running workspaces can be restarted
-	if workspace.Status != models.WorkspaceStatusRunning {
-		ctx.JSON(http.StatusConflict, gin.H{
-			"detail": "workspace is not running",
-		})
+	if respondQuotaError(ctx, models.CheckContainersQuota(*workspace.User, len(containers))) {
 		return
 	}
 
+	previousStatus := workspace.Status
 	workspace, err = models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
@@ -477,6 +601,7 @@ func HandleRestartWorkspace(ctx *gin.Context) {
 
 	workspace.ClearLogs()
 	workspace.AppendLogs("Restarting workspace...")
+	recordWorkspaceEvent(ctx, workspace, previousStatus, "Workspace restart requested")
 
 	// start bg task for restart
 	bgtasks.BgTasksEnqueuer.Enqueue("restart_workspace", work.Q{"workspace_id": workspace.ID})
@@ -502,33 +627,8 @@ type UpdateWorkspaceRequestBody struct {
 // @Success 200 {object} serializers.WorkspaceSerializer
 // @Router /api/v1/workspace/:id [put]
 func HandleUpdateWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
-		return
-	}
-
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
 	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
 		return
 	}
 
@@ -548,7 +648,7 @@ func HandleUpdateWorkspace(ctx *gin.Context) {
 	}
 
 	// update environment variables and/or git source
-	workspace, err = models.UpdateWorkspace(
+	workspace, err := models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
 		workspace.Status,
@@ -595,32 +695,8 @@ func HandleUpdateWorkspace(ctx *gin.Context) {
 // @Success 204
 // @Router /api/v1/workspace/:id [delete]
 func HandleDeleteWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
-		return
-	}
-
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleOwner)
 	if workspace == nil {
-		utils.ErrorResponse(ctx, http.StatusNotFound, "workspace not found")
 		return
 	}
 
@@ -637,7 +713,8 @@ func HandleDeleteWorkspace(ctx *gin.Context) {
 		skipErrors = true
 	}
 
-	workspace, err = models.UpdateWorkspace(
+	previousStatus := workspace.Status
+	workspace, err := models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
 		models.WorkspaceStatusDeleting,
@@ -657,6 +734,7 @@ func HandleDeleteWorkspace(ctx *gin.Context) {
 
 	workspace.ClearLogs()
 	workspace.AppendLogs("Deleting workspace...")
+	recordWorkspaceEvent(ctx, workspace, previousStatus, "Workspace deletion requested")
 
 	// start bg task
 	bgtasks.BgTasksEnqueuer.Enqueue(
@@ -669,56 +747,90 @@ func HandleDeleteWorkspace(ctx *gin.Context) {
 	})
 }
 
-// HandleDeleteWorkspace godoc
+type UpdateWorkspaceConfigurationBody struct {
+	// values of the parameters of the latest template version,
+	// parameters that are not set keep their current value
+	ParameterValues map[string]any `json:"parameter_values"`
+}
+
+// HandleUpdateWorkspaceConfiguration godoc
 // @Summary Update workspace configuration
 // @Schemes
-// @Description Update workspace configuration, retrieving the configuration files from the git repository or template
+// @Description Update workspace configuration, retrieving the configuration files from the git repository or template.
+// @Description The body is optional, it can be used to change the values of the template parameters
 // @Tags Workspaces
 // @Accept json
 // @Produce json
+// @Param request body UpdateWorkspaceConfigurationBody false "Request body"
 // @Success 200
 // @Router /api/v1/workspace/:workspaceId/update-config [post]
 func HandleUpdateWorkspaceConfiguration(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
 		return
 	}
 
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
+	if workspace.Status != models.WorkspaceStatusStopped {
+		ctx.JSON(http.StatusNotAcceptable, gin.H{
+			"detail": "cannot update, workspace is running",
 		})
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
+	var reqBody UpdateWorkspaceConfigurationBody
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil {
+			utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+			return
+		}
 	}
 
-	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
-		return
-	}
+	// the values are validated against the version the workspace is updated to
+	if workspace.ConfigSource == models.WorkspaceConfigSourceTemplate {
+		template, err := models.RetrieveWorkspaceTemplateByID(workspace.TemplateVersion.TemplateID)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
 
-	if workspace.Status != models.WorkspaceStatusStopped {
-		ctx.JSON(http.StatusNotAcceptable, gin.H{
-			"detail": "cannot update, workspace is running",
-		})
+		if template == nil {
+			utils.ErrorResponse(ctx, http.StatusFailedDependency, "the template of the workspace does not exist")
+			return
+		}
+
+		latestVersion, err := models.RetrieveLatestTemplateVersionByTemplate(*template)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if latestVersion == nil {
+			utils.ErrorResponse(ctx, http.StatusFailedDependency, "the template has no published versions")
+			return
+		}
+
+		values, err := resolveTemplateParameterValues(
+			ctx,
+			*latestVersion,
+			workspace.ParameterValues,
+			reqBody.ParameterValues,
+		)
+		if err != nil {
+			return
+		}
+
+		workspace, err = models.SetWorkspaceTemplateParameterValues(workspace, values)
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+	} else if len(reqBody.ParameterValues) > 0 {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "template parameters can be set only for templates")
 		return
 	}
 
-	workspace, err = models.UpdateWorkspace(
+	previousStatus := workspace.Status
+	workspace, err := models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
 		models.WorkspaceStatusStarting,
@@ -739,6 +851,7 @@ func HandleUpdateWorkspaceConfiguration(ctx *gin.Context) {
 	workspace.ClearLogs()
 
 	workspace.AppendLogs("Updating workspace configuration sources...")
+	recordWorkspaceEvent(ctx, workspace, previousStatus, "Workspace configuration update requested")
 	bgtasks.BgTasksEnqueuer.Enqueue("update_workspace_config", work.Q{"workspace_id": workspace.ID})
 
 	ctx.JSON(http.StatusOK, gin.H{
@@ -761,33 +874,8 @@ type SetRunnerForWorkspaceBody struct {
 // @Success 200 {object} serializers.WorkspaceSerializer
 // @Router /api/v1/workspace/:workspaceId/set-runner [post]
 func HandleSetRunnerForWorkspace(ctx *gin.Context) {
-	user, err := utils.GetUserFromContext(ctx)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-	id, err := utils.GetUIntParamFromContext(ctx, "workspaceId")
-	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
-		return
-	}
-
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, id)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{
-			"detail": "internal server error",
-		})
-		return
-	}
-
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
 	if workspace == nil {
-		ctx.JSON(http.StatusNotFound, gin.H{
-			"detail": "workspace not found",
-		})
 		return
 	}
 
@@ -843,6 +931,40 @@ func HandleSetRunnerForWorkspace(ctx *gin.Context) {
 		return
 	}
 
+	// check if the owner is allowed to use the runner
+	allowed, err := runner.IsAllowedForUser(*workspace.User)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if !allowed {
+		utils.ErrorResponse(ctx, http.StatusForbidden, "you are not allowed to use the requested runner")
+		return
+	}
+
+	// check if the runner satisfies the placement constraints
+	requiredLabels, _, err := workspace.GetRunnerLabelSelectors()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if err := models.CheckRunnerLabels(*runner, requiredLabels); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limits, err := workspace.GetRequestedResourceLimits()
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if respondResourceLimitsError(ctx, models.CheckRunnerResourceLimitsSupport(*runner, limits)) {
+		return
+	}
+
 	workspace, err = models.UpdateWorkspace(
 		workspace,
 		workspace.Name,
@@ -865,3 +987,105 @@ func HandleSetRunnerForWorkspace(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, serializers.LoadWorkspaceSerializer(workspace))
 }
+
+type SetIdleTimeoutForWorkspaceBody struct {
+	IdleTimeoutMinutes *uint `json:"idle_timeout_minutes"`
+}
+
+// HandleSetIdleTimeoutForWorkspace godoc
+// @Summary Set the inactivity window for a workspace
+// @Schemes
+// @Description Set after how many minutes of inactivity the workspace is stopped,
+// @Description 0 disables the automatic shutdown, null inherits the value from the template
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param request body SetIdleTimeoutForWorkspaceBody true "Request body"
+// @Success 200 {object} serializers.WorkspaceSerializer
+// @Router /api/v1/workspace/:workspaceId/set-idle-timeout [post]
+func HandleSetIdleTimeoutForWorkspace(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
+		return
+	}
+
+	var reqBody SetIdleTimeoutForWorkspaceBody
+	if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+		return
+	}
+
+	workspace, err := models.SetWorkspaceIdleTimeout(workspace, reqBody.IdleTimeoutMinutes)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.LoadWorkspaceSerializer(workspace))
+}
+
+type SetResourceLimitsForWorkspaceBody struct {
+	CPULimit      *float64 `json:"cpu_limit"`
+	MemoryLimitMB *uint    `json:"memory_limit_mb"`
+	DiskLimitMB   *uint    `json:"disk_limit_mb"`
+}
+
+// HandleSetResourceLimitsForWorkspace godoc
+// @Summary Set the resource limits for a workspace
+// @Schemes
+// @Description Set the cpu, memory and disk limits of a workspace, null inherits the limit
+// @Description from the template. Limits cannot exceed the maximum set by the administrators,
+// @Description the new limits are applied the next time the workspace is started
+// @Tags Workspaces
+// @Accept json
+// @Produce json
+// @Param request body SetResourceLimitsForWorkspaceBody true "Request body"
+// @Success 200 {object} serializers.WorkspaceSerializer
+// @Router /api/v1/workspace/:workspaceId/set-resource-limits [post]
+func HandleSetResourceLimitsForWorkspace(ctx *gin.Context) {
+	workspace := getWorkspaceFromContext(ctx, models.WorkspaceRoleAdmin)
+	if workspace == nil {
+		return
+	}
+
+	var reqBody SetResourceLimitsForWorkspaceBody
+	if err := ctx.ShouldBindBodyWithJSON(&reqBody); err != nil {
+		utils.ErrorResponse(ctx, http.StatusBadRequest, "missing or invalid request argument")
+		return
+	}
+
+	limits := models.ResourceLimits{
+		CPULimit:      reqBody.CPULimit,
+		MemoryLimitMB: reqBody.MemoryLimitMB,
+		DiskLimitMB:   reqBody.DiskLimitMB,
+	}
+	if respondResourceLimitsError(ctx, models.ValidateResourceLimits(limits)) {
+		return
+	}
+
+	// the runner of the workspace must be able to enforce the new limits
+	if workspace.Runner != nil {
+		updated := *workspace
+		updated.ResourceLimits = limits
+		requestedLimits, err := updated.GetRequestedResourceLimits()
+		if err != nil {
+			utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		if respondResourceLimitsError(
+			ctx,
+			models.CheckRunnerResourceLimitsSupport(*workspace.Runner, requestedLimits),
+		) {
+			return
+		}
+	}
+
+	workspace, err := models.SetWorkspaceResourceLimits(workspace, limits)
+	if err != nil {
+		utils.ErrorResponse(ctx, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, serializers.LoadWorkspaceSerializer(workspace))
+}
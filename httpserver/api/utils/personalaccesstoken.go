@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// key of the gin context where the authorized personal access token is stored
+const authorizedPersonalAccessTokenKey = "authorized-personal-access-token"
+
+// checks if the request is authenticated with a personal access token
+func IsPersonalAccessTokenRequest(ctx *gin.Context) bool {
+	t, _ := getTokenFromAuthorizationHeader(ctx)
+	return models.IsPersonalAccessToken(t)
+}
+
+// retrieve the personal access token from the authorization header,
+// nil is returned if the request is not authenticated with a personal access token
+func GetPersonalAccessTokenFromContext(ctx *gin.Context) (*models.PersonalAccessToken, error) {
+	t, _ := getTokenFromAuthorizationHeader(ctx)
+	if !models.IsPersonalAccessToken(t) {
+		return nil, nil
+	}
+
+	token, err := models.RetrievePersonalAccessTokenByToken(t)
+	if err != nil || token == nil {
+		return nil, fmt.Errorf("missing or invalid authorization token")
+	}
+
+	if token.IsExpired() || token.User.Deactivated {
+		return nil, fmt.Errorf("missing or invalid authorization token")
+	}
+
+	return token, nil
+}
+
+// mark the personal access token as authorized for the current request,
+// it must be called by the permission middlewares after checking the scopes
+func AuthorizePersonalAccessToken(ctx *gin.Context, token *models.PersonalAccessToken) {
+	ctx.Set(authorizedPersonalAccessTokenKey, token)
+}
+
+// get the personal access token authorized for the current request, if any
+func GetAuthorizedPersonalAccessToken(ctx *gin.Context) *models.PersonalAccessToken {
+	value, exists := ctx.Get(authorizedPersonalAccessTokenKey)
+	if !exists {
+		return nil
+	}
+	token, _ := value.(*models.PersonalAccessToken)
+	return token
+}
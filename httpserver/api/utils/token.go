@@ -76,5 +76,13 @@ func GetTokenFromContext(ctx *gin.Context) (models.Token, error) {
 		return models.Token{}, fmt.Errorf("missing or invalid authorization token")
 	}
 
+	// deactivated users cannot be authenticated even if a token has not been revoked
+	if token.User.Deactivated || (token.ImpersonatedUser != nil && token.ImpersonatedUser.Deactivated) {
+		return models.Token{}, fmt.Errorf("missing or invalid authorization token")
+	}
+
+	// the last use is only informative, the request is not refused if it cannot be stored
+	models.TouchToken(&token, ctx.ClientIP())
+
 	return token, nil
 }
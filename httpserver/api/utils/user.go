@@ -6,8 +6,13 @@ import (
 	"gitlab.com/codebox4073715/codebox/httpserver/api/users/serializers"
 )
 
-// get the current user from the request context
+// get the current user from the request context, personal access tokens
+// are accepted only once they have been authorized by the permission middlewares
 func GetUserFromContext(ctx *gin.Context) (models.User, error) {
+	if pat := GetAuthorizedPersonalAccessToken(ctx); pat != nil {
+		return pat.User, nil
+	}
+
 	token, err := GetTokenFromContext(ctx)
 	if err != nil {
 		return models.User{}, err
@@ -21,6 +21,7 @@ const NotificationEventStop = "stop"
 const NotificationEventRestart = "restart"
 const NotificationEventRunning = "running"
 const NotificationEventStopped = "stopped"
+const NotificationEventIdleWarning = "idle_warning"
 
 type ClientChannel struct {
 	UserID int
@@ -170,3 +171,17 @@ func SendWorkspaceStoppedNotification(workspace models.Workspace) {
 	}
 	hub.SendNotification(notification)
 }
+
+/*
+send notification to warn that the workspace is going to
+be stopped due to inactivity
+*/
+func SendWorkspaceIdleWarningNotification(workspace models.Workspace) {
+	hub := GetWorkspaceNotificationsHub()
+	notification := NotificationMessage{
+		Type:      NotificationTypeWorkspace,
+		Event:     NotificationEventIdleWarning,
+		Workspace: &workspace,
+	}
+	hub.SendNotification(notification)
+}
@@ -1,22 +1,27 @@
 package permissions
 
 import (
-	"net/http"
-
 	"github.com/gin-gonic/gin"
-	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/db/models"
 )
 
 /*
 Wrap a Gin handler to require that the user is authenticated.
+Personal access tokens need the workspaces:read scope for
+GET requests and the workspaces:write scope otherwise.
 If the user is not authenticated, returns 401 Unauthorized.
+If the personal access token lacks the scope, returns 403 Forbidden.
 Otherwise, calls the original handler.
 */
 func AuthenticationRequiredRoute(handler gin.HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		_, err := utils.GetUserFromContext(c)
+		_, status, err := authenticateRequest(c, scopeByMethod(
+			c,
+			models.PersonalAccessTokenScopeWorkspacesRead,
+			models.PersonalAccessTokenScopeWorkspacesWrite,
+		))
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			c.AbortWithStatusJSON(status, gin.H{
 				"detail": err.Error(),
 			})
 		} else {
@@ -0,0 +1,101 @@
+package permissions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+)
+
+// key of the gin context where the scope required by the route is stored
+const requiredScopeKey = "personal-access-token-required-scope"
+
+/*
+Wrap a permission middleware to choose the scope that personal access
+tokens must have to use the route, instead of the default scope chosen
+by the middleware. It is used by the routes that do more than their
+http method suggests, like websockets opened with a GET request.
+*/
+func ScopeRequiredRoute(handler gin.HandlerFunc, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(requiredScopeKey, scope)
+		handler(c)
+	}
+}
+
+/*
+Wrap a Gin handler to refuse the requests authenticated
+with a personal access token, for the apis that manage the
+account and its credentials.
+If the user is not authenticated, returns 401 Unauthorized.
+If a personal access token is used, returns 403 Forbidden.
+Otherwise, calls the original handler.
+*/
+func SessionRequiredRoute(handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if utils.IsPersonalAccessTokenRequest(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"detail": "personal access tokens cannot be used for this api",
+			})
+			return
+		}
+
+		_, err := utils.GetUserFromContext(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"detail": err.Error(),
+			})
+		} else {
+			handler(c)
+		}
+	}
+}
+
+// get the scope for the requests that only read data and for the
+// ones that modify it, depending on the http method of the request
+func scopeByMethod(c *gin.Context, readScope string, writeScope string) string {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return readScope
+	default:
+		return writeScope
+	}
+}
+
+// authenticate the request with the session token or with a personal access token,
+// personal access tokens must have the scope required by the route, the default
+// scope is used unless the route has been wrapped with ScopeRequiredRoute.
+// The http status code to return is returned along with the error
+func authenticateRequest(c *gin.Context, defaultScope string) (models.User, int, error) {
+	pat, err := utils.GetPersonalAccessTokenFromContext(c)
+	if err != nil {
+		return models.User{}, http.StatusUnauthorized, err
+	}
+
+	if pat == nil {
+		user, err := utils.GetUserFromContext(c)
+		if err != nil {
+			return models.User{}, http.StatusUnauthorized, err
+		}
+		return user, 0, nil
+	}
+
+	scope := defaultScope
+	if s := c.GetString(requiredScopeKey); s != "" {
+		scope = s
+	}
+
+	if !pat.HasScope(scope) {
+		return models.User{}, http.StatusForbidden, fmt.Errorf(
+			"the personal access token does not have the '%s' scope", scope,
+		)
+	}
+
+	// the last use is only informative, the request is not refused if it cannot be stored
+	models.TouchPersonalAccessToken(pat)
+
+	utils.AuthorizePersonalAccessToken(c, pat)
+	return pat.User, 0, nil
+}
@@ -4,20 +4,26 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"gitlab.com/codebox4073715/codebox/httpserver/api/utils"
+	"gitlab.com/codebox4073715/codebox/db/models"
 )
 
 /*
 Wrap a Gin handler to require that the user is a template manager.
+Personal access tokens need the workspaces:read scope for
+GET requests and the templates:write scope otherwise.
 If the user is not authenticated, returns 401 Unauthorized.
 If the user is authenticated but not a template manager, returns 403 Forbidden.
 Otherwise, calls the original handler.
 */
 func TemplateManagerRequiredRoute(handler gin.HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		user, err := utils.GetUserFromContext(c)
+		user, status, err := authenticateRequest(c, scopeByMethod(
+			c,
+			models.PersonalAccessTokenScopeWorkspacesRead,
+			models.PersonalAccessTokenScopeTemplatesWrite,
+		))
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			c.AbortWithStatusJSON(status, gin.H{
 				"detail": err.Error(),
 			})
 		} else {
@@ -22,6 +22,17 @@ func ForwardHttpPort(
 	containerName string,
 	portNumber uint,
 	path string,
+) {
+	forwardHttpPort(c, workspaceId, containerName, portNumber, path, nil)
+}
+
+func forwardHttpPort(
+	c *gin.Context,
+	workspaceId uint,
+	containerName string,
+	portNumber uint,
+	path string,
+	modifyResponse func(*http.Response) error,
 ) {
 	// retrieve workspace details
 	workspace, err := models.RetrieveWorkspaceById(workspaceId)
@@ -70,7 +81,17 @@ func ForwardHttpPort(
 		return
 	}
 
+	// private ports can be accessed with a share token or by the collaborators
+	shared := false
 	if !port.Public {
+		granted, responded := authorizeWithShareToken(c, workspace, container, port)
+		if responded {
+			return
+		}
+		shared = granted
+	}
+
+	if !port.Public && !shared {
 		user, err := utils.GetUserFromContext(c)
 		if err != nil {
 			requestProtocol := c.Request.Header.Get("X-Forwarded-Proto")
@@ -97,7 +118,14 @@ func ForwardHttpPort(
 			return
 		}
 
-		if user.ID != workspace.UserID {
+		// private ports are accessible by the owner and by the developers of the workspace
+		role, err := models.RetrieveWorkspaceRoleForUser(*workspace, user)
+		if err != nil {
+			httperrors.RenderError(c, http.StatusInternalServerError, "Unknown error")
+			return
+		}
+
+		if !models.WorkspaceRoleAllows(role, models.WorkspaceRoleDeveloper) {
 			httperrors.RenderError(
 				c,
 				http.StatusNotFound,
@@ -112,11 +140,13 @@ func ForwardHttpPort(
 		return
 	}
 
+	utils.RegisterContainerActivity(container, models.ContainerActivityPortForward)
+
 	ri := runnerinterface.RunnerInterface{
 		Runner: workspace.Runner,
 	}
 
-	if err := ri.AgentForwardHttp(workspace, container, port, path, c.Writer, c.Request); err != nil {
+	if err := ri.AgentForwardHttp(workspace, container, port, path, c.Writer, c.Request, modifyResponse); err != nil {
 		httperrors.RenderError(c, http.StatusInternalServerError, "Unknown error")
 		return
 	}
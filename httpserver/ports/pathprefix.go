@@ -0,0 +1,183 @@
+package ports
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/config"
+)
+
+/*
+PortPathPrefix returns the prefix of the urls of a port when
+subdomains are disabled, e.g. /p/<workspace>/<container>/<port>
+*/
+func PortPathPrefix(workspaceId uint, containerName string, portNumber uint) string {
+	return fmt.Sprintf("/p/%d/%s/%d", workspaceId, containerName, portNumber)
+}
+
+/*
+rewritePathPrefixResponse returns a function that rewrites the responses of a port
+exposed through a path prefix. The service is not aware of the prefix, so redirects
+and cookies are moved under the prefix, otherwise they would point to codebox.
+portNumber is used to recognise the redirects to the address of the service itself
+*/
+func rewritePathPrefixResponse(prefix string, host string, portNumber uint) func(*http.Response) error {
+	return func(res *http.Response) error {
+		if location := res.Header.Get("Location"); location != "" {
+			res.Header.Set("Location", rewriteLocationForPathPrefix(location, prefix, host, portNumber))
+		}
+
+		cookies := res.Header.Values("Set-Cookie")
+		if len(cookies) > 0 {
+			res.Header.Del("Set-Cookie")
+			for _, cookie := range cookies {
+				res.Header.Add("Set-Cookie", rewriteCookiePathForPathPrefix(cookie, prefix))
+			}
+		}
+		return nil
+	}
+}
+
+/*
+rewriteLocationForPathPrefix adds the prefix to redirects to absolute paths and to urls
+of the service itself, relative paths and urls of other hosts are left untouched
+*/
+func rewriteLocationForPathPrefix(location string, prefix string, host string, portNumber uint) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	if u.Host != "" {
+		if !isPortHost(u.Host, host, portNumber) {
+			return location
+		}
+		// the redirect keeps the scheme and the host used to reach codebox
+		u.Scheme = ""
+		u.Host = ""
+		u.User = nil
+	} else if u.Scheme != "" || !strings.HasPrefix(u.Path, "/") {
+		return location
+	}
+
+	if u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/") {
+		// the service is already aware of the prefix
+		return u.String()
+	}
+
+	u.Path = prefix + u.Path
+	if u.RawPath != "" {
+		u.RawPath = prefix + u.RawPath
+	}
+	return u.String()
+}
+
+// isPortHost checks if the host of a redirect is codebox or the address of the service
+func isPortHost(locationHost string, host string, portNumber uint) bool {
+	if locationHost == host {
+		return true
+	}
+
+	hostname, port, err := net.SplitHostPort(locationHost)
+	if err != nil {
+		hostname = locationHost
+		port = ""
+	}
+
+	if hostname != "localhost" && hostname != "127.0.0.1" && hostname != "::1" && hostname != "0.0.0.0" {
+		return false
+	}
+	return port == "" || port == fmt.Sprint(portNumber)
+}
+
+/*
+rewriteCookiePathForPathPrefix scopes the cookies set by the service to its prefix,
+cookies without a path already default to the path of the request
+*/
+func rewriteCookiePathForPathPrefix(cookie string, prefix string) string {
+	attributes := strings.Split(cookie, ";")
+	for i, attribute := range attributes {
+		name, value, found := strings.Cut(strings.TrimSpace(attribute), "=")
+		if !found || !strings.EqualFold(name, "path") {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if !strings.HasPrefix(value, "/") || value == prefix || strings.HasPrefix(value, prefix+"/") {
+			continue
+		}
+
+		attributes[i] = fmt.Sprintf(" Path=%s%s", prefix, value)
+	}
+	return strings.Join(attributes, ";")
+}
+
+/*
+removeCodeboxAuthCookies removes the authentication cookies of codebox from the request.
+On the path prefix the port is served on the origin of codebox, so the browser sends
+them to the service, that must not receive the tokens of its visitors
+*/
+func removeCodeboxAuthCookies(req *http.Request) {
+	cookieHeaders := req.Header.Values("Cookie")
+	if len(cookieHeaders) == 0 {
+		return
+	}
+
+	req.Header.Del("Cookie")
+	for _, header := range cookieHeaders {
+		cookies := []string{}
+		for _, cookie := range strings.Split(header, ";") {
+			cookie = strings.TrimSpace(cookie)
+			name, _, _ := strings.Cut(cookie, "=")
+			if cookie == "" ||
+				name == config.Environment.AuthCookieName ||
+				name == config.Environment.SubdomainAuthCookieName {
+				continue
+			}
+			cookies = append(cookies, cookie)
+		}
+
+		if len(cookies) > 0 {
+			req.Header.Add("Cookie", strings.Join(cookies, "; "))
+		}
+	}
+}
+
+/*
+ForwardHttpPortWithPathPrefix forwards a request received on the path prefix of a port,
+the prefix is stripped before the request is sent to the service and it is
+passed in the X-Forwarded-Prefix header, WebSocket upgrades are forwarded as well.
+The authentication cookies of codebox are not forwarded
+*/
+func ForwardHttpPortWithPathPrefix(
+	c *gin.Context,
+	workspaceId uint,
+	containerName string,
+	portNumber uint,
+	path string,
+) {
+	prefix := PortPathPrefix(workspaceId, containerName, portNumber)
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	if c.Request.URL.RawQuery != "" {
+		path += "?" + c.Request.URL.RawQuery
+	}
+
+	c.Request.Header.Set("X-Forwarded-Prefix", prefix)
+	removeCodeboxAuthCookies(c.Request)
+
+	forwardHttpPort(
+		c,
+		workspaceId,
+		containerName,
+		portNumber,
+		path,
+		rewritePathPrefixResponse(prefix, c.Request.Host, portNumber),
+	)
+}
@@ -0,0 +1,153 @@
+package ports
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/codebox4073715/codebox/config"
+	dbconn "gitlab.com/codebox4073715/codebox/db/connection"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	"gitlab.com/codebox4073715/codebox/testutils"
+)
+
+func TestRewriteLocationForPathPrefix(t *testing.T) {
+	prefix := "/p/1/development/3000"
+	host := "codebox.example.com"
+
+	cases := []struct {
+		location string
+		expected string
+	}{
+		{"/login", "/p/1/development/3000/login"},
+		{"/login?next=%2F", "/p/1/development/3000/login?next=%2F"},
+		{"/p/1/development/3000/login", "/p/1/development/3000/login"},
+		{"login", "login"},
+		{"../login", "../login"},
+		{"http://localhost:3000/login", "/p/1/development/3000/login"},
+		{"http://127.0.0.1:3000/", "/p/1/development/3000/"},
+		{"http://localhost:8080/login", "http://localhost:8080/login"},
+		{"https://codebox.example.com/login", "/p/1/development/3000/login"},
+		{"https://example.com/login", "https://example.com/login"},
+		{"//example.com/login", "//example.com/login"},
+	}
+
+	for _, c := range cases {
+		if got := rewriteLocationForPathPrefix(c.location, prefix, host, 3000); got != c.expected {
+			t.Fatalf("rewriteLocationForPathPrefix(%q) = %q, expected %q", c.location, got, c.expected)
+		}
+	}
+}
+
+func TestRewriteCookiePathForPathPrefix(t *testing.T) {
+	prefix := "/p/1/development/3000"
+
+	cases := []struct {
+		cookie   string
+		expected string
+	}{
+		{"session=abc; Path=/; HttpOnly", "session=abc; Path=/p/1/development/3000/; HttpOnly"},
+		{"session=abc; path=/admin", "session=abc; Path=/p/1/development/3000/admin"},
+		{"session=abc; HttpOnly", "session=abc; HttpOnly"},
+		{"session=abc; Path=/p/1/development/3000/", "session=abc; Path=/p/1/development/3000/"},
+	}
+
+	for _, c := range cases {
+		if got := rewriteCookiePathForPathPrefix(c.cookie, prefix); got != c.expected {
+			t.Fatalf("rewriteCookiePathForPathPrefix(%q) = %q, expected %q", c.cookie, got, c.expected)
+		}
+	}
+}
+
+func TestRewritePathPrefixResponse(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("Location", "/dashboard")
+	res.Header.Add("Set-Cookie", "a=1; Path=/")
+	res.Header.Add("Set-Cookie", "b=2")
+
+	if err := rewritePathPrefixResponse("/p/1/development/3000", "codebox.example.com", 3000)(res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if location := res.Header.Get("Location"); location != "/p/1/development/3000/dashboard" {
+		t.Fatalf("unexpected location %q", location)
+	}
+
+	cookies := res.Header.Values("Set-Cookie")
+	if len(cookies) != 2 || cookies[0] != "a=1; Path=/p/1/development/3000/" || cookies[1] != "b=2" {
+		t.Fatalf("unexpected cookies %v", cookies)
+	}
+}
+
+/*
+The service exposed on the path prefix is served on the origin of codebox,
+it must never receive the authentication cookies of its visitors
+*/
+func TestForwardHttpPortWithPathPrefixRemovesAuthCookies(t *testing.T) {
+	testutils.WithSetupAndTearDownTestEnvironment(t, func(t *testing.T) {
+		var upstreamCookies []string
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upstreamCookies = r.Header.Values("Cookie")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer upstream.Close()
+
+		runner, err := models.CreateRunner("path-prefix-runner", "docker", true, upstream.URL)
+		if err != nil {
+			t.Fatalf("Failed to create runner: '%s'", err)
+		}
+
+		user, err := models.RetrieveUserByEmail("user1@user.com")
+		if err != nil || user == nil {
+			t.Fatalf("Failed to retrieve user: '%s'", err)
+		}
+
+		gitSource, err := models.CreateGitWorkspaceSource("https://example.com/repo.git", "main", "docker-compose.yml")
+		if err != nil {
+			t.Fatalf("Failed to create git source: '%s'", err)
+		}
+
+		workspace, err := models.CreateWorkspace(
+			"path-prefix",
+			user,
+			"docker_compose",
+			runner,
+			models.WorkspaceConfigSourceGit,
+			nil,
+			gitSource,
+			[]string{},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create workspace: '%s'", err)
+		}
+
+		container := models.WorkspaceContainer{
+			WorkspaceID:   workspace.ID,
+			ContainerName: "development",
+		}
+		if err := dbconn.DB.Create(&container).Error; err != nil {
+			t.Fatalf("Failed to create container: '%s'", err)
+		}
+
+		if _, err := models.CreateContainerPort(container, "web", 3000, true); err != nil {
+			t.Fatalf("Failed to create port: '%s'", err)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", fmt.Sprintf("/p/%d/development/3000/", workspace.ID), nil)
+		c.Request.Header.Add("Cookie", fmt.Sprintf(
+			"%s=session-token; theme=dark; %s=subdomain-token",
+			config.Environment.AuthCookieName,
+			config.Environment.SubdomainAuthCookieName,
+		))
+		c.Request.Header.Add("Cookie", config.Environment.AuthCookieName+"=session-token")
+
+		ForwardHttpPortWithPathPrefix(c, workspace.ID, "development", 3000, "/")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{"theme=dark"}, upstreamCookies)
+	})
+}
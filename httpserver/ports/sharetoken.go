@@ -0,0 +1,149 @@
+package ports
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/cache"
+	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/db/models"
+	httperrors "gitlab.com/codebox4073715/codebox/httpserver/errors"
+)
+
+const shareTokenPasswordField = "codebox_share_password"
+
+// wrong passwords allowed for a share token from the same ip address in the window
+const shareTokenMaxPasswordFailures = 5
+const shareTokenPasswordFailuresWindowSeconds = 300
+
+func shareTokenCookieName(port *models.WorkspaceContainerPort) string {
+	return fmt.Sprintf("codebox_share_%d", port.ID)
+}
+
+/*
+shareTokenCookiePath returns the path the share token cookie is scoped to,
+ports forwarded through subdomains have their own host so the whole host is used
+*/
+func shareTokenCookiePath(c *gin.Context, workspace *models.Workspace, container *models.WorkspaceContainer, port *models.WorkspaceContainerPort) string {
+	viewPath := fmt.Sprintf(
+		"/views/port-forward/workspace/%d/container/%s/port/%d",
+		workspace.ID,
+		container.ContainerName,
+		port.PortNumber,
+	)
+	if strings.HasPrefix(c.Request.URL.Path, viewPath) {
+		return viewPath
+	}
+
+	prefix := PortPathPrefix(workspace.ID, container.ContainerName, port.PortNumber)
+	if strings.HasPrefix(c.Request.URL.Path, prefix+"/") {
+		return prefix + "/"
+	}
+	return "/"
+}
+
+/*
+authorizeWithShareToken checks if the request can access a private port using a share token,
+either through the cookie set when the token has been used or through the query parameter.
+It returns true if access is granted, if responded is true a response has already been sent
+*/
+func authorizeWithShareToken(
+	c *gin.Context,
+	workspace *models.Workspace,
+	container *models.WorkspaceContainer,
+	port *models.WorkspaceContainerPort,
+) (granted bool, responded bool) {
+	// the cookie is set after the token has been used and the password has been checked
+	if cookie, err := c.Cookie(shareTokenCookieName(port)); err == nil {
+		if id, secret, found := strings.Cut(cookie, "."); found {
+			tokenID, err := strconv.ParseUint(id, 10, 64)
+			if err == nil {
+				token, err := models.RetrievePortShareTokenByID(*port, uint(tokenID))
+				if err == nil && token != nil && time.Now().Before(token.ExpiresAt) && token.CheckCookieSecret(secret) {
+					return true, false
+				}
+			}
+		}
+	}
+
+	value := c.Query(models.PortShareTokenQueryParam)
+	if value == "" {
+		return false, false
+	}
+
+	token, err := models.RetrievePortShareTokenByToken(*port, value)
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Unknown error")
+		return false, true
+	}
+
+	if token == nil || !token.IsValid() {
+		httperrors.RenderError(c, http.StatusNotFound, "This link has expired or it is not valid")
+		return false, true
+	}
+
+	if token.HasPassword() {
+		failuresKey := fmt.Sprintf("portshare-failure-%d-%s", token.ID, c.ClientIP())
+		failures, err := cache.GetKeysByPatternFromCache(fmt.Sprintf("%s-*", failuresKey))
+		if err != nil {
+			httperrors.RenderError(c, http.StatusInternalServerError, "Unknown error")
+			return false, true
+		}
+
+		if len(failures) >= shareTokenMaxPasswordFailures {
+			httperrors.RenderError(c, http.StatusTooManyRequests, "Too many attempts, try again later")
+			return false, true
+		}
+
+		if c.Request.Method != http.MethodPost {
+			c.HTML(http.StatusUnauthorized, "portshare.html", gin.H{})
+			return false, true
+		}
+
+		if !token.CheckPassword(c.PostForm(shareTokenPasswordField)) {
+			cache.SetKeyToCache(
+				fmt.Sprintf("%s-%d", failuresKey, time.Now().UnixNano()),
+				[]byte("1"),
+				shareTokenPasswordFailuresWindowSeconds,
+			)
+			c.HTML(http.StatusUnauthorized, "portshare.html", gin.H{
+				"error": "Wrong password",
+			})
+			return false, true
+		}
+	}
+
+	used, err := models.UsePortShareToken(token)
+	if err != nil {
+		httperrors.RenderError(c, http.StatusInternalServerError, "Unknown error")
+		return false, true
+	}
+
+	if !used {
+		httperrors.RenderError(c, http.StatusNotFound, "This link has expired or it is not valid")
+		return false, true
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		shareTokenCookieName(port),
+		fmt.Sprintf("%d.%s", token.ID, token.CookieSecret),
+		int(time.Until(token.ExpiresAt).Seconds()),
+		shareTokenCookiePath(c, workspace, container, port),
+		"",
+		config.Environment.UsesHttps(),
+		true,
+	)
+
+	// the token is removed from the url so it is not forwarded to the port
+	location := *c.Request.URL
+	query := location.Query()
+	query.Del(models.PortShareTokenQueryParam)
+	location.RawQuery = query.Encode()
+	c.Redirect(http.StatusSeeOther, location.RequestURI())
+	return false, true
+}
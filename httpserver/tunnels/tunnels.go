@@ -0,0 +1,117 @@
+package tunnels
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/*
+Tunnel is a raw TCP stream between a client and a port of a container,
+carried by binary WebSocket messages. Bytes are counted in both directions
+*/
+type Tunnel struct {
+	ID            uint64
+	WorkspaceID   uint
+	ContainerName string
+	PortNumber    uint
+	UserEmail     string
+	RemoteAddr    string
+	StartedAt     time.Time
+	bytesSent     atomic.Uint64 // from the client to the container
+	bytesReceived atomic.Uint64 // from the container to the client
+}
+
+func (t *Tunnel) BytesSent() uint64 {
+	return t.bytesSent.Load()
+}
+
+func (t *Tunnel) BytesReceived() uint64 {
+	return t.bytesReceived.Load()
+}
+
+var (
+	activeTunnels   = map[uint64]*Tunnel{}
+	activeTunnelsMu sync.Mutex
+	lastTunnelID    uint64
+)
+
+/*
+Register adds a tunnel to the list of active tunnels and assigns it an id,
+the returned function removes it and must be called when the tunnel is closed
+*/
+func Register(t *Tunnel) func() {
+	activeTunnelsMu.Lock()
+	defer activeTunnelsMu.Unlock()
+
+	lastTunnelID++
+	t.ID = lastTunnelID
+	t.StartedAt = time.Now()
+	activeTunnels[t.ID] = t
+
+	return func() {
+		activeTunnelsMu.Lock()
+		defer activeTunnelsMu.Unlock()
+		delete(activeTunnels, t.ID)
+	}
+}
+
+/*
+ListByContainer returns the active tunnels to the ports of a container
+*/
+func ListByContainer(workspaceID uint, containerName string) []*Tunnel {
+	activeTunnelsMu.Lock()
+	defer activeTunnelsMu.Unlock()
+
+	tunnels := []*Tunnel{}
+	for _, t := range activeTunnels {
+		if t.WorkspaceID == workspaceID && t.ContainerName == containerName {
+			tunnels = append(tunnels, t)
+		}
+	}
+
+	sort.Slice(tunnels, func(i, j int) bool {
+		return tunnels[i].ID < tunnels[j].ID
+	})
+	return tunnels
+}
+
+/*
+Pipe copies the binary messages between the client and the agent until
+one of the two connections is closed, then both connections are closed
+*/
+func Pipe(t *Tunnel, client *websocket.Conn, agent *websocket.Conn) {
+	done := make(chan struct{}, 2)
+
+	copyMessages := func(from *websocket.Conn, to *websocket.Conn, counter *atomic.Uint64) {
+		defer func() { done <- struct{}{} }()
+		for {
+			mt, data, err := from.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if mt != websocket.BinaryMessage {
+				continue
+			}
+
+			if err := to.WriteMessage(websocket.BinaryMessage, data); err != nil {
+				return
+			}
+			counter.Add(uint64(len(data)))
+		}
+	}
+
+	go copyMessages(client, agent, &t.bytesSent)
+	go copyMessages(agent, client, &t.bytesReceived)
+
+	// when one side is closed the other one is closed as well,
+	// so the pending read of the other goroutine returns
+	<-done
+	client.Close()
+	agent.Close()
+	<-done
+}
@@ -0,0 +1,101 @@
+package tunnels
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var testUpgrader = websocket.Upgrader{}
+
+func wsUrl(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+/*
+Send data through a tunnel to an echo server that
+simulates the agent, bytes are counted in both directions
+*/
+func TestPipe(t *testing.T) {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := testUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			mt, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(mt, append(data, data...))
+		}
+	}))
+	defer agent.Close()
+
+	tunnel := &Tunnel{WorkspaceID: 1, ContainerName: "development", PortNumber: 5432}
+	piped := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agentConn, _, err := websocket.DefaultDialer.Dial(wsUrl(agent), nil)
+		if err != nil {
+			t.Errorf("cannot connect to the agent: %v", err)
+			return
+		}
+
+		clientConn, err := testUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			agentConn.Close()
+			return
+		}
+
+		unregister := Register(tunnel)
+		Pipe(tunnel, clientConn, agentConn)
+		unregister()
+		close(piped)
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial(wsUrl(server), nil)
+	if err != nil {
+		t.Fatalf("cannot connect to the server: %v", err)
+	}
+
+	if err := client.WriteMessage(websocket.BinaryMessage, []byte("ping")); err != nil {
+		t.Fatalf("cannot write message: %v", err)
+	}
+
+	mt, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("cannot read message: %v", err)
+	}
+
+	if mt != websocket.BinaryMessage || string(data) != "pingping" {
+		t.Fatalf("unexpected message %d %q", mt, data)
+	}
+
+	if tunnels := ListByContainer(1, "development"); len(tunnels) != 1 || tunnels[0] != tunnel {
+		t.Fatalf("expected the tunnel to be active, got %v", tunnels)
+	}
+
+	client.Close()
+
+	select {
+	case <-piped:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("the tunnel has not been closed")
+	}
+
+	if tunnel.BytesSent() != 4 || tunnel.BytesReceived() != 8 {
+		t.Fatalf("unexpected counters, sent %d received %d", tunnel.BytesSent(), tunnel.BytesReceived())
+	}
+
+	if tunnels := ListByContainer(1, "development"); len(tunnels) != 0 {
+		t.Fatalf("expected no active tunnels, got %d", len(tunnels))
+	}
+}
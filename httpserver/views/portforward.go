@@ -56,3 +56,29 @@ func HandlePortForwardView(c *gin.Context) {
 		c.Request.URL.Path,
 	)
 }
+
+/*
+View that handles path-based port forward,
+used when subdomains are disabled
+*/
+func HandlePathPortForwardView(c *gin.Context) {
+	workspaceId, err := strconv.Atoi(c.Param("workspaceId"))
+	if err != nil || workspaceId <= 0 {
+		httperrors.RenderError(c, http.StatusNotFound, "Workspace not found")
+		return
+	}
+
+	portNumber, err := strconv.Atoi(c.Param("portNumber"))
+	if err != nil || portNumber <= 0 || portNumber >= 65536 {
+		httperrors.RenderError(c, http.StatusBadRequest, "Invalid port number")
+		return
+	}
+
+	ports.ForwardHttpPortWithPathPrefix(
+		c,
+		uint(workspaceId),
+		c.Param("containerName"),
+		uint(portNumber),
+		c.Param("path"),
+	)
+}
@@ -2,6 +2,7 @@ package views
 
 import (
 	"github.com/gin-gonic/gin"
+	"gitlab.com/codebox4073715/codebox/config"
 	"gitlab.com/codebox4073715/codebox/httpserver/permissions"
 )
 
@@ -17,4 +18,12 @@ func ViewsRoutes(router *gin.Engine) {
 			HandlePortForwardView,
 		)
 	}
+
+	// without subdomains ports are exposed under /p/<workspace>/<container>/<port>/
+	if !config.Environment.UseSubDomains {
+		router.Any(
+			"/p/:workspaceId/:containerName/:portNumber/*path",
+			HandlePathPortForwardView,
+		)
+	}
 }
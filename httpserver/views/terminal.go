@@ -37,7 +37,7 @@ func HandleTerminalView(ctx *gin.Context) {
 		return
 	}
 
-	workspace, err := models.RetrieveWorkspaceByUserAndId(user, workspaceId)
+	workspace, role, err := models.RetrieveWorkspaceWithRoleByUserAndId(user, workspaceId)
 	if err != nil {
 		ctx.HTML(http.StatusNotFound, "errors.html", gin.H{
 			"title":   "Unknown error",
@@ -46,7 +46,7 @@ func HandleTerminalView(ctx *gin.Context) {
 		return
 	}
 
-	if workspace == nil {
+	if workspace == nil || !models.WorkspaceRoleAllows(role, models.WorkspaceRoleDeveloper) {
 		ctx.HTML(http.StatusNotFound, "errors.html", gin.H{
 			"title":   "Not found",
 			"message": "Workspace not found",
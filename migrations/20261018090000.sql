@@ -0,0 +1,6 @@
+-- Modify "workspace_containers" table
+ALTER TABLE `workspace_containers` ADD COLUMN `last_ssh_activity` datetime(3) NULL, ADD COLUMN `last_terminal_activity` datetime(3) NULL, ADD COLUMN `last_port_forward_activity` datetime(3) NULL;
+-- Modify "workspace_templates" table
+ALTER TABLE `workspace_templates` ADD COLUMN `idle_timeout_minutes` bigint unsigned NULL DEFAULT 0;
+-- Modify "workspaces" table
+ALTER TABLE `workspaces` ADD COLUMN `idle_timeout_minutes` bigint unsigned NULL, ADD COLUMN `idle_shutdown_at` datetime(3) NULL;
@@ -0,0 +1,19 @@
+-- Modify "users" table
+ALTER TABLE `users` ADD COLUMN `time_zone` varchar(64) NULL DEFAULT "UTC";
+-- Modify "workspaces" table
+ALTER TABLE `workspaces` ADD COLUMN `start_schedule` varchar(255) NULL DEFAULT "", ADD COLUMN `stop_schedule` varchar(255) NULL DEFAULT "", ADD COLUMN `schedule_last_check` datetime(3) NULL;
+-- Create "workspace_schedule_skipped_runs" table
+CREATE TABLE `workspace_schedule_skipped_runs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `workspace_id` bigint unsigned NOT NULL,
+  `action` varchar(20) NOT NULL,
+  `scheduled_at` datetime(3) NOT NULL,
+  `reason` text NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `fk_workspace_schedule_skipped_runs_workspace` (`workspace_id`),
+  INDEX `idx_workspace_schedule_skipped_runs_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_workspace_schedule_skipped_runs_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
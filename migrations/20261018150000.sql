@@ -0,0 +1,2 @@
+-- Modify "workspaces" table
+ALTER TABLE `workspaces` ADD COLUMN `error_reason` text NULL, ADD COLUMN `start_deadline` datetime(3) NULL, ADD COLUMN `start_heartbeat_at` datetime(3) NULL, ADD COLUMN `start_submitted` bool NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- Modify "git_workspace_sources" table
+ALTER TABLE `git_workspace_sources` ADD COLUMN `required_runner_labels` text NULL, ADD COLUMN `preferred_runner_labels` text NULL;
+-- Modify "runners" table
+ALTER TABLE `runners` ADD COLUMN `labels` text NULL;
+-- Modify "workspace_templates" table
+ALTER TABLE `workspace_templates` ADD COLUMN `required_runner_labels` text NULL, ADD COLUMN `preferred_runner_labels` text NULL;
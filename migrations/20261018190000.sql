@@ -0,0 +1,2 @@
+-- Modify "workspaces" table
+ALTER TABLE `workspaces` ADD COLUMN `runner_access_revoked` bool NULL DEFAULT 0;
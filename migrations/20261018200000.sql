@@ -0,0 +1,30 @@
+-- Create "quota_settings" table
+CREATE TABLE `quota_settings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `max_workspaces` bigint unsigned NULL,
+  `max_running_workspaces` bigint unsigned NULL,
+  `max_containers_per_workspace` bigint unsigned NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_quota_settings_deleted_at` (`deleted_at`)
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
+-- Create "workspace_quotas" table
+CREATE TABLE `workspace_quotas` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `group_id` bigint unsigned NULL,
+  `user_id` bigint unsigned NULL,
+  `max_workspaces` bigint unsigned NULL,
+  `max_running_workspaces` bigint unsigned NULL,
+  `max_containers_per_workspace` bigint unsigned NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_workspace_quotas_deleted_at` (`deleted_at`),
+  UNIQUE INDEX `idx_workspace_quotas_group_id` (`group_id`),
+  UNIQUE INDEX `idx_workspace_quotas_user_id` (`user_id`),
+  CONSTRAINT `fk_workspace_quotas_group` FOREIGN KEY (`group_id`) REFERENCES `groups` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_workspace_quotas_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
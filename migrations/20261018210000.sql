@@ -0,0 +1,19 @@
+-- Create "secrets" table
+CREATE TABLE `secrets` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `encrypted_value` text NULL,
+  `scope` varchar(255) NOT NULL,
+  `user_id` bigint unsigned NULL,
+  `group_id` bigint unsigned NULL,
+  `workspace_id` bigint unsigned NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_secrets_group_name` (`group_id`, `name`),
+  UNIQUE INDEX `idx_secrets_user_name` (`user_id`, `name`),
+  UNIQUE INDEX `idx_secrets_workspace_name` (`workspace_id`, `name`),
+  CONSTRAINT `fk_secrets_group` FOREIGN KEY (`group_id`) REFERENCES `groups` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_secrets_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_secrets_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,18 @@
+-- Create "workspace_events" table
+CREATE TABLE `workspace_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `workspace_id` bigint unsigned NOT NULL,
+  `from_status` varchar(30) NULL,
+  `to_status` varchar(30) NOT NULL,
+  `source` varchar(30) NOT NULL,
+  `actor_id` bigint unsigned NULL,
+  `message` text NULL,
+  `error_reason` text NULL,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `fk_workspace_events_actor` (`actor_id`),
+  INDEX `fk_workspace_events_workspace` (`workspace_id`),
+  INDEX `idx_workspace_events_created_at` (`created_at`),
+  CONSTRAINT `fk_workspace_events_actor` FOREIGN KEY (`actor_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT `fk_workspace_events_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,20 @@
+-- Modify "runners" table
+ALTER TABLE `runners` ADD COLUMN `capabilities` text NULL;
+-- Modify "workspace_containers" table
+ALTER TABLE `workspace_containers` ADD COLUMN `cpu_limit` double NULL, ADD COLUMN `memory_limit_mb` bigint unsigned NULL, ADD COLUMN `disk_limit_mb` bigint unsigned NULL;
+-- Modify "workspace_templates" table
+ALTER TABLE `workspace_templates` ADD COLUMN `cpu_limit` double NULL, ADD COLUMN `memory_limit_mb` bigint unsigned NULL, ADD COLUMN `disk_limit_mb` bigint unsigned NULL;
+-- Modify "workspaces" table
+ALTER TABLE `workspaces` ADD COLUMN `cpu_limit` double NULL, ADD COLUMN `memory_limit_mb` bigint unsigned NULL, ADD COLUMN `disk_limit_mb` bigint unsigned NULL;
+-- Create "resource_limits_settings" table
+CREATE TABLE `resource_limits_settings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `max_cpu_limit` double NULL,
+  `max_memory_limit_mb` bigint unsigned NULL,
+  `max_disk_limit_mb` bigint unsigned NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_resource_limits_settings_deleted_at` (`deleted_at`)
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
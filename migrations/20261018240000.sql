@@ -0,0 +1,4 @@
+-- Modify "workspace_template_versions" table
+ALTER TABLE `workspace_template_versions` ADD COLUMN `parameters` text NULL;
+-- Modify "workspaces" table
+ALTER TABLE `workspaces` ADD COLUMN `parameter_values` text NULL;
@@ -0,0 +1,14 @@
+-- Create "workspace_collaborators" table
+CREATE TABLE `workspace_collaborators` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `workspace_id` bigint unsigned NOT NULL,
+  `user_id` bigint unsigned NOT NULL,
+  `role` varchar(30) NOT NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `fk_workspace_collaborators_user` (`user_id`),
+  UNIQUE INDEX `idx_workspace_collaborators_user` (`workspace_id`, `user_id`),
+  CONSTRAINT `fk_workspace_collaborators_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT `fk_workspace_collaborators_workspace` FOREIGN KEY (`workspace_id`) REFERENCES `workspaces` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,21 @@
+-- Create "port_share_tokens" table
+CREATE TABLE `port_share_tokens` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `port_id` bigint unsigned NOT NULL,
+  `name` varchar(255) NULL,
+  `token` varchar(255) NOT NULL,
+  `cookie_secret` varchar(255) NOT NULL,
+  `password_hash` varchar(255) NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `max_uses` bigint unsigned NULL,
+  `uses` bigint unsigned NULL DEFAULT 0,
+  `created_by_id` bigint unsigned NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `fk_port_share_tokens_created_by` (`created_by_id`),
+  INDEX `fk_port_share_tokens_port` (`port_id`),
+  UNIQUE INDEX `uni_port_share_tokens_token` (`token`),
+  CONSTRAINT `fk_port_share_tokens_created_by` FOREIGN KEY (`created_by_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT `fk_port_share_tokens_port` FOREIGN KEY (`port_id`) REFERENCES `workspace_container_ports` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
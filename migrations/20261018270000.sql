@@ -0,0 +1,21 @@
+-- Create "oidc_settings" table
+CREATE TABLE `oidc_settings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `enabled` bool NULL DEFAULT 0,
+  `provider_name` varchar(255) NULL,
+  `issuer_url` varchar(255) NULL,
+  `client_id` varchar(255) NULL,
+  `client_secret` varchar(255) NULL,
+  `scopes` varchar(255) NULL,
+  `email_claim` varchar(255) NULL,
+  `first_name_claim` varchar(255) NULL,
+  `last_name_claim` varchar(255) NULL,
+  `groups_claim` varchar(255) NULL,
+  `auto_provision_users` bool NULL DEFAULT 0,
+  `password_login_disabled` bool NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  INDEX `idx_oidc_settings_deleted_at` (`deleted_at`)
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,24 @@
+-- Modify "users" table
+ALTER TABLE `users` ADD COLUMN `ldap_dn` varchar(255) NULL, ADD COLUMN `deactivated` bool NOT NULL DEFAULT 0;
+-- Create "ldap_settings" table
+CREATE TABLE `ldap_settings` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  `enabled` bool NULL DEFAULT 0,
+  `url` varchar(255) NULL,
+  `start_tls` bool NULL DEFAULT 0,
+  `insecure_skip_verify` bool NULL DEFAULT 0,
+  `bind_dn` varchar(255) NULL,
+  `bind_password` varchar(255) NULL,
+  `user_base_dn` varchar(255) NULL,
+  `user_filter` varchar(255) NULL,
+  `email_attribute` varchar(255) NULL,
+  `first_name_attribute` varchar(255) NULL,
+  `last_name_attribute` varchar(255) NULL,
+  `groups_attribute` varchar(255) NULL,
+  `auto_provision_users` bool NULL DEFAULT 0,
+  PRIMARY KEY (`id`),
+  INDEX `idx_ldap_settings_deleted_at` (`deleted_at`)
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
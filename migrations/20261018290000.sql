@@ -0,0 +1,21 @@
+-- Modify "users" table
+ALTER TABLE `users` ADD COLUMN `totp_secret` varchar(64) NULL, ADD COLUMN `totp_enabled` bool NOT NULL DEFAULT 0, ADD COLUMN `totp_last_used_step` bigint NOT NULL DEFAULT 0;
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `second_factor_verified` bool NOT NULL DEFAULT 0;
+-- Modify "authentication_settings" table
+ALTER TABLE `authentication_settings` ADD COLUMN `two_factor_required_for_superusers` bool NULL DEFAULT 0, ADD COLUMN `two_factor_required_for_template_managers` bool NULL DEFAULT 0;
+-- Create "two_factor_recovery_codes" table
+CREATE TABLE `two_factor_recovery_codes` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `code_hash` varchar(64) NOT NULL,
+  `used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `fk_two_factor_recovery_codes_user` (`user_id`),
+  INDEX `idx_two_factor_recovery_codes_code_hash` (`code_hash`),
+  INDEX `idx_two_factor_recovery_codes_deleted_at` (`deleted_at`),
+  CONSTRAINT `fk_two_factor_recovery_codes_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,19 @@
+-- Create "personal_access_tokens" table
+CREATE TABLE `personal_access_tokens` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `token_hash` varchar(64) NOT NULL,
+  `token_prefix` varchar(16) NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `expires_at` datetime(3) NOT NULL,
+  `last_used_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  `deleted_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `fk_personal_access_tokens_user` (`user_id`),
+  INDEX `idx_personal_access_tokens_deleted_at` (`deleted_at`),
+  UNIQUE INDEX `uni_personal_access_tokens_token_hash` (`token_hash`),
+  CONSTRAINT `fk_personal_access_tokens_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON UPDATE NO ACTION ON DELETE CASCADE
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
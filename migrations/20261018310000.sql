@@ -0,0 +1,2 @@
+-- Modify "tokens" table
+ALTER TABLE `tokens` ADD COLUMN `ip_address` varchar(64) NULL, ADD COLUMN `user_agent` varchar(255) NULL, ADD COLUMN `last_used_at` datetime(3) NULL;
@@ -0,0 +1,11 @@
+-- Modify "workspace_templates" table
+ALTER TABLE `workspace_templates` ADD COLUMN `restricted` bool NULL DEFAULT 0;
+-- Create "workspace_template_allowed_groups" table
+CREATE TABLE `workspace_template_allowed_groups` (
+  `workspace_template_id` bigint unsigned NOT NULL,
+  `group_id` bigint unsigned NOT NULL,
+  PRIMARY KEY (`workspace_template_id`, `group_id`),
+  INDEX `fk_workspace_template_allowed_groups_group` (`group_id`),
+  CONSTRAINT `fk_workspace_template_allowed_groups_group` FOREIGN KEY (`group_id`) REFERENCES `groups` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT `fk_workspace_template_allowed_groups_workspace_template` FOREIGN KEY (`workspace_template_id`) REFERENCES `workspace_templates` (`id`) ON UPDATE NO ACTION ON DELETE NO ACTION
+) CHARSET utf8mb4 COLLATE utf8mb4_0900_ai_ci;
@@ -1,10 +1,13 @@
 package runnerinterface
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"gitlab.com/codebox4073715/codebox/config"
 	"gitlab.com/codebox4073715/codebox/db/models"
 	"gitlab.com/codebox4073715/codebox/httpserver/proxy"
@@ -51,6 +54,7 @@ func (ri *RunnerInterface) AgentForwardHttp(
 	path string,
 	rw http.ResponseWriter,
 	req *http.Request,
+	modifyResponse func(*http.Response) error, // optional, edits the responses of the port
 ) error {
 	url := fmt.Sprintf(
 		"%s/api/v1/workspace/%d/container/%s/http-reverse-proxy?request_protocol=http&target_port=%d&target_path=%s&%s=%s",
@@ -68,6 +72,7 @@ func (ri *RunnerInterface) AgentForwardHttp(
 	if err != nil {
 		return err
 	}
+	proxy.ModifyResponse = modifyResponse
 
 	proxy.ServeHTTP(rw, req)
 	return nil
@@ -98,6 +103,51 @@ func (ri *RunnerInterface) AgentForwardSsh(
 	return nil
 }
 
+/*
+AgentDialTcp opens a WebSocket connection to the agent of a container,
+the agent forwards the binary messages to a port of the container as a raw TCP stream
+*/
+func (ri *RunnerInterface) AgentDialTcp(
+	workspace *models.Workspace,
+	container *models.WorkspaceContainer,
+	portNumber uint,
+) (*websocket.Conn, error) {
+	target, err := url.Parse(fmt.Sprintf(
+		"%s/api/v1/workspace/%d/container/%s/tcp-proxy?target_port=%d",
+		ri.getRunnerBaseUrl(),
+		workspace.ID,
+		container.ContainerName,
+		portNumber,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Scheme == "https" {
+		target.Scheme = "wss"
+	} else {
+		target.Scheme = "ws"
+	}
+
+	headers := http.Header{}
+	headers.Set(config.Environment.RunnerTokenHeader, ri.Runner.Token)
+
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true},
+	}
+
+	conn, res, err := dialer.Dial(target.String(), headers)
+	if err != nil {
+		if res != nil {
+			return nil, fmt.Errorf("cannot connect to the agent, status code %d", res.StatusCode)
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
 func (ri *RunnerInterface) AgentForwardTerminal(
 	workspace *models.Workspace,
 	container *models.WorkspaceContainer,
@@ -1,5 +1,12 @@
 package runnerinterface
 
+import "gitlab.com/codebox4073715/codebox/db/models"
+
+type RunnerInfo struct {
+	Version      string   `json:"version"`
+	Capabilities []string `json:"capabilities"`
+}
+
 type RunnerExposedPort struct {
 	PortNumber  int    `json:"port_number"`
 	ServiceName string `json:"service_name"`
@@ -15,6 +22,18 @@ type RunnerContainer struct {
 	ContainerUserName string              `json:"container_user_name"`
 	ExposedPorts      []RunnerExposedPort `json:"exposed_ports"`
 	WorkspacePath     string              `json:"workspace_path"`
+	CPULimit          *float64            `json:"cpu_limit"` // actual limits applied by the runner
+	MemoryLimitMB     *uint               `json:"memory_limit_mb"`
+	DiskLimitMB       *uint               `json:"disk_limit_mb"`
+}
+
+// retrieve the limits applied by the runner to the container
+func (c RunnerContainer) GetResourceLimits() models.ResourceLimits {
+	return models.ResourceLimits{
+		CPULimit:      c.CPULimit,
+		MemoryLimitMB: c.MemoryLimitMB,
+		DiskLimitMB:   c.DiskLimitMB,
+	}
 }
 
 type RunnerWorkspaceStatusResponse struct {
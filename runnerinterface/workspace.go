@@ -16,43 +16,57 @@ import (
 	"gitlab.com/codebox4073715/codebox/db/models"
 )
 
-func (ri *RunnerInterface) GetRunnerVersion() (version string, err error) {
+/*
+GetRunnerInfo retrieves the version of the runner and the
+capabilities it advertises, older runners do not advertise any
+*/
+func (ri *RunnerInterface) GetRunnerInfo() (info RunnerInfo, err error) {
 	url := fmt.Sprintf("%s/api/v1/version/", ri.getRunnerBaseUrl())
 	client := ri.getRequestsClient()
 
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 
 	if err != nil {
-		return "", err
+		return RunnerInfo{}, err
 	}
 	req.Header.Set(config.Environment.RunnerTokenHeader, ri.Runner.Token)
 
 	res, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return RunnerInfo{}, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return "", fmt.Errorf("receivedstatus %d", res.StatusCode)
+		return RunnerInfo{}, fmt.Errorf("receivedstatus %d", res.StatusCode)
 	}
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return "", err
+		return RunnerInfo{}, err
 	}
 
-	var jsonResponse map[string]interface{}
-	if err = json.Unmarshal(body, &jsonResponse); err != nil {
-		return "", err
+	if err = json.Unmarshal(body, &info); err != nil {
+		return RunnerInfo{}, err
 	}
 
-	version, ok := jsonResponse["version"].(string)
-	if !ok {
-		return "", errors.New("invalid response")
+	if info.Version == "" {
+		return RunnerInfo{}, errors.New("invalid response")
 	}
 
-	return version, nil
+	if info.Capabilities == nil {
+		info.Capabilities = []string{}
+	}
+
+	return info, nil
+}
+
+func (ri *RunnerInterface) GetRunnerVersion() (version string, err error) {
+	info, err := ri.GetRunnerInfo()
+	if err != nil {
+		return "", err
+	}
+	return info.Version, nil
 }
 
 func (ri *RunnerInterface) StartWorkspace(workspace *models.Workspace) (err error) {
@@ -77,6 +91,29 @@ func (ri *RunnerInterface) StartWorkspace(workspace *models.Workspace) (err erro
 			return errors.New("source files do not exists")
 		}
 		configFilePath = workspace.TemplateVersion.Sources.GetAbsolutePath()
+
+		// the parameters are rendered in a copy of the sources
+		if len(workspace.TemplateVersion.Parameters) > 0 {
+			values, err := models.ResolveTemplateParameterValues(
+				workspace.TemplateVersion.Parameters,
+				workspace.ParameterValues,
+			)
+			if err != nil {
+				return err
+			}
+
+			renderedFile, err := os.CreateTemp("", fmt.Sprintf("codebox-%d-*.tar.gz", workspace.ID))
+			if err != nil {
+				return err
+			}
+			renderedFile.Close()
+			defer os.Remove(renderedFile.Name())
+
+			if err := workspace.TemplateVersion.RenderSources(values, renderedFile.Name()); err != nil {
+				return err
+			}
+			configFilePath = renderedFile.Name()
+		}
 	}
 
 	configFile, err := os.Open(configFilePath)
@@ -105,17 +142,47 @@ func (ri *RunnerInterface) StartWorkspace(workspace *models.Workspace) (err erro
 	_ = writer.WriteField("git_user_name", fmt.Sprintf("%s %s", workspace.User.FirstName, workspace.User.LastName))
 	_ = writer.WriteField("git_user_email", workspace.User.Email)
 
-	// add default variables to environment
-	_ = writer.WriteField(
-		"environment",
-		strings.Join(
-			append(
-				workspace.EnvironmentVariables,
-				workspace.GetDefaultEnvironmentVariables()...,
-			),
-			";",
-		),
-	)
+	// secrets are only sent to the runner, they must never be written to the logs
+	workspaceSecrets, err := models.ResolveWorkspaceSecrets(*workspace)
+	if err != nil {
+		return err
+	}
+
+	// add secrets and default variables to environment
+	environment := append([]string{}, workspace.EnvironmentVariables...)
+	environment = append(environment, workspaceSecrets...)
+	environment = append(environment, workspace.GetDefaultEnvironmentVariables()...)
+	_ = writer.WriteField("environment", strings.Join(environment, ";"))
+
+	// a runner that cannot enforce the requested limits must not start the workspace
+	requestedLimits, err := workspace.GetRequestedResourceLimits()
+	if err != nil {
+		return err
+	}
+
+	if err := models.CheckRunnerResourceLimitsSupport(*workspace.Runner, requestedLimits); err != nil {
+		return err
+	}
+
+	// limits are sent only if set, the maximum resource limits
+	// are enforced only by the runners that support them
+	limits := models.ResourceLimits{}
+	if workspace.Runner.HasCapability(models.RunnerCapabilityResourceLimits) {
+		limits, err = workspace.GetResourceLimits()
+		if err != nil {
+			return err
+		}
+	}
+
+	if limits.CPULimit != nil {
+		_ = writer.WriteField("cpu_limit", strconv.FormatFloat(*limits.CPULimit, 'f', -1, 64))
+	}
+	if limits.MemoryLimitMB != nil {
+		_ = writer.WriteField("memory_limit_mb", strconv.FormatUint(uint64(*limits.MemoryLimitMB), 10))
+	}
+	if limits.DiskLimitMB != nil {
+		_ = writer.WriteField("disk_limit_mb", strconv.FormatUint(uint64(*limits.DiskLimitMB), 10))
+	}
 
 	err = writer.Close()
 	if err != nil {
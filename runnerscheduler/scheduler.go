@@ -0,0 +1,226 @@
+package runnerscheduler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+// returned when no runner can host the workspace
+var ErrNoRunnerAvailable = errors.New("no runner available for the requested workspace")
+
+/*
+Candidate is a runner that can host the workspace,
+together with the information used by the strategies
+*/
+type Candidate struct {
+	Runner           models.Runner
+	ActiveWorkspaces int64
+	PreferredMatches int
+}
+
+/*
+Strategy chooses a runner among the candidates,
+Pick returns nil if none of the candidates is suitable.
+The returned string describes why the runner has been chosen
+*/
+type Strategy interface {
+	Name() string
+	Pick(candidates []Candidate) (*Candidate, string)
+}
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Strategy{}
+)
+
+/*
+RegisterStrategy makes a strategy available, the strategy
+is selected with CODEBOX_RUNNER_SCHEDULING_STRATEGY
+*/
+func RegisterStrategy(strategy Strategy) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[strategy.Name()] = strategy
+}
+
+/*
+GetStrategy retrieves a registered strategy by its name
+*/
+func GetStrategy(name string) Strategy {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	return strategies[name]
+}
+
+/*
+ValidateStrategy checks that a strategy with the given name has been registered,
+it's used at startup to validate CODEBOX_RUNNER_SCHEDULING_STRATEGY
+*/
+func ValidateStrategy(name string) error {
+	if GetStrategy(name) == nil {
+		return fmt.Errorf("CODEBOX_RUNNER_SCHEDULING_STRATEGY unsupported strategy: %s", name)
+	}
+	return nil
+}
+
+func init() {
+	RegisterStrategy(&LeastWorkspacesStrategy{})
+	RegisterStrategy(&MaxWorkspacesStrategy{})
+}
+
+/*
+ListCandidates returns the runners that can host a workspace of the given type
+for the user: the runner must be online, support the workspace type, have the
+required labels, be allowed for the groups of the user and be able to enforce
+the resource limits of the workspace
+*/
+func ListCandidates(
+	user models.User,
+	workspaceType string,
+	required models.RunnerLabels,
+	preferred models.RunnerLabels,
+	limits models.ResourceLimits,
+) ([]Candidate, error) {
+	runners, err := models.ListOnlineRunners()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []Candidate{}
+	for _, runner := range runners {
+		if !SupportsWorkspaceType(runner, workspaceType) {
+			continue
+		}
+
+		if models.CheckRunnerLabels(runner, required) != nil {
+			continue
+		}
+
+		if models.CheckRunnerResourceLimitsSupport(runner, limits) != nil {
+			continue
+		}
+
+		allowed, err := runner.IsAllowedForUser(user)
+		if err != nil {
+			return nil, err
+		}
+
+		if !allowed {
+			continue
+		}
+
+		activeWorkspaces, err := models.CountActiveWorkspacesByRunner(runner)
+		if err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, Candidate{
+			Runner:           runner,
+			ActiveWorkspaces: activeWorkspaces,
+			PreferredMatches: preferred.CountMatches(runner.Labels),
+		})
+	}
+
+	return candidates, nil
+}
+
+/*
+SupportsWorkspaceType checks if the type of the runner
+supports the requested workspace type
+*/
+func SupportsWorkspaceType(runner models.Runner, workspaceType string) bool {
+	rt := config.RetrieveRunnerTypeByID(runner.Type)
+	if rt == nil {
+		return false
+	}
+
+	for _, supportedType := range rt.SupportedTypes {
+		if supportedType.ID == workspaceType {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+KeepPreferredCandidates keeps only the candidates that match
+the highest number of preferred labels
+*/
+func KeepPreferredCandidates(candidates []Candidate) []Candidate {
+	best := 0
+	for _, candidate := range candidates {
+		if candidate.PreferredMatches > best {
+			best = candidate.PreferredMatches
+		}
+	}
+
+	preferred := []Candidate{}
+	for _, candidate := range candidates {
+		if candidate.PreferredMatches == best {
+			preferred = append(preferred, candidate)
+		}
+	}
+	return preferred
+}
+
+/*
+SelectRunner chooses a runner for a new workspace using the strategy set in
+the configuration. Runners without the required labels are excluded, the
+strategy chooses among the runners that match most of the preferred labels.
+It returns the runner and a message that explains the choice
+*/
+func SelectRunner(
+	user models.User,
+	workspaceType string,
+	required models.RunnerLabels,
+	preferred models.RunnerLabels,
+	limits models.ResourceLimits,
+) (*models.Runner, string, error) {
+	strategy := GetStrategy(config.Environment.RunnerSchedulingStrategy)
+	if strategy == nil {
+		return nil, "", fmt.Errorf(
+			"unknown runner scheduling strategy '%s'",
+			config.Environment.RunnerSchedulingStrategy,
+		)
+	}
+
+	candidates, err := ListCandidates(user, workspaceType, required, preferred, limits)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(candidates) == 0 {
+		return nil, "", ErrNoRunnerAvailable
+	}
+
+	// fallback to the other candidates if the strategy
+	// refuses all the runners with the preferred labels
+	chosen, reason := strategy.Pick(KeepPreferredCandidates(candidates))
+	if chosen == nil {
+		chosen, reason = strategy.Pick(candidates)
+	}
+
+	if chosen == nil {
+		return nil, "", ErrNoRunnerAvailable
+	}
+
+	if len(preferred) > 0 {
+		reason = fmt.Sprintf(
+			"%s, %d of %d preferred labels matched",
+			reason,
+			chosen.PreferredMatches,
+			len(preferred),
+		)
+	}
+
+	return &chosen.Runner, fmt.Sprintf(
+		"Runner '%s' has been selected automatically (strategy '%s'): %s",
+		chosen.Runner.Name,
+		strategy.Name(),
+		reason,
+	), nil
+}
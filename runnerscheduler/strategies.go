@@ -0,0 +1,84 @@
+package runnerscheduler
+
+import (
+	"fmt"
+
+	"gitlab.com/codebox4073715/codebox/config"
+)
+
+/*
+LeastWorkspacesStrategy chooses the runner with the lowest number of
+active workspaces, ties are broken by runner id
+*/
+type LeastWorkspacesStrategy struct{}
+
+// names of the built-in strategies
+const (
+	StrategyLeastWorkspaces = "least_workspaces"
+	StrategyMaxWorkspaces   = "max_workspaces"
+)
+
+func (s *LeastWorkspacesStrategy) Name() string {
+	return StrategyLeastWorkspaces
+}
+
+func (s *LeastWorkspacesStrategy) Pick(candidates []Candidate) (*Candidate, string) {
+	var chosen *Candidate
+	for i := range candidates {
+		if chosen == nil ||
+			candidates[i].ActiveWorkspaces < chosen.ActiveWorkspaces ||
+			(candidates[i].ActiveWorkspaces == chosen.ActiveWorkspaces &&
+				candidates[i].Runner.ID < chosen.Runner.ID) {
+			chosen = &candidates[i]
+		}
+	}
+
+	if chosen == nil {
+		return nil, ""
+	}
+
+	return chosen, fmt.Sprintf(
+		"%d active workspaces, the lowest among %d eligible runners",
+		chosen.ActiveWorkspaces,
+		len(candidates),
+	)
+}
+
+/*
+MaxWorkspacesStrategy fills runners one at a time, it chooses the first runner
+(by id) that has less active workspaces than CODEBOX_RUNNER_MAX_WORKSPACES.
+If the limit is 0 it behaves as LeastWorkspacesStrategy
+*/
+type MaxWorkspacesStrategy struct{}
+
+func (s *MaxWorkspacesStrategy) Name() string {
+	return StrategyMaxWorkspaces
+}
+
+func (s *MaxWorkspacesStrategy) Pick(candidates []Candidate) (*Candidate, string) {
+	limit := int64(config.Environment.RunnerMaxWorkspaces)
+	if limit == 0 {
+		return (&LeastWorkspacesStrategy{}).Pick(candidates)
+	}
+
+	var chosen *Candidate
+	for i := range candidates {
+		if candidates[i].ActiveWorkspaces >= limit {
+			continue
+		}
+
+		if chosen == nil || candidates[i].Runner.ID < chosen.Runner.ID {
+			chosen = &candidates[i]
+		}
+	}
+
+	if chosen == nil {
+		return nil, ""
+	}
+
+	return chosen, fmt.Sprintf(
+		"%d active workspaces, below the limit of %d workspaces per runner",
+		chosen.ActiveWorkspaces,
+		limit,
+	)
+}
@@ -0,0 +1,140 @@
+package runnerscheduler
+
+import (
+	"testing"
+
+	"gitlab.com/codebox4073715/codebox/config"
+	"gitlab.com/codebox4073715/codebox/db/models"
+)
+
+func TestLeastWorkspacesStrategy(t *testing.T) {
+	candidates := []Candidate{
+		{Runner: models.Runner{ID: 1, Name: "runner-1"}, ActiveWorkspaces: 3},
+		{Runner: models.Runner{ID: 2, Name: "runner-2"}, ActiveWorkspaces: 1},
+		{Runner: models.Runner{ID: 3, Name: "runner-3"}, ActiveWorkspaces: 1},
+	}
+
+	chosen, reason := (&LeastWorkspacesStrategy{}).Pick(candidates)
+	if chosen == nil {
+		t.Fatal("expected a runner to be chosen")
+	}
+
+	if chosen.Runner.ID != 2 {
+		t.Errorf("expected runner 2, got %d", chosen.Runner.ID)
+	}
+
+	if reason == "" {
+		t.Error("expected a reason")
+	}
+
+	chosen, _ = (&LeastWorkspacesStrategy{}).Pick([]Candidate{})
+	if chosen != nil {
+		t.Error("expected no runner for empty candidates")
+	}
+}
+
+func TestMaxWorkspacesStrategy(t *testing.T) {
+	previous := config.Environment
+	defer func() { config.Environment = previous }()
+
+	candidates := []Candidate{
+		{Runner: models.Runner{ID: 1, Name: "runner-1"}, ActiveWorkspaces: 2},
+		{Runner: models.Runner{ID: 2, Name: "runner-2"}, ActiveWorkspaces: 1},
+		{Runner: models.Runner{ID: 3, Name: "runner-3"}, ActiveWorkspaces: 0},
+	}
+
+	tests := []struct {
+		name          string
+		maxWorkspaces int
+		expectedID    uint
+	}{
+		{name: "first runner is full", maxWorkspaces: 2, expectedID: 2},
+		{name: "all runners have room", maxWorkspaces: 5, expectedID: 1},
+		{name: "only last runner has room", maxWorkspaces: 1, expectedID: 3},
+		{name: "no limit", maxWorkspaces: 0, expectedID: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Environment = &config.EnvVars{RunnerMaxWorkspaces: tt.maxWorkspaces}
+			chosen, _ := (&MaxWorkspacesStrategy{}).Pick(candidates)
+			if chosen == nil {
+				t.Fatal("expected a runner to be chosen")
+			}
+			if chosen.Runner.ID != tt.expectedID {
+				t.Errorf("expected runner %d, got %d", tt.expectedID, chosen.Runner.ID)
+			}
+		})
+	}
+
+	config.Environment = &config.EnvVars{RunnerMaxWorkspaces: 1}
+	chosen, _ := (&MaxWorkspacesStrategy{}).Pick(candidates[:2])
+	if chosen != nil {
+		t.Error("expected no runner when all runners are full")
+	}
+}
+
+func TestKeepPreferredCandidates(t *testing.T) {
+	candidates := []Candidate{
+		{Runner: models.Runner{ID: 1, Name: "runner-1"}, PreferredMatches: 0},
+		{Runner: models.Runner{ID: 2, Name: "runner-2"}, PreferredMatches: 2},
+		{Runner: models.Runner{ID: 3, Name: "runner-3"}, PreferredMatches: 2},
+	}
+
+	preferred := KeepPreferredCandidates(candidates)
+	if len(preferred) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(preferred))
+	}
+
+	for _, candidate := range preferred {
+		if candidate.Runner.ID == 1 {
+			t.Error("runner 1 should have been excluded")
+		}
+	}
+}
+
+func TestRunnerLabelsSelector(t *testing.T) {
+	labels := models.RunnerLabels{"gpu": "true", "zone": "eu"}
+
+	selector := models.RunnerLabels{"gpu": "true", "zone": "us", "ssd": ""}
+	unmet := selector.Unmet(labels)
+	if len(unmet) != 2 || unmet[0] != "ssd" || unmet[1] != "zone=us" {
+		t.Errorf("unexpected unmet labels %v", unmet)
+	}
+
+	if selector.CountMatches(labels) != 1 {
+		t.Errorf("expected 1 match, got %d", selector.CountMatches(labels))
+	}
+
+	err := models.CheckRunnerLabels(models.Runner{Name: "runner-1", Labels: labels}, models.RunnerLabels{"zone": ""})
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	err = models.CheckRunnerLabels(models.Runner{Name: "runner-1", Labels: labels}, selector)
+	if err == nil || err.Error() != "runner 'runner-1' does not satisfy the required labels: ssd, zone=us" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestValidateStrategy(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    string
+		expectError bool
+	}{
+		{name: "least workspaces strategy", strategy: StrategyLeastWorkspaces, expectError: false},
+		{name: "max workspaces strategy", strategy: StrategyMaxWorkspaces, expectError: false},
+		{name: "empty strategy", strategy: "", expectError: true},
+		{name: "unsupported strategy", strategy: "random", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStrategy(tt.strategy)
+			if (err != nil) != tt.expectError {
+				t.Errorf("ValidateStrategy() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
@@ -0,0 +1,334 @@
+/*
+Package ldap authenticates users against an LDAP or Active Directory server.
+Users are searched with a service account (bind DN), then the password of the
+user is checked binding as the DN of the entry that has been found
+*/
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// timeout used to connect to the server and for every request
+const requestTimeout = 10 * time.Second
+
+// Active Directory attribute whose ACCOUNTDISABLE flag is set for disabled accounts
+const userAccountControlAttribute = "userAccountControl"
+
+const accountDisableFlag = 0x2
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrUserNotFound       = errors.New("user not found in the directory")
+)
+
+/*
+Config contains the parameters used to connect to the directory and the
+attributes mapped to the fields of the users. UserFilter must contain %s,
+that is replaced with the escaped login of the user
+*/
+type Config struct {
+	Url                string // ldap://host:389 or ldaps://host:636
+	StartTLS           bool
+	InsecureSkipVerify bool
+	BindDN             string
+	BindPassword       string
+	UserBaseDN         string
+	UserFilter         string
+	EmailAttribute     string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	GroupsAttribute    string // if empty the groups are not read
+}
+
+/*
+Entry is a user found in the directory
+*/
+type Entry struct {
+	DN        string
+	Email     string
+	FirstName string
+	LastName  string
+	Groups    []string // DNs of the groups
+	Disabled  bool     // the account has been disabled in Active Directory
+}
+
+/*
+ValidateUrl checks that the url uses the ldap or ldaps scheme
+*/
+func ValidateUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid url")
+	}
+
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return fmt.Errorf("invalid url, the scheme must be ldap or ldaps")
+	}
+	return nil
+}
+
+/*
+BuildUserFilter returns the filter used to search the user, special
+characters of the login are escaped to prevent filter injections
+*/
+func BuildUserFilter(filter string, login string) string {
+	return strings.ReplaceAll(filter, "%s", ldap.EscapeFilter(login))
+}
+
+/*
+BuildAnyUserFilter returns the user filter matching every login,
+it is used to check that an entry is still a user of the directory
+*/
+func BuildAnyUserFilter(filter string) string {
+	return strings.ReplaceAll(filter, "%s", "*")
+}
+
+/*
+IsAccountDisabled checks the ACCOUNTDISABLE flag of an Active Directory
+userAccountControl value, directories without the attribute never disable accounts
+*/
+func IsAccountDisabled(userAccountControl string) bool {
+	value, err := strconv.ParseInt(strings.TrimSpace(userAccountControl), 10, 64)
+	if err != nil {
+		return false
+	}
+	return value&accountDisableFlag != 0
+}
+
+/*
+GroupName returns the value of the first attribute of a group DN,
+for example developers for cn=developers,ou=groups,dc=example,dc=com
+*/
+func GroupName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+/*
+GroupNames returns the names of the groups in the list of DNs,
+DNs that cannot be parsed are ignored
+*/
+func GroupNames(dns []string) []string {
+	names := []string{}
+	for _, dn := range dns {
+		if name := GroupName(dn); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	u, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}, nil
+}
+
+/*
+connect opens a connection to the server, upgrades it with StartTLS
+if required and binds with the service account
+*/
+func (c *Config) connect() (*ldap.Conn, error) {
+	if err := ValidateUrl(c.Url); err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ldap.DialURL(
+		c.Url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: requestTimeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to the directory: %w", err)
+	}
+	conn.SetTimeout(requestTimeout)
+
+	if c.StartTLS && strings.HasPrefix(c.Url, "ldap://") {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("starttls failed: %w", err)
+		}
+	}
+
+	if c.BindDN != "" {
+		err = conn.Bind(c.BindDN, c.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("cannot bind with the service account: %w", err)
+	}
+
+	return conn, nil
+}
+
+func (c *Config) attributes() []string {
+	attributes := []string{
+		c.EmailAttribute,
+		c.FirstNameAttribute,
+		c.LastNameAttribute,
+		userAccountControlAttribute,
+	}
+	if c.GroupsAttribute != "" {
+		attributes = append(attributes, c.GroupsAttribute)
+	}
+	return attributes
+}
+
+/*
+searchUser searches the entry of a user, ErrUserNotFound is returned
+if no entry or more than one entry match the filter
+*/
+func (c *Config) searchUser(conn *ldap.Conn, baseDN string, scope int, filter string) (*Entry, error) {
+	result, err := conn.Search(ldap.NewSearchRequest(
+		baseDN,
+		scope,
+		ldap.NeverDerefAliases,
+		2,
+		int(requestTimeout.Seconds()),
+		false,
+		filter,
+		c.attributes(),
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) ||
+			ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	if len(result.Entries) != 1 {
+		return nil, ErrUserNotFound
+	}
+
+	entry := result.Entries[0]
+	e := &Entry{
+		DN:        entry.DN,
+		Email:     strings.TrimSpace(entry.GetAttributeValue(c.EmailAttribute)),
+		FirstName: entry.GetAttributeValue(c.FirstNameAttribute),
+		LastName:  entry.GetAttributeValue(c.LastNameAttribute),
+		Groups:    []string{},
+		Disabled:  IsAccountDisabled(entry.GetAttributeValue(userAccountControlAttribute)),
+	}
+	if c.GroupsAttribute != "" {
+		e.Groups = entry.GetAttributeValues(c.GroupsAttribute)
+	}
+	return e, nil
+}
+
+/*
+Authenticate checks the credentials of the user and returns its entry,
+ErrInvalidCredentials is returned if the user does not exist or the password is wrong
+*/
+func (c *Config) Authenticate(login string, password string) (*Entry, error) {
+	// an empty password would be an unauthenticated bind, that always succeeds
+	if login == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := c.searchUser(
+		conn,
+		c.UserBaseDN,
+		ldap.ScopeWholeSubtree,
+		BuildUserFilter(c.UserFilter, login),
+	)
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	if entry.Disabled {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("bind failed: %w", err)
+	}
+
+	return entry, nil
+}
+
+/*
+FindMissingUsers returns the DNs of the list that do not exist anymore in the
+directory, that do not match the user filter anymore or whose account has been
+disabled, a single connection is used for all the DNs. An error is returned if
+the directory cannot be queried, in that case nothing must be assumed about the users
+*/
+func (c *Config) FindMissingUsers(dns []string) ([]string, error) {
+	missing := []string{}
+	if len(dns) == 0 {
+		return missing, nil
+	}
+
+	conn, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	filter := BuildAnyUserFilter(c.UserFilter)
+	for _, dn := range dns {
+		entry, err := c.searchUser(conn, dn, ldap.ScopeBaseObject, filter)
+		if err != nil {
+			if !errors.Is(err, ErrUserNotFound) {
+				return nil, err
+			}
+			missing = append(missing, dn)
+			continue
+		}
+
+		if entry.Disabled {
+			missing = append(missing, dn)
+		}
+	}
+	return missing, nil
+}
+
+/*
+CheckConnection connects to the server and binds with the service account
+*/
+func (c *Config) CheckConnection() error {
+	conn, err := c.connect()
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
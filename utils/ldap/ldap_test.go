@@ -0,0 +1,91 @@
+package ldap_test
+
+import (
+	"errors"
+	"testing"
+
+	"gitlab.com/codebox4073715/codebox/utils/ldap"
+)
+
+func TestBuildUserFilter(t *testing.T) {
+	cases := map[string]string{
+		"user@example.com": "(&(objectClass=person)(mail=user@example.com))",
+		"*)(uid=*":         "(&(objectClass=person)(mail=\\2a\\29\\28uid=\\2a))",
+	}
+
+	for login, expected := range cases {
+		if filter := ldap.BuildUserFilter("(&(objectClass=person)(mail=%s))", login); filter != expected {
+			t.Fatalf("BuildUserFilter(%q) = %q, expected %q", login, filter, expected)
+		}
+	}
+}
+
+func TestBuildAnyUserFilter(t *testing.T) {
+	filter := ldap.BuildAnyUserFilter("(&(objectClass=person)(sAMAccountName=%s))")
+	if filter != "(&(objectClass=person)(sAMAccountName=*))" {
+		t.Fatalf("unexpected filter %q", filter)
+	}
+}
+
+func TestIsAccountDisabled(t *testing.T) {
+	cases := map[string]bool{
+		"512": false, // normal account
+		"514": true,  // normal account, disabled
+		"2":   true,
+		"":    false, // not an Active Directory
+		"abc": false,
+	}
+
+	for value, expected := range cases {
+		if disabled := ldap.IsAccountDisabled(value); disabled != expected {
+			t.Fatalf("IsAccountDisabled(%q) = %v, expected %v", value, disabled, expected)
+		}
+	}
+}
+
+func TestGroupNames(t *testing.T) {
+	names := ldap.GroupNames([]string{
+		"cn=developers,ou=groups,dc=example,dc=com",
+		"CN=Domain Admins,CN=Users,DC=example,DC=com",
+		"not a dn",
+	})
+
+	if len(names) != 2 || names[0] != "developers" || names[1] != "Domain Admins" {
+		t.Fatalf("unexpected group names %v", names)
+	}
+}
+
+func TestValidateUrl(t *testing.T) {
+	for _, valid := range []string{"ldap://ldap.example.com", "ldaps://ldap.example.com:636"} {
+		if err := ldap.ValidateUrl(valid); err != nil {
+			t.Fatalf("ValidateUrl(%q) unexpected error: %v", valid, err)
+		}
+	}
+
+	for _, invalid := range []string{"", "http://ldap.example.com", "ldap://"} {
+		if err := ldap.ValidateUrl(invalid); err == nil {
+			t.Fatalf("ValidateUrl(%q) expected an error", invalid)
+		}
+	}
+}
+
+/*
+An empty password would be an unauthenticated bind, it must be refused
+before connecting to the server
+*/
+func TestAuthenticateEmptyPassword(t *testing.T) {
+	config := ldap.Config{
+		Url:        "ldap://127.0.0.1:1",
+		UserBaseDN: "dc=example,dc=com",
+		UserFilter: "(mail=%s)",
+	}
+
+	if _, err := config.Authenticate("user@example.com", ""); !errors.Is(err, ldap.ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() expected invalid credentials, got %v", err)
+	}
+
+	// the server is not reachable
+	if _, err := config.Authenticate("user@example.com", "password"); err == nil || errors.Is(err, ldap.ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() expected a connection error, got %v", err)
+	}
+}
@@ -0,0 +1,372 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signing algorithms accepted for id tokens
+var validSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// max size of the responses of the provider
+const maxResponseSize = 1 << 20
+
+/*
+ProviderConfiguration contains the endpoints of an OpenID Connect provider,
+retrieved from its discovery document
+*/
+type ProviderConfiguration struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+/*
+Client performs the authorization code flow with PKCE against an OpenID Connect provider
+*/
+type Client struct {
+	ClientID     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+	HttpClient   *http.Client
+	Provider     *ProviderConfiguration
+}
+
+/*
+TokenResponse is the response of the token endpoint
+*/
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Claims are the claims of an id token or of the userinfo endpoint
+type Claims map[string]any
+
+/*
+GenerateRandomString generates a random url safe string,
+used for states, nonces and code verifiers
+*/
+func GenerateRandomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+/*
+CodeChallenge returns the S256 PKCE code challenge of a code verifier
+*/
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func getHttpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func getJSON(client *http.Client, req *http.Request, v any) error {
+	res, err := getHttpClient(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("%s returned status code %d: %s", req.URL.String(), res.StatusCode, string(body))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+/*
+Discover retrieves the configuration of the provider from
+the discovery document of the issuer
+*/
+func Discover(httpClient *http.Client, issuerUrl string) (*ProviderConfiguration, error) {
+	issuerUrl = strings.TrimSuffix(issuerUrl, "/")
+
+	req, err := http.NewRequest(http.MethodGet, issuerUrl+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var provider ProviderConfiguration
+	if err := getJSON(httpClient, req, &provider); err != nil {
+		return nil, fmt.Errorf("cannot retrieve the configuration of the provider: %w", err)
+	}
+
+	if strings.TrimSuffix(provider.Issuer, "/") != issuerUrl {
+		return nil, fmt.Errorf("the issuer of the provider %s does not match %s", provider.Issuer, issuerUrl)
+	}
+
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JwksUri == "" {
+		return nil, errors.New("the configuration of the provider is not complete")
+	}
+	return &provider, nil
+}
+
+/*
+AuthorizationUrl returns the url the user is redirected to in order to authenticate
+*/
+func (c *Client) AuthorizationUrl(state string, nonce string, codeVerifier string) string {
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", c.ClientID)
+	query.Set("redirect_uri", c.RedirectUrl)
+	query.Set("scope", strings.Join(c.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(codeVerifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(c.Provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return c.Provider.AuthorizationEndpoint + separator + query.Encode()
+}
+
+/*
+ExchangeCode exchanges the authorization code returned by the provider for the tokens
+*/
+func (c *Client) ExchangeCode(code string, codeVerifier string) (*TokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.RedirectUrl)
+	form.Set("client_id", c.ClientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, c.Provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+
+	var token TokenResponse
+	if err := getJSON(c.HttpClient, req, &token); err != nil {
+		return nil, fmt.Errorf("cannot exchange the authorization code: %w", err)
+	}
+
+	if token.IDToken == "" {
+		return nil, errors.New("the provider did not return an id token")
+	}
+	return &token, nil
+}
+
+/*
+VerifyIDToken checks the signature of an id token with the keys of the provider,
+the issuer, the audience, the expiration and the nonce. The claims are returned
+*/
+func (c *Client) VerifyIDToken(rawIDToken string, nonce string) (Claims, error) {
+	keys, err := c.retrieveKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(
+		rawIDToken,
+		claims,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			if kid == "" && len(keys) == 1 {
+				for _, key := range keys {
+					return key, nil
+				}
+			}
+
+			key, ok := keys[kid]
+			if !ok {
+				return nil, fmt.Errorf("unknown signing key %s", kid)
+			}
+			return key, nil
+		},
+		jwt.WithValidMethods(validSigningMethods),
+		jwt.WithIssuer(c.Provider.Issuer),
+		jwt.WithAudience(c.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("invalid id token: nonce does not match")
+	}
+	return Claims(claims), nil
+}
+
+/*
+RetrieveUserInfo retrieves the claims of the user from the userinfo endpoint
+*/
+func (c *Client) RetrieveUserInfo(accessToken string) (Claims, error) {
+	req, err := http.NewRequest(http.MethodGet, c.Provider.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	claims := Claims{}
+	if err := getJSON(c.HttpClient, req, &claims); err != nil {
+		return nil, fmt.Errorf("cannot retrieve user info: %w", err)
+	}
+	return claims, nil
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// retrieveKeys retrieves the signing keys of the provider indexed by key id
+func (c *Client) retrieveKeys() (map[string]any, error) {
+	req, err := http.NewRequest(http.MethodGet, c.Provider.JwksUri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(c.HttpClient, req, &jwks); err != nil {
+		return nil, fmt.Errorf("cannot retrieve the keys of the provider: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJsonWebKey(jwk)
+		if err != nil {
+			// keys of unsupported types are ignored
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("the provider has no valid signing keys")
+	}
+	return keys, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parseJsonWebKey(jwk jsonWebKey) (any, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+/*
+GetString returns the value of a string claim, an empty string is returned
+if the claim does not exist or it is not a string
+*/
+func (c Claims) GetString(name string) string {
+	value, _ := c[name].(string)
+	return value
+}
+
+/*
+GetStringList returns the values of a claim that can be either
+a list of strings or a string of space or comma separated values
+*/
+func (c Claims) GetStringList(name string) []string {
+	values := []string{}
+	switch value := c[name].(type) {
+	case []any:
+		for _, v := range value {
+			if s, ok := v.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = append(values, value...)
+	case string:
+		values = append(values, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ' ' || r == ','
+		})...)
+	}
+	return values
+}
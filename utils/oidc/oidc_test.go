@@ -0,0 +1,179 @@
+package oidc_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/utils/oidc"
+	"gitlab.com/codebox4073715/codebox/utils/oidc/oidctest"
+)
+
+func newTestClient(t *testing.T, provider *oidctest.Provider) *oidc.Client {
+	configuration, err := oidc.Discover(nil, provider.Issuer())
+	if err != nil {
+		t.Fatalf("Discover() unexpected error: %v", err)
+	}
+
+	return &oidc.Client{
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectUrl:  "http://codebox.example.com/api/v1/auth/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+		Provider:     configuration,
+	}
+}
+
+// authorize follows the authorization url and returns the code sent to the redirect url
+func authorize(t *testing.T, client *oidc.Client, state string, nonce string, verifier string) string {
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	res, err := httpClient.Get(client.AuthorizationUrl(state, nonce, verifier))
+	if err != nil {
+		t.Fatalf("cannot reach the authorization endpoint: %v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Fatalf("unexpected status code %d from the authorization endpoint", res.StatusCode)
+	}
+
+	location, err := url.Parse(res.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+
+	if location.Query().Get("state") != state {
+		t.Fatalf("the state has not been returned")
+	}
+	return location.Query().Get("code")
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	provider := oidctest.NewProvider("codebox", "secret")
+	defer provider.Close()
+	provider.Claims = map[string]any{
+		"sub":    "1",
+		"email":  "user@example.com",
+		"groups": []string{"developers", "admins"},
+	}
+
+	client := newTestClient(t, provider)
+	verifier := oidc.GenerateRandomString()
+	code := authorize(t, client, "state", "nonce", verifier)
+
+	// the code cannot be exchanged without the verifier
+	if _, err := client.ExchangeCode(code, oidc.GenerateRandomString()); err == nil {
+		t.Fatalf("ExchangeCode() expected an error with a wrong code verifier")
+	}
+
+	code = authorize(t, client, "state", "nonce", verifier)
+	token, err := client.ExchangeCode(code, verifier)
+	if err != nil {
+		t.Fatalf("ExchangeCode() unexpected error: %v", err)
+	}
+
+	if _, err := client.VerifyIDToken(token.IDToken, "other-nonce"); err == nil {
+		t.Fatalf("VerifyIDToken() expected an error with a wrong nonce")
+	}
+
+	claims, err := client.VerifyIDToken(token.IDToken, "nonce")
+	if err != nil {
+		t.Fatalf("VerifyIDToken() unexpected error: %v", err)
+	}
+
+	if claims.GetString("email") != "user@example.com" {
+		t.Fatalf("unexpected email claim %q", claims.GetString("email"))
+	}
+
+	groups := claims.GetStringList("groups")
+	if len(groups) != 2 || groups[0] != "developers" || groups[1] != "admins" {
+		t.Fatalf("unexpected groups claim %v", groups)
+	}
+
+	userInfo, err := client.RetrieveUserInfo(token.AccessToken)
+	if err != nil {
+		t.Fatalf("RetrieveUserInfo() unexpected error: %v", err)
+	}
+
+	if userInfo.GetString("sub") != "1" {
+		t.Fatalf("unexpected sub claim %q", userInfo.GetString("sub"))
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider := oidctest.NewProvider("codebox", "secret")
+	defer provider.Close()
+
+	client := newTestClient(t, provider)
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss":   provider.Issuer(),
+			"aud":   "codebox",
+			"sub":   "1",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		}
+	}
+
+	if _, err := client.VerifyIDToken(provider.SignIDToken(validClaims()), "nonce"); err != nil {
+		t.Fatalf("VerifyIDToken() unexpected error: %v", err)
+	}
+
+	cases := map[string]func(claims map[string]any){
+		"wrong audience": func(claims map[string]any) { claims["aud"] = "other-client" },
+		"wrong issuer":   func(claims map[string]any) { claims["iss"] = "https://other.example.com" },
+		"expired":        func(claims map[string]any) { claims["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"no expiration":  func(claims map[string]any) { delete(claims, "exp") },
+	}
+
+	for name, edit := range cases {
+		claims := validClaims()
+		edit(claims)
+		if _, err := client.VerifyIDToken(provider.SignIDToken(claims), "nonce"); err == nil {
+			t.Fatalf("VerifyIDToken() expected an error for a token with %s", name)
+		}
+	}
+
+	// tokens signed by other keys are rejected
+	other := oidctest.NewProvider("codebox", "secret")
+	defer other.Close()
+	claims := validClaims()
+	if _, err := client.VerifyIDToken(other.SignIDToken(claims), "nonce"); err == nil {
+		t.Fatalf("VerifyIDToken() expected an error for a token signed by another key")
+	}
+}
+
+func TestDiscoverWrongIssuer(t *testing.T) {
+	provider := oidctest.NewProvider("codebox", "secret")
+	defer provider.Close()
+
+	if _, err := oidc.Discover(nil, provider.Issuer()+"/other"); err == nil {
+		t.Fatalf("Discover() expected an error for a wrong issuer")
+	}
+}
+
+func TestClaimsStringList(t *testing.T) {
+	claims := oidc.Claims{
+		"list":   []any{"a", "b", 1},
+		"string": "a b,c",
+	}
+
+	if values := claims.GetStringList("list"); len(values) != 2 {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	if values := claims.GetStringList("string"); len(values) != 3 {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	if values := claims.GetStringList("missing"); len(values) != 0 {
+		t.Fatalf("unexpected values %v", values)
+	}
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"gitlab.com/codebox4073715/codebox/config"
+)
+
+// returned when CODEBOX_SECRETS_MASTER_KEY is not set
+var ErrMasterKeyNotConfigured = errors.New("the secrets master key is not configured")
+
+// returned when a value cannot be decrypted with the current master key
+var ErrInvalidCiphertext = errors.New("failed to decrypt secret value")
+
+// check if the master key used to encrypt secrets is set
+func IsConfigured() bool {
+	return config.Environment != nil && config.Environment.SecretsMasterKey != ""
+}
+
+func getMasterKey() ([]byte, error) {
+	if !IsConfigured() {
+		return nil, ErrMasterKeyNotConfigured
+	}
+	return base64.StdEncoding.DecodeString(config.Environment.SecretsMasterKey)
+}
+
+/*
+Encrypt a value with AES-256-GCM using the master key, the
+returned string is the base64 encoded nonce followed by the ciphertext
+*/
+func Encrypt(plaintext string) (string, error) {
+	key, err := getMasterKey()
+	if err != nil {
+		return "", err
+	}
+	return encrypt(key, plaintext)
+}
+
+// decrypt a value encrypted with Encrypt
+func Decrypt(ciphertext string) (string, error) {
+	key, err := getMasterKey()
+	if err != nil {
+		return "", err
+	}
+	return decrypt(key, ciphertext)
+}
+
+func encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decrypt(key []byte, ciphertext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"gitlab.com/codebox4073715/codebox/config"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	config.Environment = &config.EnvVars{
+		SecretsMasterKey: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=",
+	}
+	defer func() { config.Environment = nil }()
+
+	ciphertext, err := Encrypt("my-secret-token")
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error: %v", err)
+	}
+
+	if ciphertext == "my-secret-token" {
+		t.Fatalf("Encrypt() returned the plaintext")
+	}
+
+	other, err := Encrypt("my-secret-token")
+	if err != nil {
+		t.Fatalf("Encrypt() unexpected error: %v", err)
+	}
+
+	if other == ciphertext {
+		t.Fatalf("Encrypt() returned the same ciphertext twice")
+	}
+
+	plaintext, err := Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt() unexpected error: %v", err)
+	}
+
+	if plaintext != "my-secret-token" {
+		t.Fatalf("Decrypt() = %s, want my-secret-token", plaintext)
+	}
+
+	// a value encrypted with another key cannot be decrypted
+	config.Environment.SecretsMasterKey = "YWJjZGVmMDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODk="
+	if _, err := Decrypt(ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Fatalf("Decrypt() error = %v, want %v", err, ErrInvalidCiphertext)
+	}
+}
+
+func TestMasterKeyNotConfigured(t *testing.T) {
+	config.Environment = &config.EnvVars{}
+	defer func() { config.Environment = nil }()
+
+	if IsConfigured() {
+		t.Fatalf("IsConfigured() = true, want false")
+	}
+
+	if _, err := Encrypt("value"); !errors.Is(err, ErrMasterKeyNotConfigured) {
+		t.Fatalf("Encrypt() error = %v, want %v", err, ErrMasterKeyNotConfigured)
+	}
+}
@@ -379,6 +379,11 @@ func (tgm *TarGZManager) RetrieveEntry(path string) (*TarEntry, error) {
 	return nil, nil
 }
 
+// WriteEntries replaces the content of the tar.gz archive with the entries
+func (tgm *TarGZManager) WriteEntries(entries []TarEntry) error {
+	return tgm.writeAll(entries)
+}
+
 // writeAll writes all files from the map into the tar.gz archive
 func (tgm *TarGZManager) writeAll(entries []TarEntry) error {
 	file, err := os.Create(tgm.Filepath)
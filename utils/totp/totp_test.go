@@ -0,0 +1,74 @@
+package totp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/codebox4073715/codebox/utils/totp"
+)
+
+// base32 of the ascii secret 12345678901234567890 used by the test vectors of RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	// last 6 digits of the SHA1 test vectors of RFC 6238
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := totp.GenerateCode(rfcSecret, time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateCode() unexpected error: %v", err)
+		}
+		if code != expected {
+			t.Fatalf("GenerateCode() at %d = %s, expected %s", unix, code, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := totp.GenerateSecret()
+	now := time.Now()
+
+	code, err := totp.GenerateCode(secret, now)
+	if err != nil {
+		t.Fatalf("GenerateCode() unexpected error: %v", err)
+	}
+
+	step, ok := totp.Validate(secret, code, now)
+	if !ok || step != totp.Step(now) {
+		t.Fatalf("Validate() refused the current code")
+	}
+
+	// codes of the previous period are accepted to tolerate clock drift
+	if _, ok := totp.Validate(secret, code, now.Add(totp.Period*time.Second)); !ok {
+		t.Fatalf("Validate() refused the code of the previous period")
+	}
+
+	if _, ok := totp.Validate(secret, code, now.Add(5*totp.Period*time.Second)); ok {
+		t.Fatalf("Validate() accepted an expired code")
+	}
+
+	for _, invalid := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := totp.Validate(secret, invalid, now); ok {
+			t.Fatalf("Validate() accepted the invalid code %q", invalid)
+		}
+	}
+}
+
+func TestProvisioningUri(t *testing.T) {
+	uri := totp.ProvisioningUri("Codebox", "user@example.com", rfcSecret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Codebox:user@example.com?") {
+		t.Fatalf("unexpected provisioning uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+rfcSecret) || !strings.Contains(uri, "issuer=Codebox") {
+		t.Fatalf("unexpected provisioning uri %s", uri)
+	}
+}